@@ -0,0 +1,446 @@
+package cmd
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"strings"
+	"text/tabwriter"
+
+	"github.com/LaneBirmingham/coding-agent-sync/internal/archive"
+	"github.com/LaneBirmingham/coding-agent-sync/internal/config"
+	"github.com/LaneBirmingham/coding-agent-sync/internal/keys"
+	"github.com/LaneBirmingham/coding-agent-sync/internal/sync"
+	"github.com/spf13/cobra"
+)
+
+func newArchiveCmd() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "archive",
+		Short: "Work with exported archives",
+	}
+
+	cmd.AddCommand(newArchiveVerifyCmd())
+	cmd.AddCommand(newArchiveUpgradeCmd())
+	cmd.AddCommand(newArchiveInspectCmd())
+	cmd.AddCommand(newArchiveLsCmd())
+	cmd.AddCommand(newArchiveCatCmd())
+	cmd.AddCommand(newArchiveDiffCmd())
+
+	return cmd
+}
+
+func newArchiveVerifyCmd() *cobra.Command {
+	return &cobra.Command{
+		Use:   "verify <archive>",
+		Short: "Check archive entries against their recorded checksums",
+		Args:  cobra.ExactArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			return doArchiveVerify(cmd.OutOrStdout(), args[0])
+		},
+	}
+}
+
+func doArchiveVerify(out io.Writer, path string) error {
+	report, err := archive.Verify(path)
+	if err != nil {
+		return err
+	}
+
+	if report.Version == archive.FormatVersionV1 {
+		return fmt.Errorf("%s is a v1 archive without checksums (run cas archive upgrade to add them)", path)
+	}
+	if !report.OK() {
+		for _, p := range report.Problems {
+			fmt.Fprintf(out, "FAIL %s\n", p)
+		}
+		return fmt.Errorf("%s failed verification (%d problem(s))", path, len(report.Problems))
+	}
+
+	fmt.Fprintf(out, "OK %s (format v%s, %d entries, %s)\n", path, report.Version, report.Entries, report.Digest)
+	if report.Encrypted {
+		fmt.Fprintln(out, "encrypted")
+	}
+	if report.SignedBy != "" {
+		fmt.Fprintf(out, "signed by %s\n", report.SignedBy)
+	} else {
+		fmt.Fprintln(out, "unsigned")
+	}
+	return nil
+}
+
+func newArchiveUpgradeCmd() *cobra.Command {
+	var (
+		flagOutput string
+		flagKeys   archiveKeyFlags
+		flagOpts   exportOptions
+	)
+
+	cmd := &cobra.Command{
+		Use:   "upgrade <archive>",
+		Short: "Convert an archive to the current format",
+		Long: `Rewrite an archive in the current format, adding per-entry checksums and an
+archive digest. Without --output the archive is upgraded in place.
+
+Rewriting drops a signature and would store encrypted content in the clear, so
+signed archives need --sign and encrypted ones need --encrypt or --recipient,
+along with the keys to decrypt them.`,
+		Args: cobra.ExactArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			return doArchiveUpgrade(args[0], flagOutput, flagKeys, flagOpts)
+		},
+	}
+
+	cmd.Flags().StringVarP(&flagOutput, "output", "o", "", "output path (defaults to rewriting the input)")
+	flagKeys.register(cmd)
+	cmd.Flags().StringVar(&flagOpts.SigningKey, "sign", "", "re-sign the archive with an ed25519 private key file")
+	cmd.Flags().BoolVar(&flagOpts.Encrypt, "encrypt", false, "re-encrypt the archive with the decryption passphrase")
+	cmd.Flags().StringArrayVar(&flagOpts.Recipients, "recipient", nil, "re-encrypt to an X25519 recipient (\"x25519 <key>\" or .pub file); repeatable")
+
+	return cmd
+}
+
+func doArchiveUpgrade(input, output string, f archiveKeyFlags, opts exportOptions) error {
+	if output == "" {
+		output = input
+	}
+
+	if flagVerbose {
+		fmt.Fprintf(os.Stderr, "verbose: archive upgrade input=%s output=%s\n", input, output)
+	}
+
+	ropts, err := readOptions(f)
+	if err != nil {
+		return err
+	}
+	var wopts archive.WriteOptions
+	if opts.SigningKey != "" {
+		if wopts.SigningKey, err = keys.LoadSigningKey(opts.SigningKey); err != nil {
+			return err
+		}
+	}
+	if opts.Encrypt {
+		if len(ropts.Passphrase) == 0 {
+			return fmt.Errorf("--encrypt needs a passphrase ($CAS_PASSPHRASE or --passphrase-file)")
+		}
+		wopts.Passphrase = ropts.Passphrase
+	}
+	for _, r := range opts.Recipients {
+		recipient, err := keys.LoadRecipient(r)
+		if err != nil {
+			return err
+		}
+		wopts.Recipients = append(wopts.Recipients, recipient.Key)
+	}
+
+	a, err := archive.Upgrade(input, output, ropts, wopts)
+	if err != nil {
+		return err
+	}
+
+	fmt.Fprintf(os.Stderr, "archive written to %s (format v%s, %s)\n", output, a.Manifest.Version, a.Manifest.Digest)
+	return nil
+}
+
+// archiveKeyFlags holds the decryption flags shared by commands that read archives.
+type archiveKeyFlags struct {
+	Identities     []string
+	PassphraseFile string
+}
+
+func (f *archiveKeyFlags) register(cmd *cobra.Command) {
+	cmd.Flags().StringArrayVar(&f.Identities, "identity", nil, "X25519 identity file for encrypted archives; repeatable")
+	cmd.Flags().StringVar(&f.PassphraseFile, "passphrase-file", "", "read the decryption passphrase from a file (default $CAS_PASSPHRASE)")
+}
+
+// readArchive reads an archive, decrypting it with the keys named in f.
+func readArchive(path string, f archiveKeyFlags) (*archive.Archive, error) {
+	opts, err := readOptions(f)
+	if err != nil {
+		return nil, err
+	}
+	return archive.ReadWith(path, opts)
+}
+
+// readOptions loads the decryption keys named in f.
+func readOptions(f archiveKeyFlags) (archive.ReadOptions, error) {
+	passphrase, err := readPassphrase(f.PassphraseFile)
+	if err != nil {
+		return archive.ReadOptions{}, err
+	}
+	opts := archive.ReadOptions{Passphrase: []byte(passphrase)}
+	for _, p := range f.Identities {
+		id, err := keys.LoadIdentity(p)
+		if err != nil {
+			return archive.ReadOptions{}, err
+		}
+		opts.Identities = append(opts.Identities, id)
+	}
+	return opts, nil
+}
+
+func newArchiveInspectCmd() *cobra.Command {
+	var flagKeys archiveKeyFlags
+
+	cmd := &cobra.Command{
+		Use:   "inspect <archive>",
+		Short: "Show an archive's manifest, instructions and skills",
+		Args:  cobra.ExactArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			return doArchiveInspect(cmd.OutOrStdout(), args[0], flagKeys)
+		},
+	}
+
+	flagKeys.register(cmd)
+
+	return cmd
+}
+
+func doArchiveInspect(out io.Writer, path string, keyFlags archiveKeyFlags) error {
+	a, err := readArchive(path, keyFlags)
+	if err != nil {
+		return err
+	}
+	m := a.Manifest
+
+	tw := tabwriter.NewWriter(out, 0, 0, 2, ' ', 0)
+	fmt.Fprintf(tw, "archive:\t%s\n", path)
+	fmt.Fprintf(tw, "format:\tv%s\n", m.Version)
+	if a.IsBundle() {
+		names := make([]string, 0, len(m.Sections))
+		for _, s := range m.Sections {
+			names = append(names, s.String())
+		}
+		fmt.Fprintf(tw, "sections:\t%s\n", strings.Join(names, ", "))
+	} else {
+		fmt.Fprintf(tw, "agent:\t%s\n", m.Agent)
+		fmt.Fprintf(tw, "scope:\t%s\n", m.Scope)
+	}
+	fmt.Fprintf(tw, "exported at:\t%s\n", m.ExportedAt.Format("2006-01-02 15:04:05 MST"))
+	if m.CASVersion != "" {
+		fmt.Fprintf(tw, "cas version:\t%s\n", m.CASVersion)
+	}
+	if m.Package != nil {
+		fmt.Fprintf(tw, "package:\t%s\n", m.Package)
+	}
+	if m.Digest != "" {
+		fmt.Fprintf(tw, "digest:\t%s\n", m.Digest)
+	}
+	if m.Delta != nil {
+		fmt.Fprintf(tw, "delta base:\t%s\n", m.Delta.BaseDigest)
+	}
+	if m.Encrypted() {
+		fmt.Fprintf(tw, "encrypted:\t%s (%d recipient(s))\n", m.Encryption.Scheme, len(m.Encryption.Recipients))
+	}
+	if a.Signature != nil {
+		fmt.Fprintf(tw, "signed by:\t%s (not checked against trusted keys)\n", a.Signature.KeyID)
+	} else {
+		fmt.Fprintf(tw, "signed by:\t-\n")
+	}
+	if err := tw.Flush(); err != nil {
+		return err
+	}
+
+	for _, sec := range a.Contents() {
+		fmt.Fprintf(out, "\n[%s]\n", sec.SectionInfo)
+		if sec.Instructions != nil && sec.Instructions.Content != "" {
+			fmt.Fprintf(out, "instructions: %d bytes\n", len(sec.Instructions.Content))
+		} else {
+			fmt.Fprintln(out, "instructions: none")
+		}
+		fmt.Fprintf(out, "skills: %d\n", len(sec.Skills))
+		tw := tabwriter.NewWriter(out, 0, 0, 2, ' ', 0)
+		for _, skill := range sec.Skills {
+			desc := skill.Description()
+			if desc == "" {
+				desc = "-"
+			}
+			fmt.Fprintf(tw, "  %s\t%s\n", skill.Name, desc)
+		}
+		if err := tw.Flush(); err != nil {
+			return err
+		}
+		if m.Delta == nil {
+			continue
+		}
+		if ds := m.Delta.Section(sec.SectionInfo); ds != nil {
+			if ds.DeleteInstructions {
+				fmt.Fprintln(out, "deletes instructions")
+			}
+			if len(ds.DeletedSkills) > 0 {
+				fmt.Fprintf(out, "deletes skills: %s\n", strings.Join(ds.DeletedSkills, ", "))
+			}
+		}
+	}
+	return nil
+}
+
+func newArchiveLsCmd() *cobra.Command {
+	var flagKeys archiveKeyFlags
+
+	cmd := &cobra.Command{
+		Use:   "ls <archive>",
+		Short: "List the entries in an archive",
+		Args:  cobra.ExactArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			return doArchiveLs(cmd.OutOrStdout(), args[0], flagKeys)
+		},
+	}
+
+	flagKeys.register(cmd)
+
+	return cmd
+}
+
+func doArchiveLs(out io.Writer, path string, keyFlags archiveKeyFlags) error {
+	a, err := readArchive(path, keyFlags)
+	if err != nil {
+		return err
+	}
+
+	for _, name := range a.EntryNames() {
+		data, err := a.Entry(name)
+		if err != nil {
+			return err
+		}
+		fmt.Fprintf(out, "%8d  %s\n", len(data), name)
+	}
+	return nil
+}
+
+func newArchiveCatCmd() *cobra.Command {
+	var flagKeys archiveKeyFlags
+
+	cmd := &cobra.Command{
+		Use:   "cat <archive> <entry>",
+		Short: "Print a single archive entry",
+		Long:  "Print one entry from an archive, for example cas archive cat team.zip skills/foo/SKILL.md. Use cas archive ls to list entry names.",
+		Args:  cobra.ExactArgs(2),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			return doArchiveCat(cmd.OutOrStdout(), args[0], args[1], flagKeys)
+		},
+	}
+
+	flagKeys.register(cmd)
+
+	return cmd
+}
+
+func doArchiveCat(out io.Writer, path, name string, keyFlags archiveKeyFlags) error {
+	a, err := readArchive(path, keyFlags)
+	if err != nil {
+		return err
+	}
+
+	data, err := a.Entry(name)
+	if err != nil {
+		return err
+	}
+	_, err = out.Write(data)
+	return err
+}
+
+func newArchiveDiffCmd() *cobra.Command {
+	var (
+		flagAgent   string
+		flagScope   string
+		flagSection string
+		flagKeys    archiveKeyFlags
+	)
+
+	cmd := &cobra.Command{
+		Use:   "diff <old> <new> | diff <archive> --agent <agent>",
+		Short: "Compare two archives, or an archive with an agent's current config",
+		Long: `Show added, removed and changed skills and instruction diffs between two
+archives. With --agent, compare the archive with the agent's current config
+at --scope, showing what cas import would change; skills only the agent has
+are listed as kept, since import never deletes them. For bundles, --section
+picks the section to compare (default: the one matching --agent and --scope).`,
+		Args: cobra.RangeArgs(1, 2),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			return doArchiveDiff(cmd.OutOrStdout(), args, flagAgent, flagScope, flagSection, flagKeys)
+		},
+	}
+
+	cmd.Flags().StringVar(&flagAgent, "agent", "", "compare with this agent's current config instead of a second archive")
+	cmd.Flags().StringVar(&flagScope, "scope", "local", "scope of the agent config (local, global)")
+	cmd.Flags().StringVar(&flagSection, "section", "", "bundle section to compare with --agent (agent/scope)")
+	flagKeys.register(cmd)
+
+	return cmd
+}
+
+func doArchiveDiff(out io.Writer, args []string, agentStr, scopeStr, sectionStr string, keyFlags archiveKeyFlags) error {
+	if (len(args) == 2) == (agentStr != "") {
+		return fmt.Errorf("specify either two archives or one archive and --agent")
+	}
+	if agentStr == "" && sectionStr != "" {
+		return fmt.Errorf("--section requires --agent")
+	}
+
+	a, err := readArchive(args[0], keyFlags)
+	if err != nil {
+		return err
+	}
+
+	var diffs []sync.SectionDiff
+	if agentStr != "" {
+		dest := config.Section{}
+		if dest.Agent, err = config.ParseAgent(agentStr); err != nil {
+			return err
+		}
+		if dest.Scope, err = config.ParseScope(scopeStr); err != nil {
+			return err
+		}
+		var from config.Section
+		if sectionStr != "" {
+			if from, err = config.ParseSection(sectionStr); err != nil {
+				return err
+			}
+		}
+		root, err := projectRoot()
+		if err != nil {
+			return err
+		}
+		d, err := sync.DiffAgent(args[0], a, from, dest, root)
+		if err != nil {
+			return err
+		}
+		diffs = append(diffs, d)
+	} else {
+		b, err := readArchive(args[1], keyFlags)
+		if err != nil {
+			return err
+		}
+		diffs = sync.DiffArchives(args[0], a, args[1], b)
+	}
+
+	for i, d := range diffs {
+		if i > 0 {
+			fmt.Fprintln(out)
+		}
+		printSectionDiff(out, d)
+	}
+	return nil
+}
+
+func printSectionDiff(out io.Writer, d sync.SectionDiff) {
+	fmt.Fprintf(out, "=== %s -> %s\n", d.Old, d.New)
+	if d.Empty() {
+		fmt.Fprintln(out, "no differences")
+	}
+	if d.Instructions != "" {
+		fmt.Fprintln(out, "instructions: changed")
+	}
+	for _, s := range d.Skills {
+		fmt.Fprintf(out, "skill %s: %s\n", s.Name, s.Status)
+	}
+	if d.Instructions != "" {
+		fmt.Fprintf(out, "\n%s", d.Instructions)
+	}
+	for _, s := range d.Skills {
+		if s.Diff != "" {
+			fmt.Fprintf(out, "\n%s", s.Diff)
+		}
+	}
+}
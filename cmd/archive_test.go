@@ -0,0 +1,127 @@
+package cmd
+
+import (
+	"bytes"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/LaneBirmingham/coding-agent-sync/internal/agent"
+	"github.com/LaneBirmingham/coding-agent-sync/internal/archive"
+)
+
+func TestArchiveUpgradeThenVerify(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "v1.zip")
+	if err := archive.Write(path, &archive.Archive{
+		Manifest: &archive.Manifest{
+			Version:    archive.FormatVersionV1,
+			Agent:      "claude",
+			Scope:      "local",
+			ExportedAt: time.Now().UTC(),
+		},
+		Instructions: &agent.Instruction{Content: "# A"},
+	}); err != nil {
+		t.Fatal(err)
+	}
+
+	var out bytes.Buffer
+	if err := doArchiveVerify(&out, path); err == nil {
+		t.Fatal("expected v1 archive to fail verification")
+	}
+
+	if err := doArchiveUpgrade(path, "", archiveKeyFlags{}, exportOptions{}); err != nil {
+		t.Fatalf("upgrade: %v", err)
+	}
+
+	out.Reset()
+	if err := doArchiveVerify(&out, path); err != nil {
+		t.Fatalf("expected upgraded archive to verify, got %v", err)
+	}
+	if !strings.HasPrefix(out.String(), "OK ") {
+		t.Fatalf("unexpected verify output %q", out.String())
+	}
+}
+
+func TestArchiveInspectLsCat(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "team.zip")
+	skill := "---\nname: review\ndescription: Review pull requests\n---\nSteps"
+	if err := archive.Write(path, &archive.Archive{
+		Manifest: &archive.Manifest{
+			Version:    archive.FormatVersion,
+			Agent:      "claude",
+			Scope:      "local",
+			ExportedAt: time.Now().UTC(),
+		},
+		Instructions: &agent.Instruction{Content: "# Team"},
+		Skills:       []agent.Skill{{Name: "review", Content: skill}},
+	}); err != nil {
+		t.Fatal(err)
+	}
+
+	var out bytes.Buffer
+	if err := doArchiveInspect(&out, path, archiveKeyFlags{}); err != nil {
+		t.Fatal(err)
+	}
+	for _, want := range []string{"[claude/local]", "instructions: 6 bytes", "review  Review pull requests"} {
+		if !strings.Contains(out.String(), want) {
+			t.Errorf("inspect output missing %q:\n%s", want, out.String())
+		}
+	}
+
+	out.Reset()
+	if err := doArchiveLs(&out, path, archiveKeyFlags{}); err != nil {
+		t.Fatal(err)
+	}
+	for _, want := range []string{"manifest.json", "6  instructions.md", "skills/review/SKILL.md"} {
+		if !strings.Contains(out.String(), want) {
+			t.Errorf("ls output missing %q:\n%s", want, out.String())
+		}
+	}
+
+	out.Reset()
+	if err := doArchiveCat(&out, path, "skills/review/SKILL.md", archiveKeyFlags{}); err != nil {
+		t.Fatal(err)
+	}
+	if out.String() != skill {
+		t.Fatalf("unexpected cat output %q", out.String())
+	}
+	if err := doArchiveCat(&out, path, "skills/missing/SKILL.md", archiveKeyFlags{}); err == nil {
+		t.Fatal("expected error for missing entry")
+	}
+}
+
+func TestArchiveDiff(t *testing.T) {
+	dir := t.TempDir()
+	write := func(name, inst string) string {
+		path := filepath.Join(dir, name)
+		if err := archive.Write(path, &archive.Archive{
+			Manifest: &archive.Manifest{
+				Version:    archive.FormatVersion,
+				Agent:      "claude",
+				Scope:      "local",
+				ExportedAt: time.Now().UTC(),
+			},
+			Instructions: &agent.Instruction{Content: inst},
+		}); err != nil {
+			t.Fatal(err)
+		}
+		return path
+	}
+	old, new := write("old.zip", "# A\n"), write("new.zip", "# B\n")
+
+	var out bytes.Buffer
+	if err := doArchiveDiff(&out, []string{old, new}, "", "local", "", archiveKeyFlags{}); err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(out.String(), "instructions: changed") || !strings.Contains(out.String(), "-# A\n+# B\n") {
+		t.Fatalf("unexpected diff output:\n%s", out.String())
+	}
+
+	if err := doArchiveDiff(&out, []string{old, new}, "codex", "local", "", archiveKeyFlags{}); err == nil {
+		t.Fatal("expected two archives with --agent to fail")
+	}
+	if err := doArchiveDiff(&out, []string{old}, "", "local", "", archiveKeyFlags{}); err == nil {
+		t.Fatal("expected one archive without --agent to fail")
+	}
+}
@@ -0,0 +1,139 @@
+package cmd
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"slices"
+	"strings"
+	"text/tabwriter"
+
+	"github.com/LaneBirmingham/coding-agent-sync/internal/config"
+	"github.com/LaneBirmingham/coding-agent-sync/internal/detect"
+	"github.com/spf13/cobra"
+)
+
+const (
+	sourceAuto     = "auto"
+	targetAll      = "all"
+	targetDetected = "detected"
+)
+
+func newDetectCmd() *cobra.Command {
+	var flagJSON bool
+
+	cmd := &cobra.Command{
+		Use:   "detect",
+		Short: "List agents with config in the project and home directory",
+		Long:  "Detect which agents have instructions or skills present at local and global scope, with paths and item counts.",
+		Args:  cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			return doDetect(cmd.OutOrStdout(), flagJSON)
+		},
+	}
+
+	cmd.Flags().BoolVar(&flagJSON, "json", false, "print results as JSON")
+
+	return cmd
+}
+
+func doDetect(out io.Writer, asJSON bool) error {
+	root, err := projectRoot()
+	if err != nil {
+		return err
+	}
+
+	found, err := detect.All(root)
+	if err != nil {
+		return err
+	}
+
+	if asJSON {
+		if found == nil {
+			found = []detect.Found{}
+		}
+		enc := json.NewEncoder(out)
+		enc.SetIndent("", "  ")
+		return enc.Encode(found)
+	}
+
+	if len(found) == 0 {
+		fmt.Fprintln(out, "no agent config detected")
+		return nil
+	}
+
+	tw := tabwriter.NewWriter(out, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(tw, "AGENT\tSCOPE\tINSTRUCTIONS\tSKILLS")
+	for _, f := range found {
+		inst, skills := "-", "-"
+		if f.Instructions != nil {
+			inst = fmt.Sprintf("%s (%d bytes)", f.Instructions.Path, f.Instructions.Size)
+		}
+		if f.Skills != nil {
+			skills = fmt.Sprintf("%s (%d skill(s))", f.Skills.Path, f.Skills.Count)
+		}
+		fmt.Fprintf(tw, "%s\t%s\t%s\t%s\n", f.Agent, f.Scope, inst, skills)
+	}
+	return tw.Flush()
+}
+
+// resolveSource parses a --from value, selecting the agent with the most
+// recently modified instructions at loc when given "auto".
+func resolveSource(s string, loc config.Location) (config.Agent, error) {
+	if !strings.EqualFold(strings.TrimSpace(s), sourceAuto) {
+		return config.ParseAgent(s)
+	}
+	a, err := detect.MostRecent(loc)
+	if err != nil {
+		return "", fmt.Errorf("--from auto: %w", err)
+	}
+	fmt.Fprintf(os.Stderr, "using %s as source (most recently modified instructions)\n", a)
+	return a, nil
+}
+
+// resolveTargets parses a comma-separated --to value. The keywords "all" and
+// "detected" expand to every known agent and to agents with their own config
+// at scope, or at either scope when scope is empty.
+func resolveTargets(s string, root string, scope config.Scope) ([]config.Agent, error) {
+	var targets []config.Agent
+	for _, part := range strings.Split(s, ",") {
+		part = strings.ToLower(strings.TrimSpace(part))
+		var agents []config.Agent
+		switch part {
+		case "":
+			continue
+		case targetAll:
+			agents = config.Agents()
+		case targetDetected:
+			detected, err := detect.Agents(root, scope)
+			if err != nil {
+				return nil, err
+			}
+			agents = detected
+		default:
+			a, err := config.ParseAgent(part)
+			if err != nil {
+				return nil, err
+			}
+			agents = []config.Agent{a}
+		}
+		for _, a := range agents {
+			if !slices.Contains(targets, a) {
+				targets = append(targets, a)
+			}
+		}
+	}
+	return targets, nil
+}
+
+// isTargetKeyword reports whether a --to value includes all or detected.
+func isTargetKeyword(s string) bool {
+	for _, part := range strings.Split(s, ",") {
+		part = strings.ToLower(strings.TrimSpace(part))
+		if part == targetAll || part == targetDetected {
+			return true
+		}
+	}
+	return false
+}
@@ -18,12 +18,12 @@ func newDiffCmd() *cobra.Command {
 		Use:   "diff",
 		Short: "Preview sync changes (alias for sync --dry-run)",
 		RunE: func(cmd *cobra.Command, args []string) error {
-			return doSync(sync.All, flagFrom, flagTo, true, flagScope, flagFromScope, flagToScope)
+			return doSync(sync.All, flagFrom, flagTo, true, flagScope, flagFromScope, flagToScope, syncMode{})
 		},
 	}
 
-	cmd.Flags().StringVar(&flagFrom, "from", "", "source agent")
-	cmd.Flags().StringVar(&flagTo, "to", "", "destination agent(s)")
+	cmd.Flags().StringVar(&flagFrom, "from", "", "source agent, or auto")
+	cmd.Flags().StringVar(&flagTo, "to", "", "destination agent(s), or all/detected")
 	cmd.Flags().StringVar(&flagScope, "scope", "", "set both from and to scope (local, global)")
 	cmd.Flags().StringVar(&flagFromScope, "from-scope", "", "source scope (overrides --scope)")
 	cmd.Flags().StringVar(&flagToScope, "to-scope", "", "destination scope (overrides --scope)")
@@ -3,8 +3,12 @@ package cmd
 import (
 	"fmt"
 	"os"
+	"slices"
+	"strconv"
+	"strings"
 	"time"
 
+	"github.com/LaneBirmingham/coding-agent-sync/internal/archive"
 	"github.com/LaneBirmingham/coding-agent-sync/internal/config"
 	"github.com/LaneBirmingham/coding-agent-sync/internal/sync"
 	"github.com/spf13/cobra"
@@ -16,48 +20,152 @@ func newExportCmd() *cobra.Command {
 		flagScope  string
 		flagOutput string
 		flagDryRun bool
+		flagOpts   exportOptions
 	)
 
 	cmd := &cobra.Command{
 		Use:   "export",
-		Short: "Export agent config to a ZIP archive",
-		Long:  "Export instructions and skills from an agent to a portable ZIP archive.",
+		Short: "Export agent config to an archive",
+		Long:  "Export instructions and skills from an agent to a portable archive (ZIP, tar.gz or a directory of plain files; -o - streams to stdout). Several agents or scopes (--from claude,codex --scope local,global) produce a single bundle with one section per agent and scope.",
 		RunE: func(cmd *cobra.Command, args []string) error {
-			return doExport(flagFrom, flagScope, flagOutput, flagDryRun)
+			return doExport(flagFrom, flagScope, flagOutput, flagDryRun, flagOpts)
 		},
 	}
 
-	cmd.Flags().StringVar(&flagFrom, "from", "", "source agent (claude, copilot, codex, opencode)")
-	cmd.Flags().StringVarP(&flagScope, "scope", "", "local", "scope (local, global)")
-	cmd.Flags().StringVarP(&flagOutput, "output", "o", "", "output ZIP path (auto-generated if omitted)")
+	cmd.Flags().StringVar(&flagFrom, "from", "", "source agent(s), comma-separated (claude, copilot, codex, opencode, gemini, or auto)")
+	cmd.Flags().StringVarP(&flagScope, "scope", "", "local", "scope(s), comma-separated (local, global)")
+	cmd.Flags().StringVarP(&flagOutput, "output", "o", "", "output path, or - for stdout (auto-generated if omitted)")
+	cmd.Flags().StringVar(&flagOpts.Format, "format", "", "archive format (zip, tar.gz, dir); inferred from the output path, default zip")
 	cmd.Flags().BoolVar(&flagDryRun, "dry-run", false, "preview export without writing")
+	cmd.Flags().StringVar(&flagOpts.SigningKey, "sign", "", "sign the archive with an ed25519 private key file (see cas keygen)")
+	cmd.Flags().BoolVar(&flagOpts.Encrypt, "encrypt", false, "encrypt the archive (passphrase from $CAS_PASSPHRASE or --passphrase-file, or --recipient)")
+	cmd.Flags().StringArrayVar(&flagOpts.Recipients, "recipient", nil, "encrypt to an X25519 recipient (\"x25519 <key>\" or .pub file); repeatable")
+	cmd.Flags().StringVar(&flagOpts.PassphraseFile, "passphrase-file", "", "read the encryption passphrase from a file")
+	cmd.Flags().StringVar(&flagOpts.Since, "since", "", "write a delta holding only the changes since this base archive")
+	cmd.Flags().BoolVar(&flagOpts.Reproducible, "reproducible", false, "write byte-identical archives for identical content (timestamps from $SOURCE_DATE_EPOCH, default 1980-01-01)")
 
 	_ = cmd.MarkFlagRequired("from")
 
 	return cmd
 }
 
-func doExport(fromStr, scopeStr, output string, dryRun bool) error {
-	from, err := config.ParseAgent(fromStr)
+// exportOptions holds optional export settings.
+type exportOptions struct {
+	SigningKey     string
+	Encrypt        bool
+	Recipients     []string
+	PassphraseFile string
+	Format         string
+	Reproducible   bool
+	Since          string // base archive for a delta export
+	PackageName    string
+	PackageVersion string
+}
+
+// defaultSourceDate is the timestamp of reproducible exports when
+// $SOURCE_DATE_EPOCH is unset: the earliest time a ZIP entry can record.
+var defaultSourceDate = time.Date(1980, 1, 1, 0, 0, 0, 0, time.UTC)
+
+// sourceDate returns the time from $SOURCE_DATE_EPOCH (seconds since the Unix
+// epoch), or defaultSourceDate when it is unset.
+func sourceDate() (time.Time, error) {
+	s := strings.TrimSpace(os.Getenv("SOURCE_DATE_EPOCH"))
+	if s == "" {
+		return defaultSourceDate, nil
+	}
+	secs, err := strconv.ParseInt(s, 10, 64)
+	if err != nil || secs < 0 {
+		return time.Time{}, fmt.Errorf("invalid SOURCE_DATE_EPOCH %q (expected seconds since the Unix epoch)", s)
+	}
+	t := time.Unix(secs, 0).UTC()
+	if t.Before(defaultSourceDate) {
+		// ZIP cannot record times before 1980.
+		t = defaultSourceDate
+	}
+	return t, nil
+}
+
+// readPassphrase returns the passphrase from file, or from $CAS_PASSPHRASE when file is empty.
+func readPassphrase(file string) (string, error) {
+	if file == "" {
+		return os.Getenv("CAS_PASSPHRASE"), nil
+	}
+	data, err := os.ReadFile(file)
 	if err != nil {
-		return err
+		return "", fmt.Errorf("reading passphrase: %w", err)
 	}
+	return strings.TrimRight(string(data), "\r\n"), nil
+}
 
-	scope, err := config.ParseScope(scopeStr)
+func doExport(fromStr, scopeStr, output string, dryRun bool, opts exportOptions) error {
+	var scopes []config.Scope
+	for _, part := range strings.Split(scopeStr, ",") {
+		scope, err := config.ParseScope(strings.TrimSpace(part))
+		if err != nil {
+			return err
+		}
+		if !slices.Contains(scopes, scope) {
+			scopes = append(scopes, scope)
+		}
+	}
+
+	root, err := projectRoot()
 	if err != nil {
 		return err
 	}
 
-	root := flagRoot
-	if root == "" || root == "." {
-		root, err = os.Getwd()
-		if err != nil {
-			return fmt.Errorf("getting working directory: %w", err)
+	var sections []config.Section
+	for _, scope := range scopes {
+		for _, part := range strings.Split(fromStr, ",") {
+			from, err := resolveSource(part, config.Location{Root: root, Scope: scope})
+			if err != nil {
+				return err
+			}
+			sec := config.Section{Agent: from, Scope: scope}
+			if !slices.Contains(sections, sec) {
+				sections = append(sections, sec)
+			}
+		}
+	}
+	from, scope := sections[0].Agent, sections[0].Scope
+	bundle := len(sections) > 1
+
+	var date time.Time
+	if opts.Reproducible {
+		if opts.Encrypt {
+			return fmt.Errorf("--reproducible cannot be combined with --encrypt")
+		}
+		if date, err = sourceDate(); err != nil {
+			return err
+		}
+	}
+
+	var passphrase string
+	if opts.Encrypt && len(opts.Recipients) == 0 {
+		if passphrase, err = readPassphrase(opts.PassphraseFile); err != nil {
+			return err
 		}
+		if passphrase == "" {
+			return fmt.Errorf("--encrypt needs a passphrase ($CAS_PASSPHRASE or --passphrase-file) or --recipient")
+		}
+	}
+
+	format := archive.FormatZip
+	if opts.Format != "" {
+		if format, err = archive.ParseFormat(opts.Format); err != nil {
+			return err
+		}
+	} else if output != "" && output != config.StdioPath {
+		format = archive.FormatForPath(output)
+	}
+	if output == config.StdioPath && format == archive.FormatDir {
+		return fmt.Errorf("the dir format cannot be written to stdout")
 	}
 
-	if output == "" {
-		output = fmt.Sprintf("%s-%s-%s.zip", from, scope, time.Now().Format("20060102T150405"))
+	if output == "" && bundle {
+		output = fmt.Sprintf("bundle-%s%s", time.Now().Format("20060102T150405"), format.Ext())
+	} else if output == "" {
+		output = fmt.Sprintf("%s-%s-%s%s", from, scope, time.Now().Format("20060102T150405"), format.Ext())
 	}
 
 	cfg := &config.ExportConfig{
@@ -65,12 +173,28 @@ func doExport(fromStr, scopeStr, output string, dryRun bool) error {
 		Root:       root,
 		Scope:      scope,
 		Output:     output,
+		Format:     string(format),
 		DryRun:     dryRun,
 		CASVersion: Version,
+		SigningKey: opts.SigningKey,
+		Passphrase: passphrase,
+		Recipients: opts.Recipients,
+		SourceDate: date,
+		Since:      opts.Since,
+
+		PackageName:    opts.PackageName,
+		PackageVersion: opts.PackageVersion,
+	}
+	if bundle {
+		cfg.Sections = sections
 	}
 
 	if flagVerbose {
-		fmt.Fprintf(os.Stderr, "verbose: export from=%s scope=%s root=%s output=%s dry-run=%t\n", cfg.From, cfg.Scope, cfg.Root, cfg.Output, cfg.DryRun)
+		names := make([]string, 0, len(sections))
+		for _, sec := range sections {
+			names = append(names, sec.String())
+		}
+		fmt.Fprintf(os.Stderr, "verbose: export sections=%s root=%s output=%s dry-run=%t\n", strings.Join(names, ","), cfg.Root, cfg.Output, cfg.DryRun)
 	}
 
 	result, err := sync.Export(cfg)
@@ -79,12 +203,34 @@ func doExport(fromStr, scopeStr, output string, dryRun bool) error {
 	}
 
 	for _, action := range result.Actions {
-		fmt.Println(action)
+		// Keep stdout clean when the archive itself is streamed there.
+		if output == config.StdioPath {
+			fmt.Fprintln(os.Stderr, action)
+		} else {
+			fmt.Println(action)
+		}
 	}
 
 	if !dryRun {
-		fmt.Fprintf(os.Stderr, "archive written to %s\n", output)
+		fmt.Fprintf(os.Stderr, "archive written to %s (%s)\n", outputLabel(output), format)
+		if opts.Since != "" {
+			fmt.Fprintf(os.Stderr, "archive is a delta against %s\n", opts.Since)
+		}
+		if opts.SigningKey != "" {
+			fmt.Fprintf(os.Stderr, "archive signed with %s\n", opts.SigningKey)
+		}
+		if passphrase != "" || len(opts.Recipients) > 0 {
+			fmt.Fprintf(os.Stderr, "archive encrypted (%s)\n", archive.EncryptionScheme)
+		}
 	}
 
 	return nil
 }
+
+// outputLabel names an output path for messages, describing - as stdout.
+func outputLabel(path string) string {
+	if path == config.StdioPath {
+		return "stdout"
+	}
+	return path
+}
@@ -0,0 +1,246 @@
+package cmd
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/LaneBirmingham/coding-agent-sync/internal/config"
+	"github.com/LaneBirmingham/coding-agent-sync/internal/hooks"
+	"github.com/LaneBirmingham/coding-agent-sync/internal/sync"
+	"github.com/spf13/cobra"
+)
+
+const hookName = "pre-commit"
+
+// hookOptions selects what the pre-commit hook checks or syncs.
+type hookOptions struct {
+	From      string
+	To        string
+	Scope     string
+	FromScope string
+	ToScope   string
+	Sync      bool // sync instead of only checking for drift
+	NoStage   bool // with Sync, leave the synced files unstaged
+	Mode      syncMode
+}
+
+// args returns the hooks run arguments that reproduce o.
+func (o hookOptions) args() []string {
+	args := []string{"--from", o.From, "--to", o.To}
+	for _, f := range []struct{ name, value string }{{"--scope", o.Scope}, {"--from-scope", o.FromScope}, {"--to-scope", o.ToScope}} {
+		if f.value != "" {
+			args = append(args, f.name, f.value)
+		}
+	}
+	if o.Mode.Merge != "" && o.Mode.Merge != string(config.MergeConcat) {
+		args = append(args, "--merge", o.Mode.Merge)
+	}
+	if o.Mode.OnCollide != "" && o.Mode.OnCollide != string(config.ConflictPriority) {
+		args = append(args, "--skill-conflict", o.Mode.OnCollide)
+	}
+	if o.Mode.Managed {
+		args = append(args, "--managed-block", "--block-position", o.Mode.Position)
+	}
+	if o.Sync {
+		args = append(args, "--sync")
+	}
+	if o.NoStage {
+		args = append(args, "--no-stage")
+	}
+	return args
+}
+
+func newHooksCmd() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "hooks",
+		Short: "Manage the git pre-commit hook that keeps agent config in sync",
+	}
+	cmd.AddCommand(newHooksInstallCmd(), newHooksUninstallCmd(), newHooksRunCmd())
+	return cmd
+}
+
+// addHookFlags registers the flags shared by hooks install and hooks run.
+func addHookFlags(cmd *cobra.Command, o *hookOptions) {
+	cmd.Flags().StringVar(&o.From, "from", "", "source agent(s) in priority order, comma-separated (claude, copilot, codex, opencode, gemini, or auto)")
+	cmd.Flags().StringVar(&o.To, "to", "", "destination agent(s), comma-separated, or all/detected")
+	cmd.Flags().StringVar(&o.Scope, "scope", "", "set both from and to scope (local, global)")
+	cmd.Flags().StringVar(&o.FromScope, "from-scope", "", "source scope (overrides --scope)")
+	cmd.Flags().StringVar(&o.ToScope, "to-scope", "", "destination scope (overrides --scope)")
+	cmd.Flags().BoolVar(&o.Sync, "sync", false, "sync and stage the destination files instead of failing on drift")
+	cmd.Flags().BoolVar(&o.NoStage, "no-stage", false, "with --sync, leave the synced files unstaged")
+	addManagedBlockFlags(cmd, &o.Mode.Managed, &o.Mode.Position)
+	cmd.Flags().StringVar(&o.Mode.Merge, "merge", string(config.MergeConcat), "with several --from agents, how to combine instructions (concat, dedup, priority)")
+	cmd.Flags().StringVar(&o.Mode.OnCollide, "skill-conflict", string(config.ConflictPriority), "with several --from agents, what to do with different skills of the same name (priority, error, rename)")
+	_ = cmd.MarkFlagRequired("from")
+	_ = cmd.MarkFlagRequired("to")
+}
+
+func newHooksInstallCmd() *cobra.Command {
+	var opts hookOptions
+	cmd := &cobra.Command{
+		Use:   "install",
+		Short: "Add a pre-commit hook that checks for (or fixes) drift",
+		Long:  "Add a block to the repository's pre-commit hook that runs cas hooks run with the given options. core.hooksPath and worktrees are honored, existing hooks keep running, and installing again replaces the block.",
+		Args:  cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			return doHooksInstall(cmd.OutOrStdout(), opts)
+		},
+	}
+	addHookFlags(cmd, &opts)
+	return cmd
+}
+
+func newHooksUninstallCmd() *cobra.Command {
+	return &cobra.Command{
+		Use:   "uninstall",
+		Short: "Remove the cas block from the pre-commit hook",
+		Args:  cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			return doHooksUninstall(cmd.OutOrStdout())
+		},
+	}
+}
+
+func newHooksRunCmd() *cobra.Command {
+	var opts hookOptions
+	cmd := &cobra.Command{
+		Use:   "run",
+		Short: "Run the pre-commit check (used by the installed hook)",
+		Long:  "Fail when a destination's instructions or skills differ from the source agent. With --sync, sync instead and stage the destination files.",
+		Args:  cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			// Drift is not a usage mistake; keep the hook output short.
+			cmd.SilenceUsage = true
+			return doHooksRun(cmd.OutOrStdout(), opts)
+		},
+	}
+	addHookFlags(cmd, &opts)
+	return cmd
+}
+
+func doHooksInstall(out io.Writer, opts hookOptions) error {
+	// Validate now rather than on the next commit; the hook keeps the
+	// original values so "auto" and "detected" are resolved each time.
+	if _, err := hookSyncConfig(opts); err != nil {
+		return err
+	}
+	root, err := projectRoot()
+	if err != nil {
+		return err
+	}
+	dir, err := hooks.Dir(root)
+	if err != nil {
+		return err
+	}
+
+	// Prefer the binary doing the install; go install names it
+	// coding-agent-sync, while release builds call it cas.
+	bins := []string{"cas", "coding-agent-sync"}
+	if exe, err := os.Executable(); err == nil {
+		bins = append([]string{exe}, bins...)
+	}
+	run := hooks.Quote(append([]string{"hooks", "run"}, opts.args()...))
+	command := strings.Join([]string{
+		"cas_bin=",
+		"for b in " + hooks.Quote(bins) + "; do",
+		"\tif command -v \"$b\" >/dev/null 2>&1; then cas_bin=$b; break; fi",
+		"done",
+		"if [ -z \"$cas_bin\" ]; then",
+		"\techo " + hooks.Quote([]string{"cas hook: cas not found (tried " + strings.Join(bins, ", ") + "); install it or run cas hooks uninstall"}) + " >&2",
+		"\texit 1",
+		"fi",
+		"\"$cas_bin\" " + run + " || exit $?",
+	}, "\n")
+	path, err := hooks.Install(dir, hookName, command)
+	if errors.Is(err, hooks.ErrFramework) {
+		return fmt.Errorf("%w (see the cas-check hook in .pre-commit-hooks.yaml)", err)
+	}
+	if err != nil {
+		return err
+	}
+	fmt.Fprintf(out, "installed %s hook at %s\n", hookName, path)
+	return nil
+}
+
+func doHooksUninstall(out io.Writer) error {
+	root, err := projectRoot()
+	if err != nil {
+		return err
+	}
+	dir, err := hooks.Dir(root)
+	if err != nil {
+		return err
+	}
+	removed, err := hooks.Uninstall(dir, hookName)
+	if err != nil {
+		return err
+	}
+	if !removed {
+		fmt.Fprintf(out, "no cas hook in %s\n", filepath.Join(dir, hookName))
+		return nil
+	}
+	fmt.Fprintf(out, "removed cas hook from %s\n", filepath.Join(dir, hookName))
+	return nil
+}
+
+// hookSyncConfig builds the sync config the hook checks or syncs, with the
+// same merge and managed-block options as cas sync.
+func hookSyncConfig(opts hookOptions) (*config.SyncConfig, error) {
+	cfg, err := buildSyncConfig(opts.From, opts.To, false, opts.Scope, opts.FromScope, opts.ToScope)
+	if err != nil {
+		return nil, err
+	}
+	if err := opts.Mode.apply(cfg); err != nil {
+		return nil, err
+	}
+	return cfg, nil
+}
+
+func doHooksRun(out io.Writer, opts hookOptions) error {
+	cfg, err := hookSyncConfig(opts)
+	if err != nil {
+		return err
+	}
+
+	if !opts.Sync {
+		result, err := sync.CheckDrift(cfg, sync.All)
+		if err != nil {
+			return err
+		}
+		drifted := result.Drifted()
+		if len(drifted) == 0 {
+			return nil
+		}
+		for _, action := range drifted {
+			fmt.Fprintln(out, action)
+		}
+		return fmt.Errorf("agent config is out of sync; run cas hooks run %s --sync, or commit with --no-verify", hooks.Quote(opts.args()))
+	}
+
+	result, err := sync.SyncAll(cfg, sync.All)
+	if err != nil {
+		return err
+	}
+	for _, action := range result.Actions {
+		fmt.Fprintln(out, action)
+	}
+	if opts.NoStage {
+		return nil
+	}
+	var paths []string
+	for _, action := range result.Actions {
+		paths = append(paths, action.Paths...)
+	}
+	staged, err := hooks.Stage(cfg.Root, paths)
+	if err != nil {
+		return err
+	}
+	if len(staged) > 0 {
+		fmt.Fprintf(out, "staged %s\n", strings.Join(staged, ", "))
+	}
+	return nil
+}
@@ -0,0 +1,139 @@
+package cmd
+
+import (
+	"bytes"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestHooksInstallRunUninstall(t *testing.T) {
+	if _, err := exec.LookPath("git"); err != nil {
+		t.Skip("git not installed")
+	}
+	root := t.TempDir()
+	if out, err := exec.Command("git", "-C", root, "init", "-q").CombinedOutput(); err != nil {
+		t.Fatalf("git init: %v\n%s", err, out)
+	}
+	if err := os.WriteFile(filepath.Join(root, "CLAUDE.md"), []byte("# A"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	opts := hookOptions{From: "claude", To: "codex", Scope: "local"}
+
+	withCmdGlobals(root, false, func() {
+		var out bytes.Buffer
+		if err := doHooksInstall(&out, opts); err != nil {
+			t.Fatal(err)
+		}
+		hook, err := os.ReadFile(filepath.Join(root, ".git", "hooks", "pre-commit"))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !strings.Contains(string(hook), `"$cas_bin" hooks run --from claude --to codex --scope local || exit $?`) ||
+			!strings.Contains(string(hook), " cas coding-agent-sync; do") {
+			t.Fatalf("unexpected hook:\n%s", hook)
+		}
+		if out, err := exec.Command("sh", "-n", filepath.Join(root, ".git", "hooks", "pre-commit")).CombinedOutput(); err != nil {
+			t.Fatalf("hook is not valid shell: %v\n%s", err, out)
+		}
+
+		out.Reset()
+		err = doHooksRun(&out, opts)
+		if err == nil || !strings.Contains(out.String(), "drifted") {
+			t.Fatalf("expected drift to fail the check, got err=%v output=%q", err, out.String())
+		}
+
+		opts.Sync = true
+		out.Reset()
+		if err := doHooksRun(&out, opts); err != nil {
+			t.Fatal(err)
+		}
+		if !strings.Contains(out.String(), "staged AGENTS.md") {
+			t.Fatalf("expected AGENTS.md to be staged, got %q", out.String())
+		}
+
+		opts.Sync = false
+		if err := doHooksRun(&out, opts); err != nil {
+			t.Fatalf("expected no drift after syncing, got %v", err)
+		}
+
+		out.Reset()
+		if err := doHooksUninstall(&out); err != nil {
+			t.Fatal(err)
+		}
+		if _, err := os.Stat(filepath.Join(root, ".git", "hooks", "pre-commit")); !os.IsNotExist(err) {
+			t.Fatalf("expected the hook to be removed, got err=%v", err)
+		}
+	})
+
+	staged, err := exec.Command("git", "-C", root, "diff", "--cached", "--name-only").Output()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if strings.TrimSpace(string(staged)) != "AGENTS.md" {
+		t.Fatalf("expected AGENTS.md in the index, got %q", staged)
+	}
+}
+
+func TestHooksRunStagesWhatSyncWrote(t *testing.T) {
+	if _, err := exec.LookPath("git"); err != nil {
+		t.Skip("git not installed")
+	}
+	root := t.TempDir()
+	if out, err := exec.Command("git", "-C", root, "init", "-q").CombinedOutput(); err != nil {
+		t.Fatalf("git init: %v\n%s", err, out)
+	}
+	files := map[string]string{
+		"CLAUDE.md": "# A",
+		"GEMINI.md": "# Mine\n",
+		filepath.Join(".claude", "skills", "one", "SKILL.md"):   "one",
+		filepath.Join(".opencode", "skills", "two", "SKILL.md"): "two",
+		filepath.Join(".cas", "instructions.md"):                "# A",
+		filepath.Join(".cas", "commands", "deploy.md"):          "Deploy",
+		filepath.Join(".gemini", "skills", "mine", "SKILL.md"):  "mine",
+	}
+	for name, content := range files {
+		path := filepath.Join(root, name)
+		if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(path, []byte(content), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	withCmdGlobals(root, false, func() {
+		opts := hookOptions{From: "claude,opencode", To: "gemini", Scope: "local", Sync: true,
+			Mode: syncMode{Managed: true, Position: "bottom", Merge: "dedup"}}
+		if got := strings.Join(opts.args(), " "); !strings.Contains(got, "--merge dedup --managed-block --block-position bottom") {
+			t.Fatalf("expected the hook to keep the sync options, got %q", got)
+		}
+		if err := doHooksRun(&bytes.Buffer{}, opts); err != nil {
+			t.Fatal(err)
+		}
+		opts = hookOptions{From: "cas", To: "claude", Scope: "local", Sync: true}
+		if err := doHooksRun(&bytes.Buffer{}, opts); err != nil {
+			t.Fatal(err)
+		}
+	})
+
+	if got, _ := os.ReadFile(filepath.Join(root, "GEMINI.md")); !strings.HasPrefix(string(got), "# Mine\n") || !strings.Contains(string(got), "cas:managed") {
+		t.Fatalf("expected the sync to write a managed block below the existing text, got %q", got)
+	}
+	out, err := exec.Command("git", "-C", root, "diff", "--cached", "--name-only").Output()
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := strings.Join([]string{
+		".claude/commands/deploy.md",
+		".gemini/skills/one/SKILL.md",
+		".gemini/skills/two/SKILL.md",
+		"CLAUDE.md",
+		"GEMINI.md",
+	}, "\n")
+	if strings.TrimSpace(string(out)) != want {
+		t.Fatalf("expected the written files staged, got:\n%s", out)
+	}
+}
@@ -3,9 +3,12 @@ package cmd
 import (
 	"fmt"
 	"os"
+	"path"
 	"strings"
 
+	"github.com/LaneBirmingham/coding-agent-sync/internal/archive"
 	"github.com/LaneBirmingham/coding-agent-sync/internal/config"
+	"github.com/LaneBirmingham/coding-agent-sync/internal/keys"
 	"github.com/LaneBirmingham/coding-agent-sync/internal/sync"
 	"github.com/spf13/cobra"
 )
@@ -16,65 +19,179 @@ func newImportCmd() *cobra.Command {
 		flagScope  string
 		flagInput  string
 		flagDryRun bool
+		flagOpts   importOptions
 	)
 
 	cmd := &cobra.Command{
 		Use:   "import",
-		Short: "Import agent config from a ZIP archive",
-		Long:  "Import instructions and skills from a ZIP archive to one or more agents.",
+		Short: "Import agent config from an archive",
+		Long: `Import instructions and skills from an archive to one or more agents.
+The archive may be a ZIP, a tar.gz or a directory; -i - reads it from stdin.
+With --git, cas clones a repository (a local path, file:// or any URL git
+accepts), checks out --ref and reads the cas archive or agent layout at
+--subdir, printing the commit it resolved.
+
+Without --to, each archive section goes back to the agent and scope it was
+exported from. For bundles, --section picks sections, --scope selects the
+sections of one scope, and --map routes a section elsewhere, for example
+--map claude/global=codex or --map claude/local=gemini/global.`,
 		RunE: func(cmd *cobra.Command, args []string) error {
-			return doImport(flagTo, flagScope, flagInput, flagDryRun)
+			return doImport(flagTo, flagScope, flagInput, flagDryRun, flagOpts)
 		},
 	}
 
-	cmd.Flags().StringVar(&flagTo, "to", "", "destination agent(s), comma-separated")
-	cmd.Flags().StringVarP(&flagScope, "scope", "", "local", "scope (local, global)")
-	cmd.Flags().StringVarP(&flagInput, "input", "i", "", "input ZIP path")
+	cmd.Flags().StringVar(&flagTo, "to", "", "destination agent(s), comma-separated, or all/detected (default: each section's own agent)")
+	cmd.Flags().StringVarP(&flagScope, "scope", "", "", "scope (local, global); default local, or every section of a bundle")
+	cmd.Flags().StringVarP(&flagInput, "input", "i", "", "input archive path, or - for stdin")
 	cmd.Flags().BoolVar(&flagDryRun, "dry-run", false, "preview import without writing")
-
-	_ = cmd.MarkFlagRequired("to")
-	_ = cmd.MarkFlagRequired("input")
+	cmd.Flags().BoolVar(&flagOpts.AllowUnsigned, "allow-unsigned", false, "import unsigned or untrusted archives with a warning")
+	cmd.Flags().StringVar(&flagOpts.TrustedKeys, "trusted-keys", "", "trusted public keys file (default trusted_keys next to the config file)")
+	cmd.Flags().StringArrayVar(&flagOpts.Identities, "identity", nil, "X25519 identity file for encrypted archives; repeatable")
+	cmd.Flags().StringVar(&flagOpts.PassphraseFile, "passphrase-file", "", "read the decryption passphrase from a file (default $CAS_PASSPHRASE)")
+	cmd.Flags().StringArrayVar(&flagOpts.Sections, "section", nil, "import only this archive section (agent/scope); repeatable")
+	cmd.Flags().StringArrayVar(&flagOpts.Mappings, "map", nil, "import a section to another agent or scope (agent/scope=agent[/scope]); repeatable")
+	cmd.Flags().StringVar(&flagOpts.Only, "only", "", "import only instructions or only skills")
+	cmd.Flags().StringSliceVar(&flagOpts.Skills, "skill", nil, "import only these skills, comma-separated")
+	cmd.Flags().StringArrayVar(&flagOpts.ExcludeSkills, "exclude-skill", nil, "skip skills whose names match a glob (e.g. 'draft-*'); repeatable")
+	cmd.Flags().BoolVar(&flagOpts.IfAbsent, "if-absent", false, "never overwrite existing instructions or skills")
+	cmd.Flags().IntVar(&flagOpts.MaxEntries, "max-entries", 0, fmt.Sprintf("refuse archives with more files than this (default %d)", archive.DefaultLimits.MaxEntries))
+	cmd.Flags().Int64Var(&flagOpts.MaxEntrySize, "max-entry-size", 0, fmt.Sprintf("refuse archive files larger than this many bytes (default %d)", archive.DefaultLimits.MaxEntrySize))
+	cmd.Flags().Int64Var(&flagOpts.MaxTotalSize, "max-total-size", 0, fmt.Sprintf("refuse archives larger than this many bytes in total (default %d)", archive.DefaultLimits.MaxTotalSize))
+
+	cmd.Flags().StringVar(&flagOpts.Git, "git", "", "import from a git repository instead of --input")
+	cmd.Flags().StringVar(&flagOpts.Ref, "ref", "", "git branch, tag or commit (default: the repository's default branch)")
+	cmd.Flags().StringVar(&flagOpts.Subdir, "subdir", "", "path inside the git repository holding an archive or agent layout")
+	cmd.Flags().StringVar(&flagOpts.From, "from", "", "agent layout to read from the git repository (detected if omitted)")
 
 	return cmd
 }
 
-func doImport(toStr, scopeStr, input string, dryRun bool) error {
-	scope, err := config.ParseScope(scopeStr)
+// importOptions holds optional import settings.
+type importOptions struct {
+	AllowUnsigned  bool
+	TrustedKeys    string
+	Identities     []string
+	PassphraseFile string
+	Sections       []string
+	Mappings       []string
+	Only           string
+	Skills         []string
+	ExcludeSkills  []string
+	IfAbsent       bool
+	MaxEntries     int
+	MaxEntrySize   int64
+	MaxTotalSize   int64
+	Git            string
+	Ref            string
+	Subdir         string
+	From           string
+}
+
+func doImport(toStr, scopeStr, input string, dryRun bool, opts importOptions) error {
+	if (input == "") == (opts.Git == "") {
+		return fmt.Errorf("specify exactly one of --input or --git")
+	}
+	if opts.Git == "" && (opts.Ref != "" || opts.Subdir != "" || opts.From != "") {
+		return fmt.Errorf("--ref, --subdir and --from require --git")
+	}
+
+	var scope config.Scope
+	if scopeStr != "" {
+		var err error
+		if scope, err = config.ParseScope(scopeStr); err != nil {
+			return err
+		}
+	}
+
+	root, err := projectRoot()
 	if err != nil {
 		return err
 	}
 
-	var targets []config.Agent
-	for _, s := range strings.Split(toStr, ",") {
-		s = strings.TrimSpace(s)
-		if s == "" {
-			continue
+	targets, err := resolveTargets(toStr, root, scope)
+	if err != nil {
+		return err
+	}
+
+	if strings.TrimSpace(toStr) != "" && len(targets) == 0 {
+		return fmt.Errorf("no valid destination agents specified")
+	}
+
+	var sections []config.Section
+	for _, s := range opts.Sections {
+		sec, err := config.ParseSection(s)
+		if err != nil {
+			return err
 		}
-		a, err := config.ParseAgent(s)
+		sections = append(sections, sec)
+	}
+	var mappings []config.Mapping
+	for _, s := range opts.Mappings {
+		m, err := config.ParseMapping(s)
 		if err != nil {
 			return err
 		}
-		targets = append(targets, a)
+		mappings = append(mappings, m)
 	}
-
-	if len(targets) == 0 {
-		return fmt.Errorf("no valid destination agents specified")
+	if len(mappings) > 0 && (len(targets) > 0 || len(sections) > 0) {
+		return fmt.Errorf("--map cannot be combined with --to or --section")
 	}
 
-	root := flagRoot
-	if root == "" || root == "." {
-		root, err = os.Getwd()
-		if err != nil {
-			return fmt.Errorf("getting working directory: %w", err)
+	var skipInstructions, skipSkills bool
+	switch strings.ToLower(opts.Only) {
+	case "":
+	case "instructions":
+		skipSkills = true
+	case "skills":
+		skipInstructions = true
+	default:
+		return fmt.Errorf("unknown --only value %q (valid: instructions, skills)", opts.Only)
+	}
+	if skipSkills && (len(opts.Skills) > 0 || len(opts.ExcludeSkills) > 0) {
+		return fmt.Errorf("--skill and --exclude-skill cannot be combined with --only instructions")
+	}
+	for _, pattern := range opts.ExcludeSkills {
+		if _, err := path.Match(pattern, ""); err != nil {
+			return fmt.Errorf("invalid --exclude-skill pattern %q: %w", pattern, err)
 		}
 	}
 
+	passphrase, err := readPassphrase(opts.PassphraseFile)
+	if err != nil {
+		return err
+	}
+
 	cfg := &config.ImportConfig{
-		To:     targets,
-		Root:   root,
-		Scope:  scope,
-		Input:  input,
-		DryRun: dryRun,
+		To:            targets,
+		Root:          root,
+		Scope:         scope,
+		Input:         input,
+		DryRun:        dryRun,
+		TrustedKeys:   trustedKeysPath(opts.TrustedKeys),
+		AllowUnsigned: opts.AllowUnsigned,
+		Passphrase:    passphrase,
+		Identities:    opts.Identities,
+		Sections:      sections,
+		Mappings:      mappings,
+
+		SkipInstructions: skipInstructions,
+		SkipSkills:       skipSkills,
+		Skills:           opts.Skills,
+		ExcludeSkills:    opts.ExcludeSkills,
+		IfAbsent:         opts.IfAbsent,
+
+		MaxEntries:   opts.MaxEntries,
+		MaxEntrySize: opts.MaxEntrySize,
+		MaxTotalSize: opts.MaxTotalSize,
+	}
+
+	if opts.Git != "" {
+		cfg.Git = &config.GitSource{Repo: opts.Git, Ref: opts.Ref, Subdir: opts.Subdir}
+		if opts.From != "" {
+			if cfg.Git.From, err = config.ParseAgent(opts.From); err != nil {
+				return err
+			}
+		}
 	}
 
 	if flagVerbose {
@@ -90,9 +207,15 @@ func doImport(toStr, scopeStr, input string, dryRun bool) error {
 		return err
 	}
 
+	if result.Commit != "" {
+		fmt.Fprintf(os.Stderr, "read %s at commit %s\n", opts.Git, result.Commit)
+	}
 	for _, w := range result.Warnings {
 		fmt.Fprintf(os.Stderr, "warning: %s\n", w)
 	}
+	if result.Signer != "" {
+		fmt.Fprintf(os.Stderr, "verified signature from %s\n", result.Signer)
+	}
 
 	for _, action := range result.Actions {
 		fmt.Println(action)
@@ -100,3 +223,15 @@ func doImport(toStr, scopeStr, input string, dryRun bool) error {
 
 	return nil
 }
+
+// trustedKeysPath returns the --trusted-keys value, defaulting to the file next to the config file.
+func trustedKeysPath(flag string) string {
+	if flag != "" {
+		return flag
+	}
+	path := flagConfig
+	if path == "" {
+		path = config.DefaultConfigPath()
+	}
+	return keys.DefaultTrustedKeysPath(path)
+}
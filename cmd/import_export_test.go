@@ -1,12 +1,15 @@
 package cmd
 
 import (
+	"bytes"
+	"os"
 	"path/filepath"
 	"testing"
 	"time"
 
 	"github.com/LaneBirmingham/coding-agent-sync/internal/agent"
 	"github.com/LaneBirmingham/coding-agent-sync/internal/archive"
+	"github.com/LaneBirmingham/coding-agent-sync/internal/config"
 )
 
 func withCmdGlobals(root string, verbose bool, fn func()) {
@@ -23,7 +26,7 @@ func withCmdGlobals(root string, verbose bool, fn func()) {
 
 func TestDoExportInvalidAgent(t *testing.T) {
 	withCmdGlobals(t.TempDir(), false, func() {
-		err := doExport("not-an-agent", "local", "", true)
+		err := doExport("not-an-agent", "local", "", true, exportOptions{})
 		if err == nil {
 			t.Fatal("expected error for invalid agent")
 		}
@@ -32,7 +35,7 @@ func TestDoExportInvalidAgent(t *testing.T) {
 
 func TestDoExportInvalidScope(t *testing.T) {
 	withCmdGlobals(t.TempDir(), false, func() {
-		err := doExport("claude", "wrong", "", true)
+		err := doExport("claude", "wrong", "", true, exportOptions{})
 		if err == nil {
 			t.Fatal("expected error for invalid scope")
 		}
@@ -42,7 +45,7 @@ func TestDoExportInvalidScope(t *testing.T) {
 func TestDoExportDryRun(t *testing.T) {
 	root := t.TempDir()
 	withCmdGlobals(root, true, func() {
-		err := doExport("claude", "local", "", true)
+		err := doExport("claude", "local", "", true, exportOptions{})
 		if err != nil {
 			t.Fatalf("expected dry-run export to succeed, got %v", err)
 		}
@@ -51,7 +54,7 @@ func TestDoExportDryRun(t *testing.T) {
 
 func TestDoImportInvalidScope(t *testing.T) {
 	withCmdGlobals(t.TempDir(), false, func() {
-		err := doImport("claude", "wrong", "input.zip", true)
+		err := doImport("claude", "wrong", "input.zip", true, importOptions{})
 		if err == nil {
 			t.Fatal("expected error for invalid scope")
 		}
@@ -60,7 +63,7 @@ func TestDoImportInvalidScope(t *testing.T) {
 
 func TestDoImportNoTargets(t *testing.T) {
 	withCmdGlobals(t.TempDir(), false, func() {
-		err := doImport(" , ", "local", "input.zip", true)
+		err := doImport(" , ", "local", "input.zip", true, importOptions{})
 		if err == nil {
 			t.Fatal("expected error for empty targets")
 		}
@@ -69,7 +72,7 @@ func TestDoImportNoTargets(t *testing.T) {
 
 func TestDoImportInvalidTarget(t *testing.T) {
 	withCmdGlobals(t.TempDir(), false, func() {
-		err := doImport("unknown", "local", "input.zip", true)
+		err := doImport("unknown", "local", "input.zip", true, importOptions{})
 		if err == nil {
 			t.Fatal("expected error for invalid target")
 		}
@@ -93,9 +96,248 @@ func TestDoImportDryRun(t *testing.T) {
 	}
 
 	withCmdGlobals(root, true, func() {
-		err := doImport("copilot", "local", input, true)
+		err := doImport("copilot", "local", input, true, importOptions{AllowUnsigned: true})
 		if err != nil {
 			t.Fatalf("expected dry-run import to succeed, got %v", err)
 		}
 	})
 }
+
+func TestResolveTargetsKeywords(t *testing.T) {
+	root := t.TempDir()
+	if err := os.WriteFile(filepath.Join(root, "GEMINI.md"), []byte("# G"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	t.Setenv("HOME", t.TempDir())
+
+	all, err := resolveTargets("all", root, "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(all) != len(config.ValidAgents) {
+		t.Fatalf("expected %d agents for all, got %v", len(config.ValidAgents), all)
+	}
+
+	detected, err := resolveTargets("detected,gemini", root, "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(detected) != 1 || detected[0] != config.Gemini {
+		t.Fatalf("expected only gemini detected, got %v", detected)
+	}
+}
+
+func TestDoExportFromAuto(t *testing.T) {
+	root := t.TempDir()
+	if err := os.WriteFile(filepath.Join(root, "CLAUDE.md"), []byte("# C"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	withCmdGlobals(root, false, func() {
+		if err := doExport("auto", "local", "", true, exportOptions{}); err != nil {
+			t.Fatalf("expected auto export to succeed, got %v", err)
+		}
+	})
+	withCmdGlobals(t.TempDir(), false, func() {
+		if err := doExport("auto", "local", "", true, exportOptions{}); err == nil {
+			t.Fatal("expected auto export to fail without instructions")
+		}
+	})
+}
+
+func TestKeygenSignAndImport(t *testing.T) {
+	keyPath := filepath.Join(t.TempDir(), "signing_key")
+	if err := doKeygen("ed25519", keyPath, "ci", false); err != nil {
+		t.Fatal(err)
+	}
+	if err := doKeygen("ed25519", keyPath, "ci", false); err == nil {
+		t.Fatal("expected keygen to refuse overwriting existing keys")
+	}
+
+	root := t.TempDir()
+	if err := os.WriteFile(filepath.Join(root, "CLAUDE.md"), []byte("# Signed"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	output := filepath.Join(t.TempDir(), "signed.zip")
+	withCmdGlobals(root, false, func() {
+		if err := doExport("claude", "local", output, false, exportOptions{SigningKey: keyPath}); err != nil {
+			t.Fatalf("signed export: %v", err)
+		}
+		if err := doImport("codex", "local", output, true, importOptions{TrustedKeys: filepath.Join(t.TempDir(), "none")}); err == nil {
+			t.Fatal("expected import without trusted key to fail")
+		}
+		if err := doImport("codex", "local", output, true, importOptions{TrustedKeys: keyPath + ".pub"}); err != nil {
+			t.Fatalf("expected import with trusted key to succeed, got %v", err)
+		}
+	})
+}
+
+func TestEncryptedExportImport(t *testing.T) {
+	dir := t.TempDir()
+	idPath := filepath.Join(dir, "identity")
+	if err := doKeygen("x25519", idPath, "", false); err != nil {
+		t.Fatal(err)
+	}
+
+	root := t.TempDir()
+	if err := os.WriteFile(filepath.Join(root, "CLAUDE.md"), []byte("# Private"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	output := filepath.Join(dir, "private.zip")
+	withCmdGlobals(root, false, func() {
+		if err := doExport("claude", "local", output, false, exportOptions{Encrypt: true, Recipients: []string{idPath + ".pub"}}); err != nil {
+			t.Fatalf("encrypted export: %v", err)
+		}
+		if err := doImport("codex", "local", output, false, importOptions{AllowUnsigned: true}); err == nil {
+			t.Fatal("expected import without identity to fail")
+		}
+		if err := doImport("codex", "local", output, false, importOptions{AllowUnsigned: true, Identities: []string{idPath}}); err != nil {
+			t.Fatalf("import with identity: %v", err)
+		}
+	})
+
+	got, err := os.ReadFile(filepath.Join(root, "AGENTS.md"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(got) != "# Private" {
+		t.Fatalf("unexpected imported content %q", got)
+	}
+}
+
+func TestExportEncryptNeedsPassphrase(t *testing.T) {
+	t.Setenv("CAS_PASSPHRASE", "")
+	withCmdGlobals(t.TempDir(), false, func() {
+		if err := doExport("claude", "local", "", true, exportOptions{Encrypt: true}); err == nil {
+			t.Fatal("expected --encrypt without passphrase or recipient to fail")
+		}
+	})
+}
+
+func TestBundleExportImportWithMap(t *testing.T) {
+	t.Setenv("HOME", t.TempDir())
+	root := t.TempDir()
+	for name, content := range map[string]string{"CLAUDE.md": "# C", "GEMINI.md": "# G"} {
+		if err := os.WriteFile(filepath.Join(root, name), []byte(content), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	output := filepath.Join(t.TempDir(), "bundle.zip")
+	withCmdGlobals(root, false, func() {
+		if err := doExport("claude,gemini", "local,global", output, false, exportOptions{}); err != nil {
+			t.Fatalf("bundle export: %v", err)
+		}
+		if err := doImport("codex", "", output, true, importOptions{AllowUnsigned: true, Mappings: []string{"claude/local=opencode"}}); err == nil {
+			t.Fatal("expected --map with --to to fail")
+		}
+		if err := doImport("", "", output, false, importOptions{AllowUnsigned: true, Mappings: []string{"gemini/local=codex"}}); err != nil {
+			t.Fatalf("bundle import: %v", err)
+		}
+	})
+
+	got, err := os.ReadFile(filepath.Join(root, "AGENTS.md"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(got) != "# G" {
+		t.Fatalf("unexpected imported content %q", got)
+	}
+}
+
+func TestExportDirFormat(t *testing.T) {
+	root := t.TempDir()
+	if err := os.WriteFile(filepath.Join(root, "CLAUDE.md"), []byte("# Dir"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	output := filepath.Join(t.TempDir(), "reviewable")
+	withCmdGlobals(root, false, func() {
+		if err := doExport("claude", "local", output, false, exportOptions{Format: "dir"}); err != nil {
+			t.Fatalf("dir export: %v", err)
+		}
+		if err := doExport("claude", "local", "-", false, exportOptions{Format: "dir"}); err == nil {
+			t.Fatal("expected dir format to stdout to fail")
+		}
+		if err := doImport("opencode", "local", output, false, importOptions{AllowUnsigned: true}); err != nil {
+			t.Fatalf("dir import: %v", err)
+		}
+	})
+
+	got, err := os.ReadFile(filepath.Join(output, "instructions.md"))
+	if err != nil || string(got) != "# Dir" {
+		t.Fatalf("expected plain instructions file, got %q (%v)", got, err)
+	}
+	if got, err := os.ReadFile(filepath.Join(root, "AGENTS.md")); err != nil || string(got) != "# Dir" {
+		t.Fatalf("expected imported instructions, got %q (%v)", got, err)
+	}
+}
+
+func TestDoImportFilterValidation(t *testing.T) {
+	withCmdGlobals(t.TempDir(), false, func() {
+		for _, opts := range []importOptions{
+			{Only: "everything"},
+			{Only: "instructions", Skills: []string{"a"}},
+			{ExcludeSkills: []string{"[bad"}},
+		} {
+			if err := doImport("claude", "local", "input.zip", true, opts); err == nil {
+				t.Errorf("expected error for %+v", opts)
+			}
+		}
+	})
+}
+
+func TestDoImportSourceValidation(t *testing.T) {
+	withCmdGlobals(t.TempDir(), false, func() {
+		if err := doImport("claude", "", "", true, importOptions{}); err == nil {
+			t.Error("expected error without --input or --git")
+		}
+		if err := doImport("claude", "", "in.zip", true, importOptions{Git: "repo"}); err == nil {
+			t.Error("expected error with both --input and --git")
+		}
+		if err := doImport("claude", "", "in.zip", true, importOptions{Ref: "v1"}); err == nil {
+			t.Error("expected error for --ref without --git")
+		}
+	})
+}
+
+func TestReproducibleExport(t *testing.T) {
+	root := t.TempDir()
+	if err := os.WriteFile(filepath.Join(root, "CLAUDE.md"), []byte("# Same"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	dir := t.TempDir()
+	first, second := filepath.Join(dir, "a.zip"), filepath.Join(dir, "b.zip")
+	t.Setenv("SOURCE_DATE_EPOCH", "1700000000")
+	withCmdGlobals(root, false, func() {
+		for _, output := range []string{first, second} {
+			if err := doExport("claude", "local", output, false, exportOptions{Reproducible: true}); err != nil {
+				t.Fatalf("reproducible export: %v", err)
+			}
+		}
+		if err := doExport("claude", "local", "", true, exportOptions{Reproducible: true, Encrypt: true}); err == nil {
+			t.Fatal("expected --reproducible with --encrypt to fail")
+		}
+	})
+
+	a, err := os.ReadFile(first)
+	if err != nil {
+		t.Fatal(err)
+	}
+	b, err := os.ReadFile(second)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(a, b) {
+		t.Fatal("expected byte-identical archives")
+	}
+	got, err := archive.Read(first)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !got.Manifest.ExportedAt.Equal(time.Unix(1700000000, 0)) {
+		t.Fatalf("expected export time from SOURCE_DATE_EPOCH, got %v", got.Manifest.ExportedAt)
+	}
+
+	t.Setenv("SOURCE_DATE_EPOCH", "yesterday")
+	if _, err := sourceDate(); err == nil {
+		t.Fatal("expected invalid SOURCE_DATE_EPOCH to fail")
+	}
+}
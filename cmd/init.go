@@ -0,0 +1,97 @@
+package cmd
+
+import (
+	"fmt"
+	"io"
+
+	"github.com/LaneBirmingham/coding-agent-sync/internal/agent"
+	"github.com/LaneBirmingham/coding-agent-sync/internal/config"
+	"github.com/LaneBirmingham/coding-agent-sync/internal/sync"
+	"github.com/spf13/cobra"
+)
+
+func newInitCmd() *cobra.Command {
+	var (
+		flagFrom   string
+		flagScope  string
+		flagForce  bool
+		flagDryRun bool
+	)
+
+	cmd := &cobra.Command{
+		Use:   "init",
+		Short: "Create the canonical .cas layout from an existing agent",
+		Long:  "Copy an agent's instructions, skills, commands and rules into the agent-neutral layout (.cas/instructions.md, .cas/skills/<name>/SKILL.md, .cas/commands/<name>.md and .cas/rules/<name>.md, or the cas config directory for global scope). cas render then generates every agent's files from it.",
+		Args:  cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			return doInit(cmd.OutOrStdout(), flagFrom, flagScope, flagForce, flagDryRun)
+		},
+	}
+
+	cmd.Flags().StringVar(&flagFrom, "from", sourceAuto, "agent to copy from (claude, copilot, codex, opencode, gemini, or auto)")
+	cmd.Flags().StringVar(&flagScope, "scope", "", "scope to initialize (local, global)")
+	cmd.Flags().BoolVar(&flagForce, "force", false, "overwrite an existing canonical layout")
+	cmd.Flags().BoolVar(&flagDryRun, "dry-run", false, "preview changes without writing")
+
+	return cmd
+}
+
+func doInit(out io.Writer, from, scope string, force, dryRun bool) error {
+	cfg, err := buildSyncConfig(from, string(config.Canonical), dryRun, scope, "", "")
+	if err != nil {
+		return err
+	}
+	if cfg.From == config.Canonical {
+		return fmt.Errorf("--from must name an agent, not %s", config.Canonical)
+	}
+
+	loc := config.Location{Root: cfg.Root, Scope: cfg.ToScope}
+	if !force {
+		exists, err := canonicalExists(loc)
+		if err != nil {
+			return err
+		}
+		if exists {
+			return fmt.Errorf("%s already has instructions, skills, commands or rules; use --force to overwrite", loc.CanonicalDir())
+		}
+	}
+
+	result, err := sync.SyncAll(cfg, sync.All)
+	if err != nil {
+		return err
+	}
+	for _, action := range result.Actions {
+		fmt.Fprintln(out, action)
+	}
+	return nil
+}
+
+// canonicalExists reports whether the canonical layout at loc holds
+// instructions, skills, commands or rules.
+func canonicalExists(loc config.Location) (bool, error) {
+	impl, err := agent.Get(config.Canonical)
+	if err != nil {
+		return false, err
+	}
+	inst, _, err := agent.ReadComposedInstructions(impl, loc)
+	if err != nil {
+		return false, err
+	}
+	skills, err := impl.ReadSkills(loc)
+	if err != nil {
+		return false, err
+	}
+	if inst != nil || len(skills) > 0 {
+		return true, nil
+	}
+	for _, dirOf := range []func(agent.Agent, config.Location) (string, string){agent.CommandsDir, agent.RulesDir} {
+		docs, err := agent.ReadDocuments(dirOf(impl, loc))
+		if err != nil {
+			return false, err
+		}
+		if len(docs) > 0 {
+			return true, nil
+		}
+	}
+	return false, nil
+}
@@ -0,0 +1,101 @@
+package cmd
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/LaneBirmingham/coding-agent-sync/internal/sync"
+)
+
+func TestDoInitAndRender(t *testing.T) {
+	root := t.TempDir()
+	if err := os.WriteFile(filepath.Join(root, "CLAUDE.md"), []byte("# A"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	skill := filepath.Join(root, ".claude", "skills", "review", "SKILL.md")
+	if err := os.MkdirAll(filepath.Dir(skill), 0o755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(skill, []byte("review"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	var out bytes.Buffer
+	withCmdGlobals(root, false, func() {
+		if err := doInit(&out, "claude", "local", false, false); err != nil {
+			t.Fatal(err)
+		}
+		if err := doInit(&out, "claude", "local", false, false); err == nil || !strings.Contains(err.Error(), "--force") {
+			t.Fatalf("expected init to refuse an existing layout, got %v", err)
+		}
+
+		if err := os.WriteFile(filepath.Join(root, ".cas", "instructions.md"), []byte("# B"), 0o644); err != nil {
+			t.Fatal(err)
+		}
+		if err := doRender(&out, sync.All, "codex,claude", "local", false, syncMode{}); err != nil {
+			t.Fatal(err)
+		}
+	})
+
+	for _, name := range []string{"AGENTS.md", "CLAUDE.md"} {
+		if got, _ := os.ReadFile(filepath.Join(root, name)); string(got) != "# B" {
+			t.Fatalf("expected %s rendered from .cas, got %q", name, got)
+		}
+	}
+	if _, err := os.Stat(filepath.Join(root, ".agents", "skills", "review", "SKILL.md")); err != nil {
+		t.Fatalf("expected the skill rendered for codex: %v", err)
+	}
+}
+
+func TestDoRenderWithoutLayout(t *testing.T) {
+	withCmdGlobals(t.TempDir(), false, func() {
+		err := doRender(&bytes.Buffer{}, sync.All, "all", "local", false, syncMode{})
+		if err == nil || !strings.Contains(err.Error(), "cas init") {
+			t.Fatalf("expected a hint to run cas init, got %v", err)
+		}
+	})
+}
+
+func TestDoInitAndRenderCommandsAndRules(t *testing.T) {
+	root := t.TempDir()
+	files := map[string]string{
+		filepath.Join(".claude", "commands", "deploy.md"): "Deploy $ARGUMENTS",
+		filepath.Join(".claude", "rules", "go.md"):        "Use gofmt.",
+	}
+	for name, content := range files {
+		path := filepath.Join(root, name)
+		if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(path, []byte(content), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	withCmdGlobals(root, false, func() {
+		if err := doInit(&bytes.Buffer{}, "claude", "local", false, false); err != nil {
+			t.Fatal(err)
+		}
+		if err := doRender(&bytes.Buffer{}, sync.All, "copilot,gemini", "local", false, syncMode{}); err != nil {
+			t.Fatal(err)
+		}
+	})
+
+	want := map[string]string{
+		filepath.Join(".cas", "commands", "deploy.md"):                 "Deploy $ARGUMENTS",
+		filepath.Join(".cas", "rules", "go.md"):                        "Use gofmt.",
+		filepath.Join(".github", "prompts", "deploy.prompt.md"):        "Deploy $ARGUMENTS",
+		filepath.Join(".github", "instructions", "go.instructions.md"): "Use gofmt.",
+	}
+	for name, content := range want {
+		if got, err := os.ReadFile(filepath.Join(root, name)); err != nil || string(got) != content {
+			t.Fatalf("expected %s to hold %q, got %q (%v)", name, content, got, err)
+		}
+	}
+	if _, err := os.Stat(filepath.Join(root, ".gemini", "commands")); !os.IsNotExist(err) {
+		t.Fatalf("expected no commands written for gemini, got %v", err)
+	}
+}
@@ -0,0 +1,83 @@
+package cmd
+
+import (
+	"crypto/ed25519"
+	"fmt"
+	"os"
+	"path/filepath"
+
+	"github.com/LaneBirmingham/coding-agent-sync/internal/config"
+	"github.com/LaneBirmingham/coding-agent-sync/internal/keys"
+	"github.com/spf13/cobra"
+)
+
+func newKeygenCmd() *cobra.Command {
+	var (
+		flagOutput  string
+		flagComment string
+		flagForce   bool
+		flagType    string
+	)
+
+	cmd := &cobra.Command{
+		Use:   "keygen",
+		Short: "Generate a key pair for signing or encrypting archives",
+		Long: `Generate a key pair.
+
+With --type ed25519 (the default) the private key signs archives with cas export --sign; add the .pub line to a trusted keys file to accept them on import.
+With --type x25519 the .pub line is a recipient for cas export --recipient, and the private key is an identity for cas import --identity.`,
+		Args: cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			return doKeygen(flagType, flagOutput, flagComment, flagForce)
+		},
+	}
+
+	cmd.Flags().StringVarP(&flagOutput, "output", "o", "", "private key path; the public key is written to <path>.pub (default signing_key next to the config file)")
+	cmd.Flags().StringVar(&flagComment, "comment", "", "comment stored with the public key")
+	cmd.Flags().BoolVar(&flagForce, "force", false, "overwrite existing key files")
+	cmd.Flags().StringVar(&flagType, "type", keys.SigningKeyType, "key type (ed25519 for signing, x25519 for encryption)")
+
+	return cmd
+}
+
+func doKeygen(keyType, output, comment string, force bool) error {
+	var (
+		private, public []byte
+		id, name        string
+	)
+	switch keyType {
+	case keys.SigningKeyType:
+		priv, err := keys.GenerateSigningKey()
+		if err != nil {
+			return err
+		}
+		pub := keys.PublicKey{Key: priv.Public().(ed25519.PublicKey), Comment: comment}
+		private, public, id, name = keys.MarshalSigningKey(priv), []byte(pub.String()+"\n"), pub.ID(), "signing_key"
+	case keys.EncryptionKeyType:
+		priv, err := keys.GenerateEncryptionKey()
+		if err != nil {
+			return err
+		}
+		pub := keys.Recipient{Key: priv.PublicKey(), Comment: comment}
+		private, public, id, name = keys.MarshalEncryptionKey(priv), []byte(pub.String()+"\n"), pub.ID(), "identity"
+	default:
+		return fmt.Errorf("unknown key type %q (valid: %s, %s)", keyType, keys.SigningKeyType, keys.EncryptionKeyType)
+	}
+
+	if output == "" {
+		path := config.DefaultConfigPath()
+		if path == "" {
+			return fmt.Errorf("cannot determine default key location; pass --output")
+		}
+		output = filepath.Join(filepath.Dir(path), name)
+	}
+
+	if err := keys.WriteKeyFiles(output, private, public, force); err != nil {
+		return err
+	}
+
+	fmt.Fprintf(os.Stderr, "private key written to %s\n", output)
+	fmt.Fprintf(os.Stderr, "public key written to %s.pub\n", output)
+	fmt.Println(id)
+	return nil
+}
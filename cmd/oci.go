@@ -0,0 +1,121 @@
+package cmd
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+
+	"github.com/LaneBirmingham/coding-agent-sync/internal/archive"
+	"github.com/LaneBirmingham/coding-agent-sync/internal/oci"
+	"github.com/spf13/cobra"
+)
+
+func newPushCmd() *cobra.Command {
+	var (
+		flagInput     string
+		flagFrom      string
+		flagScope     string
+		flagPlainHTTP bool
+		flagOpts      exportOptions
+	)
+
+	cmd := &cobra.Command{
+		Use:   "push oci://<registry>/<repository>:<tag>",
+		Short: "Push an archive to an OCI registry",
+		Long: `Store a cas archive in an OCI registry as an artifact of type
+application/vnd.cas.archive.v1, with the archive manifest carried in the
+dev.cas.archive.manifest annotation. Push an existing archive with -i, or
+export one with --from and --scope as cas export would. Credentials come from
+$CAS_OCI_USERNAME and $CAS_OCI_PASSWORD; loopback registries use plain HTTP.`,
+		Args: cobra.ExactArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			return doPush(args[0], flagInput, flagFrom, flagScope, flagPlainHTTP, flagOpts)
+		},
+	}
+
+	cmd.Flags().StringVarP(&flagInput, "input", "i", "", "archive to push (ZIP or tar.gz)")
+	cmd.Flags().StringVar(&flagFrom, "from", "", "export these agent(s) instead of pushing --input")
+	cmd.Flags().StringVar(&flagScope, "scope", "local", "scope(s) to export with --from (local, global)")
+	cmd.Flags().StringVar(&flagOpts.SigningKey, "sign", "", "sign the exported archive with an ed25519 private key file")
+	cmd.Flags().BoolVar(&flagOpts.Reproducible, "reproducible", false, "export a byte-identical archive for identical content")
+	cmd.Flags().BoolVar(&flagPlainHTTP, "plain-http", false, "use http instead of https for the registry")
+
+	return cmd
+}
+
+func doPush(refStr, input, fromStr, scopeStr string, plainHTTP bool, opts exportOptions) error {
+	ref, err := oci.ParseReference(refStr)
+	if err != nil {
+		return err
+	}
+	if (input == "") == (fromStr == "") {
+		return fmt.Errorf("specify exactly one of --input or --from")
+	}
+
+	if fromStr != "" {
+		tmp, err := os.MkdirTemp("", "cas-push-*")
+		if err != nil {
+			return err
+		}
+		defer os.RemoveAll(tmp)
+		input = filepath.Join(tmp, "archive.zip")
+		if err := doExport(fromStr, scopeStr, input, false, opts); err != nil {
+			return err
+		}
+	}
+
+	data, err := os.ReadFile(input)
+	if err != nil {
+		return fmt.Errorf("reading archive: %w", err)
+	}
+	m, format, err := archive.ReadManifest(data)
+	if err != nil {
+		return fmt.Errorf("reading archive %s: %w", input, err)
+	}
+	annotations, err := oci.Annotations(m)
+	if err != nil {
+		return err
+	}
+
+	client := oci.NewClient()
+	client.PlainHTTP = plainHTTP
+	digest, err := client.Push(context.Background(), ref, data, oci.LayerType(format), annotations)
+	if err != nil {
+		return fmt.Errorf("pushing %s: %w", ref, err)
+	}
+	fmt.Fprintf(os.Stderr, "pushed %s (%s)\n", ref, digest)
+	return nil
+}
+
+// pullOCI downloads the archive at an oci:// reference to a temporary file,
+// returning its path and a function that removes it.
+func pullOCI(refStr string, plainHTTP bool) (string, func(), error) {
+	ref, err := oci.ParseReference(refStr)
+	if err != nil {
+		return "", nil, err
+	}
+	client := oci.NewClient()
+	client.PlainHTTP = plainHTTP
+	data, _, err := client.Pull(context.Background(), ref, archive.DefaultLimits.MaxTotalSize)
+	if err != nil {
+		return "", nil, fmt.Errorf("pulling %s: %w", ref, err)
+	}
+
+	f, err := os.CreateTemp("", "cas-pull-*")
+	if err != nil {
+		return "", nil, err
+	}
+	cleanup := func() { os.Remove(f.Name()) }
+	if _, err := f.Write(data); err != nil {
+		f.Close()
+		cleanup()
+		return "", nil, err
+	}
+	if err := f.Close(); err != nil {
+		cleanup()
+		return "", nil, err
+	}
+	fmt.Fprintf(os.Stderr, "pulled %s\n", ref)
+	return f.Name(), cleanup, nil
+}
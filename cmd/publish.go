@@ -0,0 +1,175 @@
+package cmd
+
+import (
+	"fmt"
+	"os"
+
+	"github.com/LaneBirmingham/coding-agent-sync/internal/archive"
+	"github.com/LaneBirmingham/coding-agent-sync/internal/oci"
+	"github.com/LaneBirmingham/coding-agent-sync/internal/registry"
+	"github.com/spf13/cobra"
+)
+
+func newPublishCmd() *cobra.Command {
+	var (
+		flagRegistry string
+		flagName     string
+		flagVersion  string
+		flagFrom     string
+		flagScope    string
+		flagOpts     exportOptions
+	)
+
+	cmd := &cobra.Command{
+		Use:   "publish",
+		Short: "Export agent config as a versioned bundle in a registry",
+		Long: `Export instructions and skills to a registry directory, such as a shared or
+NFS mount, as <name>/<version>.zip, and record the version in the registry's
+index.json. The bundle name and version are also stored in the archive
+manifest. Published versions are immutable.`,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			return doPublish(flagRegistry, flagName, flagVersion, flagFrom, flagScope, flagOpts)
+		},
+	}
+
+	cmd.Flags().StringVar(&flagRegistry, "registry", "", registryFlagUsage)
+	cmd.Flags().StringVar(&flagName, "name", "", "bundle name (lowercase letters, digits, '-', '_', '.')")
+	cmd.Flags().StringVar(&flagVersion, "version", "", "bundle version (semver, e.g. 1.3.0)")
+	cmd.Flags().StringVar(&flagFrom, "from", "", "source agent(s), comma-separated, or auto")
+	cmd.Flags().StringVar(&flagScope, "scope", "local", "scope(s), comma-separated (local, global)")
+	cmd.Flags().StringVar(&flagOpts.SigningKey, "sign", "", "sign the archive with an ed25519 private key file (see cas keygen)")
+	cmd.Flags().BoolVar(&flagOpts.Reproducible, "reproducible", false, "write a byte-identical archive for identical content")
+
+	_ = cmd.MarkFlagRequired("name")
+	_ = cmd.MarkFlagRequired("version")
+	_ = cmd.MarkFlagRequired("from")
+
+	return cmd
+}
+
+func doPublish(dir, name, versionStr, fromStr, scopeStr string, opts exportOptions) error {
+	reg, err := openRegistry(dir)
+	if err != nil {
+		return err
+	}
+	if err := registry.ValidateName(name); err != nil {
+		return err
+	}
+	version, err := registry.ParseVersion(versionStr)
+	if err != nil {
+		return err
+	}
+	if err := reg.CheckUnpublished(name, version); err != nil {
+		return err
+	}
+
+	// Export next to the final path and let Add link it into place under the
+	// registry lock, so a concurrent publish never sees a partial archive.
+	tmp, err := reg.CreateTemp()
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp)
+	opts.Format = string(archive.FormatZip)
+	opts.PackageName = name
+	opts.PackageVersion = version.String()
+	if err := doExport(fromStr, scopeStr, tmp, false, opts); err != nil {
+		return err
+	}
+
+	if _, err := reg.Add(name, version, tmp); err != nil {
+		return err
+	}
+	fmt.Fprintf(os.Stderr, "published %s@%s to %s\n", name, version, reg.Dir)
+	return nil
+}
+
+func newPullCmd() *cobra.Command {
+	var (
+		flagRegistry  string
+		flagTo        string
+		flagScope     string
+		flagDryRun    bool
+		flagPlainHTTP bool
+		flagOpts      importOptions
+	)
+
+	cmd := &cobra.Command{
+		Use:   "pull <name>[@<constraint>] | oci://<registry>/<repository>:<tag>",
+		Short: "Import a bundle version from a registry",
+		Long: `Resolve a bundle in a registry and import it, as cas import would. An
+oci:// reference pulls an archive pushed with cas push from an OCI registry.
+
+For directory registries, the constraint picks the highest matching version: ^1.2 (>=1.2.0 <2.0.0),
+~1.2 (>=1.2.0 <1.3.0), 1.2 (any 1.2.x), an exact 1.2.3, comparisons such as
+">=1.0 <2.0", or latest (the default). Pre-releases only match exactly.`,
+		Args: cobra.ExactArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			return doPull(flagRegistry, args[0], flagTo, flagScope, flagDryRun, flagPlainHTTP, flagOpts)
+		},
+	}
+
+	cmd.Flags().StringVar(&flagRegistry, "registry", "", registryFlagUsage)
+	cmd.Flags().StringVar(&flagTo, "to", "", "destination agent(s), comma-separated, or all/detected (default: each section's own agent)")
+	cmd.Flags().StringVar(&flagScope, "scope", "", "scope (local, global); default local, or every section of a bundle")
+	cmd.Flags().BoolVar(&flagDryRun, "dry-run", false, "preview import without writing")
+	cmd.Flags().BoolVar(&flagOpts.AllowUnsigned, "allow-unsigned", false, "import unsigned or untrusted bundles with a warning")
+	cmd.Flags().StringVar(&flagOpts.TrustedKeys, "trusted-keys", "", "trusted public keys file (default trusted_keys next to the config file)")
+	cmd.Flags().BoolVar(&flagOpts.IfAbsent, "if-absent", false, "never overwrite existing instructions or skills")
+	cmd.Flags().BoolVar(&flagPlainHTTP, "plain-http", false, "use http instead of https for an OCI registry")
+
+	return cmd
+}
+
+func doPull(dir, ref, toStr, scopeStr string, dryRun, plainHTTP bool, opts importOptions) error {
+	if oci.IsReference(ref) {
+		path, cleanup, err := pullOCI(ref, plainHTTP)
+		if err != nil {
+			return err
+		}
+		defer cleanup()
+		return doImport(toStr, scopeStr, path, dryRun, opts)
+	}
+
+	reg, err := openRegistry(dir)
+	if err != nil {
+		return err
+	}
+	name, constraint, err := registry.ParseRef(ref)
+	if err != nil {
+		return err
+	}
+	entry, path, err := reg.Resolve(name, constraint)
+	if err != nil {
+		return err
+	}
+	if err := checkPackage(path, name, entry.Version); err != nil {
+		return err
+	}
+
+	fmt.Fprintf(os.Stderr, "resolved %s@%s to %s\n", name, constraint, entry.Version)
+	return doImport(toStr, scopeStr, path, dryRun, opts)
+}
+
+// checkPackage confirms that the archive at path was published as
+// name@version, so an archive copied over another version's file is not
+// imported under the wrong name.
+func checkPackage(path, name, version string) error {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return err
+	}
+	m, _, err := archive.ReadManifest(data)
+	if err != nil {
+		return fmt.Errorf("reading %s: %w", path, err)
+	}
+	want := archive.Package{Name: name, Version: version}
+	if m.Package == nil || *m.Package != want {
+		got := "no package"
+		if m.Package != nil {
+			got = m.Package.String()
+		}
+		return fmt.Errorf("%s holds %s, not %s as the registry index says", path, got, want)
+	}
+	return nil
+}
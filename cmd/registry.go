@@ -0,0 +1,89 @@
+package cmd
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"strings"
+
+	"github.com/LaneBirmingham/coding-agent-sync/internal/registry"
+	"github.com/spf13/cobra"
+)
+
+// registryFlagUsage describes the --registry flag shared by the registry commands.
+const registryFlagUsage = "registry directory (default $CAS_REGISTRY)"
+
+// openRegistry returns the registry at dir, falling back to $CAS_REGISTRY.
+func openRegistry(dir string) (*registry.Registry, error) {
+	if dir == "" {
+		dir = os.Getenv("CAS_REGISTRY")
+	}
+	if dir == "" {
+		return nil, fmt.Errorf("no registry given (use --registry or set CAS_REGISTRY)")
+	}
+	return &registry.Registry{Dir: dir}, nil
+}
+
+func newRegistryCmd() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "registry",
+		Short: "Work with a directory of published bundles",
+	}
+
+	cmd.AddCommand(newRegistryLsCmd())
+
+	return cmd
+}
+
+func newRegistryLsCmd() *cobra.Command {
+	var flagRegistry string
+
+	cmd := &cobra.Command{
+		Use:   "ls [name]",
+		Short: "List published bundles and their versions",
+		Args:  cobra.MaximumNArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			name := ""
+			if len(args) == 1 {
+				name = args[0]
+			}
+			return doRegistryLs(cmd.OutOrStdout(), flagRegistry, name)
+		},
+	}
+
+	cmd.Flags().StringVar(&flagRegistry, "registry", "", registryFlagUsage)
+
+	return cmd
+}
+
+func doRegistryLs(out io.Writer, dir, name string) error {
+	reg, err := openRegistry(dir)
+	if err != nil {
+		return err
+	}
+	idx, err := reg.Index()
+	if err != nil {
+		return err
+	}
+
+	if name != "" {
+		entries, ok := idx.Bundles[name]
+		if !ok {
+			return fmt.Errorf("%w: %s", registry.ErrNotFound, name)
+		}
+		for _, e := range entries {
+			fmt.Fprintf(out, "%s  %s  %s\n", e.Version, e.PublishedAt.Format("2006-01-02 15:04:05 MST"), e.Path)
+		}
+		return nil
+	}
+
+	for _, n := range idx.Names() {
+		entries := idx.Bundles[n]
+		versions := make([]string, 0, len(entries))
+		for _, e := range entries {
+			versions = append(versions, e.Version)
+		}
+		fmt.Fprintf(out, "%s  %s\n", n, strings.Join(versions, ", "))
+	}
+	return nil
+}
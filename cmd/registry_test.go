@@ -0,0 +1,139 @@
+package cmd
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"testing"
+
+	"github.com/LaneBirmingham/coding-agent-sync/internal/archive"
+	"github.com/LaneBirmingham/coding-agent-sync/internal/oci/ocitest"
+)
+
+func TestPublishPullAndList(t *testing.T) {
+	reg := filepath.Join(t.TempDir(), "registry")
+	src := t.TempDir()
+	for version, content := range map[string]string{"1.2.0": "# v1.2", "1.3.0": "# v1.3", "2.0.0": "# v2"} {
+		if err := os.WriteFile(filepath.Join(src, "CLAUDE.md"), []byte(content), 0o644); err != nil {
+			t.Fatal(err)
+		}
+		withCmdGlobals(src, false, func() {
+			if err := doPublish(reg, "team-python", version, "claude", "local", exportOptions{}); err != nil {
+				t.Fatalf("publish %s: %v", version, err)
+			}
+		})
+	}
+	withCmdGlobals(src, false, func() {
+		if err := doPublish(reg, "team-python", "1.3.0", "claude", "local", exportOptions{}); err == nil {
+			t.Fatal("expected republishing a version to fail")
+		}
+	})
+
+	a, err := archive.Read(filepath.Join(reg, "team-python", "1.3.0.zip"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if a.Manifest.Package == nil || a.Manifest.Package.String() != "team-python@1.3.0" {
+		t.Fatalf("expected package in manifest, got %+v", a.Manifest.Package)
+	}
+
+	dst := t.TempDir()
+	withCmdGlobals(dst, false, func() {
+		if err := doPull(reg, "team-python@^1.0", "claude,codex", "local", false, false, importOptions{AllowUnsigned: true}); err != nil {
+			t.Fatalf("pull: %v", err)
+		}
+		if err := doPull(reg, "team-python@^3", "claude", "local", true, false, importOptions{AllowUnsigned: true}); err == nil {
+			t.Fatal("expected pull without a matching version to fail")
+		}
+	})
+	for _, name := range []string{"CLAUDE.md", "AGENTS.md"} {
+		if got, err := os.ReadFile(filepath.Join(dst, name)); err != nil || string(got) != "# v1.3" {
+			t.Fatalf("expected %s from 1.3.0, got %q (%v)", name, got, err)
+		}
+	}
+
+	var out bytes.Buffer
+	if err := doRegistryLs(&out, reg, ""); err != nil {
+		t.Fatal(err)
+	}
+	if strings.TrimSpace(out.String()) != "team-python  1.2.0, 1.3.0, 2.0.0" {
+		t.Fatalf("unexpected registry listing %q", out.String())
+	}
+}
+
+func TestPullRejectsArchiveOfAnotherVersion(t *testing.T) {
+	reg := filepath.Join(t.TempDir(), "registry")
+	src := t.TempDir()
+	if err := os.WriteFile(filepath.Join(src, "CLAUDE.md"), []byte("# Team"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	withCmdGlobals(src, false, func() {
+		for _, version := range []string{"1.0.0", "2.0.0"} {
+			if err := doPublish(reg, "team", version, "claude", "local", exportOptions{}); err != nil {
+				t.Fatalf("publish %s: %v", version, err)
+			}
+		}
+	})
+	if leftover, _ := filepath.Glob(filepath.Join(reg, ".publish-*")); len(leftover) > 0 {
+		t.Fatalf("expected temporary archives to be removed, got %v", leftover)
+	}
+
+	// Copy 1.0.0 over 2.0.0 and fix up the index checksum to match.
+	old, err := os.ReadFile(filepath.Join(reg, "team", "1.0.0.zip"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(reg, "team", "2.0.0.zip"), old, 0o644); err != nil {
+		t.Fatal(err)
+	}
+	index, err := os.ReadFile(filepath.Join(reg, "index.json"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	sum := sha256.Sum256(old)
+	fixed := regexp.MustCompile(`("version": "2\.0\.0",\s*"path": "[^"]+",\s*"sha256": ")[0-9a-f]+`).
+		ReplaceAll(index, []byte("${1}"+hex.EncodeToString(sum[:])))
+	if err := os.WriteFile(filepath.Join(reg, "index.json"), fixed, 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	withCmdGlobals(t.TempDir(), false, func() {
+		err := doPull(reg, "team@2.0.0", "claude", "local", false, false, importOptions{AllowUnsigned: true})
+		if err == nil || !strings.Contains(err.Error(), "holds team@1.0.0, not team@2.0.0") {
+			t.Fatalf("expected a package mismatch error, got %v", err)
+		}
+	})
+}
+
+func TestPushPullOCI(t *testing.T) {
+	reg := ocitest.NewRegistry()
+	defer reg.Close()
+	ref := "oci://" + reg.Host() + "/team/agents:1.2"
+
+	src := t.TempDir()
+	if err := os.WriteFile(filepath.Join(src, "CLAUDE.md"), []byte("# OCI"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	withCmdGlobals(src, false, func() {
+		if err := doPush(ref, "", "", "local", false, exportOptions{}); err == nil {
+			t.Fatal("expected push without --input or --from to fail")
+		}
+		if err := doPush(ref, "", "claude", "local", false, exportOptions{}); err != nil {
+			t.Fatalf("push: %v", err)
+		}
+	})
+
+	dst := t.TempDir()
+	withCmdGlobals(dst, false, func() {
+		if err := doPull("", ref, "codex", "local", false, false, importOptions{AllowUnsigned: true}); err != nil {
+			t.Fatalf("pull: %v", err)
+		}
+	})
+	if got, err := os.ReadFile(filepath.Join(dst, "AGENTS.md")); err != nil || string(got) != "# OCI" {
+		t.Fatalf("expected pulled instructions, got %q (%v)", got, err)
+	}
+}
@@ -0,0 +1,69 @@
+package cmd
+
+import (
+	"fmt"
+	"io"
+
+	"github.com/LaneBirmingham/coding-agent-sync/internal/config"
+	"github.com/LaneBirmingham/coding-agent-sync/internal/sync"
+	"github.com/spf13/cobra"
+)
+
+func newRenderCmd() *cobra.Command {
+	var (
+		flagTo       string
+		flagScope    string
+		flagDryRun   bool
+		flagManaged  bool
+		flagPosition string
+	)
+
+	cmd := &cobra.Command{
+		Use:   "render [instructions|skills|commands|rules]",
+		Short: "Generate agent files from the canonical .cas layout",
+		Long:  "Write instructions, skills, commands and rules from the canonical layout created by cas init to each destination agent that supports them. This is the same as cas sync --from cas.",
+		Args:  cobra.MaximumNArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			kind, err := parseItemKind(args)
+			if err != nil {
+				return err
+			}
+			return doRender(cmd.OutOrStdout(), kind, flagTo, flagScope, flagDryRun, syncMode{Managed: flagManaged, Position: flagPosition})
+		},
+	}
+
+	cmd.Flags().StringVar(&flagTo, "to", targetAll, "destination agent(s), comma-separated, or all/detected")
+	cmd.Flags().StringVar(&flagScope, "scope", "", "scope to render (local, global)")
+	cmd.Flags().BoolVar(&flagDryRun, "dry-run", false, "preview changes without writing")
+	addManagedBlockFlags(cmd, &flagManaged, &flagPosition)
+
+	return cmd
+}
+
+func doRender(out io.Writer, kind sync.ItemKind, to, scope string, dryRun bool, mode syncMode) error {
+	cfg, err := buildSyncConfig(string(config.Canonical), to, dryRun, scope, "", "")
+	if err != nil {
+		return err
+	}
+	if err := mode.apply(cfg); err != nil {
+		return err
+	}
+
+	loc := config.Location{Root: cfg.Root, Scope: cfg.FromScope}
+	exists, err := canonicalExists(loc)
+	if err != nil {
+		return err
+	}
+	if !exists {
+		return fmt.Errorf("no canonical layout in %s; run cas init first", loc.CanonicalDir())
+	}
+
+	result, err := sync.SyncAll(cfg, kind)
+	if err != nil {
+		return err
+	}
+	for _, action := range result.Actions {
+		fmt.Fprintln(out, action)
+	}
+	return nil
+}
@@ -1,12 +1,18 @@
 package cmd
 
 import (
+	"fmt"
+	"os"
+
+	"github.com/LaneBirmingham/coding-agent-sync/internal/agent"
+	"github.com/LaneBirmingham/coding-agent-sync/internal/config"
 	"github.com/spf13/cobra"
 )
 
 var (
 	flagRoot    string
 	flagVerbose bool
+	flagConfig  string
 )
 
 func NewRootCmd() *cobra.Command {
@@ -14,16 +20,65 @@ func NewRootCmd() *cobra.Command {
 		Use:   "cas",
 		Short: "Sync configuration between coding agents",
 		Long:  "cas (coding-agent-sync) syncs instructions and skills between Claude Code, GitHub Copilot, Codex, and OpenCode.",
+		PersistentPreRunE: func(cmd *cobra.Command, args []string) error {
+			return loadConfig()
+		},
 	}
 
 	root.PersistentFlags().StringVar(&flagRoot, "root", ".", "project root directory")
 	root.PersistentFlags().BoolVarP(&flagVerbose, "verbose", "v", false, "verbose output")
+	root.PersistentFlags().StringVar(&flagConfig, "config", "", "config file (default $CAS_CONFIG or ~/.config/cas/config.json)")
 
 	root.AddCommand(newSyncCmd())
+	root.AddCommand(newInitCmd())
+	root.AddCommand(newRenderCmd())
+	root.AddCommand(newWatchCmd())
 	root.AddCommand(newDiffCmd())
+	root.AddCommand(newHooksCmd())
 	root.AddCommand(newExportCmd())
 	root.AddCommand(newImportCmd())
+	root.AddCommand(newDetectCmd())
+	root.AddCommand(newStatusCmd())
+	root.AddCommand(newArchiveCmd())
+	root.AddCommand(newPublishCmd())
+	root.AddCommand(newPullCmd())
+	root.AddCommand(newPushCmd())
+	root.AddCommand(newRegistryCmd())
+	root.AddCommand(newKeygenCmd())
 	root.AddCommand(newVersionCmd())
 
 	return root
 }
+
+// projectRoot returns the --root directory, resolving the default to the working directory.
+func projectRoot() (string, error) {
+	root := flagRoot
+	if root == "" || root == "." {
+		wd, err := os.Getwd()
+		if err != nil {
+			return "", fmt.Errorf("getting working directory: %w", err)
+		}
+		root = wd
+	}
+	return root, nil
+}
+
+// loadConfig reads the cas config file and registers any user-defined agents.
+func loadConfig() error {
+	path, required := flagConfig, true
+	if path == "" {
+		path, required = config.DefaultConfigPath(), false
+	}
+	file, err := config.LoadFile(path, required)
+	if err != nil {
+		return err
+	}
+	agent.SetFragmentOptions(file.Fragments)
+	agent.UnregisterAll()
+	for _, def := range file.Agents {
+		if err := agent.Register(def); err != nil {
+			return fmt.Errorf("config %s: %w", path, err)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,62 @@
+package cmd
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/LaneBirmingham/coding-agent-sync/internal/agent"
+	"github.com/LaneBirmingham/coding-agent-sync/internal/config"
+	"github.com/LaneBirmingham/coding-agent-sync/internal/sync"
+)
+
+func TestLoadConfigRegistersUserDefinedAgents(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "config.json")
+	data := `{"agents":[{"name":"acme","local":{"instructions":["ACME.md"],"skills":[".acme/skills"]}}]}`
+	if err := os.WriteFile(path, []byte(data), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	prev := flagConfig
+	flagConfig = path
+	defer func() { flagConfig = prev }()
+
+	if err := loadConfig(); err != nil {
+		t.Fatalf("loadConfig: %v", err)
+	}
+	defer agent.Unregister("acme")
+	if err := loadConfig(); err != nil {
+		t.Fatalf("loading the config twice: %v", err)
+	}
+
+	if _, err := config.ParseAgent("acme"); err != nil {
+		t.Fatalf("expected acme to be a valid agent, got %v", err)
+	}
+
+	root := t.TempDir()
+	if err := os.WriteFile(filepath.Join(root, "CLAUDE.md"), []byte("# A"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	withCmdGlobals(root, false, func() {
+		if err := doSync(sync.All, "claude", "acme", false, "local", "", "", syncMode{}); err != nil {
+			t.Fatalf("sync to user-defined agent: %v", err)
+		}
+	})
+	got, err := os.ReadFile(filepath.Join(root, "ACME.md"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(got) != "# A" {
+		t.Fatalf("expected synced instructions, got %q", string(got))
+	}
+}
+
+func TestLoadConfigMissingExplicitFile(t *testing.T) {
+	prev := flagConfig
+	flagConfig = filepath.Join(t.TempDir(), "missing.json")
+	defer func() { flagConfig = prev }()
+
+	if err := loadConfig(); err == nil {
+		t.Fatal("expected error for missing --config file")
+	}
+}
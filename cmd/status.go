@@ -0,0 +1,125 @@
+package cmd
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"text/tabwriter"
+
+	"github.com/LaneBirmingham/coding-agent-sync/internal/config"
+	"github.com/LaneBirmingham/coding-agent-sync/internal/detect"
+	"github.com/spf13/cobra"
+)
+
+func newStatusCmd() *cobra.Command {
+	var (
+		flagFrom  string
+		flagScope string
+		flagJSON  bool
+	)
+
+	cmd := &cobra.Command{
+		Use:   "status",
+		Short: "Show every agent's instructions and skills at each scope",
+		Long:  "Print a table with a row per agent and a column per item kind and scope, showing where each item lives, its size or skill count, and, with --from, whether it matches the source agent.",
+		Args:  cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			return doStatus(cmd.OutOrStdout(), flagFrom, flagScope, flagJSON)
+		},
+	}
+
+	cmd.Flags().StringVar(&flagFrom, "from", "", "compare each agent with this source agent (or auto)")
+	cmd.Flags().StringVar(&flagScope, "scope", "local,global", "scope(s), comma-separated (local, global)")
+	cmd.Flags().BoolVar(&flagJSON, "json", false, "print results as JSON")
+
+	return cmd
+}
+
+func doStatus(out io.Writer, fromStr, scopeStr string, asJSON bool) error {
+	root, err := projectRoot()
+	if err != nil {
+		return err
+	}
+
+	var locs []config.Location
+	for _, part := range strings.Split(scopeStr, ",") {
+		scope, err := config.ParseScope(strings.TrimSpace(part))
+		if err != nil {
+			return err
+		}
+		loc := config.Location{Root: root, Scope: scope}
+		if !slices.Contains(locs, loc) {
+			locs = append(locs, loc)
+		}
+	}
+
+	var source config.Agent
+	if fromStr != "" {
+		if source, err = resolveSource(fromStr, locs[0]); err != nil {
+			return err
+		}
+	}
+
+	rows, err := detect.Status(locs, source)
+	if err != nil {
+		return err
+	}
+
+	if asJSON {
+		enc := json.NewEncoder(out)
+		enc.SetIndent("", "  ")
+		return enc.Encode(rows)
+	}
+
+	tw := tabwriter.NewWriter(out, 0, 0, 2, ' ', 0)
+	header := []string{"AGENT"}
+	for _, loc := range locs {
+		header = append(header, fmt.Sprintf("INSTRUCTIONS (%s)", loc.Scope), fmt.Sprintf("SKILLS (%s)", loc.Scope))
+	}
+	fmt.Fprintln(tw, strings.Join(header, "\t"))
+	for _, row := range rows {
+		cells := []string{string(row.Agent)}
+		for _, c := range row.Cells {
+			cells = append(cells, statusCell(c, root))
+		}
+		fmt.Fprintln(tw, strings.Join(cells, "\t"))
+	}
+	return tw.Flush()
+}
+
+// statusCell renders one cell: "n/a" when unsupported, "-" when absent,
+// otherwise the path with its size or skill count, and the source comparison.
+func statusCell(c detect.Cell, root string) string {
+	var s string
+	switch {
+	case !c.Supported:
+		return "n/a"
+	case !c.Present:
+		s = "-"
+	case c.Kind == detect.KindInstructions:
+		s = fmt.Sprintf("%s (%d bytes)", displayPath(c.Path, root), c.Size)
+	default:
+		s = fmt.Sprintf("%s (%d skill(s))", displayPath(c.Path, root), c.Count)
+	}
+	if c.Match != "" {
+		s += " [" + c.Match + "]"
+	}
+	return s
+}
+
+// displayPath shortens paths under the project root or the home directory.
+func displayPath(path, root string) string {
+	if rel, err := filepath.Rel(root, path); err == nil && filepath.IsLocal(rel) {
+		return rel
+	}
+	if home, err := os.UserHomeDir(); err == nil {
+		if rel, err := filepath.Rel(home, path); err == nil && filepath.IsLocal(rel) {
+			return filepath.Join("~", rel)
+		}
+	}
+	return path
+}
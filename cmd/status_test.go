@@ -0,0 +1,32 @@
+package cmd
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestDoStatus(t *testing.T) {
+	root := t.TempDir()
+	if err := os.WriteFile(filepath.Join(root, "CLAUDE.md"), []byte("# A"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	var out bytes.Buffer
+	withCmdGlobals(root, false, func() {
+		if err := doStatus(&out, "claude", "local", false); err != nil {
+			t.Fatal(err)
+		}
+	})
+	lines := strings.Split(out.String(), "\n")
+	if !strings.HasPrefix(lines[0], "AGENT") || !strings.Contains(lines[0], "SKILLS (local)") {
+		t.Fatalf("unexpected header: %q", lines[0])
+	}
+	if !strings.Contains(out.String(), "CLAUDE.md (3 bytes) [source]") {
+		t.Fatalf("expected the claude source cell, got:\n%s", out.String())
+	}
+	if !strings.Contains(out.String(), "- [differs]") {
+		t.Fatalf("expected agents without instructions to differ, got:\n%s", out.String())
+	}
+}
@@ -7,6 +7,7 @@ import (
 	"strings"
 
 	"github.com/LaneBirmingham/coding-agent-sync/internal/config"
+	"github.com/LaneBirmingham/coding-agent-sync/internal/managed"
 	"github.com/LaneBirmingham/coding-agent-sync/internal/sync"
 	"github.com/spf13/cobra"
 )
@@ -19,35 +20,40 @@ func newSyncCmd() *cobra.Command {
 		flagScope     string
 		flagFromScope string
 		flagToScope   string
+		flagLink      bool
+		flagReference bool
+		flagManaged   bool
+		flagPosition  string
+		flagMerge     string
+		flagOnCollide string
 	)
 
 	cmd := &cobra.Command{
-		Use:   "sync [instructions|skills]",
+		Use:   "sync [instructions|skills|commands|rules]",
 		Short: "Sync configuration from one agent to others",
-		Long:  "Sync instructions and/or skills from a source agent to one or more destination agents.",
+		Long:  "Sync instructions and/or skills from a source agent to one or more destination agents. Commands and rules are synced when named, or by default when the source or a destination is the canonical cas layout.",
 		Args:  cobra.MaximumNArgs(1),
 		RunE: func(cmd *cobra.Command, args []string) error {
-			kind := sync.All
-			if len(args) == 1 {
-				switch args[0] {
-				case "instructions":
-					kind = sync.Instructions
-				case "skills":
-					kind = sync.Skills
-				default:
-					return fmt.Errorf("unknown sync target %q (valid: instructions, skills)", args[0])
-				}
+			kind, err := parseItemKind(args)
+			if err != nil {
+				return err
 			}
-			return doSync(kind, flagFrom, flagTo, flagDryRun, flagScope, flagFromScope, flagToScope)
+			return doSync(kind, flagFrom, flagTo, flagDryRun, flagScope, flagFromScope, flagToScope, syncMode{Link: flagLink, Reference: flagReference, Managed: flagManaged, Position: flagPosition, Merge: flagMerge, OnCollide: flagOnCollide})
 		},
 	}
 
-	cmd.Flags().StringVar(&flagFrom, "from", "", "source agent (claude, copilot, codex, opencode)")
-	cmd.Flags().StringVar(&flagTo, "to", "", "destination agent(s), comma-separated")
+	cmd.Flags().StringVar(&flagFrom, "from", "", "source agent(s) in priority order, comma-separated (claude, copilot, codex, opencode, gemini, or auto)")
+	cmd.Flags().StringVar(&flagTo, "to", "", "destination agent(s), comma-separated, or all/detected")
 	cmd.Flags().BoolVar(&flagDryRun, "dry-run", false, "preview changes without writing")
 	cmd.Flags().StringVar(&flagScope, "scope", "", "set both from and to scope (local, global)")
 	cmd.Flags().StringVar(&flagFromScope, "from-scope", "", "source scope (overrides --scope)")
 	cmd.Flags().StringVar(&flagToScope, "to-scope", "", "destination scope (overrides --scope)")
+	cmd.Flags().BoolVar(&flagLink, "link", false, "symlink destination instructions and skills to the source instead of copying")
+	cmd.Flags().BoolVar(&flagReference, "reference", false, "make destinations that support imports load the source instructions")
+	addManagedBlockFlags(cmd, &flagManaged, &flagPosition)
+	cmd.Flags().StringVar(&flagMerge, "merge", string(config.MergeConcat), "with several --from agents, how to combine instructions (concat, dedup, priority)")
+	cmd.Flags().StringVar(&flagOnCollide, "skill-conflict", string(config.ConflictPriority), "with several --from agents, what to do with different skills of the same name (priority, error, rename)")
+	cmd.MarkFlagsMutuallyExclusive("link", "reference", "managed-block")
 
 	_ = cmd.MarkFlagRequired("from")
 	_ = cmd.MarkFlagRequired("to")
@@ -55,11 +61,60 @@ func newSyncCmd() *cobra.Command {
 	return cmd
 }
 
-func doSync(kind sync.ItemKind, from, to string, dryRun bool, scope, fromScope, toScope string) error {
+// syncMode selects how sync writes destinations; the zero value copies.
+type syncMode struct {
+	Link      bool
+	Reference bool
+	Managed   bool   // write instructions inside a managed block
+	Position  string // where a new managed block goes
+	Merge     string // merge strategy for several sources
+	OnCollide string // skill conflict policy for several sources
+}
+
+// addManagedBlockFlags registers --managed-block and --block-position.
+func addManagedBlockFlags(cmd *cobra.Command, enabled *bool, position *string) {
+	cmd.Flags().BoolVar(enabled, "managed-block", false, "write instructions only between cas:managed markers, keeping the rest of each file")
+	cmd.Flags().StringVar(position, "block-position", managed.Top, "where to add a missing managed block (top, bottom)")
+}
+
+// apply sets the write mode on cfg.
+func (m syncMode) apply(cfg *config.SyncConfig) error {
+	cfg.Link, cfg.Reference = m.Link, m.Reference
+	if len(cfg.Sources) > 1 && (m.Link || m.Reference) {
+		return fmt.Errorf("--link and --reference need a single --from agent")
+	}
+	if m.Merge != "" {
+		merge, err := config.ParseMergeStrategy(m.Merge)
+		if err != nil {
+			return err
+		}
+		cfg.Merge = merge
+	}
+	if m.OnCollide != "" {
+		policy, err := config.ParseSkillConflict(m.OnCollide)
+		if err != nil {
+			return err
+		}
+		cfg.OnCollide = policy
+	}
+	if m.Managed {
+		pos, err := managed.ParsePosition(m.Position)
+		if err != nil {
+			return err
+		}
+		cfg.ManagedBlock = pos
+	}
+	return nil
+}
+
+func doSync(kind sync.ItemKind, from, to string, dryRun bool, scope, fromScope, toScope string, mode syncMode) error {
 	cfg, err := buildSyncConfig(from, to, dryRun, scope, fromScope, toScope)
 	if err != nil {
 		return err
 	}
+	if err := mode.apply(cfg); err != nil {
+		return err
+	}
 
 	if cfg.Verbose {
 		targets := make([]string, 0, len(cfg.To))
@@ -82,17 +137,30 @@ func doSync(kind sync.ItemKind, from, to string, dryRun bool, scope, fromScope,
 	return nil
 }
 
-func itemKindLabel(kind sync.ItemKind) string {
-	switch kind {
-	case sync.Instructions:
-		return "instructions"
-	case sync.Skills:
-		return "skills"
+// parseItemKind reads the optional instructions|skills|commands|rules
+// argument of sync, render and watch.
+func parseItemKind(args []string) (sync.ItemKind, error) {
+	if len(args) == 0 {
+		return sync.All, nil
+	}
+	switch args[0] {
+	case "instructions":
+		return sync.Instructions, nil
+	case "skills":
+		return sync.Skills, nil
+	case "commands":
+		return sync.Commands, nil
+	case "rules":
+		return sync.Rules, nil
 	default:
-		return "all"
+		return sync.All, fmt.Errorf("unknown sync target %q (valid: instructions, skills, commands, rules)", args[0])
 	}
 }
 
+func itemKindLabel(kind sync.ItemKind) string {
+	return kind.String()
+}
+
 func resolveScope(specific, fallback string) (config.Scope, error) {
 	s := specific
 	if s == "" {
@@ -105,33 +173,39 @@ func resolveScope(specific, fallback string) (config.Scope, error) {
 }
 
 func buildSyncConfig(fromStr, toStr string, dryRun bool, scope, fromScopeStr, toScopeStr string) (*config.SyncConfig, error) {
-	from, err := config.ParseAgent(fromStr)
+	fromScope, err := resolveScope(fromScopeStr, scope)
+	if err != nil {
+		return nil, err
+	}
+	toScope, err := resolveScope(toScopeStr, scope)
 	if err != nil {
 		return nil, err
 	}
 
-	fromScope, err := resolveScope(fromScopeStr, scope)
+	root, err := projectRoot()
 	if err != nil {
 		return nil, err
 	}
-	toScope, err := resolveScope(toScopeStr, scope)
+
+	sources, err := resolveSources(fromStr, config.Location{Root: root, Scope: fromScope})
+	if err != nil {
+		return nil, err
+	}
+	from := sources[0]
+
+	candidates, err := resolveTargets(toStr, root, toScope)
 	if err != nil {
 		return nil, err
 	}
 
 	var targets []config.Agent
-	for _, s := range strings.Split(toStr, ",") {
-		s = strings.TrimSpace(s)
-		if s == "" {
-			continue
-		}
-		a, err := config.ParseAgent(s)
-		if err != nil {
-			return nil, err
-		}
-		// Only skip when same agent AND same scope
-		if a == from && fromScope == toScope {
-			fmt.Fprintf(os.Stderr, "warning: skipping %s (same agent and scope)\n", a)
+	for _, a := range candidates {
+		// Only skip when same agent AND same scope. Merged sources may be
+		// targets too, which is how divergent files are consolidated.
+		if len(sources) == 1 && a == from && fromScope == toScope {
+			if !isTargetKeyword(toStr) {
+				fmt.Fprintf(os.Stderr, "warning: skipping %s (same agent and scope)\n", a)
+			}
 			continue
 		}
 		targets = append(targets, a)
@@ -141,19 +215,11 @@ func buildSyncConfig(fromStr, toStr string, dryRun bool, scope, fromScopeStr, to
 		return nil, fmt.Errorf("no valid destination agents specified")
 	}
 
-	root := flagRoot
-	if root == "" || root == "." {
-		root, err = os.Getwd()
-		if err != nil {
-			return nil, fmt.Errorf("getting working directory: %w", err)
-		}
-	}
-
 	if fromScope == config.ScopeGlobal && toScope == config.ScopeGlobal && flagRoot != "" && flagRoot != "." {
 		fmt.Fprintf(os.Stderr, "warning: --root is ignored when both scopes are global\n")
 	}
 
-	return &config.SyncConfig{
+	cfg := &config.SyncConfig{
 		From:      from,
 		To:        targets,
 		Root:      root,
@@ -161,5 +227,31 @@ func buildSyncConfig(fromStr, toStr string, dryRun bool, scope, fromScopeStr, to
 		ToScope:   toScope,
 		DryRun:    dryRun,
 		Verbose:   flagVerbose,
-	}, nil
+	}
+	if len(sources) > 1 {
+		cfg.Sources = sources
+	}
+	return cfg, nil
+}
+
+// resolveSources parses a comma-separated --from value in priority order.
+func resolveSources(s string, loc config.Location) ([]config.Agent, error) {
+	var sources []config.Agent
+	for _, part := range strings.Split(s, ",") {
+		part = strings.TrimSpace(part)
+		if part == "" {
+			continue
+		}
+		a, err := resolveSource(part, loc)
+		if err != nil {
+			return nil, err
+		}
+		if !slices.Contains(sources, a) {
+			sources = append(sources, a)
+		}
+	}
+	if len(sources) == 0 {
+		return nil, fmt.Errorf("no source agent specified")
+	}
+	return sources, nil
 }
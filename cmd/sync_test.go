@@ -0,0 +1,51 @@
+package cmd
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/LaneBirmingham/coding-agent-sync/internal/config"
+	"github.com/LaneBirmingham/coding-agent-sync/internal/sync"
+)
+
+func TestBuildSyncConfigSeveralSources(t *testing.T) {
+	withCmdGlobals(t.TempDir(), false, func() {
+		cfg, err := buildSyncConfig("claude, codex,claude", "claude,gemini", false, "local", "", "")
+		if err != nil {
+			t.Fatal(err)
+		}
+		if cfg.From != config.Claude || len(cfg.Sources) != 2 || cfg.Sources[1] != config.Codex {
+			t.Fatalf("unexpected sources: %s %v", cfg.From, cfg.Sources)
+		}
+		// Sources stay targets so their files can be consolidated.
+		if len(cfg.To) != 2 {
+			t.Fatalf("expected claude to remain a target, got %v", cfg.To)
+		}
+
+		if err := (syncMode{Link: true}).apply(cfg); err == nil {
+			t.Fatal("expected --link to need a single source")
+		}
+		if err := (syncMode{Merge: "zip"}).apply(cfg); err == nil || !strings.Contains(err.Error(), "unknown merge strategy") {
+			t.Fatalf("expected an unknown strategy error, got %v", err)
+		}
+	})
+}
+
+func TestDoSyncMerge(t *testing.T) {
+	root := t.TempDir()
+	for name, content := range map[string]string{"CLAUDE.md": "# A\n", "AGENTS.md": "# B\n"} {
+		if err := os.WriteFile(filepath.Join(root, name), []byte(content), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	withCmdGlobals(root, false, func() {
+		if err := doSync(sync.Instructions, "claude,codex", "gemini", false, "local", "", "", syncMode{Merge: "dedup"}); err != nil {
+			t.Fatal(err)
+		}
+	})
+	if got, _ := os.ReadFile(filepath.Join(root, "GEMINI.md")); string(got) != "# A\n\n# B\n" {
+		t.Fatalf("unexpected merged instructions: %q", got)
+	}
+}
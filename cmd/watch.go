@@ -0,0 +1,139 @@
+package cmd
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"os"
+	"os/signal"
+	"slices"
+	"syscall"
+	"time"
+
+	"github.com/LaneBirmingham/coding-agent-sync/internal/agent"
+	"github.com/LaneBirmingham/coding-agent-sync/internal/config"
+	"github.com/LaneBirmingham/coding-agent-sync/internal/sync"
+	"github.com/LaneBirmingham/coding-agent-sync/internal/watch"
+	"github.com/spf13/cobra"
+)
+
+func newWatchCmd() *cobra.Command {
+	var (
+		flagFrom      string
+		flagTo        string
+		flagDryRun    bool
+		flagScope     string
+		flagFromScope string
+		flagToScope   string
+		flagOpts      watch.Options
+	)
+
+	cmd := &cobra.Command{
+		Use:   "watch [instructions|skills]",
+		Short: "Sync to other agents whenever the source agent's config changes",
+		Long:  "Sync once, then watch the source agent's instruction files and skill directories and sync again after each change settles. Uses inotify on Linux and polling elsewhere (or with --poll). Stop with Ctrl-C.",
+		Args:  cobra.MaximumNArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			kind, err := parseItemKind(args)
+			if err != nil {
+				return err
+			}
+			if kind == sync.Commands || kind == sync.Rules {
+				return fmt.Errorf("watch syncs instructions and skills only, not %s", kind)
+			}
+			ctx, stop := signal.NotifyContext(cmd.Context(), os.Interrupt, syscall.SIGTERM)
+			defer stop()
+			return doWatch(ctx, cmd.OutOrStdout(), kind, flagFrom, flagTo, flagDryRun, flagScope, flagFromScope, flagToScope, flagOpts)
+		},
+	}
+
+	cmd.Flags().StringVar(&flagFrom, "from", "", "source agent (claude, copilot, codex, opencode, gemini, or auto)")
+	cmd.Flags().StringVar(&flagTo, "to", "", "destination agent(s), comma-separated, or all/detected")
+	cmd.Flags().BoolVar(&flagDryRun, "dry-run", false, "report what each sync would change without writing")
+	cmd.Flags().StringVar(&flagScope, "scope", "", "set both from and to scope (local, global)")
+	cmd.Flags().StringVar(&flagFromScope, "from-scope", "", "source scope (overrides --scope)")
+	cmd.Flags().StringVar(&flagToScope, "to-scope", "", "destination scope (overrides --scope)")
+	cmd.Flags().DurationVar(&flagOpts.Debounce, "debounce", watch.DefaultDebounce, "wait this long after the last change before syncing")
+	cmd.Flags().BoolVar(&flagOpts.Poll, "poll", false, "poll for changes instead of using inotify")
+	cmd.Flags().DurationVar(&flagOpts.Interval, "interval", watch.DefaultInterval, "polling interval")
+
+	_ = cmd.MarkFlagRequired("from")
+	_ = cmd.MarkFlagRequired("to")
+
+	return cmd
+}
+
+func doWatch(ctx context.Context, out io.Writer, kind sync.ItemKind, from, to string, dryRun bool, scope, fromScope, toScope string, opts watch.Options) error {
+	cfg, err := buildSyncConfig(from, to, dryRun, scope, fromScope, toScope)
+	if err != nil {
+		return err
+	}
+	paths, err := watchPaths(cfg, kind)
+	if err != nil {
+		return err
+	}
+
+	w, err := watch.New(paths, opts)
+	if err != nil {
+		return err
+	}
+
+	run := func() {
+		result, err := sync.SyncAll(cfg, kind)
+		if err != nil {
+			// Keep watching: the next save may fix a half-written file.
+			fmt.Fprintf(os.Stderr, "error: %v\n", err)
+			return
+		}
+		stamp := time.Now().Format("15:04:05")
+		for _, action := range result.Actions {
+			fmt.Fprintf(out, "%s %s\n", stamp, action)
+		}
+	}
+
+	run()
+	fmt.Fprintf(os.Stderr, "watching %s %s config (%d path(s), %s); press Ctrl-C to stop\n", cfg.From, cfg.FromScope, len(paths), w.Backend())
+	if flagVerbose {
+		for _, p := range paths {
+			fmt.Fprintf(os.Stderr, "verbose: watching %s\n", p)
+		}
+	}
+	if err := w.Run(ctx, run); err != nil {
+		return fmt.Errorf("watching: %w", err)
+	}
+	fmt.Fprintln(os.Stderr, "stopped watching")
+	return nil
+}
+
+// watchPaths lists the source agent's instruction files and skill
+// directories that kind syncs.
+func watchPaths(cfg *config.SyncConfig, kind sync.ItemKind) ([]string, error) {
+	loc := config.Location{Root: cfg.Root, Scope: cfg.FromScope}
+	sources := cfg.Sources
+	if len(sources) == 0 {
+		sources = []config.Agent{cfg.From}
+	}
+
+	var paths []string
+	for _, a := range sources {
+		src, err := agent.Get(a)
+		if err != nil {
+			return nil, err
+		}
+		if kind != sync.Skills {
+			paths = append(paths, src.InstructionsSources(loc)...)
+			if dir := agent.FragmentsDir(src, loc); dir != "" {
+				paths = append(paths, dir)
+			}
+		}
+		if kind != sync.Instructions {
+			paths = append(paths, src.SkillsDirs(loc)...)
+		}
+	}
+	slices.Sort(paths)
+	paths = slices.Compact(paths)
+	if len(paths) == 0 {
+		return nil, fmt.Errorf("%s has nothing to watch at %s scope", cfg.From, cfg.FromScope)
+	}
+	return paths, nil
+}
@@ -0,0 +1,65 @@
+package cmd
+
+import (
+	"bytes"
+	"context"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/LaneBirmingham/coding-agent-sync/internal/sync"
+	"github.com/LaneBirmingham/coding-agent-sync/internal/watch"
+)
+
+func TestDoWatchSyncsOnChange(t *testing.T) {
+	root := t.TempDir()
+	if err := os.WriteFile(filepath.Join(root, "CLAUDE.md"), []byte("# One"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	var out bytes.Buffer // read only after doWatch returns
+	done := make(chan error)
+	withCmdGlobals(root, false, func() {
+		go func() {
+			done <- doWatch(ctx, &out, sync.Instructions, "claude", "codex", false, "local", "", "",
+				watch.Options{Debounce: 20 * time.Millisecond, Interval: 20 * time.Millisecond, Poll: true})
+		}()
+
+		waitFor(t, func() bool { return readFile(t, filepath.Join(root, "AGENTS.md")) == "# One" }, "initial sync")
+		if err := os.WriteFile(filepath.Join(root, "CLAUDE.md"), []byte("# Two"), 0o644); err != nil {
+			t.Fatal(err)
+		}
+		waitFor(t, func() bool { return readFile(t, filepath.Join(root, "AGENTS.md")) == "# Two" }, "sync after edit")
+
+		cancel()
+		if err := <-done; err != nil {
+			t.Fatal(err)
+		}
+	})
+	if got := strings.Count(out.String(), "synced"); got != 2 {
+		t.Fatalf("expected 2 syncs in output, got %d:\n%s", got, out.String())
+	}
+}
+
+func waitFor(t *testing.T, cond func() bool, what string) {
+	t.Helper()
+	deadline := time.Now().Add(5 * time.Second)
+	for !cond() {
+		if time.Now().After(deadline) {
+			t.Fatalf("timed out waiting for %s", what)
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+}
+
+func readFile(t *testing.T, path string) string {
+	t.Helper()
+	data, err := os.ReadFile(path)
+	if err != nil && !os.IsNotExist(err) {
+		t.Fatal(err)
+	}
+	return string(data)
+}
@@ -18,9 +18,16 @@ type Skill struct {
 }
 
 // Agent defines the interface for reading and writing agent configuration.
+//
+// InstructionsPath and SkillsPath are where writes go. InstructionsSources and
+// SkillsDirs list the locations reads consult, in precedence order. All four
+// return empty values when the agent does not support the scope.
 type Agent interface {
 	Name() string
 	InstructionsPath(loc config.Location) string
+	InstructionsSources(loc config.Location) []string
+	SkillsPath(loc config.Location) string
+	SkillsDirs(loc config.Location) []string
 	ReadInstructions(loc config.Location) (*Instruction, error)
 	ReadSkills(loc config.Location) ([]Skill, error)
 	WriteInstructions(loc config.Location, inst *Instruction) error
@@ -34,6 +41,8 @@ var registry = map[config.Agent]Agent{
 	config.Codex:    &Codex{},
 	config.OpenCode: &OpenCode{},
 	config.Gemini:   &Gemini{},
+
+	config.Canonical: &Canonical{},
 }
 
 // Get returns the Agent implementation for the given agent type.
@@ -44,3 +53,27 @@ func Get(a config.Agent) (Agent, error) {
 	}
 	return impl, nil
 }
+
+// PathChecker is implemented by agents whose paths can fail to resolve,
+// such as user-defined agents whose paths name environment variables.
+type PathChecker interface {
+	// CheckPaths reports why the agent's paths at loc cannot be resolved.
+	CheckPaths(loc config.Location) error
+}
+
+// GetAt is Get for an agent written to at loc. It fails when the paths the
+// agent writes there cannot be resolved, which InstructionsPath and
+// SkillsPath report only as "". Sources are read with Get, since reads skip
+// such paths for the ones after them.
+func GetAt(a config.Agent, loc config.Location) (Agent, error) {
+	impl, err := Get(a)
+	if err != nil {
+		return nil, err
+	}
+	if c, ok := impl.(PathChecker); ok {
+		if err := c.CheckPaths(loc); err != nil {
+			return nil, err
+		}
+	}
+	return impl, nil
+}
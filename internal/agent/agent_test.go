@@ -3,6 +3,8 @@ package agent
 import (
 	"os"
 	"path/filepath"
+	"slices"
+	"strings"
 	"testing"
 
 	"github.com/LaneBirmingham/coding-agent-sync/internal/config"
@@ -783,3 +785,362 @@ func TestGemini_Global_Skills_FallbackPriority(t *testing.T) {
 		t.Errorf("expected canonical global skills, got %v", skills)
 	}
 }
+
+// --- Generic (user-defined) tests ---
+
+func acmeDefinition() config.AgentDefinition {
+	return config.AgentDefinition{
+		Name: "acme",
+		Local: &config.AgentPaths{
+			Instructions:      []string{".acme/ACME.md", "ACME.md"},
+			WriteInstructions: "ACME.md",
+			Skills:            []string{".acme/skills"},
+		},
+		Global: &config.AgentPaths{
+			Instructions: []string{"$ACME_HOME/ACME.md", "~/.acme/ACME.md"},
+			Skills:       []string{"~/.acme/skills"},
+		},
+	}
+}
+
+func TestGeneric_Local_ReadPrecedenceAndWritePath(t *testing.T) {
+	root := setupTestDir(t)
+	writeTestFile(t, filepath.Join(root, ".acme", "ACME.md"), "# From .acme")
+	writeTestFile(t, filepath.Join(root, "ACME.md"), "# From root")
+
+	g, err := NewGeneric(acmeDefinition())
+	if err != nil {
+		t.Fatal(err)
+	}
+	inst, err := g.ReadInstructions(config.Local(root))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if inst == nil || inst.Content != "# From .acme" {
+		t.Errorf("expected .acme/ACME.md to take priority, got %v", inst)
+	}
+
+	if got, want := g.InstructionsPath(config.Local(root)), filepath.Join(root, "ACME.md"); got != want {
+		t.Errorf("expected write path %q, got %q", want, got)
+	}
+	if err := g.WriteInstructions(config.Local(root), &Instruction{Content: "# New"}); err != nil {
+		t.Fatal(err)
+	}
+	if got := readTestFile(t, filepath.Join(root, "ACME.md")); got != "# New" {
+		t.Errorf("expected '# New', got %q", got)
+	}
+}
+
+func TestGeneric_Local_ReadWriteSkills(t *testing.T) {
+	root := setupTestDir(t)
+	g, err := NewGeneric(acmeDefinition())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := g.WriteSkills(config.Local(root), []Skill{{Name: "s1", Content: "skill"}}); err != nil {
+		t.Fatal(err)
+	}
+	got := readTestFile(t, filepath.Join(root, ".acme", "skills", "s1", "SKILL.md"))
+	if got != "skill" {
+		t.Errorf("expected 'skill', got %q", got)
+	}
+	skills, err := g.ReadSkills(config.Local(root))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(skills) != 1 || skills[0].Name != "s1" {
+		t.Errorf("unexpected skills: %v", skills)
+	}
+}
+
+func TestGeneric_Global_ExpandsEnvAndHome(t *testing.T) {
+	home := t.TempDir()
+	acmeHome := t.TempDir()
+	t.Setenv("HOME", home)
+	t.Setenv("ACME_HOME", acmeHome)
+	writeTestFile(t, filepath.Join(acmeHome, "ACME.md"), "# Env")
+	writeTestFile(t, filepath.Join(home, ".acme", "ACME.md"), "# Home")
+
+	g, err := NewGeneric(acmeDefinition())
+	if err != nil {
+		t.Fatal(err)
+	}
+	inst, err := g.ReadInstructions(config.Global())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if inst == nil || inst.Content != "# Env" {
+		t.Errorf("expected $ACME_HOME path to take priority, got %v", inst)
+	}
+	if got, want := g.InstructionsPath(config.Global()), filepath.Join(acmeHome, "ACME.md"); got != want {
+		t.Errorf("expected %q, got %q", want, got)
+	}
+}
+
+func TestGeneric_Global_UnsetEnv(t *testing.T) {
+	home := t.TempDir()
+	t.Setenv("HOME", home)
+	t.Setenv("ACME_HOME", "")
+	writeTestFile(t, filepath.Join(home, ".acme", "ACME.md"), "# Home")
+
+	g, err := NewGeneric(acmeDefinition())
+	if err != nil {
+		t.Fatal(err)
+	}
+	inst, err := g.ReadInstructions(config.Global())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if inst == nil || inst.Content != "# Home" {
+		t.Errorf("expected the ~/.acme/ACME.md fallback, got %v", inst)
+	}
+	if got, want := g.InstructionsSources(config.Global()), []string{filepath.Join(home, ".acme", "ACME.md")}; !slices.Equal(got, want) {
+		t.Errorf("expected sources %v, got %v", want, got)
+	}
+	if path := g.InstructionsPath(config.Global()); path != "" {
+		t.Errorf("expected no write path, got %q", path)
+	}
+	if err := g.WriteInstructions(config.Global(), &Instruction{Content: "x"}); err == nil || !strings.Contains(err.Error(), "ACME_HOME") {
+		t.Fatalf("expected writing to report the unset variable, got %v", err)
+	}
+
+	if err := Register(acmeDefinition()); err != nil {
+		t.Fatal(err)
+	}
+	defer Unregister("acme")
+	if _, err := GetAt("acme", config.Global()); err == nil || !strings.Contains(err.Error(), "ACME_HOME") {
+		t.Fatalf("expected GetAt to report the unset variable, got %v", err)
+	}
+	if _, err := GetAt("acme", config.Local(t.TempDir())); err != nil {
+		t.Fatalf("expected the local scope to resolve, got %v", err)
+	}
+}
+
+func TestGeneric_UnsupportedScope(t *testing.T) {
+	def := acmeDefinition()
+	def.Global = nil
+	g, err := NewGeneric(def)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if path := g.InstructionsPath(config.Global()); path != "" {
+		t.Errorf("expected empty path for unsupported scope, got %q", path)
+	}
+	if err := g.WriteInstructions(config.Global(), &Instruction{Content: "x"}); err == nil {
+		t.Fatal("expected error writing unsupported scope")
+	}
+}
+
+// --- Canonical tests ---
+
+func TestCanonical_Local_ReadWrite(t *testing.T) {
+	dir := setupTestDir(t)
+	c := &Canonical{}
+	loc := config.Local(dir)
+
+	if err := c.WriteInstructions(loc, &Instruction{Content: "# Canonical"}); err != nil {
+		t.Fatal(err)
+	}
+	if got := readTestFile(t, filepath.Join(dir, ".cas", "instructions.md")); got != "# Canonical" {
+		t.Errorf("unexpected instructions: %q", got)
+	}
+
+	if err := c.WriteSkills(loc, []Skill{{Name: "review", Content: "review skill"}}); err != nil {
+		t.Fatal(err)
+	}
+	skills, err := c.ReadSkills(loc)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(skills) != 1 || skills[0].Name != "review" {
+		t.Errorf("unexpected skills: %v", skills)
+	}
+	if got := c.SkillsPath(loc); got != filepath.Join(dir, ".cas", "skills") {
+		t.Errorf("unexpected skills path: %q", got)
+	}
+}
+
+func TestCanonical_Global_UsesConfigDir(t *testing.T) {
+	xdg := t.TempDir()
+	t.Setenv("XDG_CONFIG_HOME", xdg)
+
+	c := &Canonical{}
+	if got := c.InstructionsPath(config.Global()); got != filepath.Join(xdg, "cas", "instructions.md") {
+		t.Errorf("unexpected global path: %q", got)
+	}
+	if _, err := Get(config.Canonical); err != nil {
+		t.Errorf("expected the canonical layout to be registered: %v", err)
+	}
+}
+
+func TestSupportsReference(t *testing.T) {
+	for _, tt := range []struct {
+		agent config.Agent
+		want  bool
+	}{
+		{config.Claude, true},
+		{config.Gemini, true},
+		{config.Codex, false},
+		{config.Copilot, false},
+		{config.OpenCode, false},
+	} {
+		impl, err := Get(tt.agent)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got := SupportsReference(impl); got != tt.want {
+			t.Errorf("SupportsReference(%s) = %t, want %t", tt.agent, got, tt.want)
+		}
+	}
+}
+
+func TestClaude_WriteReference_Global(t *testing.T) {
+	home := t.TempDir()
+	t.Setenv("HOME", home)
+
+	target := filepath.Join(home, ".codex", "AGENTS.md")
+	path, err := (&Claude{}).WriteReference(config.Global(), target)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := readTestFile(t, path); got != "@"+target+"\n" {
+		t.Errorf("expected an absolute import, got %q", got)
+	}
+}
+
+func TestReadComposedInstructions(t *testing.T) {
+	dir := setupTestDir(t)
+	loc := config.Local(dir)
+	writeTestFile(t, filepath.Join(dir, "CLAUDE.md"), "# Project\n")
+	writeTestFile(t, filepath.Join(dir, ".claude", "instructions.d", "20-testing.md"), "Run go test.\n")
+	writeTestFile(t, filepath.Join(dir, ".claude", "instructions.d", "10-code_style.md"), "Use gofmt.\n")
+	writeTestFile(t, filepath.Join(dir, ".claude", "instructions.d", "notes.txt"), "ignored")
+
+	inst, frags, err := ReadComposedInstructions(&Claude{}, loc)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := "# Project\n\nUse gofmt.\n\nRun go test.\n"; inst == nil || inst.Content != want {
+		t.Errorf("expected %q, got %v", want, inst)
+	}
+	if len(frags) != 2 || frags[0] != "10-code_style.md" || frags[1] != "20-testing.md" {
+		t.Errorf("unexpected fragments: %v", frags)
+	}
+
+	inst, frags, err = ReadComposedInstructions(&Codex{}, loc)
+	if err != nil || inst != nil || frags != nil {
+		t.Errorf("expected nothing for codex, got %v %v %v", inst, frags, err)
+	}
+}
+
+func TestReadFollowsListedSources(t *testing.T) {
+	t.Setenv("HOME", t.TempDir())
+	t.Setenv("CODEX_HOME", "")
+	for _, a := range config.ValidAgents {
+		for _, loc := range []config.Location{config.Local(t.TempDir()), config.Global()} {
+			impl, err := Get(a)
+			if err != nil {
+				t.Fatal(err)
+			}
+			// Each listed path on its own must be read.
+			for _, path := range impl.InstructionsSources(loc) {
+				if err := writeFile(path, "# "+path); err != nil {
+					t.Fatal(err)
+				}
+				inst, err := impl.ReadInstructions(loc)
+				if err != nil || inst == nil || inst.Content != "# "+path {
+					t.Errorf("%s %s: expected %s to be read, got %+v (%v)", a, loc.Scope, path, inst, err)
+				}
+				os.Remove(path)
+			}
+			for _, dir := range impl.SkillsDirs(loc) {
+				if err := writeSkillsToDir(dir, []Skill{{Name: "s", Content: dir}}); err != nil {
+					t.Fatal(err)
+				}
+				skills, err := impl.ReadSkills(loc)
+				if err != nil || len(skills) != 1 || skills[0].Content != dir {
+					t.Errorf("%s %s: expected skills from %s, got %+v (%v)", a, loc.Scope, dir, skills, err)
+				}
+				os.RemoveAll(dir)
+			}
+		}
+	}
+}
+
+func TestCompose_HeadingsAndDedup(t *testing.T) {
+	frags := []Fragment{
+		{Name: "10-code_style.md", Content: "Use gofmt.\n\nKeep it short.\n"},
+		{Name: "20-review.md", Content: "# Review\n\nKeep it short.\n"},
+	}
+	got := Compose("", frags, config.FragmentOptions{Headings: true, Dedup: true})
+	want := "## Code style\n\nUse gofmt.\n\nKeep it short.\n\n# Review\n"
+	if got != want {
+		t.Errorf("expected %q, got %q", want, got)
+	}
+}
+
+func TestCompose_DedupKeepsCodeBlocksWhole(t *testing.T) {
+	block := "```sh\ngo test ./...\n\ngo vet ./...\n```"
+	frags := []Fragment{
+		{Name: "10-build.md", Content: "go vet ./...\n\n" + block + "\n"},
+		{Name: "20-test.md", Content: "Run:\n" + block + "\n\n" + block + "\n"},
+	}
+	got := Compose("", frags, config.FragmentOptions{Dedup: true})
+	want := "go vet ./...\n\n" + block + "\n\nRun:\n" + block + "\n"
+	if got != want {
+		t.Errorf("expected %q, got %q", want, got)
+	}
+}
+
+func TestRegister_UserDefinedAgent(t *testing.T) {
+	if err := Register(acmeDefinition()); err != nil {
+		t.Fatal(err)
+	}
+	defer Unregister("acme")
+
+	a, err := config.ParseAgent("ACME")
+	if err != nil {
+		t.Fatal(err)
+	}
+	impl, err := Get(a)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if impl.Name() != "acme" {
+		t.Errorf("expected acme, got %q", impl.Name())
+	}
+
+	if err := Register(acmeDefinition()); err == nil {
+		t.Fatal("expected error registering a duplicate agent")
+	}
+	UnregisterAll()
+	if _, err := config.ParseAgent("acme"); err == nil {
+		t.Fatal("expected UnregisterAll to remove acme")
+	}
+	if err := Register(acmeDefinition()); err != nil {
+		t.Fatalf("expected acme to register again, got %v", err)
+	}
+	builtin := acmeDefinition()
+	builtin.Name = "claude"
+	if err := Register(builtin); err == nil {
+		t.Fatal("expected error redefining a built-in agent")
+	}
+}
+
+func TestSkill_Description(t *testing.T) {
+	tests := []struct {
+		content string
+		want    string
+	}{
+		{"---\nname: a\ndescription: Plain text\n---\nbody", "Plain text"},
+		{"---\ndescription: \"Quoted: yes\"\n---\n", "Quoted: yes"},
+		{"---\ndescription: >\n  Folded over\n  two lines\nname: a\n---\n", "Folded over two lines"},
+		{"---\nname: a\n---\ndescription: not frontmatter\n", ""},
+		{"# No frontmatter\ndescription: x\n", ""},
+	}
+	for _, tt := range tests {
+		if got := (Skill{Content: tt.content}).Description(); got != tt.want {
+			t.Errorf("Description(%q) = %q, want %q", tt.content, got, tt.want)
+		}
+	}
+}
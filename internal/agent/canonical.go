@@ -0,0 +1,65 @@
+package agent
+
+import (
+	"path/filepath"
+
+	"github.com/LaneBirmingham/coding-agent-sync/internal/config"
+)
+
+// Canonical implements Agent for the agent-neutral layout in
+// config.Location.CanonicalDir: instructions.md and skills/<name>/SKILL.md.
+type Canonical struct{}
+
+func (c *Canonical) Name() string { return string(config.Canonical) }
+
+func (c *Canonical) InstructionsPath(loc config.Location) string {
+	dir := loc.CanonicalDir()
+	if dir == "" {
+		return ""
+	}
+	return filepath.Join(dir, "instructions.md")
+}
+
+func (c *Canonical) InstructionsSources(loc config.Location) []string {
+	return nonEmpty(c.InstructionsPath(loc))
+}
+
+func (c *Canonical) SkillsPath(loc config.Location) string {
+	dir := loc.CanonicalDir()
+	if dir == "" {
+		return ""
+	}
+	return filepath.Join(dir, "skills")
+}
+
+func (c *Canonical) SkillsDirs(loc config.Location) []string {
+	return nonEmpty(c.SkillsPath(loc))
+}
+
+func (c *Canonical) ReadInstructions(loc config.Location) (*Instruction, error) {
+	path := c.InstructionsPath(loc)
+	if path == "" {
+		return nil, nil
+	}
+	content, err := readFile(path)
+	if err != nil || content == "" {
+		return nil, err
+	}
+	return &Instruction{Content: content}, nil
+}
+
+func (c *Canonical) ReadSkills(loc config.Location) ([]Skill, error) {
+	dir := c.SkillsPath(loc)
+	if dir == "" {
+		return nil, nil
+	}
+	return readSkillsFromDir(dir)
+}
+
+func (c *Canonical) WriteInstructions(loc config.Location, inst *Instruction) error {
+	return writeFile(c.InstructionsPath(loc), inst.Content)
+}
+
+func (c *Canonical) WriteSkills(loc config.Location, skills []Skill) error {
+	return writeSkillsToDir(c.SkillsPath(loc), skills)
+}
@@ -24,47 +24,43 @@ func (c *Claude) InstructionsPath(loc config.Location) string {
 	return filepath.Join(loc.Root, "CLAUDE.md")
 }
 
-func (c *Claude) ReadInstructions(loc config.Location) (*Instruction, error) {
+func (c *Claude) InstructionsSources(loc config.Location) []string {
 	if loc.Scope == config.ScopeGlobal {
-		home, err := os.UserHomeDir()
-		if err != nil {
-			return nil, fmt.Errorf("determining home directory: %w", err)
-		}
-		content, err := readFile(filepath.Join(home, ".claude", "CLAUDE.md"))
-		if err != nil {
-			return nil, err
-		}
-		if content == "" {
-			return nil, nil
-		}
-		return &Instruction{Content: content}, nil
+		return nonEmpty(c.InstructionsPath(loc))
 	}
-
-	// Try .claude/CLAUDE.md first, then root CLAUDE.md
-	for _, path := range []string{
+	return []string{
 		filepath.Join(loc.Root, ".claude", "CLAUDE.md"),
 		filepath.Join(loc.Root, "CLAUDE.md"),
-	} {
-		content, err := readFile(path)
-		if err != nil {
-			return nil, err
-		}
-		if content != "" {
-			return &Instruction{Content: content}, nil
-		}
 	}
-	return nil, nil
 }
 
-func (c *Claude) ReadSkills(loc config.Location) ([]Skill, error) {
+func (c *Claude) SkillsPath(loc config.Location) string {
 	if loc.Scope == config.ScopeGlobal {
 		home, err := os.UserHomeDir()
 		if err != nil {
-			return nil, fmt.Errorf("determining home directory: %w", err)
+			return ""
 		}
-		return readSkillsFromDir(filepath.Join(home, ".claude", "skills"))
+		return filepath.Join(home, ".claude", "skills")
+	}
+	return filepath.Join(loc.Root, ".claude", "skills")
+}
+
+func (c *Claude) SkillsDirs(loc config.Location) []string {
+	return nonEmpty(c.SkillsPath(loc))
+}
+
+func (c *Claude) ReadInstructions(loc config.Location) (*Instruction, error) {
+	if err := checkHome(loc); err != nil {
+		return nil, err
+	}
+	return readFirstInstruction(c.InstructionsSources(loc))
+}
+
+func (c *Claude) ReadSkills(loc config.Location) ([]Skill, error) {
+	if err := checkHome(loc); err != nil {
+		return nil, err
 	}
-	return readSkillsFromDir(filepath.Join(loc.Root, ".claude", "skills"))
+	return readFirstSkills(c.SkillsDirs(loc))
 }
 
 func (c *Claude) WriteInstructions(loc config.Location, inst *Instruction) error {
@@ -24,59 +24,71 @@ func (c *Codex) InstructionsPath(loc config.Location) string {
 	return filepath.Join(loc.Root, "AGENTS.md")
 }
 
-func (c *Codex) ReadInstructions(loc config.Location) (*Instruction, error) {
+func (c *Codex) InstructionsSources(loc config.Location) []string {
 	if loc.Scope == config.ScopeGlobal {
 		codexHome, home, err := resolveCodexAndHomeDirs()
 		if err != nil {
-			return nil, fmt.Errorf("determining codex home directory: %w", err)
+			return nil
 		}
-		paths := uniquePaths(
+		return uniquePaths(
 			filepath.Join(codexHome, "AGENTS.override.md"),
 			filepath.Join(codexHome, "AGENTS.md"),
 			filepath.Join(home, ".codex", "AGENTS.override.md"),
 			filepath.Join(home, ".codex", "AGENTS.md"),
 		)
-		return readFirstInstruction(paths)
 	}
-
-	paths := []string{
+	return []string{
 		filepath.Join(loc.Root, "AGENTS.override.md"),
 		filepath.Join(loc.Root, "AGENTS.md"),
 		filepath.Join(loc.Root, "TEAM_GUIDE.md"),
 		filepath.Join(loc.Root, ".agents.md"),
 	}
-	return readFirstInstruction(paths)
 }
 
-func (c *Codex) ReadSkills(loc config.Location) ([]Skill, error) {
+func (c *Codex) SkillsPath(loc config.Location) string {
 	if loc.Scope == config.ScopeGlobal {
 		home, err := resolveHomeDir()
 		if err != nil {
-			return nil, fmt.Errorf("determining home directory: %w", err)
+			return ""
 		}
-		skills, err := readSkillsFromDir(filepath.Join(home, ".agents", "skills"))
+		return filepath.Join(home, ".agents", "skills")
+	}
+	return filepath.Join(loc.Root, ".agents", "skills")
+}
+
+func (c *Codex) SkillsDirs(loc config.Location) []string {
+	if loc.Scope == config.ScopeGlobal {
+		codexHome, home, err := resolveCodexAndHomeDirs()
 		if err != nil {
-			return nil, err
+			return nil
 		}
-		if len(skills) > 0 {
-			return skills, nil
+		return []string{
+			filepath.Join(home, ".agents", "skills"),
+			filepath.Join(codexHome, "skills"),
 		}
+	}
+	return []string{
+		filepath.Join(loc.Root, ".agents", "skills"),
+		filepath.Join(loc.Root, ".codex", "skills"),
+	}
+}
 
-		codexHome, err := resolveCodexHomeDir()
-		if err != nil {
+func (c *Codex) ReadInstructions(loc config.Location) (*Instruction, error) {
+	if loc.Scope == config.ScopeGlobal {
+		if _, _, err := resolveCodexAndHomeDirs(); err != nil {
 			return nil, fmt.Errorf("determining codex home directory: %w", err)
 		}
-		return readSkillsFromDir(filepath.Join(codexHome, "skills"))
 	}
+	return readFirstInstruction(c.InstructionsSources(loc))
+}
 
-	skills, err := readSkillsFromDir(filepath.Join(loc.Root, ".agents", "skills"))
-	if err != nil {
-		return nil, err
-	}
-	if len(skills) > 0 {
-		return skills, nil
+func (c *Codex) ReadSkills(loc config.Location) ([]Skill, error) {
+	if loc.Scope == config.ScopeGlobal {
+		if _, _, err := resolveCodexAndHomeDirs(); err != nil {
+			return nil, fmt.Errorf("determining codex home directory: %w", err)
+		}
 	}
-	return readSkillsFromDir(filepath.Join(loc.Root, ".codex", "skills"))
+	return readFirstSkills(c.SkillsDirs(loc))
 }
 
 func (c *Codex) WriteInstructions(loc config.Location, inst *Instruction) error {
@@ -101,19 +113,6 @@ func (c *Codex) WriteSkills(loc config.Location, skills []Skill) error {
 	return writeSkillsToDir(filepath.Join(loc.Root, ".agents", "skills"), skills)
 }
 
-func readFirstInstruction(paths []string) (*Instruction, error) {
-	for _, path := range paths {
-		content, err := readFile(path)
-		if err != nil {
-			return nil, err
-		}
-		if content != "" {
-			return &Instruction{Content: content}, nil
-		}
-	}
-	return nil, nil
-}
-
 func uniquePaths(paths ...string) []string {
 	seen := make(map[string]struct{}, len(paths))
 	var out []string
@@ -21,30 +21,34 @@ func (c *Copilot) InstructionsPath(loc config.Location) string {
 	return filepath.Join(loc.Root, "AGENTS.md")
 }
 
-func (c *Copilot) ReadInstructions(loc config.Location) (*Instruction, error) {
-	if loc.Scope == config.ScopeGlobal {
-		// Copilot does not support global instructions
-		return nil, nil
-	}
-	content, err := readFile(filepath.Join(loc.Root, "AGENTS.md"))
-	if err != nil {
-		return nil, err
-	}
-	if content == "" {
-		return nil, nil
-	}
-	return &Instruction{Content: content}, nil
+func (c *Copilot) InstructionsSources(loc config.Location) []string {
+	return nonEmpty(c.InstructionsPath(loc))
 }
 
-func (c *Copilot) ReadSkills(loc config.Location) ([]Skill, error) {
+func (c *Copilot) SkillsPath(loc config.Location) string {
 	if loc.Scope == config.ScopeGlobal {
 		home, err := os.UserHomeDir()
 		if err != nil {
-			return nil, fmt.Errorf("determining home directory: %w", err)
+			return ""
 		}
-		return readSkillsFromDir(filepath.Join(home, ".copilot", "skills"))
+		return filepath.Join(home, ".copilot", "skills")
+	}
+	return filepath.Join(loc.Root, ".github", "skills")
+}
+
+func (c *Copilot) SkillsDirs(loc config.Location) []string {
+	return nonEmpty(c.SkillsPath(loc))
+}
+
+func (c *Copilot) ReadInstructions(loc config.Location) (*Instruction, error) {
+	return readFirstInstruction(c.InstructionsSources(loc))
+}
+
+func (c *Copilot) ReadSkills(loc config.Location) ([]Skill, error) {
+	if err := checkHome(loc); err != nil {
+		return nil, err
 	}
-	return readSkillsFromDir(filepath.Join(loc.Root, ".github", "skills"))
+	return readFirstSkills(c.SkillsDirs(loc))
 }
 
 func (c *Copilot) WriteInstructions(loc config.Location, inst *Instruction) error {
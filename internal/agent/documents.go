@@ -0,0 +1,169 @@
+package agent
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/LaneBirmingham/coding-agent-sync/internal/config"
+)
+
+// Document is a named markdown file kept flat in a directory, such as a
+// slash command or a rule. Name excludes the directory's file suffix.
+type Document struct {
+	Name    string
+	Content string
+}
+
+// Commander is implemented by agents that load slash commands from a
+// directory of markdown files.
+type Commander interface {
+	// CommandsDir returns the directory the agent reads commands from at
+	// loc and the suffix of each file, or "" when loc has none.
+	CommandsDir(loc config.Location) (dir, suffix string)
+}
+
+// Ruler is implemented by agents that load rules, instructions scoped to
+// some of the project's files, from a directory of markdown files.
+type Ruler interface {
+	// RulesDir returns the directory the agent reads rules from at loc and
+	// the suffix of each file, or "" when loc has none.
+	RulesDir(loc config.Location) (dir, suffix string)
+}
+
+// CommandsDir returns a's commands directory and file suffix at loc, or ""
+// when a has no commands there.
+func CommandsDir(a Agent, loc config.Location) (string, string) {
+	if c, ok := a.(Commander); ok {
+		return c.CommandsDir(loc)
+	}
+	return "", ""
+}
+
+// RulesDir returns a's rules directory and file suffix at loc, or "" when a
+// has no rules there.
+func RulesDir(a Agent, loc config.Location) (string, string) {
+	if r, ok := a.(Ruler); ok {
+		return r.RulesDir(loc)
+	}
+	return "", ""
+}
+
+// ReadDocuments reads the non-empty files in dir ending in suffix. A missing
+// dir has no documents.
+func ReadDocuments(dir, suffix string) ([]Document, error) {
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil, nil
+		}
+		return nil, err
+	}
+
+	var docs []Document
+	for _, e := range entries {
+		name, ok := strings.CutSuffix(e.Name(), suffix)
+		if e.IsDir() || !ok || name == "" {
+			continue
+		}
+		content, err := readFile(filepath.Join(dir, e.Name()))
+		if err != nil {
+			return nil, err
+		}
+		if content != "" {
+			docs = append(docs, Document{Name: name, Content: content})
+		}
+	}
+	return docs, nil
+}
+
+// WriteDocuments writes each document to dir as its name plus suffix.
+func WriteDocuments(dir, suffix string, docs []Document) error {
+	for _, d := range docs {
+		if err := validateSkillDirName(d.Name); err != nil {
+			return fmt.Errorf("invalid name %q: %w", d.Name, err)
+		}
+		if err := writeFile(filepath.Join(dir, d.Name+suffix), d.Content); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// CommandsDir returns .claude/commands in the project or home directory.
+func (c *Claude) CommandsDir(loc config.Location) (string, string) {
+	skills := c.SkillsPath(loc)
+	if skills == "" {
+		return "", ""
+	}
+	return filepath.Join(filepath.Dir(skills), "commands"), ".md"
+}
+
+// RulesDir returns .claude/rules in the project or home directory.
+func (c *Claude) RulesDir(loc config.Location) (string, string) {
+	skills := c.SkillsPath(loc)
+	if skills == "" {
+		return "", ""
+	}
+	return filepath.Join(filepath.Dir(skills), "rules"), ".md"
+}
+
+// CommandsDir returns .opencode/command, or command in OpenCode's config
+// directory for the global scope.
+func (o *OpenCode) CommandsDir(loc config.Location) (string, string) {
+	skills := o.SkillsPath(loc)
+	if skills == "" {
+		return "", ""
+	}
+	return filepath.Join(filepath.Dir(skills), "command"), ".md"
+}
+
+// CommandsDir returns the repository's .github/prompts. Copilot has no
+// global prompt files.
+func (c *Copilot) CommandsDir(loc config.Location) (string, string) {
+	if loc.Scope == config.ScopeGlobal {
+		return "", ""
+	}
+	return filepath.Join(loc.Root, ".github", "prompts"), ".prompt.md"
+}
+
+// RulesDir returns the repository's .github/instructions. Copilot has no
+// global instruction files.
+func (c *Copilot) RulesDir(loc config.Location) (string, string) {
+	if loc.Scope == config.ScopeGlobal {
+		return "", ""
+	}
+	return filepath.Join(loc.Root, ".github", "instructions"), ".instructions.md"
+}
+
+// CommandsDir returns prompts in CODEX_HOME. Codex only loads custom
+// prompts globally.
+func (c *Codex) CommandsDir(loc config.Location) (string, string) {
+	if loc.Scope != config.ScopeGlobal {
+		return "", ""
+	}
+	codexHome, err := resolveCodexHomeDir()
+	if err != nil {
+		return "", ""
+	}
+	return filepath.Join(codexHome, "prompts"), ".md"
+}
+
+// CommandsDir returns commands in the canonical layout.
+func (c *Canonical) CommandsDir(loc config.Location) (string, string) {
+	dir := loc.CanonicalDir()
+	if dir == "" {
+		return "", ""
+	}
+	return filepath.Join(dir, "commands"), ".md"
+}
+
+// RulesDir returns rules in the canonical layout.
+func (c *Canonical) RulesDir(loc config.Location) (string, string) {
+	dir := loc.CanonicalDir()
+	if dir == "" {
+		return "", ""
+	}
+	return filepath.Join(dir, "rules"), ".md"
+}
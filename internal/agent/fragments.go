@@ -0,0 +1,192 @@
+package agent
+
+import (
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"unicode"
+
+	"github.com/LaneBirmingham/coding-agent-sync/internal/config"
+)
+
+// FragmentsDirName is the directory of numbered markdown fragments that are
+// composed into an agent's instructions.
+const FragmentsDirName = "instructions.d"
+
+// fragmentDirs names the directory holding each agent's instructions.d at
+// local scope. At global scope it sits next to the instructions file.
+var fragmentDirs = map[config.Agent]string{
+	config.Claude:    ".claude",
+	config.Copilot:   ".github",
+	config.Codex:     ".codex",
+	config.OpenCode:  ".opencode",
+	config.Gemini:    ".gemini",
+	config.Canonical: config.CanonicalDir,
+}
+
+// fragmentOptions controls how fragments are composed; see SetFragmentOptions.
+var fragmentOptions config.FragmentOptions
+
+// SetFragmentOptions sets how fragments are composed, normally from the
+// config file.
+func SetFragmentOptions(opts config.FragmentOptions) { fragmentOptions = opts }
+
+// Fragment is one file of an instructions.d directory.
+type Fragment struct {
+	Name    string // file name, e.g. "10-style.md"
+	Content string
+}
+
+// FragmentsDir returns where a keeps instruction fragments at loc, or "".
+func FragmentsDir(a Agent, loc config.Location) string {
+	if loc.Scope == config.ScopeGlobal {
+		path := a.InstructionsPath(loc)
+		if path == "" {
+			return ""
+		}
+		return filepath.Join(filepath.Dir(path), FragmentsDirName)
+	}
+	dir, ok := fragmentDirs[config.Agent(a.Name())]
+	if !ok {
+		return ""
+	}
+	return filepath.Join(loc.Root, dir, FragmentsDirName)
+}
+
+// ReadFragments returns the non-empty .md files in dir, sorted by name.
+func ReadFragments(dir string) ([]Fragment, error) {
+	if dir == "" {
+		return nil, nil
+	}
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil, nil
+		}
+		return nil, err
+	}
+	var frags []Fragment
+	for _, e := range entries {
+		if e.IsDir() || filepath.Ext(e.Name()) != ".md" {
+			continue
+		}
+		content, err := readFile(filepath.Join(dir, e.Name()))
+		if err != nil {
+			return nil, err
+		}
+		if strings.TrimSpace(content) != "" {
+			frags = append(frags, Fragment{Name: e.Name(), Content: content})
+		}
+	}
+	slices.SortFunc(frags, func(a, b Fragment) int { return strings.Compare(a.Name, b.Name) })
+	return frags, nil
+}
+
+// ReadComposedInstructions reads a's instructions at loc followed by its
+// fragments, returning the names of the fragments used. Without fragments
+// it is the same as ReadInstructions.
+func ReadComposedInstructions(a Agent, loc config.Location) (*Instruction, []string, error) {
+	inst, err := a.ReadInstructions(loc)
+	if err != nil {
+		return nil, nil, err
+	}
+	frags, err := ReadFragments(FragmentsDir(a, loc))
+	if err != nil || len(frags) == 0 {
+		return inst, nil, err
+	}
+	main := ""
+	if inst != nil {
+		main = inst.Content
+	}
+	names := make([]string, len(frags))
+	for i, f := range frags {
+		names[i] = f.Name
+	}
+	return &Instruction{Content: Compose(main, frags, fragmentOptions)}, names, nil
+}
+
+// Compose joins main and the fragments with blank lines. With Headings, a
+// fragment not starting with a heading gets one made from its file name;
+// with Dedup, paragraphs repeated from earlier text are dropped.
+func Compose(main string, frags []Fragment, opts config.FragmentOptions) string {
+	var parts []string
+	if strings.TrimSpace(main) != "" {
+		parts = append(parts, strings.TrimSpace(main))
+	}
+	for _, f := range frags {
+		body := strings.TrimSpace(f.Content)
+		if opts.Headings && !strings.HasPrefix(body, "#") {
+			body = "## " + fragmentTitle(f.Name) + "\n\n" + body
+		}
+		parts = append(parts, body)
+	}
+	out := strings.Join(parts, "\n\n")
+	if opts.Dedup {
+		out = dedupParagraphs(out)
+	}
+	return out + "\n"
+}
+
+// fragmentTitle turns "10-code_style.md" into "Code style".
+func fragmentTitle(name string) string {
+	title := strings.TrimSuffix(name, filepath.Ext(name))
+	title = strings.TrimLeftFunc(title, func(r rune) bool { return unicode.IsDigit(r) || r == '-' || r == '_' || r == '.' })
+	title = strings.NewReplacer("-", " ", "_", " ").Replace(title)
+	if title == "" {
+		return name
+	}
+	r := []rune(title)
+	r[0] = unicode.ToUpper(r[0])
+	return string(r)
+}
+
+// dedupParagraphs drops paragraphs that repeat an earlier one. Headings are
+// kept, since sections may share a title.
+func dedupParagraphs(s string) string {
+	seen := make(map[string]bool)
+	var kept []string
+	for _, p := range paragraphs(s) {
+		key := strings.TrimSpace(p)
+		if key == "" {
+			continue
+		}
+		if !strings.HasPrefix(key, "#") || strings.Contains(key, "\n") {
+			if seen[key] {
+				continue
+			}
+			seen[key] = true
+		}
+		kept = append(kept, p)
+	}
+	return strings.Join(kept, "\n\n")
+}
+
+// paragraphs splits markdown at blank lines, keeping each fenced code block
+// whole with the paragraph it belongs to.
+func paragraphs(s string) []string {
+	var (
+		out   []string
+		cur   []string
+		fence bool
+	)
+	flush := func() {
+		if len(cur) > 0 {
+			out = append(out, strings.Join(cur, "\n"))
+		}
+		cur = nil
+	}
+	for _, line := range strings.Split(s, "\n") {
+		trimmed := strings.TrimSpace(line)
+		if strings.HasPrefix(trimmed, "```") || strings.HasPrefix(trimmed, "~~~") {
+			fence = !fence
+		}
+		if !fence && trimmed == "" {
+			flush()
+			continue
+		}
+		cur = append(cur, line)
+	}
+	flush()
+	return out
+}
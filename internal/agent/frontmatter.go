@@ -0,0 +1,46 @@
+package agent
+
+import "strings"
+
+// Description returns the description field from the skill's YAML
+// frontmatter, or "" when there is none. Plain, quoted and block scalar
+// values are supported; other YAML constructs are not interpreted.
+func (s Skill) Description() string {
+	lines := strings.Split(strings.ReplaceAll(s.Content, "\r\n", "\n"), "\n")
+	if len(lines) == 0 || strings.TrimSpace(lines[0]) != "---" {
+		return ""
+	}
+
+	for i := 1; i < len(lines); i++ {
+		line := lines[i]
+		if strings.TrimSpace(line) == "---" {
+			return ""
+		}
+		value, ok := strings.CutPrefix(line, "description:")
+		if !ok {
+			continue
+		}
+		value = strings.TrimSpace(value)
+		if value != "" && !strings.HasPrefix(value, "|") && !strings.HasPrefix(value, ">") {
+			return unquote(value)
+		}
+
+		// Block scalar or value on following indented lines.
+		var parts []string
+		for _, next := range lines[i+1:] {
+			if next == "" || (next[0] != ' ' && next[0] != '\t') {
+				break
+			}
+			parts = append(parts, strings.TrimSpace(next))
+		}
+		return strings.Join(parts, " ")
+	}
+	return ""
+}
+
+func unquote(s string) string {
+	if len(s) >= 2 && (s[0] == '"' || s[0] == '\'') && s[len(s)-1] == s[0] {
+		return s[1 : len(s)-1]
+	}
+	return s
+}
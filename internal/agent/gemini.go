@@ -24,58 +24,48 @@ func (g *Gemini) InstructionsPath(loc config.Location) string {
 	return filepath.Join(loc.Root, "GEMINI.md")
 }
 
-func (g *Gemini) ReadInstructions(loc config.Location) (*Instruction, error) {
+func (g *Gemini) InstructionsSources(loc config.Location) []string {
+	return nonEmpty(g.InstructionsPath(loc))
+}
+
+func (g *Gemini) SkillsPath(loc config.Location) string {
 	if loc.Scope == config.ScopeGlobal {
 		home, err := os.UserHomeDir()
 		if err != nil {
-			return nil, fmt.Errorf("determining home directory: %w", err)
-		}
-		content, err := readFile(filepath.Join(home, ".gemini", "GEMINI.md"))
-		if err != nil {
-			return nil, err
-		}
-		if content == "" {
-			return nil, nil
+			return ""
 		}
-		return &Instruction{Content: content}, nil
-	}
-
-	content, err := readFile(filepath.Join(loc.Root, "GEMINI.md"))
-	if err != nil {
-		return nil, err
-	}
-	if content == "" {
-		return nil, nil
+		return filepath.Join(home, ".gemini", "skills")
 	}
-	return &Instruction{Content: content}, nil
+	return filepath.Join(loc.Root, ".gemini", "skills")
 }
 
-func (g *Gemini) ReadSkills(loc config.Location) ([]Skill, error) {
+func (g *Gemini) SkillsDirs(loc config.Location) []string {
+	base := loc.Root
 	if loc.Scope == config.ScopeGlobal {
 		home, err := os.UserHomeDir()
 		if err != nil {
-			return nil, fmt.Errorf("determining home directory: %w", err)
-		}
-		// .agents/skills/ takes precedence over .gemini/skills/
-		skills, err := readSkillsFromDir(filepath.Join(home, ".agents", "skills"))
-		if err != nil {
-			return nil, err
+			return nil
 		}
-		if len(skills) > 0 {
-			return skills, nil
-		}
-		return readSkillsFromDir(filepath.Join(home, ".gemini", "skills"))
+		base = home
+	}
+	return []string{
+		filepath.Join(base, ".agents", "skills"),
+		filepath.Join(base, ".gemini", "skills"),
 	}
+}
 
-	// .agents/skills/ takes precedence over .gemini/skills/
-	skills, err := readSkillsFromDir(filepath.Join(loc.Root, ".agents", "skills"))
-	if err != nil {
+func (g *Gemini) ReadInstructions(loc config.Location) (*Instruction, error) {
+	if err := checkHome(loc); err != nil {
 		return nil, err
 	}
-	if len(skills) > 0 {
-		return skills, nil
+	return readFirstInstruction(g.InstructionsSources(loc))
+}
+
+func (g *Gemini) ReadSkills(loc config.Location) ([]Skill, error) {
+	if err := checkHome(loc); err != nil {
+		return nil, err
 	}
-	return readSkillsFromDir(filepath.Join(loc.Root, ".gemini", "skills"))
+	return readFirstSkills(g.SkillsDirs(loc))
 }
 
 func (g *Gemini) WriteInstructions(loc config.Location, inst *Instruction) error {
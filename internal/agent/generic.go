@@ -0,0 +1,251 @@
+package agent
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/LaneBirmingham/coding-agent-sync/internal/config"
+)
+
+// Generic implements Agent from a user-supplied config.AgentDefinition.
+type Generic struct {
+	def config.AgentDefinition
+}
+
+// NewGeneric validates a definition and returns an Agent that interprets it.
+func NewGeneric(def config.AgentDefinition) (*Generic, error) {
+	if def.Local == nil && def.Global == nil {
+		return nil, fmt.Errorf("agent %q defines neither local nor global paths", def.Name)
+	}
+	for _, p := range []*config.AgentPaths{def.Local, def.Global} {
+		if p == nil {
+			continue
+		}
+		if len(p.Instructions) == 0 && p.WriteInstructions == "" && len(p.Skills) == 0 && p.WriteSkills == "" {
+			return nil, fmt.Errorf("agent %q defines an empty scope", def.Name)
+		}
+	}
+	return &Generic{def: def}, nil
+}
+
+// Register validates a definition and makes it available through Get and config.ParseAgent.
+func Register(def config.AgentDefinition) error {
+	g, err := NewGeneric(def)
+	if err != nil {
+		return err
+	}
+	name, err := config.DefineAgent(def.Name)
+	if err != nil {
+		return err
+	}
+	registry[name] = g
+	return nil
+}
+
+// Unregister removes a user-defined agent added with Register.
+func Unregister(a config.Agent) {
+	if _, ok := registry[a].(*Generic); !ok {
+		return
+	}
+	delete(registry, a)
+	config.UndefineAgent(a)
+}
+
+// UnregisterAll removes every user-defined agent, so a config can be loaded
+// again in the same process.
+func UnregisterAll() {
+	for a, impl := range registry {
+		if _, ok := impl.(*Generic); ok {
+			Unregister(a)
+		}
+	}
+}
+
+func (g *Generic) Name() string { return g.def.Name }
+
+// CheckPaths reports why the paths the agent writes at loc cannot be
+// resolved, such as one naming an unset environment variable. The path
+// methods return "" for them, so callers use GetAt to see why. Read paths
+// that cannot be resolved are skipped in favour of the later ones.
+func (g *Generic) CheckPaths(loc config.Location) error {
+	p := g.scopePaths(loc)
+	if p == nil {
+		return nil
+	}
+	for _, path := range []string{firstNonEmpty(p.WriteInstructions, first(p.Instructions)), firstNonEmpty(p.WriteSkills, first(p.Skills))} {
+		if _, err := g.resolve(loc, path); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (g *Generic) InstructionsPath(loc config.Location) string {
+	p := g.scopePaths(loc)
+	if p == nil {
+		return ""
+	}
+	path, err := g.resolve(loc, firstNonEmpty(p.WriteInstructions, first(p.Instructions)))
+	if err != nil {
+		return ""
+	}
+	return path
+}
+
+func (g *Generic) InstructionsSources(loc config.Location) []string {
+	p := g.scopePaths(loc)
+	if p == nil {
+		return nil
+	}
+	return g.resolveAll(loc, p.Instructions)
+}
+
+func (g *Generic) SkillsPath(loc config.Location) string {
+	p := g.scopePaths(loc)
+	if p == nil {
+		return ""
+	}
+	dir, err := g.resolve(loc, firstNonEmpty(p.WriteSkills, first(p.Skills)))
+	if err != nil {
+		return ""
+	}
+	return dir
+}
+
+func (g *Generic) SkillsDirs(loc config.Location) []string {
+	p := g.scopePaths(loc)
+	if p == nil {
+		return nil
+	}
+	return g.resolveAll(loc, p.Skills)
+}
+
+func (g *Generic) ReadInstructions(loc config.Location) (*Instruction, error) {
+	p := g.scopePaths(loc)
+	if p == nil {
+		return nil, nil
+	}
+	return readFirstInstruction(g.resolveAll(loc, p.Instructions))
+}
+
+func (g *Generic) ReadSkills(loc config.Location) ([]Skill, error) {
+	p := g.scopePaths(loc)
+	if p == nil {
+		return nil, nil
+	}
+	for _, dir := range g.resolveAll(loc, p.Skills) {
+		skills, err := readSkillsFromDir(dir)
+		if err != nil {
+			return nil, err
+		}
+		if len(skills) > 0 {
+			return skills, nil
+		}
+	}
+	return nil, nil
+}
+
+func (g *Generic) WriteInstructions(loc config.Location, inst *Instruction) error {
+	p := g.scopePaths(loc)
+	if p == nil || firstNonEmpty(p.WriteInstructions, first(p.Instructions)) == "" {
+		return fmt.Errorf("%s does not support %s instructions", g.def.Name, loc.Scope)
+	}
+	path, err := g.resolve(loc, firstNonEmpty(p.WriteInstructions, first(p.Instructions)))
+	if err != nil {
+		return err
+	}
+	return writeFile(path, inst.Content)
+}
+
+func (g *Generic) WriteSkills(loc config.Location, skills []Skill) error {
+	p := g.scopePaths(loc)
+	if p == nil || firstNonEmpty(p.WriteSkills, first(p.Skills)) == "" {
+		return fmt.Errorf("%s does not support %s skills", g.def.Name, loc.Scope)
+	}
+	dir, err := g.resolve(loc, firstNonEmpty(p.WriteSkills, first(p.Skills)))
+	if err != nil {
+		return err
+	}
+	return writeSkillsToDir(dir, skills)
+}
+
+func (g *Generic) scopePaths(loc config.Location) *config.AgentPaths {
+	if loc.Scope == config.ScopeGlobal {
+		return g.def.Global
+	}
+	return g.def.Local
+}
+
+// resolve expands ~ and environment variables in p and anchors local paths at the project root.
+func (g *Generic) resolve(loc config.Location, p string) (string, error) {
+	if p == "" {
+		return "", nil
+	}
+	expanded, err := expandPath(p)
+	if err != nil {
+		return "", fmt.Errorf("agent %s: %w", g.def.Name, err)
+	}
+	if filepath.IsAbs(expanded) {
+		return filepath.Clean(expanded), nil
+	}
+	if loc.Scope == config.ScopeGlobal {
+		return "", fmt.Errorf("agent %s: global path %q must be absolute or start with ~", g.def.Name, p)
+	}
+	return filepath.Join(loc.Root, expanded), nil
+}
+
+// resolveAll resolves a precedence list, skipping the paths that cannot be
+// resolved so reads fall back to the rest.
+func (g *Generic) resolveAll(loc config.Location, paths []string) []string {
+	out := make([]string, 0, len(paths))
+	for _, p := range paths {
+		if resolved, err := g.resolve(loc, p); err == nil && resolved != "" {
+			out = append(out, resolved)
+		}
+	}
+	return uniquePaths(out...)
+}
+
+// expandPath replaces a leading ~ with the home directory and substitutes
+// $VAR and ${VAR} references. Unset variables are an error rather than
+// silently collapsing to the filesystem root.
+func expandPath(p string) (string, error) {
+	var missing []string
+	orig := p
+	p = os.Expand(p, func(name string) string {
+		v, ok := os.LookupEnv(name)
+		if !ok || v == "" {
+			missing = append(missing, name)
+		}
+		return v
+	})
+	if len(missing) > 0 {
+		return "", fmt.Errorf("path %q references unset environment variable(s): %s", orig, strings.Join(missing, ", "))
+	}
+	if p == "~" || strings.HasPrefix(p, "~/") || strings.HasPrefix(p, `~\`) {
+		home, err := resolveHomeDir()
+		if err != nil {
+			return "", fmt.Errorf("determining home directory: %w", err)
+		}
+		p = filepath.Join(home, p[1:])
+	}
+	return p, nil
+}
+
+func first(paths []string) string {
+	if len(paths) == 0 {
+		return ""
+	}
+	return paths[0]
+}
+
+func firstNonEmpty(values ...string) string {
+	for _, v := range values {
+		if v != "" {
+			return v
+		}
+	}
+	return ""
+}
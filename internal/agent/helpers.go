@@ -5,6 +5,8 @@ import (
 	"os"
 	"path/filepath"
 	"strings"
+
+	"github.com/LaneBirmingham/coding-agent-sync/internal/config"
 )
 
 // readFile reads the contents of a file, returning empty string and nil if the file doesn't exist.
@@ -27,6 +29,55 @@ func writeFile(path, content string) error {
 	return os.WriteFile(path, []byte(content), 0o644)
 }
 
+// nonEmpty returns a single-element slice for path, or nil when path is empty.
+func nonEmpty(path string) []string {
+	if path == "" {
+		return nil
+	}
+	return []string{path}
+}
+
+// checkHome reports an error when loc is the global scope and the home
+// directory cannot be determined, since the global paths would be empty.
+func checkHome(loc config.Location) error {
+	if loc.Scope != config.ScopeGlobal {
+		return nil
+	}
+	if _, err := os.UserHomeDir(); err != nil {
+		return fmt.Errorf("determining home directory: %w", err)
+	}
+	return nil
+}
+
+// readFirstInstruction reads the first of paths with content, so agents read
+// their InstructionsSources in the order they list them.
+func readFirstInstruction(paths []string) (*Instruction, error) {
+	for _, path := range paths {
+		content, err := readFile(path)
+		if err != nil {
+			return nil, err
+		}
+		if content != "" {
+			return &Instruction{Content: content}, nil
+		}
+	}
+	return nil, nil
+}
+
+// readFirstSkills reads the skills of the first of dirs that has any.
+func readFirstSkills(dirs []string) ([]Skill, error) {
+	for _, dir := range dirs {
+		skills, err := readSkillsFromDir(dir)
+		if err != nil {
+			return nil, err
+		}
+		if len(skills) > 0 {
+			return skills, nil
+		}
+	}
+	return nil, nil
+}
+
 // readSkillsFromDir reads all SKILL.md files from subdirectories of dir.
 func readSkillsFromDir(dir string) ([]Skill, error) {
 	entries, err := os.ReadDir(dir)
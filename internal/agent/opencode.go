@@ -24,58 +24,57 @@ func (o *OpenCode) InstructionsPath(loc config.Location) string {
 	return filepath.Join(loc.Root, "AGENTS.md")
 }
 
-func (o *OpenCode) ReadInstructions(loc config.Location) (*Instruction, error) {
+func (o *OpenCode) InstructionsSources(loc config.Location) []string {
 	if loc.Scope == config.ScopeGlobal {
 		home, err := os.UserHomeDir()
 		if err != nil {
-			return nil, fmt.Errorf("determining home directory: %w", err)
-		}
-		// Try canonical path first
-		content, err := readFile(filepath.Join(home, ".config", "opencode", "AGENTS.md"))
-		if err != nil {
-			return nil, err
+			return nil
 		}
-		if content != "" {
-			return &Instruction{Content: content}, nil
+		return []string{
+			filepath.Join(home, ".config", "opencode", "AGENTS.md"),
+			filepath.Join(home, ".claude", "CLAUDE.md"),
 		}
-		// Fallback: read from Claude's global config
-		content, err = readFile(filepath.Join(home, ".claude", "CLAUDE.md"))
-		if err != nil {
-			return nil, err
-		}
-		if content != "" {
-			return &Instruction{Content: content}, nil
-		}
-		return nil, nil
 	}
-	content, err := readFile(filepath.Join(loc.Root, "AGENTS.md"))
-	if err != nil {
-		return nil, err
-	}
-	if content == "" {
-		return nil, nil
-	}
-	return &Instruction{Content: content}, nil
+	return []string{filepath.Join(loc.Root, "AGENTS.md")}
 }
 
-func (o *OpenCode) ReadSkills(loc config.Location) ([]Skill, error) {
+func (o *OpenCode) SkillsPath(loc config.Location) string {
 	if loc.Scope == config.ScopeGlobal {
 		home, err := os.UserHomeDir()
 		if err != nil {
-			return nil, fmt.Errorf("determining home directory: %w", err)
+			return ""
 		}
-		// Try canonical path first
-		skills, err := readSkillsFromDir(filepath.Join(home, ".config", "opencode", "skills"))
+		return filepath.Join(home, ".config", "opencode", "skills")
+	}
+	return filepath.Join(loc.Root, ".opencode", "skills")
+}
+
+func (o *OpenCode) SkillsDirs(loc config.Location) []string {
+	if loc.Scope == config.ScopeGlobal {
+		home, err := os.UserHomeDir()
 		if err != nil {
-			return nil, err
+			return nil
 		}
-		if len(skills) > 0 {
-			return skills, nil
+		return []string{
+			filepath.Join(home, ".config", "opencode", "skills"),
+			filepath.Join(home, ".claude", "skills"),
 		}
-		// Fallback: read from Claude's global skills
-		return readSkillsFromDir(filepath.Join(home, ".claude", "skills"))
 	}
-	return readSkillsFromDir(filepath.Join(loc.Root, ".opencode", "skills"))
+	return []string{filepath.Join(loc.Root, ".opencode", "skills")}
+}
+
+func (o *OpenCode) ReadInstructions(loc config.Location) (*Instruction, error) {
+	if err := checkHome(loc); err != nil {
+		return nil, err
+	}
+	return readFirstInstruction(o.InstructionsSources(loc))
+}
+
+func (o *OpenCode) ReadSkills(loc config.Location) ([]Skill, error) {
+	if err := checkHome(loc); err != nil {
+		return nil, err
+	}
+	return readFirstSkills(o.SkillsDirs(loc))
 }
 
 func (o *OpenCode) WriteInstructions(loc config.Location, inst *Instruction) error {
@@ -0,0 +1,77 @@
+package agent
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/LaneBirmingham/coding-agent-sync/internal/config"
+)
+
+// Referencer is implemented by agents whose instructions can point at
+// another agent's file instead of holding a copy of it.
+type Referencer interface {
+	// WriteReference makes the agent's instructions at loc load target, an
+	// absolute path, and returns the file it wrote.
+	WriteReference(loc config.Location, target string) (string, error)
+}
+
+// SupportsReference reports whether a can reference another instruction file.
+func SupportsReference(a Agent) bool {
+	_, ok := a.(Referencer)
+	return ok
+}
+
+// WriteReference writes a CLAUDE.md holding only an @-import of target.
+func (c *Claude) WriteReference(loc config.Location, target string) (string, error) {
+	path := c.InstructionsPath(loc)
+	if path == "" {
+		return "", fmt.Errorf("claude does not support %s instructions", loc.Scope)
+	}
+	ref := target
+	if rel, err := filepath.Rel(filepath.Dir(path), target); err == nil && loc.Scope == config.ScopeLocal {
+		ref = filepath.ToSlash(rel)
+	}
+	return path, writeFile(path, "@"+ref+"\n")
+}
+
+// WriteReference sets context.fileName in Gemini's settings.json to target's
+// name. Gemini looks the name up itself, so target must sit next to GEMINI.md.
+func (g *Gemini) WriteReference(loc config.Location, target string) (string, error) {
+	inst := g.InstructionsPath(loc)
+	if inst == "" {
+		return "", fmt.Errorf("gemini does not support %s instructions", loc.Scope)
+	}
+	if filepath.Dir(inst) != filepath.Dir(target) {
+		return "", fmt.Errorf("gemini can only reference a file in %s, not %s", filepath.Dir(inst), target)
+	}
+	path := filepath.Join(filepath.Dir(inst), ".gemini", "settings.json")
+	if loc.Scope == config.ScopeGlobal {
+		path = filepath.Join(filepath.Dir(inst), "settings.json")
+	}
+
+	settings := map[string]any{}
+	data, err := os.ReadFile(path)
+	switch {
+	case err == nil && strings.TrimSpace(string(data)) != "":
+		if err := json.Unmarshal(data, &settings); err != nil {
+			return "", fmt.Errorf("parsing %s: %w", path, err)
+		}
+	case err != nil && !os.IsNotExist(err):
+		return "", err
+	}
+	context, _ := settings["context"].(map[string]any)
+	if context == nil {
+		context = map[string]any{}
+	}
+	context["fileName"] = filepath.Base(target)
+	settings["context"] = context
+
+	out, err := json.MarshalIndent(settings, "", "  ")
+	if err != nil {
+		return "", err
+	}
+	return path, writeFile(path, string(out)+"\n")
+}
@@ -2,20 +2,32 @@ package archive
 
 import (
 	"archive/zip"
+	"bytes"
+	"crypto/ecdh"
+	"crypto/ed25519"
 	"encoding/json"
 	"fmt"
 	"io"
-	"os"
 	pathpkg "path"
-	"path/filepath"
+	"slices"
 	"strings"
 	"time"
+	"unicode"
 
 	"github.com/LaneBirmingham/coding-agent-sync/internal/agent"
+	"github.com/LaneBirmingham/coding-agent-sync/internal/config"
 )
 
 // FormatVersion is the current archive format version.
-const FormatVersion = "1"
+const FormatVersion = "2"
+
+// FormatVersionV1 is the original archive format, which carries no checksums.
+const FormatVersionV1 = "1"
+
+const (
+	manifestEntry = "manifest.json"
+	sectionsDir   = "sections/"
+)
 
 // Manifest holds metadata about an exported archive.
 type Manifest struct {
@@ -24,6 +36,46 @@ type Manifest struct {
 	Scope      string    `json:"scope"`
 	ExportedAt time.Time `json:"exported_at"`
 	CASVersion string    `json:"cas_version,omitempty"`
+
+	// Files maps each content entry to its hex SHA-256 (v2 and later).
+	Files map[string]string `json:"files,omitempty"`
+	// Digest covers every content entry and its checksum (v2 and later).
+	Digest string `json:"digest,omitempty"`
+
+	// Encryption is set when the content is stored as an encrypted payload.
+	Encryption *Encryption `json:"encryption,omitempty"`
+
+	// Sections lists the agents and scopes in a bundle archive, whose
+	// content lives under sections/<agent>/<scope>/. Agent and Scope are
+	// empty for bundles.
+	Sections []SectionInfo `json:"sections,omitempty"`
+
+	// Package names the registry bundle and version the archive was published as.
+	Package *Package `json:"package,omitempty"`
+
+	// Delta is set when the archive only holds the changes since a base archive.
+	Delta *Delta `json:"delta,omitempty"`
+}
+
+// Package identifies a published bundle version.
+type Package struct {
+	Name    string `json:"name"`
+	Version string `json:"version"`
+}
+
+func (p Package) String() string { return p.Name + "@" + p.Version }
+
+// SectionInfo names one agent and scope in a bundle manifest.
+type SectionInfo struct {
+	Agent string `json:"agent"`
+	Scope string `json:"scope"`
+}
+
+func (s SectionInfo) String() string { return s.Agent + "/" + s.Scope }
+
+// Encrypted reports whether the archive content is encrypted.
+func (m *Manifest) Encrypted() bool {
+	return m.Encryption != nil
 }
 
 // Archive represents the contents of an export archive.
@@ -31,123 +83,473 @@ type Archive struct {
 	Manifest     *Manifest
 	Instructions *agent.Instruction
 	Skills       []agent.Skill
+	Signature    *Signature // set by Read when the archive is signed
+	Sections     []Section  // bundle content; Instructions and Skills are unused
+	Format       Format     // container format, set by Read
+
+	manifestData []byte  // raw manifest.json as read, for signature checks
+	files        []entry // entries as read, with encrypted content decrypted
+}
+
+// Section holds the content for one agent and scope of a bundle archive.
+type Section struct {
+	SectionInfo
+	Instructions *agent.Instruction
+	Skills       []agent.Skill
 }
 
-// Write creates a ZIP archive at path from the given Archive.
+// IsBundle reports whether the archive holds multiple agent/scope sections.
+func (a *Archive) IsBundle() bool {
+	return len(a.Manifest.Sections) > 0
+}
+
+// Contents returns the archive's sections. A single-agent archive yields one
+// section named after its manifest agent and scope.
+func (a *Archive) Contents() []Section {
+	if a.IsBundle() {
+		return a.Sections
+	}
+	return []Section{{
+		SectionInfo:  SectionInfo{Agent: a.Manifest.Agent, Scope: a.Manifest.Scope},
+		Instructions: a.Instructions,
+		Skills:       a.Skills,
+	}}
+}
+
+// WriteOptions controls optional archive features.
+type WriteOptions struct {
+	SigningKey ed25519.PrivateKey // sign the manifest when set
+	Passphrase []byte             // encrypt the content with a passphrase
+	Recipients []*ecdh.PublicKey  // encrypt the content to X25519 recipients
+	Format     Format             // container format; inferred from the path when empty
+
+	// ModTime, when set, makes the output reproducible: entries are written in
+	// sorted order with this timestamp and fixed permissions, so identical
+	// content yields identical bytes. It cannot be combined with encryption.
+	ModTime time.Time
+}
+
+// ReadOptions supplies keys for decrypting encrypted archives and the
+// resource limits applied while reading.
+type ReadOptions struct {
+	Passphrase []byte
+	Identities []*ecdh.PrivateKey
+	Limits     Limits // zero fields use DefaultLimits
+}
+
+// entry is a single named file inside an archive container.
+type entry struct {
+	name string
+	data []byte
+}
+
+// Write creates an archive at path from the given Archive, in the format
+// implied by the path (see FormatForPath).
+// For current-format manifests, Write records per-entry checksums and the
+// archive digest in a.Manifest before writing it.
 func Write(path string, a *Archive) error {
-	if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
-		return fmt.Errorf("creating output directory: %w", err)
+	return WriteWith(path, a, WriteOptions{})
+}
+
+// WriteWith is Write with optional features such as signing.
+func WriteWith(path string, a *Archive, opts WriteOptions) error {
+	entries, err := a.encode(opts)
+	if err != nil {
+		return err
+	}
+	format := opts.Format
+	if format == "" {
+		format = FormatForPath(path)
+	}
+	return writeContainer(path, format, entries, opts.ModTime)
+}
+
+// WriteTo writes the archive to w as a ZIP, or as tar.gz when opts.Format says so.
+func WriteTo(w io.Writer, a *Archive, opts WriteOptions) error {
+	entries, err := a.encode(opts)
+	if err != nil {
+		return err
+	}
+	return writeStream(w, opts.Format, entries, opts.ModTime)
+}
+
+// encode lays out the archive entries, encrypting and signing as requested.
+func (a *Archive) encode(opts WriteOptions) ([]entry, error) {
+	encrypt := len(opts.Passphrase) > 0 || len(opts.Recipients) > 0
+	if (opts.SigningKey != nil || encrypt) && a.Manifest.Version != FormatVersion {
+		return nil, fmt.Errorf("signing and encryption require archive format v%s", FormatVersion)
+	}
+	if encrypt && !opts.ModTime.IsZero() {
+		return nil, fmt.Errorf("reproducible archives cannot be encrypted")
 	}
 
-	f, err := os.Create(path)
+	entries, err := a.contentEntries()
 	if err != nil {
-		return fmt.Errorf("creating archive file: %w", err)
+		return nil, err
+	}
+	if !opts.ModTime.IsZero() {
+		slices.SortFunc(entries, func(x, y entry) int { return strings.Compare(x.name, y.name) })
+	}
+
+	a.Manifest.Encryption = nil
+	if encrypt {
+		var plain bytes.Buffer
+		if err := writeZipTo(&plain, entries, time.Time{}); err != nil {
+			return nil, err
+		}
+		enc, payload, err := encryptPayload(plain.Bytes(), opts)
+		if err != nil {
+			return nil, fmt.Errorf("encrypting archive: %w", err)
+		}
+		a.Manifest.Encryption = enc
+		entries = []entry{{name: payloadEntry, data: payload}}
 	}
-	defer f.Close()
 
-	w := zip.NewWriter(f)
-	defer w.Close()
+	if a.Manifest.Version == FormatVersion {
+		a.Manifest.Files, a.Manifest.Digest = checksums(entries)
+	}
 
-	// Write manifest.json
 	manifestData, err := json.MarshalIndent(a.Manifest, "", "  ")
 	if err != nil {
-		return fmt.Errorf("marshaling manifest: %w", err)
+		return nil, fmt.Errorf("marshaling manifest: %w", err)
 	}
-	if err := writeEntry(w, "manifest.json", manifestData); err != nil {
-		return err
+	meta := []entry{{name: manifestEntry, data: manifestData}}
+	if opts.SigningKey != nil {
+		sig, err := sign(manifestData, opts.SigningKey)
+		if err != nil {
+			return nil, err
+		}
+		meta = append(meta, entry{name: signatureEntry, data: sig})
 	}
+	return append(meta, entries...), nil
+}
 
-	// Write instructions.md if present
-	if a.Instructions != nil && a.Instructions.Content != "" {
-		if err := writeEntry(w, "instructions.md", []byte(a.Instructions.Content)); err != nil {
-			return err
+// Read loads an Archive from a ZIP file, tar.gz file or directory at path,
+// verifying checksums for v2 archives.
+func Read(path string) (*Archive, error) {
+	return ReadWith(path, ReadOptions{})
+}
+
+// ReadWith is Read with keys for transparently decrypting encrypted archives.
+func ReadWith(path string, opts ReadOptions) (*Archive, error) {
+	entries, format, err := readContainer(path, opts.Limits)
+	if err != nil {
+		return nil, err
+	}
+	return load(entries, format, opts)
+}
+
+// ReadFrom reads a ZIP or tar.gz archive from r, detecting the format.
+func ReadFrom(r io.Reader, opts ReadOptions) (*Archive, error) {
+	max := opts.Limits.withDefaults().MaxTotalSize
+	data, err := io.ReadAll(io.LimitReader(r, max+1))
+	if err != nil {
+		return nil, fmt.Errorf("reading archive: %w", err)
+	}
+	if int64(len(data)) > max {
+		return nil, fmt.Errorf("reading archive: %w (over %d bytes)", ErrArchiveTooLarge, max)
+	}
+	entries, format, err := readBytes(data, opts.Limits)
+	if err != nil {
+		return nil, err
+	}
+	return load(entries, format, opts)
+}
+
+// ReadManifest returns the manifest of the ZIP or tar.gz archive in data
+// without verifying, decrypting or decoding its content.
+func ReadManifest(data []byte) (*Manifest, Format, error) {
+	entries, format, err := readBytes(data, Limits{})
+	if err != nil {
+		return nil, "", err
+	}
+	m, err := parseManifest(entries)
+	if err != nil {
+		return nil, "", err
+	}
+	return m, format, nil
+}
+
+// load verifies, decrypts and decodes the entries of an archive.
+func load(entries []entry, format Format, opts ReadOptions) (*Archive, error) {
+	m, err := parseManifest(entries)
+	if err != nil {
+		return nil, err
+	}
+
+	if m.Version == FormatVersion {
+		if problems := verify(m, entries); len(problems) > 0 {
+			return nil, &IntegrityError{Problems: problems}
 		}
 	}
 
-	// Write skills
-	for _, s := range a.Skills {
-		if err := validateSkillName(s.Name); err != nil {
-			return fmt.Errorf("invalid skill name %q: %w", s.Name, err)
+	content := entries
+	if m.Encrypted() {
+		if content, err = decryptEntries(m, entries, opts); err != nil {
+			return nil, err
 		}
-		entryPath := fmt.Sprintf("skills/%s/SKILL.md", s.Name)
-		if err := writeEntry(w, entryPath, []byte(s.Content)); err != nil {
-			return err
+	}
+
+	a, err := decode(m, content)
+	if err != nil {
+		return nil, err
+	}
+	a.Format = format
+	if err := a.attachSignature(entries); err != nil {
+		return nil, err
+	}
+	a.files = entries
+	if m.Encrypted() {
+		a.files = slices.DeleteFunc(slices.Clone(entries), func(e entry) bool { return !isMetadataEntry(e.name) })
+		a.files = append(a.files, content...)
+	}
+	return a, nil
+}
+
+// EntryNames lists the files in an archive returned by Read, in archive
+// order. Encrypted archives list their decrypted content entries.
+func (a *Archive) EntryNames() []string {
+	names := make([]string, 0, len(a.files))
+	for _, e := range a.files {
+		names = append(names, e.name)
+	}
+	return names
+}
+
+// Entry returns the contents of a named file in an archive returned by Read.
+func (a *Archive) Entry(name string) ([]byte, error) {
+	for _, e := range a.files {
+		if e.name == name {
+			return e.data, nil
 		}
 	}
+	return nil, fmt.Errorf("archive has no entry %q", name)
+}
 
-	return nil
+// contentEntries lays out the instructions and skills as archive entries.
+func (a *Archive) contentEntries() ([]entry, error) {
+	if !a.IsBundle() {
+		return sectionEntries("", a.Instructions, a.Skills)
+	}
+
+	if len(a.Sections) != len(a.Manifest.Sections) {
+		return nil, fmt.Errorf("bundle manifest lists %d section(s) but archive has %d", len(a.Manifest.Sections), len(a.Sections))
+	}
+	var entries []entry
+	for i, sec := range a.Sections {
+		if sec.SectionInfo != a.Manifest.Sections[i] {
+			return nil, fmt.Errorf("bundle section %s does not match manifest section %s", sec.SectionInfo, a.Manifest.Sections[i])
+		}
+		if err := validateSection(sec.SectionInfo); err != nil {
+			return nil, err
+		}
+		e, err := sectionEntries(sec.prefix(), sec.Instructions, sec.Skills)
+		if err != nil {
+			return nil, fmt.Errorf("section %s: %w", sec.SectionInfo, err)
+		}
+		entries = append(entries, e...)
+	}
+	return entries, nil
 }
 
-// Read loads an Archive from a ZIP file at path.
-func Read(path string) (*Archive, error) {
-	r, err := zip.OpenReader(path)
-	if err != nil {
-		return nil, fmt.Errorf("opening archive: %w", err)
+// sectionEntries lays out instructions and skills under prefix.
+func sectionEntries(prefix string, inst *agent.Instruction, skills []agent.Skill) ([]entry, error) {
+	var entries []entry
+
+	if inst != nil && inst.Content != "" {
+		entries = append(entries, entry{name: prefix + "instructions.md", data: []byte(inst.Content)})
 	}
-	defer r.Close()
 
-	a := &Archive{}
-	foundManifest := false
+	for _, s := range skills {
+		if err := validateSkillName(s.Name); err != nil {
+			return nil, fmt.Errorf("invalid skill name %q: %w", s.Name, err)
+		}
+		entries = append(entries, entry{name: fmt.Sprintf("%sskills/%s/SKILL.md", prefix, s.Name), data: []byte(s.Content)})
+	}
+	if err := checkSkillNames(skillNameList(skills)); err != nil {
+		return nil, err
+	}
 
-	for _, f := range r.File {
-		name := f.Name
+	return entries, nil
+}
 
-		switch {
-		case name == "manifest.json":
-			data, err := readEntry(f)
-			if err != nil {
-				return nil, fmt.Errorf("reading manifest: %w", err)
-			}
-			var m Manifest
-			if err := json.Unmarshal(data, &m); err != nil {
-				return nil, fmt.Errorf("parsing manifest: %w", err)
-			}
-			if m.Version != FormatVersion {
-				return nil, fmt.Errorf("unsupported archive format version %q (expected %q)", m.Version, FormatVersion)
-			}
-			a.Manifest = &m
-			foundManifest = true
+// prefix returns the entry name prefix for the section's content.
+func (s SectionInfo) prefix() string {
+	return sectionsDir + s.Agent + "/" + s.Scope + "/"
+}
 
-		case name == "instructions.md":
-			data, err := readEntry(f)
-			if err != nil {
-				return nil, fmt.Errorf("reading instructions: %w", err)
-			}
-			a.Instructions = &agent.Instruction{Content: string(data)}
-
-		case strings.HasPrefix(name, "skills/") && strings.HasSuffix(name, "/SKILL.md"):
-			// Extract skill name from "skills/<name>/SKILL.md"
-			cleanName := pathpkg.Clean(name)
-			parts := strings.Split(cleanName, "/")
-			if len(parts) != 3 {
-				return nil, fmt.Errorf("invalid skill path %q: malformed entry", name)
-			}
-			if parts[0] != "skills" || parts[2] != "SKILL.md" {
-				return nil, fmt.Errorf("invalid skill path %q: malformed entry", name)
+// EntryPrefix returns the prefix of the entry names holding a section's
+// content: empty for single-section archives.
+func (a *Archive) EntryPrefix(s SectionInfo) string {
+	if !a.IsBundle() {
+		return ""
+	}
+	return s.prefix()
+}
+
+func parseManifest(entries []entry) (*Manifest, error) {
+	for _, e := range entries {
+		if e.name != manifestEntry {
+			continue
+		}
+		var m Manifest
+		if err := json.Unmarshal(e.data, &m); err != nil {
+			return nil, fmt.Errorf("parsing manifest: %w", err)
+		}
+		if m.Version != FormatVersion && m.Version != FormatVersionV1 {
+			return nil, fmt.Errorf("unsupported archive format version %q (expected %q or %q)", m.Version, FormatVersionV1, FormatVersion)
+		}
+		return &m, nil
+	}
+	return nil, fmt.Errorf("archive missing manifest.json")
+}
+
+// decode builds an Archive from raw entries.
+func decode(m *Manifest, entries []entry) (*Archive, error) {
+	a := &Archive{Manifest: m}
+	if err := validateDelta(m); err != nil {
+		return nil, err
+	}
+
+	sections := make(map[string]*Section, len(m.Sections))
+	if len(m.Sections) > 0 {
+		a.Sections = make([]Section, len(m.Sections))
+		for i, info := range m.Sections {
+			if err := validateSection(info); err != nil {
+				return nil, err
 			}
-			if err := validateSkillName(parts[1]); err != nil {
-				return nil, fmt.Errorf("invalid skill path %q: %w", name, err)
+			if sections[info.prefix()] != nil {
+				return nil, fmt.Errorf("bundle lists section %s more than once", info)
 			}
-			data, err := readEntry(f)
-			if err != nil {
-				return nil, fmt.Errorf("reading skill %s: %w", parts[1], err)
+			a.Sections[i].SectionInfo = info
+			sections[info.prefix()] = &a.Sections[i]
+		}
+	}
+
+	for _, e := range entries {
+		if !a.IsBundle() {
+			if err := decodeEntry(e.name, e.data, &a.Instructions, &a.Skills); err != nil {
+				return nil, err
 			}
-			a.Skills = append(a.Skills, agent.Skill{
-				Name:    parts[1],
-				Content: string(data),
-			})
+			continue
+		}
+		if !strings.HasPrefix(e.name, sectionsDir) {
+			continue
+		}
+		parts := strings.SplitN(e.name, "/", 4)
+		if len(parts) != 4 {
+			return nil, fmt.Errorf("invalid bundle entry %q: malformed entry", e.name)
+		}
+		sec := sections[strings.Join(parts[:3], "/")+"/"]
+		if sec == nil {
+			return nil, fmt.Errorf("bundle entry %q belongs to a section not listed in the manifest", e.name)
+		}
+		if err := decodeEntry(parts[3], e.data, &sec.Instructions, &sec.Skills); err != nil {
+			return nil, fmt.Errorf("section %s: %w", sec.SectionInfo, err)
 		}
 	}
 
-	if !foundManifest {
-		return nil, fmt.Errorf("archive missing manifest.json")
+	if err := checkSkillNames(skillNameList(a.Skills)); err != nil {
+		return nil, err
+	}
+	for _, sec := range a.Sections {
+		if err := checkSkillNames(skillNameList(sec.Skills)); err != nil {
+			return nil, fmt.Errorf("section %s: %w", sec.SectionInfo, err)
+		}
 	}
 
 	return a, nil
 }
 
-func writeEntry(w *zip.Writer, name string, data []byte) error {
-	fw, err := w.Create(name)
+func skillNameList(skills []agent.Skill) []string {
+	names := make([]string, 0, len(skills))
+	for _, s := range skills {
+		names = append(names, s.Name)
+	}
+	return names
+}
+
+// decodeEntry adds one instructions or skill entry, named relative to its
+// section, to inst or skills. Unrecognized entries are ignored.
+func decodeEntry(name string, data []byte, inst **agent.Instruction, skills *[]agent.Skill) error {
+	switch {
+	case name == "instructions.md":
+		*inst = &agent.Instruction{Content: string(data)}
+
+	case strings.HasPrefix(name, "skills/") && strings.HasSuffix(name, "/SKILL.md"):
+		// Extract skill name from "skills/<name>/SKILL.md"
+		cleanName := pathpkg.Clean(name)
+		parts := strings.Split(cleanName, "/")
+		if len(parts) != 3 {
+			return fmt.Errorf("invalid skill path %q: malformed entry", name)
+		}
+		if parts[0] != "skills" || parts[2] != "SKILL.md" {
+			return fmt.Errorf("invalid skill path %q: malformed entry", name)
+		}
+		if err := validateSkillName(parts[1]); err != nil {
+			return fmt.Errorf("invalid skill path %q: %w", name, err)
+		}
+		*skills = append(*skills, agent.Skill{
+			Name:    parts[1],
+			Content: string(data),
+		})
+	}
+	return nil
+}
+
+// decryptEntries decrypts the payload of an encrypted archive and returns its content entries.
+func decryptEntries(m *Manifest, entries []entry, opts ReadOptions) ([]entry, error) {
+	for _, e := range entries {
+		if e.name != payloadEntry {
+			continue
+		}
+		plain, err := decryptPayload(m.Encryption, e.data, opts)
+		if err != nil {
+			return nil, err
+		}
+		return readZipFrom(bytes.NewReader(plain), int64(len(plain)), opts.Limits)
+	}
+	return nil, fmt.Errorf("encrypted archive missing %s", payloadEntry)
+}
+
+// writeZipTo writes entries as a ZIP. A non-zero modTime stamps every entry
+// with that time and mode 0644; otherwise entries carry no timestamp.
+func writeZipTo(out io.Writer, entries []entry, modTime time.Time) error {
+	w := zip.NewWriter(out)
+	for _, e := range entries {
+		if err := writeEntry(w, e.name, e.data, modTime); err != nil {
+			return err
+		}
+	}
+	if err := w.Close(); err != nil {
+		return fmt.Errorf("finalizing archive: %w", err)
+	}
+	return nil
+}
+
+func readZipFrom(ra io.ReaderAt, size int64, limits Limits) ([]entry, error) {
+	r, err := zip.NewReader(ra, size)
+	if err != nil {
+		return nil, fmt.Errorf("opening archive: %w", err)
+	}
+
+	er := newEntryReader(limits)
+	for _, f := range r.File {
+		open := func() (io.ReadCloser, error) { return f.Open() }
+		if err := er.add(f.Name, f.Mode(), int64(f.UncompressedSize64), open); err != nil {
+			return nil, err
+		}
+	}
+	return er.entries, nil
+}
+
+func writeEntry(w *zip.Writer, name string, data []byte, modTime time.Time) error {
+	hdr := &zip.FileHeader{Name: name, Method: zip.Deflate}
+	if !modTime.IsZero() {
+		hdr.Modified = modTime
+		hdr.SetMode(0o644)
+	}
+	fw, err := w.CreateHeader(hdr)
 	if err != nil {
 		return fmt.Errorf("creating entry %s: %w", name, err)
 	}
@@ -157,13 +559,15 @@ func writeEntry(w *zip.Writer, name string, data []byte) error {
 	return nil
 }
 
-func readEntry(f *zip.File) ([]byte, error) {
-	rc, err := f.Open()
-	if err != nil {
-		return nil, err
+// validateSection checks that a bundle section names a usable agent directory and a known scope.
+func validateSection(s SectionInfo) error {
+	if s.Agent == "" || s.Agent == "." || s.Agent == ".." || strings.ContainsAny(s.Agent, "/\\\x00") {
+		return fmt.Errorf("invalid bundle section agent %q", s.Agent)
+	}
+	if _, err := config.ParseScope(s.Scope); err != nil || s.Scope != strings.ToLower(s.Scope) {
+		return fmt.Errorf("invalid bundle section scope %q", s.Scope)
 	}
-	defer rc.Close()
-	return io.ReadAll(rc)
+	return nil
 }
 
 func validateSkillName(name string) error {
@@ -176,5 +580,12 @@ func validateSkillName(name string) error {
 	if strings.ContainsRune(name, '\x00') {
 		return fmt.Errorf("skill name must not contain NUL")
 	}
+	// Marks and joiners are legitimate in many scripts; look-alike names are
+	// caught by the collision key instead.
+	for _, r := range name {
+		if unicode.IsControl(r) || isBidiControl(r) {
+			return fmt.Errorf("skill name must not contain control or bidirectional formatting characters (%U)", r)
+		}
+	}
 	return nil
 }
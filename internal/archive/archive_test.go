@@ -1,14 +1,25 @@
 package archive
 
 import (
+	"archive/tar"
 	"archive/zip"
+	"bytes"
+	"compress/gzip"
+	"crypto/ecdh"
+	"crypto/ed25519"
+	"encoding/json"
+	"errors"
+	"io"
+	"io/fs"
 	"os"
 	"path/filepath"
+	"slices"
 	"strings"
 	"testing"
 	"time"
 
 	"github.com/LaneBirmingham/coding-agent-sync/internal/agent"
+	"github.com/LaneBirmingham/coding-agent-sync/internal/keys"
 )
 
 func TestRoundTrip(t *testing.T) {
@@ -251,3 +262,752 @@ func TestWriteInvalidSkillName(t *testing.T) {
 		t.Fatalf("expected invalid skill name error, got %v", err)
 	}
 }
+
+func writeRawZip(t *testing.T, path string, files map[string]string, order ...string) {
+	t.Helper()
+	f, err := os.Create(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	w := zip.NewWriter(f)
+	for _, name := range order {
+		fw, err := w.Create(name)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if _, err := fw.Write([]byte(files[name])); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := w.Close(); err != nil {
+		t.Fatal(err)
+	}
+	if err := f.Close(); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func testArchive() *Archive {
+	return &Archive{
+		Manifest: &Manifest{
+			Version:    FormatVersion,
+			Agent:      "claude",
+			Scope:      "local",
+			ExportedAt: time.Now().Truncate(time.Second),
+		},
+		Instructions: &agent.Instruction{Content: "# Instructions"},
+		Skills:       []agent.Skill{{Name: "s1", Content: "skill one"}},
+	}
+}
+
+func TestWriteRecordsChecksums(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "v2.zip")
+	a := testArchive()
+	if err := Write(path, a); err != nil {
+		t.Fatal(err)
+	}
+
+	got, err := Read(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(got.Manifest.Files) != 2 {
+		t.Fatalf("expected 2 checksummed entries, got %v", got.Manifest.Files)
+	}
+	if !strings.HasPrefix(got.Manifest.Digest, "sha256:") {
+		t.Fatalf("expected sha256 digest, got %q", got.Manifest.Digest)
+	}
+
+	report, err := Verify(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !report.OK() || report.Entries != 2 {
+		t.Fatalf("expected clean report with 2 entries, got %+v", report)
+	}
+}
+
+func TestReadDetectsTampering(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "good.zip")
+	a := testArchive()
+	if err := Write(path, a); err != nil {
+		t.Fatal(err)
+	}
+	manifest, err := json.Marshal(a.Manifest)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tampered := filepath.Join(dir, "tampered.zip")
+	writeRawZip(t, tampered, map[string]string{
+		"manifest.json":         string(manifest),
+		"instructions.md":       "# Instructions (edited)",
+		"skills/s1/SKILL.md":    "skill one",
+		"skills/extra/SKILL.md": "injected",
+	}, "manifest.json", "instructions.md", "skills/s1/SKILL.md", "skills/extra/SKILL.md")
+
+	_, err = Read(tampered)
+	var integrityErr *IntegrityError
+	if !errors.As(err, &integrityErr) {
+		t.Fatalf("expected IntegrityError, got %v", err)
+	}
+
+	report, err := Verify(tampered)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if report.OK() {
+		t.Fatal("expected verification to fail")
+	}
+	joined := strings.Join(report.Problems, "\n")
+	for _, want := range []string{"instructions.md: checksum mismatch", "skills/extra/SKILL.md: not listed", "archive digest mismatch"} {
+		if !strings.Contains(joined, want) {
+			t.Errorf("expected problem %q, got:\n%s", want, joined)
+		}
+	}
+}
+
+func TestReadDetectsMissingEntry(t *testing.T) {
+	dir := t.TempDir()
+	a := testArchive()
+	if err := Write(filepath.Join(dir, "good.zip"), a); err != nil {
+		t.Fatal(err)
+	}
+	manifest, err := json.Marshal(a.Manifest)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	path := filepath.Join(dir, "missing.zip")
+	writeRawZip(t, path, map[string]string{
+		"manifest.json":   string(manifest),
+		"instructions.md": "# Instructions",
+	}, "manifest.json", "instructions.md")
+
+	_, err = Read(path)
+	if err == nil || !strings.Contains(err.Error(), "listed in manifest but missing") {
+		t.Fatalf("expected missing entry error, got %v", err)
+	}
+}
+
+func TestUpgradeKeepsSignatureAndEncryption(t *testing.T) {
+	dir := t.TempDir()
+	priv, err := keys.GenerateSigningKey()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	signed := filepath.Join(dir, "signed.zip")
+	if err := WriteWith(signed, testArchive(), WriteOptions{SigningKey: priv}); err != nil {
+		t.Fatal(err)
+	}
+	out := filepath.Join(dir, "out.zip")
+	if _, err := Upgrade(signed, out, ReadOptions{}, WriteOptions{}); err == nil || !strings.Contains(err.Error(), "re-sign") {
+		t.Fatalf("expected a signed archive to need re-signing, got %v", err)
+	}
+	if _, err := Upgrade(signed, out, ReadOptions{}, WriteOptions{SigningKey: priv}); err != nil {
+		t.Fatal(err)
+	}
+	if got, err := Read(out); err != nil || got.Signature == nil {
+		t.Fatalf("expected the upgraded archive to be signed, got %v", err)
+	}
+
+	encrypted := filepath.Join(dir, "encrypted.zip")
+	pw := []byte("correct horse")
+	if err := WriteWith(encrypted, testArchive(), WriteOptions{Passphrase: pw}); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := Upgrade(encrypted, out, ReadOptions{Passphrase: pw}, WriteOptions{}); err == nil || !strings.Contains(err.Error(), "re-encrypt") {
+		t.Fatalf("expected an encrypted archive to need re-encrypting, got %v", err)
+	}
+	if _, err := Upgrade(encrypted, out, ReadOptions{Passphrase: pw}, WriteOptions{Passphrase: pw}); err != nil {
+		t.Fatal(err)
+	}
+	if report, err := Verify(out); err != nil || !report.Encrypted {
+		t.Fatalf("expected the upgraded archive to stay encrypted, got %+v (%v)", report, err)
+	}
+}
+
+func TestReadV1AndUpgrade(t *testing.T) {
+	dir := t.TempDir()
+	v1 := filepath.Join(dir, "v1.zip")
+	writeRawZip(t, v1, map[string]string{
+		"manifest.json":      `{"version":"1","agent":"claude","scope":"local","exported_at":"2026-01-01T00:00:00Z"}`,
+		"instructions.md":    "# V1",
+		"skills/s1/SKILL.md": "skill",
+	}, "manifest.json", "instructions.md", "skills/s1/SKILL.md")
+
+	got, err := Read(v1)
+	if err != nil {
+		t.Fatalf("expected v1 archive to be readable, got %v", err)
+	}
+	if got.Manifest.Version != FormatVersionV1 || got.Instructions.Content != "# V1" {
+		t.Fatalf("unexpected v1 archive: %+v", got.Manifest)
+	}
+
+	report, err := Verify(v1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if report.OK() {
+		t.Fatal("expected v1 archive to be reported as unverifiable")
+	}
+
+	v2 := filepath.Join(dir, "v2.zip")
+	if _, err := Upgrade(v1, v2, ReadOptions{}, WriteOptions{}); err != nil {
+		t.Fatal(err)
+	}
+	report, err = Verify(v2)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !report.OK() || report.Version != FormatVersion {
+		t.Fatalf("expected upgraded archive to verify, got %+v", report)
+	}
+	upgraded, err := Read(v2)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if upgraded.Instructions.Content != "# V1" || len(upgraded.Skills) != 1 {
+		t.Fatalf("upgrade lost content: %+v", upgraded)
+	}
+}
+
+func TestSignedArchive(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "signed.zip")
+	priv, err := keys.GenerateSigningKey()
+	if err != nil {
+		t.Fatal(err)
+	}
+	pub := keys.PublicKey{Key: priv.Public().(ed25519.PublicKey)}
+
+	a := testArchive()
+	if err := WriteWith(path, a, WriteOptions{SigningKey: priv}); err != nil {
+		t.Fatal(err)
+	}
+
+	got, err := Read(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got.Signature == nil || got.Signature.KeyID != pub.ID() {
+		t.Fatalf("expected signature from %s, got %+v", pub.ID(), got.Signature)
+	}
+	if _, err := got.VerifySignature([]keys.PublicKey{pub}); err != nil {
+		t.Fatalf("expected trusted signature, got %v", err)
+	}
+	if _, err := got.VerifySignature(nil); !errors.Is(err, ErrUntrustedKey) {
+		t.Fatalf("expected untrusted key error, got %v", err)
+	}
+
+	report, err := Verify(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !report.OK() || report.SignedBy != pub.ID() {
+		t.Fatalf("expected signed report, got %+v", report)
+	}
+
+	// Alter the manifest but keep the original signature.
+	got.Manifest.Agent = "codex"
+	manifest, err := json.MarshalIndent(got.Manifest, "", "  ")
+	if err != nil {
+		t.Fatal(err)
+	}
+	sig, err := json.Marshal(got.Signature)
+	if err != nil {
+		t.Fatal(err)
+	}
+	forged := filepath.Join(dir, "forged.zip")
+	writeRawZip(t, forged, map[string]string{
+		"manifest.json":      string(manifest),
+		"manifest.sig":       string(sig),
+		"instructions.md":    "# Instructions",
+		"skills/s1/SKILL.md": "skill one",
+	}, "manifest.json", "manifest.sig", "instructions.md", "skills/s1/SKILL.md")
+
+	forgedArchive, err := Read(forged)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := forgedArchive.VerifySignature([]keys.PublicKey{pub}); !errors.Is(err, ErrBadSignature) {
+		t.Fatalf("expected bad signature error, got %v", err)
+	}
+}
+
+func TestSignRequiresCurrentFormat(t *testing.T) {
+	priv, err := keys.GenerateSigningKey()
+	if err != nil {
+		t.Fatal(err)
+	}
+	a := testArchive()
+	a.Manifest.Version = FormatVersionV1
+	if err := WriteWith(filepath.Join(t.TempDir(), "v1.zip"), a, WriteOptions{SigningKey: priv}); err == nil {
+		t.Fatal("expected error signing a v1 archive")
+	}
+}
+
+func TestEncryptedArchivePassphrase(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "enc.zip")
+	a := testArchive()
+	if err := WriteWith(path, a, WriteOptions{Passphrase: []byte("correct horse")}); err != nil {
+		t.Fatal(err)
+	}
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if strings.Contains(string(data), "skill one") || strings.Contains(string(data), "skills/s1") {
+		t.Fatal("encrypted archive leaks plaintext content or skill names")
+	}
+
+	if _, err := Read(path); !errors.Is(err, ErrEncrypted) {
+		t.Fatalf("expected ErrEncrypted without a passphrase, got %v", err)
+	}
+	if _, err := ReadWith(path, ReadOptions{Passphrase: []byte("wrong")}); !errors.Is(err, ErrNoMatchingKey) {
+		t.Fatalf("expected ErrNoMatchingKey for a wrong passphrase, got %v", err)
+	}
+
+	got, err := ReadWith(path, ReadOptions{Passphrase: []byte("correct horse")})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !got.Manifest.Encrypted() {
+		t.Fatal("expected manifest to report encryption")
+	}
+	if got.Instructions == nil || got.Instructions.Content != "# Instructions" || len(got.Skills) != 1 {
+		t.Fatalf("unexpected decrypted content: %+v", got)
+	}
+
+	report, err := Verify(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !report.OK() || !report.Encrypted {
+		t.Fatalf("expected encrypted archive to verify without a key, got %+v", report)
+	}
+}
+
+func TestEncryptedArchiveRecipients(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "enc.zip")
+	alice, err := keys.GenerateEncryptionKey()
+	if err != nil {
+		t.Fatal(err)
+	}
+	bob, err := keys.GenerateEncryptionKey()
+	if err != nil {
+		t.Fatal(err)
+	}
+	mallory, err := keys.GenerateEncryptionKey()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// Large enough to span several payload chunks.
+	a := testArchive()
+	a.Instructions.Content = strings.Repeat("x", 3*payloadChunk+17)
+	if err := WriteWith(path, a, WriteOptions{Recipients: []*ecdh.PublicKey{alice.PublicKey(), bob.PublicKey()}}); err != nil {
+		t.Fatal(err)
+	}
+
+	for _, id := range []*ecdh.PrivateKey{alice, bob} {
+		got, err := ReadWith(path, ReadOptions{Identities: []*ecdh.PrivateKey{id}})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got.Instructions.Content != a.Instructions.Content {
+			t.Fatal("decrypted instructions do not match")
+		}
+		if names := strings.Join(got.EntryNames(), ","); names != "manifest.json,instructions.md,skills/s1/SKILL.md" {
+			t.Fatalf("expected decrypted entry names, got %s", names)
+		}
+	}
+
+	if _, err := ReadWith(path, ReadOptions{Identities: []*ecdh.PrivateKey{mallory}}); !errors.Is(err, ErrNoMatchingKey) {
+		t.Fatalf("expected ErrNoMatchingKey for a non-recipient, got %v", err)
+	}
+}
+
+func TestStreamDetectsTruncation(t *testing.T) {
+	key := make([]byte, fileKeySize)
+	nonce := make([]byte, 16)
+	sealed, err := streamSeal(key, nonce, []byte(strings.Repeat("y", 2*payloadChunk)))
+	if err != nil {
+		t.Fatal(err)
+	}
+	// Drop the final chunk; the new last chunk lacks the final flag.
+	truncated := sealed[:payloadChunk+16]
+	if _, err := streamOpen(key, nonce, truncated); err == nil {
+		t.Fatal("expected truncated payload to fail")
+	}
+}
+
+func TestBundleRoundTrip(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "bundle.zip")
+	sections := []Section{
+		{
+			SectionInfo:  SectionInfo{Agent: "claude", Scope: "local"},
+			Instructions: &agent.Instruction{Content: "# Local"},
+			Skills:       []agent.Skill{{Name: "s1", Content: "one"}},
+		},
+		{
+			SectionInfo:  SectionInfo{Agent: "codex", Scope: "global"},
+			Instructions: &agent.Instruction{Content: "# Global"},
+		},
+	}
+	a := &Archive{
+		Manifest: &Manifest{
+			Version:    FormatVersion,
+			ExportedAt: time.Now().Truncate(time.Second),
+			Sections:   []SectionInfo{sections[0].SectionInfo, sections[1].SectionInfo},
+		},
+		Sections: sections,
+	}
+	if err := Write(path, a); err != nil {
+		t.Fatal(err)
+	}
+	if _, ok := a.Manifest.Files["sections/claude/local/skills/s1/SKILL.md"]; !ok {
+		t.Fatalf("expected section entries in manifest, got %v", a.Manifest.Files)
+	}
+
+	got, err := Read(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !got.IsBundle() || len(got.Contents()) != 2 {
+		t.Fatalf("expected a bundle with 2 sections, got %+v", got.Manifest.Sections)
+	}
+	local, global := got.Contents()[0], got.Contents()[1]
+	if local.String() != "claude/local" || local.Instructions.Content != "# Local" || len(local.Skills) != 1 {
+		t.Fatalf("unexpected local section: %+v", local)
+	}
+	if global.String() != "codex/global" || global.Instructions.Content != "# Global" || len(global.Skills) != 0 {
+		t.Fatalf("unexpected global section: %+v", global)
+	}
+}
+
+func TestContentsOfSingleArchive(t *testing.T) {
+	a := testArchive()
+	got := a.Contents()
+	if a.IsBundle() || len(got) != 1 || got[0].String() != "claude/local" || got[0].Instructions != a.Instructions {
+		t.Fatalf("unexpected contents: %+v", got)
+	}
+}
+
+func TestReadBundleUndeclaredSection(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "bundle.zip")
+	manifest := `{"version":"1","agent":"","scope":"","sections":[{"agent":"claude","scope":"local"}]}`
+	writeRawZip(t, path, map[string]string{
+		"manifest.json":                          manifest,
+		"sections/claude/local/instructions.md":  "# A",
+		"sections/gemini/global/instructions.md": "# B",
+	}, "manifest.json", "sections/claude/local/instructions.md", "sections/gemini/global/instructions.md")
+
+	_, err := Read(path)
+	if err == nil || !strings.Contains(err.Error(), "not listed in the manifest") {
+		t.Fatalf("expected undeclared section error, got %v", err)
+	}
+}
+
+func TestReadBundleInvalidSection(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "bundle.zip")
+	writeRawZip(t, path, map[string]string{
+		"manifest.json": `{"version":"1","agent":"","scope":"","sections":[{"agent":"..","scope":"local"}]}`,
+	}, "manifest.json")
+
+	_, err := Read(path)
+	if err == nil || !strings.Contains(err.Error(), "invalid bundle section agent") {
+		t.Fatalf("expected invalid section error, got %v", err)
+	}
+}
+
+func TestFormatsRoundTrip(t *testing.T) {
+	dir := t.TempDir()
+	for _, tc := range []struct {
+		path   string
+		format Format
+	}{
+		{filepath.Join(dir, "a.zip"), FormatZip},
+		{filepath.Join(dir, "a.tar.gz"), FormatTarGz},
+		{filepath.Join(dir, "a.tgz"), FormatTarGz},
+		{filepath.Join(dir, "plain") + "/", FormatDir},
+	} {
+		if err := Write(tc.path, testArchive()); err != nil {
+			t.Fatalf("%s: %v", tc.path, err)
+		}
+		got, err := Read(tc.path)
+		if err != nil {
+			t.Fatalf("%s: %v", tc.path, err)
+		}
+		if got.Format != tc.format {
+			t.Errorf("%s: expected format %s, got %s", tc.path, tc.format, got.Format)
+		}
+		if got.Instructions.Content != "# Instructions" || len(got.Skills) != 1 || got.Skills[0].Content != "skill one" {
+			t.Errorf("%s: unexpected content %+v", tc.path, got)
+		}
+		report, err := Verify(tc.path)
+		if err != nil || !report.OK() {
+			t.Errorf("%s: expected archive to verify, got %+v, %v", tc.path, report, err)
+		}
+	}
+
+	data, err := os.ReadFile(filepath.Join(dir, "plain", "skills", "s1", "SKILL.md"))
+	if err != nil || string(data) != "skill one" {
+		t.Fatalf("expected plain skill file in directory archive, got %q, %v", data, err)
+	}
+}
+
+func TestWriteDirReplacesOnlyArchives(t *testing.T) {
+	dir := filepath.Join(t.TempDir(), "out")
+	a := testArchive()
+	if err := WriteWith(dir, a, WriteOptions{Format: FormatDir}); err != nil {
+		t.Fatal(err)
+	}
+	a.Skills = nil
+	if err := WriteWith(dir, a, WriteOptions{Format: FormatDir}); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := os.Stat(filepath.Join(dir, "skills")); !os.IsNotExist(err) {
+		t.Fatalf("expected stale skill to be removed, got %v", err)
+	}
+	if _, err := Read(dir); err != nil {
+		t.Fatal(err)
+	}
+
+	other := t.TempDir()
+	if err := os.WriteFile(filepath.Join(other, "notes.txt"), []byte("keep"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	if err := WriteWith(other, testArchive(), WriteOptions{Format: FormatDir}); err == nil {
+		t.Fatal("expected refusal to replace a non-archive directory")
+	}
+}
+
+func TestStreamRoundTrip(t *testing.T) {
+	for _, format := range []Format{FormatZip, FormatTarGz} {
+		var buf bytes.Buffer
+		if err := WriteTo(&buf, testArchive(), WriteOptions{Format: format}); err != nil {
+			t.Fatal(err)
+		}
+		got, err := ReadFrom(&buf, ReadOptions{})
+		if err != nil {
+			t.Fatalf("%s: %v", format, err)
+		}
+		if got.Format != format || got.Instructions.Content != "# Instructions" {
+			t.Fatalf("%s: unexpected archive %+v", format, got)
+		}
+	}
+
+	if err := WriteTo(io.Discard, testArchive(), WriteOptions{Format: FormatDir}); err == nil {
+		t.Fatal("expected error streaming a directory archive")
+	}
+	if _, err := ReadFrom(strings.NewReader("not an archive"), ReadOptions{}); err == nil || !strings.Contains(err.Error(), "unrecognized format") {
+		t.Fatalf("expected unrecognized format error, got %v", err)
+	}
+}
+
+// rawZipEntry is one file for writeZipEntries, which unlike writeRawZip
+// allows duplicate names and custom modes.
+type rawZipEntry struct {
+	name string
+	data string
+	mode fs.FileMode
+}
+
+func writeZipEntries(t *testing.T, path string, entries ...rawZipEntry) {
+	t.Helper()
+	var buf bytes.Buffer
+	w := zip.NewWriter(&buf)
+	for _, e := range entries {
+		hdr := &zip.FileHeader{Name: e.name, Method: zip.Deflate}
+		if e.mode != 0 {
+			hdr.SetMode(e.mode)
+		}
+		fw, err := w.CreateHeader(hdr)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if _, err := fw.Write([]byte(e.data)); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := w.Close(); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(path, buf.Bytes(), 0o644); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestReadRejectsHostileEntries(t *testing.T) {
+	manifest := rawZipEntry{name: "manifest.json", data: `{"version":"1","agent":"claude","scope":"local"}`}
+	tests := []struct {
+		name    string
+		entries []rawZipEntry
+		limits  Limits
+		want    error
+	}{
+		{"duplicate manifest", []rawZipEntry{manifest, manifest}, Limits{}, ErrDuplicateEntry},
+		{"duplicate skill", []rawZipEntry{manifest, {name: "skills/a/SKILL.md"}, {name: "skills/a/./SKILL.md"}}, Limits{}, ErrDuplicateEntry},
+		{"symlink", []rawZipEntry{manifest, {name: "instructions.md", data: "/etc/passwd", mode: fs.ModeSymlink | 0o777}}, Limits{}, ErrUnsupportedEntry},
+		{"escaping name", []rawZipEntry{manifest, {name: "../evil"}}, Limits{}, ErrUnsafeName},
+		{"absolute name", []rawZipEntry{manifest, {name: "/etc/evil"}}, Limits{}, ErrUnsafeName},
+		{"too many entries", []rawZipEntry{manifest, {name: "a"}, {name: "b"}}, Limits{MaxEntries: 2}, ErrTooManyEntries},
+		{"entry too large", []rawZipEntry{manifest, {name: "instructions.md", data: strings.Repeat("0", 4096)}}, Limits{MaxEntrySize: 1024}, ErrEntryTooLarge},
+		{"total too large", []rawZipEntry{manifest, {name: "a", data: strings.Repeat("0", 800)}, {name: "b", data: strings.Repeat("0", 800)}}, Limits{MaxEntrySize: 1000, MaxTotalSize: 1500}, ErrArchiveTooLarge},
+		{"case collision", []rawZipEntry{manifest, {name: "skills/Review/SKILL.md"}, {name: "skills/review/SKILL.md"}}, Limits{}, ErrSkillNameCollision},
+		{"kelvin sign collision", []rawZipEntry{manifest, {name: "skills/k/SKILL.md"}, {name: "skills/K/SKILL.md"}}, Limits{}, ErrSkillNameCollision},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			path := filepath.Join(t.TempDir(), "hostile.zip")
+			writeZipEntries(t, path, tt.entries...)
+			_, err := ReadWith(path, ReadOptions{Limits: tt.limits})
+			if !errors.Is(err, tt.want) {
+				t.Fatalf("expected %v, got %v", tt.want, err)
+			}
+		})
+	}
+}
+
+func TestSkillNameValidation(t *testing.T) {
+	for _, name := range []string{"café", "cafe\u0301", "समीक्षा", "ตรวจสอบ", "검토", "review\u200dnotes"} {
+		if err := validateSkillName(name); err != nil {
+			t.Errorf("expected %q to be accepted, got %v", name, err)
+		}
+	}
+	for _, name := range []string{"re\u202eview", "a\u2066b", "tab\there", "bell\a"} {
+		if err := validateSkillName(name); err == nil {
+			t.Errorf("expected %q to be rejected", name)
+		}
+	}
+}
+
+func TestSkillNameCollisions(t *testing.T) {
+	tests := []struct{ a, b string }{
+		{"café", "cafe\u0301"},
+		{"Ångström", "\u212Bngstro\u0308m"},
+		{"검토", "\u1100\u1165\u11B7\u1110\u1169"},
+		{"ｒｅｖｉｅｗ", "review"},
+		{"ﬁx", "FIX"},
+		{"v²", "v2"},
+		{"zero\u200bwidth", "zerowidth"},
+		{"ệ", "e\u0302\u0323"},
+	}
+	for _, tt := range tests {
+		if err := checkSkillNames([]string{tt.a, tt.b}); !errors.Is(err, ErrSkillNameCollision) {
+			t.Errorf("expected %q and %q to collide, got %v", tt.a, tt.b, err)
+		}
+	}
+	if err := checkSkillNames([]string{"समीक्षा", "समीक्ष", "review", "reviews"}); err != nil {
+		t.Errorf("expected distinct names to be accepted, got %v", err)
+	}
+}
+
+func TestReadRejectsComposedAndDecomposedSkillName(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "nfd.zip")
+	writeZipEntries(t, path,
+		rawZipEntry{name: "manifest.json", data: `{"version":"1","agent":"claude","scope":"local"}`},
+		rawZipEntry{name: "skills/cafe\u0301/SKILL.md", data: "decomposed"},
+		rawZipEntry{name: "skills/café/SKILL.md", data: "composed"},
+	)
+	if _, err := Read(path); !errors.Is(err, ErrSkillNameCollision) {
+		t.Fatalf("expected decomposed and composed names to collide, got %v", err)
+	}
+}
+
+func TestReadReportsEntryError(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "big.zip")
+	writeZipEntries(t, path,
+		rawZipEntry{name: "manifest.json", data: `{"version":"1","agent":"claude","scope":"local"}`},
+		rawZipEntry{name: "instructions.md", data: strings.Repeat("x", 2048)},
+	)
+	_, err := ReadWith(path, ReadOptions{Limits: Limits{MaxEntrySize: 1024}})
+	var entryErr *EntryError
+	if !errors.As(err, &entryErr) || entryErr.Entry != "instructions.md" {
+		t.Fatalf("expected EntryError for instructions.md, got %v", err)
+	}
+}
+
+func TestReadRejectsSymlinksInOtherFormats(t *testing.T) {
+	dir := filepath.Join(t.TempDir(), "plain")
+	if err := WriteWith(dir, testArchive(), WriteOptions{Format: FormatDir}); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Symlink("/etc/passwd", filepath.Join(dir, "skills", "s1", "extra")); err != nil {
+		t.Skip("symlinks not supported:", err)
+	}
+	if _, err := Read(dir); !errors.Is(err, ErrUnsupportedEntry) {
+		t.Fatalf("expected ErrUnsupportedEntry for directory symlink, got %v", err)
+	}
+
+	var buf bytes.Buffer
+	gz := gzip.NewWriter(&buf)
+	tw := tar.NewWriter(gz)
+	if err := tw.WriteHeader(&tar.Header{Typeflag: tar.TypeSymlink, Name: "instructions.md", Linkname: "/etc/passwd"}); err != nil {
+		t.Fatal(err)
+	}
+	if err := tw.Close(); err != nil {
+		t.Fatal(err)
+	}
+	if err := gz.Close(); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := ReadFrom(&buf, ReadOptions{}); !errors.Is(err, ErrUnsupportedEntry) {
+		t.Fatalf("expected ErrUnsupportedEntry for tar symlink, got %v", err)
+	}
+}
+
+func TestReproducibleWrite(t *testing.T) {
+	stamp := time.Date(2024, 5, 1, 12, 0, 0, 0, time.UTC)
+	build := func(skills ...agent.Skill) *Archive {
+		a := testArchive()
+		a.Manifest.ExportedAt = stamp
+		a.Skills = skills
+		return a
+	}
+	one, two := agent.Skill{Name: "one", Content: "1"}, agent.Skill{Name: "two", Content: "2"}
+
+	for _, format := range []Format{FormatZip, FormatTarGz} {
+		t.Run(string(format), func(t *testing.T) {
+			var first, second bytes.Buffer
+			if err := WriteTo(&first, build(one, two), WriteOptions{Format: format, ModTime: stamp}); err != nil {
+				t.Fatal(err)
+			}
+			if err := WriteTo(&second, build(two, one), WriteOptions{Format: format, ModTime: stamp}); err != nil {
+				t.Fatal(err)
+			}
+			if !bytes.Equal(first.Bytes(), second.Bytes()) {
+				t.Fatal("expected identical bytes for identical content")
+			}
+		})
+	}
+
+	var buf bytes.Buffer
+	if err := WriteTo(&buf, build(two, one), WriteOptions{ModTime: stamp}); err != nil {
+		t.Fatal(err)
+	}
+	zr, err := zip.NewReader(bytes.NewReader(buf.Bytes()), int64(buf.Len()))
+	if err != nil {
+		t.Fatal(err)
+	}
+	var names []string
+	for _, f := range zr.File {
+		names = append(names, f.Name)
+		if !f.Modified.Equal(stamp) || f.Mode().Perm() != 0o644 {
+			t.Errorf("entry %s: modified %v mode %v", f.Name, f.Modified, f.Mode())
+		}
+	}
+	want := []string{"manifest.json", "instructions.md", "skills/one/SKILL.md", "skills/two/SKILL.md"}
+	if !slices.Equal(names, want) {
+		t.Fatalf("expected sorted entries %v, got %v", want, names)
+	}
+
+	err = WriteTo(io.Discard, build(one), WriteOptions{ModTime: stamp, Passphrase: []byte("pw")})
+	if err == nil {
+		t.Fatal("expected reproducible encrypted write to fail")
+	}
+}
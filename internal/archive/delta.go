@@ -0,0 +1,208 @@
+package archive
+
+import (
+	"fmt"
+	"slices"
+
+	"github.com/LaneBirmingham/coding-agent-sync/internal/agent"
+	"github.com/LaneBirmingham/coding-agent-sync/internal/conditional"
+)
+
+// Delta marks a patch archive holding only what changed since a base
+// archive. Its content is the added and changed instructions and skills; the
+// base checksums let an importer confirm the destination still holds the base.
+type Delta struct {
+	// BaseDigest is the digest of the base archive's content entries for the
+	// sections the delta covers.
+	BaseDigest string         `json:"base_digest"`
+	Sections   []DeltaSection `json:"sections"`
+}
+
+// DeltaSection records the base state of one section and what the delta removes from it.
+type DeltaSection struct {
+	SectionInfo
+	BaseInstructions   string            `json:"base_instructions,omitempty"` // SHA-256 of the base instructions; empty when there were none
+	BaseSkills         map[string]string `json:"base_skills,omitempty"`       // skill name to SHA-256 of its SKILL.md in the base
+	DeleteInstructions bool              `json:"delete_instructions,omitempty"`
+	DeletedSkills      []string          `json:"deleted_skills,omitempty"`
+
+	// Base content with cas:if sections, which import renders for each
+	// destination. An importer renders it the same way to know what the
+	// destination holds, since the checksums above cover the raw content.
+	BaseInstructionsContent string            `json:"base_instructions_content,omitempty"`
+	BaseSkillsContent       map[string]string `json:"base_skills_content,omitempty"`
+}
+
+// Section returns the delta for one section, or nil when there is none.
+func (d *Delta) Section(s SectionInfo) *DeltaSection {
+	for i := range d.Sections {
+		if d.Sections[i].SectionInfo == s {
+			return &d.Sections[i]
+		}
+	}
+	return nil
+}
+
+// Hash returns the SHA-256 checksum used in deltas for instruction or skill content.
+func Hash(content string) string {
+	return sha256Hex([]byte(content))
+}
+
+// DeltaFrom reduces a to the changes since base: unchanged instructions and
+// skills are dropped, removed ones are listed for deletion, and the base
+// checksums are recorded in a.Manifest.Delta. Both archives must share a
+// layout: the same agent and scope, or both bundles. Base sections missing
+// from a bundle are left out of the delta; include an empty section to delete
+// their content.
+func (a *Archive) DeltaFrom(base *Archive) error {
+	if base.Manifest.Delta != nil {
+		return fmt.Errorf("delta base must be a full archive, not a delta")
+	}
+	if a.IsBundle() != base.IsBundle() {
+		return fmt.Errorf("delta base and new archive must both be bundles or both single-section archives")
+	}
+	if !a.IsBundle() && (a.Manifest.Agent != base.Manifest.Agent || a.Manifest.Scope != base.Manifest.Scope) {
+		return fmt.Errorf("delta base holds %s/%s, not %s/%s", base.Manifest.Agent, base.Manifest.Scope, a.Manifest.Agent, a.Manifest.Scope)
+	}
+
+	if _, err := base.contentEntries(); err != nil {
+		return fmt.Errorf("reading delta base: %w", err)
+	}
+	d := &Delta{}
+
+	if !a.IsBundle() {
+		info := SectionInfo{Agent: a.Manifest.Agent, Scope: a.Manifest.Scope}
+		d.Sections = append(d.Sections, reduce(info, &a.Instructions, &a.Skills, base.Instructions, base.Skills))
+		d.BaseDigest = d.DigestWith(false, nil)
+		a.Manifest.Delta = d
+		return nil
+	}
+
+	for i := range a.Sections {
+		sec := &a.Sections[i]
+		var baseInst *agent.Instruction
+		var baseSkills []agent.Skill
+		if j := slices.IndexFunc(base.Sections, func(s Section) bool { return s.SectionInfo == sec.SectionInfo }); j >= 0 {
+			baseInst, baseSkills = base.Sections[j].Instructions, base.Sections[j].Skills
+		}
+		d.Sections = append(d.Sections, reduce(sec.SectionInfo, &sec.Instructions, &sec.Skills, baseInst, baseSkills))
+	}
+	d.BaseDigest = d.DigestWith(true, nil)
+	a.Manifest.Delta = d
+	return nil
+}
+
+// DigestWith returns the digest of the base content recorded in d, with the
+// checksums of current standing in for its section's base. An importer
+// passes the checksums it finds at a destination; the result equals
+// BaseDigest only when the destination still holds that section's base.
+func (d *Delta) DigestWith(bundle bool, current *DeltaSection) string {
+	files := make(map[string]string)
+	for _, ds := range d.Sections {
+		if current != nil && current.SectionInfo == ds.SectionInfo {
+			ds = *current
+		}
+		prefix := ""
+		if bundle {
+			prefix = ds.prefix()
+		}
+		if ds.BaseInstructions != "" {
+			files[prefix+"instructions.md"] = ds.BaseInstructions
+		}
+		for name, sum := range ds.BaseSkills {
+			files[fmt.Sprintf("%sskills/%s/SKILL.md", prefix, name)] = sum
+		}
+	}
+	return digestOf(files)
+}
+
+// reduce drops the instructions and skills that match the base and records
+// the base checksums and deletions for one section.
+func reduce(info SectionInfo, inst **agent.Instruction, skills *[]agent.Skill, baseInst *agent.Instruction, baseSkills []agent.Skill) DeltaSection {
+	ds := DeltaSection{SectionInfo: info}
+
+	baseContent := ""
+	if baseInst != nil {
+		baseContent = baseInst.Content
+	}
+	newContent := ""
+	if *inst != nil {
+		newContent = (*inst).Content
+	}
+	if baseContent != "" {
+		ds.BaseInstructions = Hash(baseContent)
+	}
+	if conditional.Has(baseContent) {
+		ds.BaseInstructionsContent = baseContent
+	}
+	switch {
+	case newContent == baseContent:
+		*inst = nil
+	case newContent == "":
+		*inst = nil
+		ds.DeleteInstructions = true
+	}
+
+	base := make(map[string]string, len(baseSkills))
+	for _, s := range baseSkills {
+		base[s.Name] = s.Content
+	}
+	if len(base) > 0 {
+		ds.BaseSkills = make(map[string]string, len(base))
+		for name, content := range base {
+			ds.BaseSkills[name] = Hash(content)
+			if conditional.Has(content) {
+				if ds.BaseSkillsContent == nil {
+					ds.BaseSkillsContent = make(map[string]string)
+				}
+				ds.BaseSkillsContent[name] = content
+			}
+		}
+	}
+	var changed []agent.Skill
+	seen := make(map[string]bool, len(*skills))
+	for _, s := range *skills {
+		seen[s.Name] = true
+		if content, ok := base[s.Name]; !ok || content != s.Content {
+			changed = append(changed, s)
+		}
+	}
+	for _, s := range baseSkills {
+		if !seen[s.Name] {
+			ds.DeletedSkills = append(ds.DeletedSkills, s.Name)
+		}
+	}
+	*skills = changed
+	return ds
+}
+
+// validateDelta checks the section and skill names a delta refers to.
+func validateDelta(m *Manifest) error {
+	if m.Delta == nil {
+		return nil
+	}
+	for _, ds := range m.Delta.Sections {
+		if m.Sections != nil && !slices.Contains(m.Sections, ds.SectionInfo) {
+			return fmt.Errorf("delta refers to section %s not listed in the manifest", ds.SectionInfo)
+		}
+		for name := range ds.BaseSkills {
+			if err := validateSkillName(name); err != nil {
+				return fmt.Errorf("delta base skill %q: %w", name, err)
+			}
+		}
+		for _, name := range ds.DeletedSkills {
+			if err := validateSkillName(name); err != nil {
+				return fmt.Errorf("delta deleted skill %q: %w", name, err)
+			}
+		}
+		if ds.BaseInstructionsContent != "" && Hash(ds.BaseInstructionsContent) != ds.BaseInstructions {
+			return fmt.Errorf("delta base instructions of section %s do not match their checksum", ds.SectionInfo)
+		}
+		for name, content := range ds.BaseSkillsContent {
+			if sum, ok := ds.BaseSkills[name]; !ok || Hash(content) != sum {
+				return fmt.Errorf("delta base skill %q does not match its checksum", name)
+			}
+		}
+	}
+	return nil
+}
@@ -0,0 +1,112 @@
+package archive
+
+import (
+	"path/filepath"
+	"slices"
+	"testing"
+
+	"github.com/LaneBirmingham/coding-agent-sync/internal/agent"
+)
+
+func TestDeltaFrom(t *testing.T) {
+	base := testArchive()
+	base.Skills = []agent.Skill{{Name: "keep", Content: "same"}, {Name: "edit", Content: "old"}, {Name: "gone", Content: "bye"}}
+
+	a := testArchive()
+	a.Skills = []agent.Skill{{Name: "keep", Content: "same"}, {Name: "edit", Content: "new"}, {Name: "added", Content: "hi"}}
+	if err := a.DeltaFrom(base); err != nil {
+		t.Fatal(err)
+	}
+
+	if a.Instructions != nil {
+		t.Fatalf("expected unchanged instructions to be dropped, got %q", a.Instructions.Content)
+	}
+	if names := skillNameList(a.Skills); !slices.Equal(names, []string{"edit", "added"}) {
+		t.Fatalf("expected changed and added skills, got %v", names)
+	}
+	d := a.Manifest.Delta
+	baseEntries, _ := base.contentEntries()
+	if _, digest := checksums(baseEntries); d == nil || d.BaseDigest != digest {
+		t.Fatalf("expected base digest %s, got %+v", digest, d)
+	}
+	ds := d.Section(SectionInfo{Agent: "claude", Scope: "local"})
+	if ds == nil {
+		t.Fatal("expected a delta section for claude/local")
+	}
+	if ds.BaseInstructions != Hash("# Instructions") || ds.DeleteInstructions {
+		t.Fatalf("unexpected instructions delta: %+v", ds)
+	}
+	if !slices.Equal(ds.DeletedSkills, []string{"gone"}) || len(ds.BaseSkills) != 3 || ds.BaseSkills["edit"] != Hash("old") {
+		t.Fatalf("unexpected skills delta: %+v", ds)
+	}
+	if got := d.DigestWith(false, ds); got != d.BaseDigest {
+		t.Fatalf("expected the recorded base to reproduce the digest, got %s", got)
+	}
+	changed := *ds
+	changed.BaseInstructions = Hash("# Edited")
+	if d.DigestWith(false, &changed) == d.BaseDigest {
+		t.Fatal("expected different content to change the digest")
+	}
+
+	path := filepath.Join(t.TempDir(), "delta.zip")
+	if err := Write(path, a); err != nil {
+		t.Fatal(err)
+	}
+	got, err := Read(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got.Manifest.Delta == nil || got.Manifest.Delta.BaseDigest != d.BaseDigest || len(got.Skills) != 2 {
+		t.Fatalf("delta did not round-trip: %+v", got.Manifest.Delta)
+	}
+}
+
+func TestDeltaFromDeletedInstructions(t *testing.T) {
+	a := testArchive()
+	a.Instructions = nil
+	if err := a.DeltaFrom(testArchive()); err != nil {
+		t.Fatal(err)
+	}
+	if ds := a.Manifest.Delta.Sections[0]; !ds.DeleteInstructions {
+		t.Fatalf("expected instructions to be deleted, got %+v", ds)
+	}
+}
+
+func TestDeltaFromRejectsMismatchedBase(t *testing.T) {
+	other := testArchive()
+	other.Manifest.Agent = "codex"
+	if err := testArchive().DeltaFrom(other); err == nil {
+		t.Fatal("expected an error for a base from another agent")
+	}
+
+	bundle := &Archive{
+		Manifest: &Manifest{Version: FormatVersion, Sections: []SectionInfo{{Agent: "claude", Scope: "local"}}},
+		Sections: []Section{{SectionInfo: SectionInfo{Agent: "claude", Scope: "local"}}},
+	}
+	if err := testArchive().DeltaFrom(bundle); err == nil {
+		t.Fatal("expected an error for a bundle base of a single-section archive")
+	}
+
+	delta := testArchive()
+	if err := delta.DeltaFrom(testArchive()); err != nil {
+		t.Fatal(err)
+	}
+	if err := testArchive().DeltaFrom(delta); err == nil {
+		t.Fatal("expected an error for a delta base")
+	}
+}
+
+func TestReadRejectsUnsafeDeltaSkill(t *testing.T) {
+	a := testArchive()
+	a.Manifest.Delta = &Delta{Sections: []DeltaSection{{
+		SectionInfo:   SectionInfo{Agent: "claude", Scope: "local"},
+		DeletedSkills: []string{"../escape"},
+	}}}
+	path := filepath.Join(t.TempDir(), "bad.zip")
+	if err := Write(path, a); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := Read(path); err == nil {
+		t.Fatal("expected an error for an unsafe deleted skill name")
+	}
+}
@@ -0,0 +1,319 @@
+package archive
+
+import (
+	"bytes"
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/ecdh"
+	"crypto/hkdf"
+	"crypto/hmac"
+	"crypto/pbkdf2"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/binary"
+	"encoding/json"
+	"errors"
+	"fmt"
+)
+
+// EncryptionScheme identifies the payload encryption format. It follows the
+// structure of age (a random file key wrapped once per recipient, an HMAC over
+// the header, and a chunked STREAM payload) using AES-256-GCM and PBKDF2 from
+// the Go standard library. Files are not interchangeable with the age tool.
+const EncryptionScheme = "cas-age-v1"
+
+const (
+	payloadEntry = "payload.enc"
+
+	stanzaX25519     = "x25519"
+	stanzaPassphrase = "passphrase"
+
+	fileKeySize     = 16
+	payloadChunk    = 64 * 1024
+	pbkdf2Iters     = 600_000
+	maxPBKDF2Iters  = 10_000_000
+	headerMACLabel  = "cas-age-v1/header"
+	payloadLabel    = "cas-age-v1/payload"
+	x25519Label     = "cas-age-v1/x25519"
+	passphraseLabel = "cas-age-v1/passphrase"
+)
+
+var (
+	// ErrEncrypted is returned when reading an encrypted archive without a key.
+	ErrEncrypted = errors.New("archive is encrypted (provide a passphrase or identity)")
+	// ErrNoMatchingKey is returned when no supplied key unlocks the archive.
+	ErrNoMatchingKey = errors.New("no supplied passphrase or identity can decrypt the archive")
+)
+
+// Encryption describes how the payload of an encrypted archive is protected.
+type Encryption struct {
+	Scheme     string   `json:"scheme"`
+	Recipients []Stanza `json:"recipients"`
+	Nonce      []byte   `json:"nonce"`
+	MAC        []byte   `json:"mac"`
+}
+
+// Stanza wraps the file key for one recipient.
+type Stanza struct {
+	Type       string `json:"type"`
+	Ephemeral  []byte `json:"ephemeral,omitempty"`  // x25519: ephemeral public key
+	Salt       []byte `json:"salt,omitempty"`       // passphrase: PBKDF2 salt
+	Iterations int    `json:"iterations,omitempty"` // passphrase: PBKDF2 iterations
+	WrappedKey []byte `json:"wrapped_key"`
+}
+
+// encryptPayload encrypts plaintext to the recipients or passphrase in opts.
+func encryptPayload(plaintext []byte, opts WriteOptions) (*Encryption, []byte, error) {
+	if len(opts.Passphrase) > 0 && len(opts.Recipients) > 0 {
+		return nil, nil, fmt.Errorf("encrypt to either a passphrase or recipients, not both")
+	}
+
+	fileKey := make([]byte, fileKeySize)
+	if _, err := rand.Read(fileKey); err != nil {
+		return nil, nil, err
+	}
+
+	enc := &Encryption{Scheme: EncryptionScheme}
+	if len(opts.Passphrase) > 0 {
+		s, err := wrapPassphrase(fileKey, opts.Passphrase)
+		if err != nil {
+			return nil, nil, err
+		}
+		enc.Recipients = append(enc.Recipients, s)
+	}
+	for _, r := range opts.Recipients {
+		s, err := wrapX25519(fileKey, r)
+		if err != nil {
+			return nil, nil, err
+		}
+		enc.Recipients = append(enc.Recipients, s)
+	}
+
+	enc.Nonce = make([]byte, 16)
+	if _, err := rand.Read(enc.Nonce); err != nil {
+		return nil, nil, err
+	}
+	mac, err := headerMAC(fileKey, enc)
+	if err != nil {
+		return nil, nil, err
+	}
+	enc.MAC = mac
+
+	ciphertext, err := streamSeal(fileKey, enc.Nonce, plaintext)
+	if err != nil {
+		return nil, nil, err
+	}
+	return enc, ciphertext, nil
+}
+
+// decryptPayload recovers the file key with one of the supplied keys and decrypts ciphertext.
+func decryptPayload(enc *Encryption, ciphertext []byte, opts ReadOptions) ([]byte, error) {
+	if enc.Scheme != EncryptionScheme {
+		return nil, fmt.Errorf("unsupported encryption scheme %q", enc.Scheme)
+	}
+	if len(opts.Passphrase) == 0 && len(opts.Identities) == 0 {
+		return nil, ErrEncrypted
+	}
+
+	fileKey := unwrapFileKey(enc.Recipients, opts)
+	if fileKey == nil {
+		return nil, ErrNoMatchingKey
+	}
+
+	mac, err := headerMAC(fileKey, enc)
+	if err != nil {
+		return nil, err
+	}
+	if !hmac.Equal(mac, enc.MAC) {
+		return nil, fmt.Errorf("encryption header MAC mismatch")
+	}
+	return streamOpen(fileKey, enc.Nonce, ciphertext)
+}
+
+// unwrapFileKey tries each supplied key against each stanza, returning nil if none match.
+func unwrapFileKey(stanzas []Stanza, opts ReadOptions) []byte {
+	for _, s := range stanzas {
+		switch s.Type {
+		case stanzaPassphrase:
+			if len(opts.Passphrase) == 0 {
+				continue
+			}
+			if key, err := unwrapPassphrase(s, opts.Passphrase); err == nil {
+				return key
+			}
+		case stanzaX25519:
+			for _, id := range opts.Identities {
+				if key, err := unwrapX25519(s, id); err == nil {
+					return key
+				}
+			}
+		}
+	}
+	return nil
+}
+
+func wrapX25519(fileKey []byte, recipient *ecdh.PublicKey) (Stanza, error) {
+	eph, err := ecdh.X25519().GenerateKey(rand.Reader)
+	if err != nil {
+		return Stanza{}, err
+	}
+	shared, err := eph.ECDH(recipient)
+	if err != nil {
+		return Stanza{}, err
+	}
+	wrapKey, err := hkdf.Key(sha256.New, shared, append(eph.PublicKey().Bytes(), recipient.Bytes()...), x25519Label, 32)
+	if err != nil {
+		return Stanza{}, err
+	}
+	wrapped, err := aeadSeal(wrapKey, make([]byte, 12), fileKey)
+	if err != nil {
+		return Stanza{}, err
+	}
+	return Stanza{Type: stanzaX25519, Ephemeral: eph.PublicKey().Bytes(), WrappedKey: wrapped}, nil
+}
+
+func unwrapX25519(s Stanza, id *ecdh.PrivateKey) ([]byte, error) {
+	eph, err := ecdh.X25519().NewPublicKey(s.Ephemeral)
+	if err != nil {
+		return nil, err
+	}
+	shared, err := id.ECDH(eph)
+	if err != nil {
+		return nil, err
+	}
+	wrapKey, err := hkdf.Key(sha256.New, shared, append(s.Ephemeral, id.PublicKey().Bytes()...), x25519Label, 32)
+	if err != nil {
+		return nil, err
+	}
+	return aeadOpen(wrapKey, make([]byte, 12), s.WrappedKey)
+}
+
+func wrapPassphrase(fileKey, passphrase []byte) (Stanza, error) {
+	salt := make([]byte, 16)
+	if _, err := rand.Read(salt); err != nil {
+		return Stanza{}, err
+	}
+	wrapKey, err := pbkdf2.Key(sha256.New, string(passphrase), append([]byte(passphraseLabel), salt...), pbkdf2Iters, 32)
+	if err != nil {
+		return Stanza{}, err
+	}
+	wrapped, err := aeadSeal(wrapKey, make([]byte, 12), fileKey)
+	if err != nil {
+		return Stanza{}, err
+	}
+	return Stanza{Type: stanzaPassphrase, Salt: salt, Iterations: pbkdf2Iters, WrappedKey: wrapped}, nil
+}
+
+func unwrapPassphrase(s Stanza, passphrase []byte) ([]byte, error) {
+	if s.Iterations <= 0 || s.Iterations > maxPBKDF2Iters {
+		return nil, fmt.Errorf("passphrase work factor %d out of range", s.Iterations)
+	}
+	wrapKey, err := pbkdf2.Key(sha256.New, string(passphrase), append([]byte(passphraseLabel), s.Salt...), s.Iterations, 32)
+	if err != nil {
+		return nil, err
+	}
+	return aeadOpen(wrapKey, make([]byte, 12), s.WrappedKey)
+}
+
+// headerMAC authenticates the recipient stanzas and nonce under the file key.
+func headerMAC(fileKey []byte, enc *Encryption) ([]byte, error) {
+	header, err := json.Marshal(Encryption{Scheme: enc.Scheme, Recipients: enc.Recipients, Nonce: enc.Nonce})
+	if err != nil {
+		return nil, err
+	}
+	key, err := hkdf.Key(sha256.New, fileKey, nil, headerMACLabel, 32)
+	if err != nil {
+		return nil, err
+	}
+	h := hmac.New(sha256.New, key)
+	h.Write(header)
+	return h.Sum(nil), nil
+}
+
+// streamSeal encrypts plaintext in fixed-size chunks, each with a nonce made
+// of a big-endian counter and a final-chunk flag, so truncation is detected.
+func streamSeal(fileKey, nonce, plaintext []byte) ([]byte, error) {
+	aead, err := payloadAEAD(fileKey, nonce)
+	if err != nil {
+		return nil, err
+	}
+	var out bytes.Buffer
+	for i := uint64(0); ; i++ {
+		n := min(payloadChunk, len(plaintext))
+		chunk := plaintext[:n]
+		plaintext = plaintext[n:]
+		last := len(plaintext) == 0
+		out.Write(aead.Seal(nil, chunkNonce(i, last), chunk, nil))
+		if last {
+			return out.Bytes(), nil
+		}
+	}
+}
+
+func streamOpen(fileKey, nonce, ciphertext []byte) ([]byte, error) {
+	aead, err := payloadAEAD(fileKey, nonce)
+	if err != nil {
+		return nil, err
+	}
+	sealed := payloadChunk + aead.Overhead()
+	var out bytes.Buffer
+	for i := uint64(0); ; i++ {
+		n := min(sealed, len(ciphertext))
+		chunk := ciphertext[:n]
+		ciphertext = ciphertext[n:]
+		last := len(ciphertext) == 0
+		plain, err := aead.Open(nil, chunkNonce(i, last), chunk, nil)
+		if err != nil {
+			return nil, fmt.Errorf("decrypting payload chunk %d: %w", i, err)
+		}
+		out.Write(plain)
+		if last {
+			return out.Bytes(), nil
+		}
+	}
+}
+
+func payloadAEAD(fileKey, nonce []byte) (cipher.AEAD, error) {
+	key, err := hkdf.Key(sha256.New, fileKey, nonce, payloadLabel, 32)
+	if err != nil {
+		return nil, err
+	}
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, err
+	}
+	return cipher.NewGCM(block)
+}
+
+func chunkNonce(counter uint64, last bool) []byte {
+	nonce := make([]byte, 12)
+	binary.BigEndian.PutUint64(nonce[3:11], counter)
+	if last {
+		nonce[11] = 1
+	}
+	return nonce
+}
+
+func aeadSeal(key, nonce, plaintext []byte) ([]byte, error) {
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, err
+	}
+	aead, err := cipher.NewGCM(block)
+	if err != nil {
+		return nil, err
+	}
+	return aead.Seal(nil, nonce, plaintext, nil), nil
+}
+
+func aeadOpen(key, nonce, ciphertext []byte) ([]byte, error) {
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, err
+	}
+	aead, err := cipher.NewGCM(block)
+	if err != nil {
+		return nil, err
+	}
+	return aead.Open(nil, nonce, ciphertext, nil)
+}
@@ -0,0 +1,266 @@
+package archive
+
+import (
+	"archive/tar"
+	"bytes"
+	"compress/gzip"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+// Format is the container an archive is stored in.
+type Format string
+
+const (
+	FormatZip   Format = "zip"
+	FormatTarGz Format = "tar.gz"
+	FormatDir   Format = "dir"
+)
+
+// ParseFormat converts a string to a Format, returning an error if invalid.
+func ParseFormat(s string) (Format, error) {
+	switch f := Format(strings.ToLower(s)); f {
+	case FormatZip, FormatTarGz, FormatDir:
+		return f, nil
+	case "tgz":
+		return FormatTarGz, nil
+	default:
+		return "", fmt.Errorf("unknown archive format %q (valid: zip, tar.gz, dir)", s)
+	}
+}
+
+// Ext returns the conventional file name extension for the format.
+func (f Format) Ext() string {
+	switch f {
+	case FormatTarGz:
+		return ".tar.gz"
+	case FormatDir:
+		return ""
+	default:
+		return ".zip"
+	}
+}
+
+// FormatForPath infers the container format from an output path: .tar.gz and
+// .tgz select tar.gz, a trailing separator or an existing directory selects
+// dir, and anything else is a ZIP.
+func FormatForPath(path string) Format {
+	lower := strings.ToLower(path)
+	switch {
+	case strings.HasSuffix(lower, ".tar.gz"), strings.HasSuffix(lower, ".tgz"):
+		return FormatTarGz
+	case strings.HasSuffix(path, "/"), strings.HasSuffix(path, string(filepath.Separator)):
+		return FormatDir
+	}
+	if info, err := os.Stat(path); err == nil && info.IsDir() {
+		return FormatDir
+	}
+	return FormatZip
+}
+
+// writeContainer writes entries to path in the given format.
+func writeContainer(path string, format Format, entries []entry, modTime time.Time) (err error) {
+	if format == FormatDir {
+		return writeDir(path, entries, modTime)
+	}
+
+	if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+		return fmt.Errorf("creating output directory: %w", err)
+	}
+
+	f, err := os.Create(path)
+	if err != nil {
+		return fmt.Errorf("creating archive file: %w", err)
+	}
+	defer func() {
+		if cerr := f.Close(); cerr != nil && err == nil {
+			err = fmt.Errorf("closing archive file: %w", cerr)
+		}
+	}()
+
+	return writeStream(f, format, entries, modTime)
+}
+
+// writeStream writes entries to w as a ZIP or tar.gz stream, stamped with
+// modTime when it is set.
+func writeStream(w io.Writer, format Format, entries []entry, modTime time.Time) error {
+	switch format {
+	case FormatZip, "":
+		return writeZipTo(w, entries, modTime)
+	case FormatTarGz:
+		return writeTarGz(w, entries, modTime)
+	default:
+		return fmt.Errorf("archive format %s cannot be written to a stream", format)
+	}
+}
+
+// readContainer reads the entries of the archive at path, detecting its format.
+func readContainer(path string, limits Limits) ([]entry, Format, error) {
+	info, err := os.Stat(path)
+	if err != nil {
+		return nil, "", fmt.Errorf("opening archive: %w", err)
+	}
+	if info.IsDir() {
+		entries, err := readDir(path, limits)
+		return entries, FormatDir, err
+	}
+	if max := limits.withDefaults().MaxTotalSize; info.Size() > max {
+		return nil, "", fmt.Errorf("opening archive: %w (%d bytes, limit %d)", ErrArchiveTooLarge, info.Size(), max)
+	}
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, "", fmt.Errorf("opening archive: %w", err)
+	}
+	return readBytes(data, limits)
+}
+
+// readBytes detects whether data is a ZIP or tar.gz archive and reads its entries.
+func readBytes(data []byte, limits Limits) ([]entry, Format, error) {
+	switch {
+	case bytes.HasPrefix(data, []byte("PK\x03\x04")), bytes.HasPrefix(data, []byte("PK\x05\x06")):
+		entries, err := readZipFrom(bytes.NewReader(data), int64(len(data)), limits)
+		return entries, FormatZip, err
+	case bytes.HasPrefix(data, []byte{0x1f, 0x8b}):
+		entries, err := readTarGz(bytes.NewReader(data), limits)
+		return entries, FormatTarGz, err
+	default:
+		return nil, "", fmt.Errorf("opening archive: unrecognized format (expected zip, tar.gz or a directory)")
+	}
+}
+
+func writeTarGz(w io.Writer, entries []entry, modTime time.Time) error {
+	gz := gzip.NewWriter(w)
+	tw := tar.NewWriter(gz)
+	if modTime.IsZero() {
+		modTime = time.Now()
+	}
+	modTime = modTime.UTC().Truncate(time.Second)
+	for _, e := range entries {
+		hdr := &tar.Header{
+			Typeflag: tar.TypeReg,
+			Name:     e.name,
+			Mode:     0o644,
+			Size:     int64(len(e.data)),
+			ModTime:  modTime,
+			Format:   tar.FormatPAX,
+		}
+		if err := tw.WriteHeader(hdr); err != nil {
+			return fmt.Errorf("creating entry %s: %w", e.name, err)
+		}
+		if _, err := tw.Write(e.data); err != nil {
+			return fmt.Errorf("writing entry %s: %w", e.name, err)
+		}
+	}
+	if err := tw.Close(); err != nil {
+		return fmt.Errorf("finalizing archive: %w", err)
+	}
+	if err := gz.Close(); err != nil {
+		return fmt.Errorf("finalizing archive: %w", err)
+	}
+	return nil
+}
+
+func readTarGz(r io.Reader, limits Limits) ([]entry, error) {
+	gz, err := gzip.NewReader(r)
+	if err != nil {
+		return nil, fmt.Errorf("opening archive: %w", err)
+	}
+	defer gz.Close()
+
+	er := newEntryReader(limits)
+	tr := tar.NewReader(gz)
+	for {
+		hdr, err := tr.Next()
+		if err == io.EOF {
+			return er.entries, nil
+		}
+		if err != nil {
+			return nil, fmt.Errorf("reading archive: %w", err)
+		}
+		open := func() (io.ReadCloser, error) { return io.NopCloser(tr), nil }
+		if err := er.add(hdr.Name, hdr.FileInfo().Mode(), hdr.Size, open); err != nil {
+			return nil, err
+		}
+	}
+}
+
+// writeDir writes entries as plain files under dir. The files are written to
+// a temporary sibling first and then moved into place, so an existing archive
+// directory is replaced whole. A non-empty directory that does not hold an
+// archive is left alone. A non-zero modTime is applied to every file.
+func writeDir(dir string, entries []entry, modTime time.Time) error {
+	dir = filepath.Clean(dir)
+	if existing, err := os.ReadDir(dir); err == nil && len(existing) > 0 {
+		if _, err := os.Stat(filepath.Join(dir, manifestEntry)); err != nil {
+			return fmt.Errorf("%s is a non-empty directory without %s; refusing to replace it", dir, manifestEntry)
+		}
+	}
+
+	parent := filepath.Dir(dir)
+	if err := os.MkdirAll(parent, 0o755); err != nil {
+		return fmt.Errorf("creating output directory: %w", err)
+	}
+	tmp, err := os.MkdirTemp(parent, ".cas-archive-*")
+	if err != nil {
+		return fmt.Errorf("creating output directory: %w", err)
+	}
+	defer os.RemoveAll(tmp)
+
+	for _, e := range entries {
+		path := filepath.Join(tmp, filepath.FromSlash(e.name))
+		if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+			return fmt.Errorf("creating entry %s: %w", e.name, err)
+		}
+		if err := os.WriteFile(path, e.data, 0o644); err != nil {
+			return fmt.Errorf("writing entry %s: %w", e.name, err)
+		}
+		if !modTime.IsZero() {
+			if err := os.Chtimes(path, modTime, modTime); err != nil {
+				return fmt.Errorf("writing entry %s: %w", e.name, err)
+			}
+		}
+	}
+	if err := os.Chmod(tmp, 0o755); err != nil {
+		return fmt.Errorf("creating output directory: %w", err)
+	}
+
+	if err := os.RemoveAll(dir); err != nil {
+		return fmt.Errorf("replacing %s: %w", dir, err)
+	}
+	if err := os.Rename(tmp, dir); err != nil {
+		return fmt.Errorf("replacing %s: %w", dir, err)
+	}
+	return nil
+}
+
+func readDir(dir string, limits Limits) ([]entry, error) {
+	er := newEntryReader(limits)
+	err := filepath.WalkDir(dir, func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if path == dir {
+			return nil
+		}
+		rel, err := filepath.Rel(dir, path)
+		if err != nil {
+			return err
+		}
+		info, err := d.Info()
+		if err != nil {
+			return err
+		}
+		open := func() (io.ReadCloser, error) { return os.Open(path) }
+		return er.add(filepath.ToSlash(rel), info.Mode(), info.Size(), open)
+	})
+	if err != nil {
+		return nil, fmt.Errorf("reading archive directory: %w", err)
+	}
+	return er.entries, nil
+}
@@ -0,0 +1,195 @@
+package archive
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"slices"
+	"strings"
+)
+
+// digestPrefix labels the algorithm used for Manifest.Digest.
+const digestPrefix = "sha256:"
+
+// IntegrityError reports checksum problems found while reading a v2 archive.
+type IntegrityError struct {
+	Problems []string
+}
+
+func (e *IntegrityError) Error() string {
+	return fmt.Sprintf("archive integrity check failed: %s", strings.Join(e.Problems, "; "))
+}
+
+// Report describes the result of verifying an archive.
+type Report struct {
+	Version   string
+	Entries   int
+	Digest    string
+	SignedBy  string // key ID of a signature that matched the manifest
+	Encrypted bool
+	Problems  []string
+}
+
+// OK reports whether the archive carries checksums and all of them matched.
+func (r *Report) OK() bool {
+	return r.Version != FormatVersionV1 && len(r.Problems) == 0
+}
+
+// Verify checks every entry of the archive at path against the checksums in
+// its manifest. Problems are collected in the report rather than returned as
+// errors so callers can show all of them at once.
+func Verify(path string) (*Report, error) {
+	entries, _, err := readContainer(path, Limits{})
+	if err != nil {
+		return nil, err
+	}
+	m, err := parseManifest(entries)
+	if err != nil {
+		return nil, err
+	}
+
+	r := &Report{Version: m.Version, Digest: m.Digest, Encrypted: m.Encrypted()}
+	for _, e := range entries {
+		if !isMetadataEntry(e.name) {
+			r.Entries++
+		}
+	}
+	if m.Version == FormatVersionV1 {
+		return r, nil
+	}
+	r.Problems = verify(m, entries)
+
+	a := &Archive{}
+	if err := a.attachSignature(entries); err != nil {
+		r.Problems = append(r.Problems, err.Error())
+	} else if a.Signature != nil {
+		if err := a.checkSignature(); err != nil {
+			r.Problems = append(r.Problems, err.Error())
+		} else {
+			r.SignedBy = a.Signature.KeyID
+		}
+	}
+	return r, nil
+}
+
+// Upgrade rewrites the archive at in as a current-format archive at out,
+// keeping its container format. Rewriting invalidates a signature and would
+// store encrypted content in the clear, so a signed archive is refused unless
+// wopts re-signs it, and an encrypted one unless ropts can decrypt it and
+// wopts re-encrypts it.
+func Upgrade(in, out string, ropts ReadOptions, wopts WriteOptions) (*Archive, error) {
+	entries, _, err := readContainer(in, ropts.Limits)
+	if err != nil {
+		return nil, err
+	}
+	m, err := parseManifest(entries)
+	if err != nil {
+		return nil, err
+	}
+	if m.Encrypted() && len(wopts.Passphrase) == 0 && len(wopts.Recipients) == 0 {
+		return nil, fmt.Errorf("%s is encrypted and upgrading would write it in the clear; pass a passphrase or recipients to re-encrypt it", in)
+	}
+
+	a, err := ReadWith(in, ropts)
+	if err != nil {
+		return nil, err
+	}
+	if a.Signature != nil && wopts.SigningKey == nil {
+		return nil, fmt.Errorf("%s is signed by %s and upgrading would drop the signature; pass a signing key to re-sign it", in, a.Signature.KeyID)
+	}
+	a.Manifest.Version = FormatVersion
+	wopts.Format = a.Format
+	if err := WriteWith(out, a, wopts); err != nil {
+		return nil, err
+	}
+	return a, nil
+}
+
+// checksums returns the per-entry SHA-256 map and the archive digest for
+// content entries.
+func checksums(entries []entry) (map[string]string, string) {
+	files := make(map[string]string, len(entries))
+	for _, e := range entries {
+		files[e.name] = sha256Hex(e.data)
+	}
+	return files, digestOf(files)
+}
+
+// digestOf hashes the sorted "<sha256>  <name>" lines of files, so the digest
+// changes when any entry is added, removed, renamed, or modified.
+func digestOf(files map[string]string) string {
+	names := make([]string, 0, len(files))
+	for name := range files {
+		names = append(names, name)
+	}
+	slices.Sort(names)
+
+	h := sha256.New()
+	for _, name := range names {
+		fmt.Fprintf(h, "%s  %s\n", files[name], name)
+	}
+	return digestPrefix + hex.EncodeToString(h.Sum(nil))
+}
+
+// verify compares entries against the checksums recorded in m.
+func verify(m *Manifest, entries []entry) []string {
+	var problems []string
+	if len(m.Files) == 0 && m.Digest == "" {
+		return []string{"manifest has no checksums"}
+	}
+
+	actual := make(map[string]string, len(entries))
+	for _, e := range entries {
+		if isMetadataEntry(e.name) {
+			continue
+		}
+		if _, dup := actual[e.name]; dup {
+			problems = append(problems, fmt.Sprintf("%s: duplicate entry", e.name))
+			continue
+		}
+		got := sha256Hex(e.data)
+		actual[e.name] = got
+		want, ok := m.Files[e.name]
+		if !ok {
+			problems = append(problems, fmt.Sprintf("%s: not listed in manifest", e.name))
+			continue
+		}
+		if got != want {
+			problems = append(problems, fmt.Sprintf("%s: checksum mismatch (manifest %s, actual %s)", e.name, short(want), short(got)))
+		}
+	}
+
+	names := make([]string, 0, len(m.Files))
+	for name := range m.Files {
+		names = append(names, name)
+	}
+	slices.Sort(names)
+	for _, name := range names {
+		if _, ok := actual[name]; !ok {
+			problems = append(problems, fmt.Sprintf("%s: listed in manifest but missing", name))
+		}
+	}
+
+	if got := digestOf(actual); got != m.Digest {
+		problems = append(problems, fmt.Sprintf("archive digest mismatch (manifest %s, actual %s)", m.Digest, got))
+	}
+	return problems
+}
+
+// isMetadataEntry reports whether name describes the archive rather than
+// carrying content, and is therefore excluded from checksums.
+func isMetadataEntry(name string) bool {
+	return name == manifestEntry || name == signatureEntry
+}
+
+func sha256Hex(data []byte) string {
+	sum := sha256.Sum256(data)
+	return hex.EncodeToString(sum[:])
+}
+
+func short(sum string) string {
+	if len(sum) > 12 {
+		return sum[:12]
+	}
+	return sum
+}
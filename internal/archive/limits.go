@@ -0,0 +1,183 @@
+package archive
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	pathpkg "path"
+	"strings"
+	"unicode"
+)
+
+// Limits bounds the resources spent reading an archive. Zero fields fall back
+// to DefaultLimits.
+type Limits struct {
+	MaxEntries   int   // number of files in the container
+	MaxEntrySize int64 // uncompressed bytes in any one file
+	MaxTotalSize int64 // uncompressed bytes across all files, and bytes of the archive itself
+}
+
+// DefaultLimits are generous for instructions and skills while keeping a
+// hostile archive from exhausting memory.
+var DefaultLimits = Limits{
+	MaxEntries:   10_000,
+	MaxEntrySize: 16 << 20,
+	MaxTotalSize: 256 << 20,
+}
+
+func (l Limits) withDefaults() Limits {
+	if l.MaxEntries <= 0 {
+		l.MaxEntries = DefaultLimits.MaxEntries
+	}
+	if l.MaxEntrySize <= 0 {
+		l.MaxEntrySize = DefaultLimits.MaxEntrySize
+	}
+	if l.MaxTotalSize <= 0 {
+		l.MaxTotalSize = DefaultLimits.MaxTotalSize
+	}
+	return l
+}
+
+var (
+	// ErrTooManyEntries is returned when an archive holds more files than Limits.MaxEntries.
+	ErrTooManyEntries = errors.New("too many entries")
+	// ErrEntryTooLarge is returned when a file exceeds Limits.MaxEntrySize.
+	ErrEntryTooLarge = errors.New("entry too large")
+	// ErrArchiveTooLarge is returned when the archive exceeds Limits.MaxTotalSize.
+	ErrArchiveTooLarge = errors.New("archive too large")
+	// ErrDuplicateEntry is returned when two entries share a name.
+	ErrDuplicateEntry = errors.New("duplicate entry")
+	// ErrUnsupportedEntry is returned for symlinks, devices and other non-regular files.
+	ErrUnsupportedEntry = errors.New("unsupported entry type")
+	// ErrUnsafeName is returned for absolute entry names or names that escape the archive root.
+	ErrUnsafeName = errors.New("unsafe entry name")
+	// ErrSkillNameCollision is returned when two skill names differ only by case or
+	// would otherwise map to the same directory on some file systems.
+	ErrSkillNameCollision = errors.New("skill name collision")
+)
+
+// EntryError reports a problem with one archive entry. Err is one of the
+// sentinel errors above, possibly wrapped with detail.
+type EntryError struct {
+	Entry string
+	Err   error
+}
+
+func (e *EntryError) Error() string {
+	return fmt.Sprintf("archive entry %q: %v", e.Entry, e.Err)
+}
+
+func (e *EntryError) Unwrap() error { return e.Err }
+
+// entryReader collects container entries while enforcing Limits.
+type entryReader struct {
+	limits  Limits
+	entries []entry
+	seen    map[string]bool
+	total   int64
+}
+
+func newEntryReader(limits Limits) *entryReader {
+	return &entryReader{limits: limits.withDefaults(), seen: make(map[string]bool)}
+}
+
+// add validates one container entry and reads it. Directories are skipped.
+// size is the declared uncompressed size, which is checked before reading and
+// enforced again while reading since headers can lie.
+func (er *entryReader) add(name string, mode fs.FileMode, size int64, open func() (io.ReadCloser, error)) error {
+	if mode.IsDir() {
+		return nil
+	}
+	if !mode.IsRegular() {
+		return &EntryError{Entry: name, Err: fmt.Errorf("%w %s", ErrUnsupportedEntry, mode.Type())}
+	}
+	if err := checkEntryName(name); err != nil {
+		return &EntryError{Entry: name, Err: err}
+	}
+	key := pathpkg.Clean(name)
+	if er.seen[key] {
+		return &EntryError{Entry: name, Err: ErrDuplicateEntry}
+	}
+	if len(er.entries) >= er.limits.MaxEntries {
+		return &EntryError{Entry: name, Err: fmt.Errorf("%w (limit %d)", ErrTooManyEntries, er.limits.MaxEntries)}
+	}
+
+	// The encrypted payload holds every content file, so it is bounded by the total instead.
+	max := er.limits.MaxEntrySize
+	if name == payloadEntry {
+		max = er.limits.MaxTotalSize
+	}
+	if size > max {
+		return &EntryError{Entry: name, Err: fmt.Errorf("%w (%d bytes, limit %d)", ErrEntryTooLarge, size, max)}
+	}
+
+	rc, err := open()
+	if err != nil {
+		return &EntryError{Entry: name, Err: err}
+	}
+	defer rc.Close()
+	data, err := io.ReadAll(io.LimitReader(rc, max+1))
+	if err != nil {
+		return &EntryError{Entry: name, Err: err}
+	}
+	if int64(len(data)) > max {
+		return &EntryError{Entry: name, Err: fmt.Errorf("%w (over %d bytes)", ErrEntryTooLarge, max)}
+	}
+
+	er.total += int64(len(data))
+	if er.total > er.limits.MaxTotalSize {
+		return &EntryError{Entry: name, Err: fmt.Errorf("%w (over %d bytes uncompressed)", ErrArchiveTooLarge, er.limits.MaxTotalSize)}
+	}
+
+	er.seen[key] = true
+	er.entries = append(er.entries, entry{name: name, data: data})
+	return nil
+}
+
+// checkEntryName rejects names that are empty, absolute, use backslashes or
+// NUL, or climb out of the archive root. Other odd names are left to decode.
+func checkEntryName(name string) error {
+	switch {
+	case name == "":
+		return fmt.Errorf("%w: empty name", ErrUnsafeName)
+	case strings.HasPrefix(name, "/"):
+		return fmt.Errorf("%w: absolute path", ErrUnsafeName)
+	case strings.ContainsAny(name, "\\\x00"):
+		return fmt.Errorf("%w: backslash or NUL", ErrUnsafeName)
+	}
+	if clean := pathpkg.Clean(name); clean == ".." || strings.HasPrefix(clean, "../") {
+		return fmt.Errorf("%w: escapes the archive root", ErrUnsafeName)
+	}
+	return nil
+}
+
+// checkSkillNames rejects skill lists where two names would land in the same
+// directory on a case-insensitive or normalizing file system, or look alike.
+func checkSkillNames(names []string) error {
+	seen := make(map[string]string, len(names))
+	for _, name := range names {
+		key := nameKey(name)
+		if prev, ok := seen[key]; ok {
+			return fmt.Errorf("%w: %q and %q", ErrSkillNameCollision, prev, name)
+		}
+		seen[key] = name
+	}
+	return nil
+}
+
+// foldName maps every rune to the smallest rune in its Unicode case-folding
+// orbit, so names equal under simple case folding share a key.
+func foldName(name string) string {
+	var b strings.Builder
+	for _, r := range name {
+		min := r
+		for f := unicode.SimpleFold(r); f != r; f = unicode.SimpleFold(f) {
+			if f < min {
+				min = f
+			}
+		}
+		b.WriteRune(min)
+	}
+	return b.String()
+}
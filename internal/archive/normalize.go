@@ -0,0 +1,93 @@
+package archive
+
+import "slices"
+
+// Hangul syllable composition constants from the Unicode standard, section 3.12.
+const (
+	hangulBase  = 0xAC00
+	hangulCount = 11172
+	jamoLBase   = 0x1100
+	jamoVBase   = 0x1161
+	jamoTBase   = 0x11A7
+	jamoVCount  = 21
+	jamoTCount  = 28
+)
+
+// nameKey returns the key skill names collide under, an approximation of
+// Unicode's NFKC_Casefold: names that differ only in case, in precomposed
+// versus decomposed letters or Hangul syllables, in compatibility forms such
+// as fullwidth letters or ligatures, or in default-ignorable characters share
+// a key. Comparing decomposed forms finds the same collisions as comparing
+// composed ones, so the key is never recomposed.
+func nameKey(name string) string {
+	// Folding can pick a precomposed letter such as Å for the Ångström sign,
+	// so decompose again afterwards.
+	return decompose(foldName(decompose(name)))
+}
+
+// decompose applies the compatibility decomposition in the tables, drops
+// default-ignorable characters and puts combining marks in canonical order.
+func decompose(s string) string {
+	var out []rune
+	for _, r := range s {
+		switch {
+		case isIgnorable(r):
+		case r >= hangulBase && r < hangulBase+hangulCount:
+			i := r - hangulBase
+			out = append(out, jamoLBase+i/(jamoVCount*jamoTCount), jamoVBase+i%(jamoVCount*jamoTCount)/jamoTCount)
+			if t := i % jamoTCount; t != 0 {
+				out = append(out, jamoTBase+t)
+			}
+		case r >= 0xFF01 && r <= 0xFF5E:
+			out = append(out, r-0xFEE0)
+		default:
+			if d, ok := compatibilityDecomposition[r]; ok {
+				out = append(out, []rune(d)...)
+			} else if d, ok := canonicalDecomposition[r]; ok {
+				out = append(out, []rune(d)...)
+			} else {
+				out = append(out, r)
+			}
+		}
+	}
+
+	// Sort each run of marks by combining class; the sort is stable, so marks
+	// of the same class keep their order.
+	for i := 0; i < len(out); {
+		j := i
+		for j < len(out) && combiningClass[out[j]] != 0 {
+			j++
+		}
+		if j > i+1 {
+			slices.SortStableFunc(out[i:j], func(a, b rune) int {
+				return int(combiningClass[a]) - int(combiningClass[b])
+			})
+		}
+		i = j + 1
+	}
+	return string(out)
+}
+
+// isIgnorable reports whether r is a default-ignorable character that
+// renders as nothing, such as a zero-width space or a variation selector.
+func isIgnorable(r rune) bool {
+	switch {
+	case r == 0x00AD, r == 0x034F, r == 0x061C, r == 0x115F, r == 0x1160, r == 0x3164, r == 0xFEFF, r == 0xFFA0:
+		return true
+	case r >= 0x17B4 && r <= 0x17B5, r >= 0x180B && r <= 0x180F:
+		return true
+	case r >= 0x200B && r <= 0x200F, r >= 0x202A && r <= 0x202E, r >= 0x2060 && r <= 0x206F:
+		return true
+	case r >= 0xFE00 && r <= 0xFE0F, r >= 0xE0000 && r <= 0xE0FFF:
+		return true
+	}
+	return false
+}
+
+// isBidiControl reports whether r is one of the explicit bidirectional
+// formatting characters, which can make a name display differently from the
+// directory it names.
+func isBidiControl(r rune) bool {
+	return r == 0x061C || r == 0x200E || r == 0x200F ||
+		(r >= 0x202A && r <= 0x202E) || (r >= 0x2066 && r <= 0x2069)
+}
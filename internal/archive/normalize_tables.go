@@ -0,0 +1,166 @@
+package archive
+
+// Tables for nameKey, taken from Unicode 14 UnicodeData.txt. They cover the
+// precomposed Latin letters and the compatibility forms most likely to turn up
+// in skill names; the standard library has no normalization data.
+
+// canonicalDecomposition maps precomposed Latin letters to their full
+// canonical decomposition.
+var canonicalDecomposition = map[rune]string{
+	0x00C0: "A\u0300", 0x00C1: "A\u0301", 0x00C2: "A\u0302", 0x00C3: "A\u0303",
+	0x00C4: "A\u0308", 0x00C5: "A\u030a", 0x00C7: "C\u0327", 0x00C8: "E\u0300",
+	0x00C9: "E\u0301", 0x00CA: "E\u0302", 0x00CB: "E\u0308", 0x00CC: "I\u0300",
+	0x00CD: "I\u0301", 0x00CE: "I\u0302", 0x00CF: "I\u0308", 0x00D1: "N\u0303",
+	0x00D2: "O\u0300", 0x00D3: "O\u0301", 0x00D4: "O\u0302", 0x00D5: "O\u0303",
+	0x00D6: "O\u0308", 0x00D9: "U\u0300", 0x00DA: "U\u0301", 0x00DB: "U\u0302",
+	0x00DC: "U\u0308", 0x00DD: "Y\u0301", 0x00E0: "a\u0300", 0x00E1: "a\u0301",
+	0x00E2: "a\u0302", 0x00E3: "a\u0303", 0x00E4: "a\u0308", 0x00E5: "a\u030a",
+	0x00E7: "c\u0327", 0x00E8: "e\u0300", 0x00E9: "e\u0301", 0x00EA: "e\u0302",
+	0x00EB: "e\u0308", 0x00EC: "i\u0300", 0x00ED: "i\u0301", 0x00EE: "i\u0302",
+	0x00EF: "i\u0308", 0x00F1: "n\u0303", 0x00F2: "o\u0300", 0x00F3: "o\u0301",
+	0x00F4: "o\u0302", 0x00F5: "o\u0303", 0x00F6: "o\u0308", 0x00F9: "u\u0300",
+	0x00FA: "u\u0301", 0x00FB: "u\u0302", 0x00FC: "u\u0308", 0x00FD: "y\u0301",
+	0x00FF: "y\u0308", 0x0100: "A\u0304", 0x0101: "a\u0304", 0x0102: "A\u0306",
+	0x0103: "a\u0306", 0x0104: "A\u0328", 0x0105: "a\u0328", 0x0106: "C\u0301",
+	0x0107: "c\u0301", 0x0108: "C\u0302", 0x0109: "c\u0302", 0x010A: "C\u0307",
+	0x010B: "c\u0307", 0x010C: "C\u030c", 0x010D: "c\u030c", 0x010E: "D\u030c",
+	0x010F: "d\u030c", 0x0112: "E\u0304", 0x0113: "e\u0304", 0x0114: "E\u0306",
+	0x0115: "e\u0306", 0x0116: "E\u0307", 0x0117: "e\u0307", 0x0118: "E\u0328",
+	0x0119: "e\u0328", 0x011A: "E\u030c", 0x011B: "e\u030c", 0x011C: "G\u0302",
+	0x011D: "g\u0302", 0x011E: "G\u0306", 0x011F: "g\u0306", 0x0120: "G\u0307",
+	0x0121: "g\u0307", 0x0122: "G\u0327", 0x0123: "g\u0327", 0x0124: "H\u0302",
+	0x0125: "h\u0302", 0x0128: "I\u0303", 0x0129: "i\u0303", 0x012A: "I\u0304",
+	0x012B: "i\u0304", 0x012C: "I\u0306", 0x012D: "i\u0306", 0x012E: "I\u0328",
+	0x012F: "i\u0328", 0x0130: "I\u0307", 0x0134: "J\u0302", 0x0135: "j\u0302",
+	0x0136: "K\u0327", 0x0137: "k\u0327", 0x0139: "L\u0301", 0x013A: "l\u0301",
+	0x013B: "L\u0327", 0x013C: "l\u0327", 0x013D: "L\u030c", 0x013E: "l\u030c",
+	0x0143: "N\u0301", 0x0144: "n\u0301", 0x0145: "N\u0327", 0x0146: "n\u0327",
+	0x0147: "N\u030c", 0x0148: "n\u030c", 0x014C: "O\u0304", 0x014D: "o\u0304",
+	0x014E: "O\u0306", 0x014F: "o\u0306", 0x0150: "O\u030b", 0x0151: "o\u030b",
+	0x0154: "R\u0301", 0x0155: "r\u0301", 0x0156: "R\u0327", 0x0157: "r\u0327",
+	0x0158: "R\u030c", 0x0159: "r\u030c", 0x015A: "S\u0301", 0x015B: "s\u0301",
+	0x015C: "S\u0302", 0x015D: "s\u0302", 0x015E: "S\u0327", 0x015F: "s\u0327",
+	0x0160: "S\u030c", 0x0161: "s\u030c", 0x0162: "T\u0327", 0x0163: "t\u0327",
+	0x0164: "T\u030c", 0x0165: "t\u030c", 0x0168: "U\u0303", 0x0169: "u\u0303",
+	0x016A: "U\u0304", 0x016B: "u\u0304", 0x016C: "U\u0306", 0x016D: "u\u0306",
+	0x016E: "U\u030a", 0x016F: "u\u030a", 0x0170: "U\u030b", 0x0171: "u\u030b",
+	0x0172: "U\u0328", 0x0173: "u\u0328", 0x0174: "W\u0302", 0x0175: "w\u0302",
+	0x0176: "Y\u0302", 0x0177: "y\u0302", 0x0178: "Y\u0308", 0x0179: "Z\u0301",
+	0x017A: "z\u0301", 0x017B: "Z\u0307", 0x017C: "z\u0307", 0x017D: "Z\u030c",
+	0x017E: "z\u030c", 0x01A0: "O\u031b", 0x01A1: "o\u031b", 0x01AF: "U\u031b",
+	0x01B0: "u\u031b", 0x01CD: "A\u030c", 0x01CE: "a\u030c", 0x01CF: "I\u030c",
+	0x01D0: "i\u030c", 0x01D1: "O\u030c", 0x01D2: "o\u030c", 0x01D3: "U\u030c",
+	0x01D4: "u\u030c", 0x01D5: "U\u0308\u0304", 0x01D6: "u\u0308\u0304", 0x01D7: "U\u0308\u0301",
+	0x01D8: "u\u0308\u0301", 0x01D9: "U\u0308\u030c", 0x01DA: "u\u0308\u030c", 0x01DB: "U\u0308\u0300",
+	0x01DC: "u\u0308\u0300", 0x01DE: "A\u0308\u0304", 0x01DF: "a\u0308\u0304", 0x01E0: "A\u0307\u0304",
+	0x01E1: "a\u0307\u0304", 0x01E2: "Æ\u0304", 0x01E3: "æ\u0304", 0x01E6: "G\u030c",
+	0x01E7: "g\u030c", 0x01E8: "K\u030c", 0x01E9: "k\u030c", 0x01EA: "O\u0328",
+	0x01EB: "o\u0328", 0x01EC: "O\u0328\u0304", 0x01ED: "o\u0328\u0304", 0x01EE: "Ʒ\u030c",
+	0x01EF: "ʒ\u030c", 0x01F0: "j\u030c", 0x01F4: "G\u0301", 0x01F5: "g\u0301",
+	0x01F8: "N\u0300", 0x01F9: "n\u0300", 0x01FA: "A\u030a\u0301", 0x01FB: "a\u030a\u0301",
+	0x01FC: "Æ\u0301", 0x01FD: "æ\u0301", 0x01FE: "Ø\u0301", 0x01FF: "ø\u0301",
+	0x0200: "A\u030f", 0x0201: "a\u030f", 0x0202: "A\u0311", 0x0203: "a\u0311",
+	0x0204: "E\u030f", 0x0205: "e\u030f", 0x0206: "E\u0311", 0x0207: "e\u0311",
+	0x0208: "I\u030f", 0x0209: "i\u030f", 0x020A: "I\u0311", 0x020B: "i\u0311",
+	0x020C: "O\u030f", 0x020D: "o\u030f", 0x020E: "O\u0311", 0x020F: "o\u0311",
+	0x0210: "R\u030f", 0x0211: "r\u030f", 0x0212: "R\u0311", 0x0213: "r\u0311",
+	0x0214: "U\u030f", 0x0215: "u\u030f", 0x0216: "U\u0311", 0x0217: "u\u0311",
+	0x0218: "S\u0326", 0x0219: "s\u0326", 0x021A: "T\u0326", 0x021B: "t\u0326",
+	0x021E: "H\u030c", 0x021F: "h\u030c", 0x0226: "A\u0307", 0x0227: "a\u0307",
+	0x0228: "E\u0327", 0x0229: "e\u0327", 0x022A: "O\u0308\u0304", 0x022B: "o\u0308\u0304",
+	0x022C: "O\u0303\u0304", 0x022D: "o\u0303\u0304", 0x022E: "O\u0307", 0x022F: "o\u0307",
+	0x0230: "O\u0307\u0304", 0x0231: "o\u0307\u0304", 0x0232: "Y\u0304", 0x0233: "y\u0304",
+	0x1E00: "A\u0325", 0x1E01: "a\u0325", 0x1E02: "B\u0307", 0x1E03: "b\u0307",
+	0x1E04: "B\u0323", 0x1E05: "b\u0323", 0x1E06: "B\u0331", 0x1E07: "b\u0331",
+	0x1E08: "C\u0327\u0301", 0x1E09: "c\u0327\u0301", 0x1E0A: "D\u0307", 0x1E0B: "d\u0307",
+	0x1E0C: "D\u0323", 0x1E0D: "d\u0323", 0x1E0E: "D\u0331", 0x1E0F: "d\u0331",
+	0x1E10: "D\u0327", 0x1E11: "d\u0327", 0x1E12: "D\u032d", 0x1E13: "d\u032d",
+	0x1E14: "E\u0304\u0300", 0x1E15: "e\u0304\u0300", 0x1E16: "E\u0304\u0301", 0x1E17: "e\u0304\u0301",
+	0x1E18: "E\u032d", 0x1E19: "e\u032d", 0x1E1A: "E\u0330", 0x1E1B: "e\u0330",
+	0x1E1C: "E\u0327\u0306", 0x1E1D: "e\u0327\u0306", 0x1E1E: "F\u0307", 0x1E1F: "f\u0307",
+	0x1E20: "G\u0304", 0x1E21: "g\u0304", 0x1E22: "H\u0307", 0x1E23: "h\u0307",
+	0x1E24: "H\u0323", 0x1E25: "h\u0323", 0x1E26: "H\u0308", 0x1E27: "h\u0308",
+	0x1E28: "H\u0327", 0x1E29: "h\u0327", 0x1E2A: "H\u032e", 0x1E2B: "h\u032e",
+	0x1E2C: "I\u0330", 0x1E2D: "i\u0330", 0x1E2E: "I\u0308\u0301", 0x1E2F: "i\u0308\u0301",
+	0x1E30: "K\u0301", 0x1E31: "k\u0301", 0x1E32: "K\u0323", 0x1E33: "k\u0323",
+	0x1E34: "K\u0331", 0x1E35: "k\u0331", 0x1E36: "L\u0323", 0x1E37: "l\u0323",
+	0x1E38: "L\u0323\u0304", 0x1E39: "l\u0323\u0304", 0x1E3A: "L\u0331", 0x1E3B: "l\u0331",
+	0x1E3C: "L\u032d", 0x1E3D: "l\u032d", 0x1E3E: "M\u0301", 0x1E3F: "m\u0301",
+	0x1E40: "M\u0307", 0x1E41: "m\u0307", 0x1E42: "M\u0323", 0x1E43: "m\u0323",
+	0x1E44: "N\u0307", 0x1E45: "n\u0307", 0x1E46: "N\u0323", 0x1E47: "n\u0323",
+	0x1E48: "N\u0331", 0x1E49: "n\u0331", 0x1E4A: "N\u032d", 0x1E4B: "n\u032d",
+	0x1E4C: "O\u0303\u0301", 0x1E4D: "o\u0303\u0301", 0x1E4E: "O\u0303\u0308", 0x1E4F: "o\u0303\u0308",
+	0x1E50: "O\u0304\u0300", 0x1E51: "o\u0304\u0300", 0x1E52: "O\u0304\u0301", 0x1E53: "o\u0304\u0301",
+	0x1E54: "P\u0301", 0x1E55: "p\u0301", 0x1E56: "P\u0307", 0x1E57: "p\u0307",
+	0x1E58: "R\u0307", 0x1E59: "r\u0307", 0x1E5A: "R\u0323", 0x1E5B: "r\u0323",
+	0x1E5C: "R\u0323\u0304", 0x1E5D: "r\u0323\u0304", 0x1E5E: "R\u0331", 0x1E5F: "r\u0331",
+	0x1E60: "S\u0307", 0x1E61: "s\u0307", 0x1E62: "S\u0323", 0x1E63: "s\u0323",
+	0x1E64: "S\u0301\u0307", 0x1E65: "s\u0301\u0307", 0x1E66: "S\u030c\u0307", 0x1E67: "s\u030c\u0307",
+	0x1E68: "S\u0323\u0307", 0x1E69: "s\u0323\u0307", 0x1E6A: "T\u0307", 0x1E6B: "t\u0307",
+	0x1E6C: "T\u0323", 0x1E6D: "t\u0323", 0x1E6E: "T\u0331", 0x1E6F: "t\u0331",
+	0x1E70: "T\u032d", 0x1E71: "t\u032d", 0x1E72: "U\u0324", 0x1E73: "u\u0324",
+	0x1E74: "U\u0330", 0x1E75: "u\u0330", 0x1E76: "U\u032d", 0x1E77: "u\u032d",
+	0x1E78: "U\u0303\u0301", 0x1E79: "u\u0303\u0301", 0x1E7A: "U\u0304\u0308", 0x1E7B: "u\u0304\u0308",
+	0x1E7C: "V\u0303", 0x1E7D: "v\u0303", 0x1E7E: "V\u0323", 0x1E7F: "v\u0323",
+	0x1E80: "W\u0300", 0x1E81: "w\u0300", 0x1E82: "W\u0301", 0x1E83: "w\u0301",
+	0x1E84: "W\u0308", 0x1E85: "w\u0308", 0x1E86: "W\u0307", 0x1E87: "w\u0307",
+	0x1E88: "W\u0323", 0x1E89: "w\u0323", 0x1E8A: "X\u0307", 0x1E8B: "x\u0307",
+	0x1E8C: "X\u0308", 0x1E8D: "x\u0308", 0x1E8E: "Y\u0307", 0x1E8F: "y\u0307",
+	0x1E90: "Z\u0302", 0x1E91: "z\u0302", 0x1E92: "Z\u0323", 0x1E93: "z\u0323",
+	0x1E94: "Z\u0331", 0x1E95: "z\u0331", 0x1E96: "h\u0331", 0x1E97: "t\u0308",
+	0x1E98: "w\u030a", 0x1E99: "y\u030a", 0x1E9B: "ſ\u0307", 0x1EA0: "A\u0323",
+	0x1EA1: "a\u0323", 0x1EA2: "A\u0309", 0x1EA3: "a\u0309", 0x1EA4: "A\u0302\u0301",
+	0x1EA5: "a\u0302\u0301", 0x1EA6: "A\u0302\u0300", 0x1EA7: "a\u0302\u0300", 0x1EA8: "A\u0302\u0309",
+	0x1EA9: "a\u0302\u0309", 0x1EAA: "A\u0302\u0303", 0x1EAB: "a\u0302\u0303", 0x1EAC: "A\u0323\u0302",
+	0x1EAD: "a\u0323\u0302", 0x1EAE: "A\u0306\u0301", 0x1EAF: "a\u0306\u0301", 0x1EB0: "A\u0306\u0300",
+	0x1EB1: "a\u0306\u0300", 0x1EB2: "A\u0306\u0309", 0x1EB3: "a\u0306\u0309", 0x1EB4: "A\u0306\u0303",
+	0x1EB5: "a\u0306\u0303", 0x1EB6: "A\u0323\u0306", 0x1EB7: "a\u0323\u0306", 0x1EB8: "E\u0323",
+	0x1EB9: "e\u0323", 0x1EBA: "E\u0309", 0x1EBB: "e\u0309", 0x1EBC: "E\u0303",
+	0x1EBD: "e\u0303", 0x1EBE: "E\u0302\u0301", 0x1EBF: "e\u0302\u0301", 0x1EC0: "E\u0302\u0300",
+	0x1EC1: "e\u0302\u0300", 0x1EC2: "E\u0302\u0309", 0x1EC3: "e\u0302\u0309", 0x1EC4: "E\u0302\u0303",
+	0x1EC5: "e\u0302\u0303", 0x1EC6: "E\u0323\u0302", 0x1EC7: "e\u0323\u0302", 0x1EC8: "I\u0309",
+	0x1EC9: "i\u0309", 0x1ECA: "I\u0323", 0x1ECB: "i\u0323", 0x1ECC: "O\u0323",
+	0x1ECD: "o\u0323", 0x1ECE: "O\u0309", 0x1ECF: "o\u0309", 0x1ED0: "O\u0302\u0301",
+	0x1ED1: "o\u0302\u0301", 0x1ED2: "O\u0302\u0300", 0x1ED3: "o\u0302\u0300", 0x1ED4: "O\u0302\u0309",
+	0x1ED5: "o\u0302\u0309", 0x1ED6: "O\u0302\u0303", 0x1ED7: "o\u0302\u0303", 0x1ED8: "O\u0323\u0302",
+	0x1ED9: "o\u0323\u0302", 0x1EDA: "O\u031b\u0301", 0x1EDB: "o\u031b\u0301", 0x1EDC: "O\u031b\u0300",
+	0x1EDD: "o\u031b\u0300", 0x1EDE: "O\u031b\u0309", 0x1EDF: "o\u031b\u0309", 0x1EE0: "O\u031b\u0303",
+	0x1EE1: "o\u031b\u0303", 0x1EE2: "O\u031b\u0323", 0x1EE3: "o\u031b\u0323", 0x1EE4: "U\u0323",
+	0x1EE5: "u\u0323", 0x1EE6: "U\u0309", 0x1EE7: "u\u0309", 0x1EE8: "U\u031b\u0301",
+	0x1EE9: "u\u031b\u0301", 0x1EEA: "U\u031b\u0300", 0x1EEB: "u\u031b\u0300", 0x1EEC: "U\u031b\u0309",
+	0x1EED: "u\u031b\u0309", 0x1EEE: "U\u031b\u0303", 0x1EEF: "u\u031b\u0303", 0x1EF0: "U\u031b\u0323",
+	0x1EF1: "u\u031b\u0323", 0x1EF2: "Y\u0300", 0x1EF3: "y\u0300", 0x1EF4: "Y\u0323",
+	0x1EF5: "y\u0323", 0x1EF6: "Y\u0309", 0x1EF7: "y\u0309", 0x1EF8: "Y\u0303",
+	0x1EF9: "y\u0303",
+}
+
+// compatibilityDecomposition maps ligatures, superscripts, subscripts and
+// Roman numerals to their compatibility decomposition. Fullwidth ASCII is
+// handled separately.
+var compatibilityDecomposition = map[rune]string{
+	0x00AA: "a", 0x00B2: "2", 0x00B3: "3", 0x00B5: "\u03bc", 0x00B9: "1", 0x00BA: "o",
+	0x017F: "s", 0xFB00: "ff", 0xFB01: "fi", 0xFB02: "fl", 0xFB03: "ffi", 0xFB04: "ffl",
+	0xFB05: "st", 0xFB06: "st", 0x2070: "0", 0x2071: "i", 0x2074: "4", 0x2075: "5",
+	0x2076: "6", 0x2077: "7", 0x2078: "8", 0x2079: "9", 0x207A: "+", 0x207B: "\u2212",
+	0x207C: "=", 0x207D: "(", 0x207E: ")", 0x207F: "n", 0x2080: "0", 0x2081: "1",
+	0x2082: "2", 0x2083: "3", 0x2084: "4", 0x2085: "5", 0x2086: "6", 0x2087: "7",
+	0x2088: "8", 0x2089: "9", 0x208A: "+", 0x208B: "\u2212", 0x208C: "=", 0x208D: "(",
+	0x208E: ")", 0x2090: "a", 0x2091: "e", 0x2092: "o", 0x2093: "x", 0x2094: "ə",
+	0x2095: "h", 0x2096: "k", 0x2097: "l", 0x2098: "m", 0x2099: "n", 0x209A: "p",
+	0x209B: "s", 0x209C: "t", 0x2160: "I", 0x2161: "II", 0x2162: "III", 0x2163: "IV",
+	0x2164: "V", 0x2165: "VI", 0x2166: "VII", 0x2167: "VIII", 0x2168: "IX", 0x2169: "X",
+	0x216A: "XI", 0x216B: "XII", 0x216C: "L", 0x216D: "C", 0x216E: "D", 0x216F: "M",
+	0x2170: "i", 0x2171: "ii", 0x2172: "iii", 0x2173: "iv", 0x2174: "v", 0x2175: "vi",
+	0x2176: "vii", 0x2177: "viii", 0x2178: "ix", 0x2179: "x", 0x217A: "xi", 0x217B: "xii",
+	0x217C: "l", 0x217D: "c", 0x217E: "d", 0x217F: "m",
+}
+
+// combiningClass holds the canonical combining class of the marks used in
+// canonicalDecomposition.
+var combiningClass = map[rune]uint8{
+	0x0300: 230, 0x0301: 230, 0x0302: 230, 0x0303: 230, 0x0304: 230, 0x0306: 230,
+	0x0307: 230, 0x0308: 230, 0x0309: 230, 0x030A: 230, 0x030B: 230, 0x030C: 230,
+	0x030F: 230, 0x0311: 230, 0x031B: 216, 0x0323: 220, 0x0324: 220, 0x0325: 220,
+	0x0326: 220, 0x0327: 202, 0x0328: 202, 0x032D: 220, 0x032E: 220, 0x0330: 220,
+	0x0331: 220,
+}
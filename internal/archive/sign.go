@@ -0,0 +1,104 @@
+package archive
+
+import (
+	"bytes"
+	"crypto/ed25519"
+	"encoding/json"
+	"errors"
+	"fmt"
+
+	"github.com/LaneBirmingham/coding-agent-sync/internal/keys"
+)
+
+const signatureEntry = "manifest.sig"
+
+var (
+	// ErrUnsigned is returned when an archive carries no signature.
+	ErrUnsigned = errors.New("archive is not signed")
+	// ErrBadSignature is returned when a signature does not match the manifest.
+	ErrBadSignature = errors.New("archive signature is invalid")
+	// ErrUntrustedKey is returned when a valid signature comes from a key not in the trusted set.
+	ErrUntrustedKey = errors.New("archive is signed by an untrusted key")
+)
+
+// Signature is an ed25519 signature over the exact bytes of manifest.json.
+// Because v2 manifests record a checksum for every entry, the signature
+// covers the whole archive.
+type Signature struct {
+	Algorithm string `json:"algorithm"`
+	KeyID     string `json:"key_id"`
+	PublicKey []byte `json:"public_key"`
+	Signature []byte `json:"signature"`
+}
+
+func sign(manifestData []byte, priv ed25519.PrivateKey) ([]byte, error) {
+	pub := priv.Public().(ed25519.PublicKey)
+	sig := Signature{
+		Algorithm: keys.SigningKeyType,
+		KeyID:     keys.Fingerprint(pub),
+		PublicKey: pub,
+		Signature: ed25519.Sign(priv, manifestData),
+	}
+	data, err := json.MarshalIndent(sig, "", "  ")
+	if err != nil {
+		return nil, fmt.Errorf("marshaling signature: %w", err)
+	}
+	return data, nil
+}
+
+func parseSignature(data []byte) (*Signature, error) {
+	var sig Signature
+	if err := json.Unmarshal(data, &sig); err != nil {
+		return nil, fmt.Errorf("parsing signature: %w", err)
+	}
+	if sig.Algorithm != keys.SigningKeyType {
+		return nil, fmt.Errorf("unsupported signature algorithm %q", sig.Algorithm)
+	}
+	if len(sig.PublicKey) != ed25519.PublicKeySize || len(sig.Signature) != ed25519.SignatureSize {
+		return nil, fmt.Errorf("malformed signature")
+	}
+	return &sig, nil
+}
+
+// attachSignature records the raw manifest and any signature found in entries.
+func (a *Archive) attachSignature(entries []entry) error {
+	for _, e := range entries {
+		switch e.name {
+		case manifestEntry:
+			a.manifestData = e.data
+		case signatureEntry:
+			sig, err := parseSignature(e.data)
+			if err != nil {
+				return err
+			}
+			a.Signature = sig
+		}
+	}
+	return nil
+}
+
+// checkSignature reports whether the signature matches the manifest bytes,
+// without regard to whether the key is trusted.
+func (a *Archive) checkSignature() error {
+	if a.Signature == nil {
+		return ErrUnsigned
+	}
+	if !ed25519.Verify(a.Signature.PublicKey, a.manifestData, a.Signature.Signature) {
+		return fmt.Errorf("%w (key %s)", ErrBadSignature, a.Signature.KeyID)
+	}
+	return nil
+}
+
+// VerifySignature checks that the archive is signed by one of the trusted keys
+// and returns the matching key.
+func (a *Archive) VerifySignature(trusted []keys.PublicKey) (keys.PublicKey, error) {
+	if err := a.checkSignature(); err != nil {
+		return keys.PublicKey{}, err
+	}
+	for _, k := range trusted {
+		if bytes.Equal(k.Key, a.Signature.PublicKey) {
+			return k, nil
+		}
+	}
+	return keys.PublicKey{}, fmt.Errorf("%w (key %s)", ErrUntrustedKey, a.Signature.KeyID)
+}
@@ -0,0 +1,188 @@
+package conditional
+
+import (
+	"fmt"
+	"slices"
+	"strings"
+
+	"github.com/LaneBirmingham/coding-agent-sync/internal/config"
+)
+
+// Markers are HTML comments on a line of their own:
+//
+//	<!-- cas:if agent=claude,codex scope=local -->
+//	...
+//	<!-- cas:endif -->
+//
+// Every condition must hold; a comma-separated value matches any of its
+// entries. Sections may nest. Markers inside fenced code blocks, or not at
+// the start of a line, are text.
+const (
+	markerOpen  = "<!-- cas:"
+	markerClose = "-->"
+)
+
+// Target is what a section's conditions are matched against.
+type Target struct {
+	Agent config.Agent
+	Scope config.Scope
+}
+
+// Has reports whether content contains conditional markers.
+func Has(content string) bool {
+	return strings.Contains(content, markerOpen)
+}
+
+// Render returns content with the sections that do not apply to t removed
+// and every marker line stripped.
+func Render(content string, t Target) (string, error) {
+	if !Has(content) {
+		return content, nil
+	}
+	return process(content, func(c condition) bool {
+		switch c.key {
+		case "agent":
+			return slices.Contains(c.values, string(t.Agent))
+		default:
+			return slices.Contains(c.values, string(t.Scope))
+		}
+	})
+}
+
+// Validate reports the first unmatched or malformed marker in content.
+func Validate(content string) error {
+	if !Has(content) {
+		return nil
+	}
+	_, err := process(content, func(condition) bool { return true })
+	return err
+}
+
+type condition struct {
+	key    string
+	values []string
+}
+
+func process(content string, match func(condition) bool) (string, error) {
+	var (
+		out   strings.Builder
+		open  []int // line numbers of the enclosing cas:if markers
+		skip  int   // depth of the outermost section being dropped, or 0
+		fence string
+	)
+	for i, line := range strings.SplitAfter(content, "\n") {
+		n := i + 1
+		trimmed := strings.TrimSpace(line)
+
+		if f := fenceOf(trimmed); f != "" && (fence == "" || strings.HasPrefix(trimmed, fence)) {
+			if fence == "" {
+				fence = f
+			} else {
+				fence = ""
+			}
+		}
+		if fence != "" || !strings.HasPrefix(trimmed, markerOpen) {
+			if skip == 0 {
+				out.WriteString(line)
+			}
+			continue
+		}
+
+		directive, conds, err := parseMarker(trimmed)
+		if err != nil {
+			return "", fmt.Errorf("line %d: %w", n, err)
+		}
+		switch directive {
+		case "if":
+			open = append(open, n)
+			if skip == 0 && !matchAll(conds, match) {
+				skip = len(open)
+			}
+		case "endif":
+			if len(open) == 0 {
+				return "", fmt.Errorf("line %d: cas:endif without a matching cas:if", n)
+			}
+			if skip == len(open) {
+				skip = 0
+			}
+			open = open[:len(open)-1]
+		}
+	}
+	if len(open) > 0 {
+		return "", fmt.Errorf("line %d: cas:if is never closed by cas:endif", open[len(open)-1])
+	}
+	return out.String(), nil
+}
+
+// parseMarker reads a marker line such as "<!-- cas:if agent=claude -->".
+func parseMarker(line string) (string, []condition, error) {
+	if !strings.HasSuffix(line, markerClose) {
+		return "", nil, fmt.Errorf("cas marker must end with --> on the same line")
+	}
+	fields := strings.Fields(strings.TrimSuffix(strings.TrimPrefix(line, markerOpen), markerClose))
+	if len(fields) == 0 {
+		return "", nil, fmt.Errorf("empty cas marker")
+	}
+	switch fields[0] {
+	case "endif":
+		if len(fields) > 1 {
+			return "", nil, fmt.Errorf("cas:endif takes no conditions")
+		}
+		return "endif", nil, nil
+	case "if":
+	default:
+		return "", nil, fmt.Errorf("unknown marker cas:%s (valid: cas:if, cas:endif)", fields[0])
+	}
+	if len(fields) == 1 {
+		return "", nil, fmt.Errorf("cas:if needs a condition such as agent=claude or scope=global")
+	}
+
+	var conds []condition
+	for _, f := range fields[1:] {
+		key, value, ok := strings.Cut(f, "=")
+		if !ok || value == "" {
+			return "", nil, fmt.Errorf("malformed condition %q (want key=value)", f)
+		}
+		c := condition{key: key}
+		for _, v := range strings.Split(value, ",") {
+			switch key {
+			case "agent":
+				a, err := config.ParseAgent(v)
+				if err != nil {
+					return "", nil, err
+				}
+				v = string(a)
+			case "scope":
+				s, err := config.ParseScope(v)
+				if err != nil {
+					return "", nil, err
+				}
+				v = string(s)
+			default:
+				return "", nil, fmt.Errorf("unknown condition %q (valid: agent, scope)", key)
+			}
+			c.values = append(c.values, v)
+		}
+		conds = append(conds, c)
+	}
+	return "if", conds, nil
+}
+
+func matchAll(conds []condition, match func(condition) bool) bool {
+	for _, c := range conds {
+		if !match(c) {
+			return false
+		}
+	}
+	return true
+}
+
+// fenceOf returns the fence a line opens or closes, or "".
+func fenceOf(line string) string {
+	for _, f := range []string{"```", "~~~"} {
+		if strings.HasPrefix(line, f) {
+			return f
+		}
+	}
+	return ""
+}
@@ -0,0 +1,77 @@
+package conditional
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/LaneBirmingham/coding-agent-sync/internal/config"
+)
+
+const source = `# Guide
+<!-- cas:if agent=claude -->
+Use the Task tool.
+<!-- cas:endif -->
+<!-- cas:if agent=codex,gemini scope=global -->
+Personal notes.
+<!-- cas:if agent=gemini -->
+Gemini only.
+<!-- cas:endif -->
+<!-- cas:endif -->
+` + "```md\n<!-- cas:if agent=claude -->\n```\n" + `Done.
+`
+
+func TestRender(t *testing.T) {
+	tests := []struct {
+		target Target
+		want   string
+	}{
+		{Target{config.Claude, config.ScopeLocal}, "# Guide\nUse the Task tool.\n"},
+		{Target{config.Codex, config.ScopeLocal}, "# Guide\n"},
+		{Target{config.Codex, config.ScopeGlobal}, "# Guide\nPersonal notes.\n"},
+		{Target{config.Gemini, config.ScopeGlobal}, "# Guide\nPersonal notes.\nGemini only.\n"},
+	}
+	for _, tt := range tests {
+		got, err := Render(source, tt.target)
+		if err != nil {
+			t.Fatal(err)
+		}
+		want := tt.want + "```md\n<!-- cas:if agent=claude -->\n```\nDone.\n"
+		if got != want {
+			t.Errorf("Render(%v) =\n%s\nwant\n%s", tt.target, got, want)
+		}
+	}
+}
+
+func TestRenderWithoutMarkers(t *testing.T) {
+	in := "plain <!-- cas:if agent=claude --> mention\n"
+	if got, err := Render(in, Target{config.Codex, config.ScopeLocal}); err != nil || got != in {
+		t.Fatalf("expected content unchanged, got %q, %v", got, err)
+	}
+}
+
+func TestValidate(t *testing.T) {
+	tests := []struct {
+		content string
+		want    string
+	}{
+		{"a\n<!-- cas:endif -->\n", "line 2: cas:endif without a matching cas:if"},
+		{"<!-- cas:if agent=claude -->\na\n", "line 1: cas:if is never closed"},
+		{"<!-- cas:if agent=claude\n", "line 1: cas marker must end with -->"},
+		{"<!-- cas:if -->\n<!-- cas:endif -->\n", "needs a condition"},
+		{"<!-- cas:if agent -->\n<!-- cas:endif -->\n", "malformed condition"},
+		{"<!-- cas:if os=linux -->\n<!-- cas:endif -->\n", "unknown condition"},
+		{"<!-- cas:if agent=nope -->\n<!-- cas:endif -->\n", "unknown agent"},
+		{"<!-- cas:if scope=team -->\n<!-- cas:endif -->\n", "unknown scope"},
+		{"<!-- cas:else -->\n", "unknown marker cas:else"},
+		{"<!-- cas:if agent=claude -->\n<!-- cas:endif agent=claude -->\n", "takes no conditions"},
+	}
+	for _, tt := range tests {
+		err := Validate(tt.content)
+		if err == nil || !strings.Contains(err.Error(), tt.want) {
+			t.Errorf("Validate(%q) = %v, want %q", tt.content, err, tt.want)
+		}
+	}
+	if err := Validate(source); err != nil {
+		t.Errorf("expected the sample to be valid, got %v", err)
+	}
+}
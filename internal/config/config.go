@@ -2,7 +2,10 @@ package config
 
 import (
 	"fmt"
+	"path/filepath"
+	"slices"
 	"strings"
+	"time"
 )
 
 // Agent represents a supported coding agent.
@@ -14,19 +17,68 @@ const (
 	Codex    Agent = "codex"
 	OpenCode Agent = "opencode"
 	Gemini   Agent = "gemini"
+
+	// Canonical is the agent-neutral source of truth kept in CanonicalDir.
+	// It is accepted wherever an agent is named but is not one of Agents, so
+	// "all" and "detected" never write to it.
+	Canonical Agent = "cas"
 )
 
+// CanonicalDir is the project directory holding the canonical layout.
+const CanonicalDir = ".cas"
+
 // ValidAgents lists all supported agents.
 var ValidAgents = []Agent{Claude, Copilot, Codex, OpenCode, Gemini}
 
+// definedAgents lists user-defined agents registered via DefineAgent.
+var definedAgents []Agent
+
 // ParseAgent converts a string to an Agent, returning an error if invalid.
 func ParseAgent(s string) (Agent, error) {
-	switch Agent(strings.ToLower(s)) {
-	case Claude, Copilot, Codex, OpenCode, Gemini:
-		return Agent(strings.ToLower(s)), nil
-	default:
-		return "", fmt.Errorf("unknown agent %q (valid: claude, copilot, codex, opencode, gemini)", s)
+	a := Agent(strings.ToLower(s))
+	switch a {
+	case Claude, Copilot, Codex, OpenCode, Gemini, Canonical:
+		return a, nil
+	}
+	if slices.Contains(definedAgents, a) {
+		return a, nil
+	}
+	names := make([]string, 0, len(ValidAgents)+len(definedAgents))
+	for _, v := range Agents() {
+		names = append(names, string(v))
+	}
+	return "", fmt.Errorf("unknown agent %q (valid: %s)", s, strings.Join(names, ", "))
+}
+
+// Agents returns the built-in agents followed by any user-defined agents.
+func Agents() []Agent {
+	return append(slices.Clone(ValidAgents), definedAgents...)
+}
+
+// DefineAgent registers a user-defined agent name so ParseAgent accepts it.
+func DefineAgent(name string) (Agent, error) {
+	if name == "" {
+		return "", fmt.Errorf("agent name must not be empty")
+	}
+	for _, r := range name {
+		if (r < 'a' || r > 'z') && (r < '0' || r > '9') && r != '-' && r != '_' {
+			return "", fmt.Errorf("agent name %q must contain only lowercase letters, digits, '-' or '_'", name)
+		}
 	}
+	a := Agent(name)
+	if slices.Contains(ValidAgents, a) || a == Canonical {
+		return "", fmt.Errorf("agent %q is built in and cannot be redefined", name)
+	}
+	if slices.Contains(definedAgents, a) {
+		return "", fmt.Errorf("agent %q is defined more than once", name)
+	}
+	definedAgents = append(definedAgents, a)
+	return a, nil
+}
+
+// UndefineAgent removes a user-defined agent name registered via DefineAgent.
+func UndefineAgent(a Agent) {
+	definedAgents = slices.DeleteFunc(definedAgents, func(d Agent) bool { return d == a })
 }
 
 // Scope represents whether config is project-level or user-level.
@@ -53,38 +105,202 @@ type Location struct {
 	Scope Scope
 }
 
+// CanonicalDir returns the canonical layout directory for the location:
+// .cas under the project root, or the cas config directory (next to the
+// default config file) for global scope. It returns "" when the home
+// directory is unknown.
+func (l Location) CanonicalDir() string {
+	if l.Scope == ScopeGlobal {
+		return defaultConfigDir()
+	}
+	return filepath.Join(l.Root, CanonicalDir)
+}
+
 // Local returns a Location for project-level config at the given root.
 func Local(root string) Location { return Location{Root: root, Scope: ScopeLocal} }
 
 // Global returns a Location for user-level config.
 func Global() Location { return Location{Scope: ScopeGlobal} }
 
+// Section identifies one agent and scope, such as a section of a bundle archive.
+type Section struct {
+	Agent Agent
+	Scope Scope
+}
+
+func (s Section) String() string { return string(s.Agent) + "/" + string(s.Scope) }
+
+// ParseSection parses "agent/scope". The agent is not checked against known
+// agents, since a bundle may hold sections for agents defined elsewhere.
+func ParseSection(s string) (Section, error) {
+	name, scopeStr, ok := strings.Cut(strings.TrimSpace(s), "/")
+	if !ok || name == "" {
+		return Section{}, fmt.Errorf("invalid section %q (expected agent/scope)", s)
+	}
+	scope, err := ParseScope(scopeStr)
+	if err != nil {
+		return Section{}, fmt.Errorf("invalid section %q: %w", s, err)
+	}
+	return Section{Agent: Agent(strings.ToLower(name)), Scope: scope}, nil
+}
+
+// Mapping routes an archive section to a destination agent and scope.
+type Mapping struct {
+	From Section
+	To   Section
+}
+
+// ParseMapping parses "agent/scope=agent[/scope]". The destination scope
+// defaults to the source scope, and the destination agent must be known.
+func ParseMapping(s string) (Mapping, error) {
+	src, dst, ok := strings.Cut(s, "=")
+	if !ok {
+		return Mapping{}, fmt.Errorf("invalid mapping %q (expected agent/scope=agent[/scope])", s)
+	}
+	from, err := ParseSection(src)
+	if err != nil {
+		return Mapping{}, err
+	}
+	to := Section{Scope: from.Scope}
+	name, scopeStr, hasScope := strings.Cut(strings.TrimSpace(dst), "/")
+	if to.Agent, err = ParseAgent(name); err != nil {
+		return Mapping{}, fmt.Errorf("invalid mapping %q: %w", s, err)
+	}
+	if hasScope {
+		if to.Scope, err = ParseScope(scopeStr); err != nil {
+			return Mapping{}, fmt.Errorf("invalid mapping %q: %w", s, err)
+		}
+	}
+	return Mapping{From: from, To: to}, nil
+}
+
 // SyncConfig holds the configuration for a sync operation.
 type SyncConfig struct {
-	From      Agent
+	From Agent
+	// Sources lists every source agent, From first, when instructions and
+	// skills are merged from several; nil syncs from From alone.
+	Sources   []Agent
+	Merge     MergeStrategy // how merged instructions are combined
+	OnCollide SkillConflict // what happens when merged sources share a skill name
 	To        []Agent
 	Root      string
 	FromScope Scope
 	ToScope   Scope
 	DryRun    bool
 	Verbose   bool
+	Link      bool // symlink destinations to the source instead of copying
+	Reference bool // make destinations import the source instructions where supported
+	// ManagedBlock, when set to "top" or "bottom", writes instructions only
+	// inside the destination's managed block, adding one there if missing.
+	ManagedBlock string
+}
+
+// MergeStrategy says how instructions from several source agents are combined.
+type MergeStrategy string
+
+const (
+	MergeConcat   MergeStrategy = "concat"   // each source under a heading naming it
+	MergeDedup    MergeStrategy = "dedup"    // sections with the same heading kept once, from the first source
+	MergePriority MergeStrategy = "priority" // the first source with instructions wins
+)
+
+// ParseMergeStrategy converts a string to a MergeStrategy.
+func ParseMergeStrategy(s string) (MergeStrategy, error) {
+	switch m := MergeStrategy(strings.ToLower(s)); m {
+	case MergeConcat, MergeDedup, MergePriority:
+		return m, nil
+	}
+	return "", fmt.Errorf("unknown merge strategy %q (valid: concat, dedup, priority)", s)
 }
 
+// SkillConflict says what happens when merged sources have different skills
+// with the same name.
+type SkillConflict string
+
+const (
+	ConflictPriority SkillConflict = "priority" // keep the first source's skill
+	ConflictError    SkillConflict = "error"    // refuse to sync
+	ConflictRename   SkillConflict = "rename"   // keep both, suffixing later ones with the agent name
+)
+
+// ParseSkillConflict converts a string to a SkillConflict.
+func ParseSkillConflict(s string) (SkillConflict, error) {
+	switch c := SkillConflict(strings.ToLower(s)); c {
+	case ConflictPriority, ConflictError, ConflictRename:
+		return c, nil
+	}
+	return "", fmt.Errorf("unknown skill conflict policy %q (valid: priority, error, rename)", s)
+}
+
+// StdioPath is the archive path that means stdout for exports and stdin for imports.
+const StdioPath = "-"
+
 // ExportConfig holds the configuration for an export operation.
 type ExportConfig struct {
 	From       Agent
 	Root       string
 	Scope      Scope
-	Output     string // output ZIP path
+	Output     string // output archive path, or StdioPath
+	Format     string // archive container (zip, tar.gz, dir); inferred from Output when empty
 	DryRun     bool
 	CASVersion string
+	SigningKey string // path to an ed25519 private key; signs the archive when set
+
+	Passphrase string   // encrypt the archive with this passphrase
+	Recipients []string // encrypt the archive to these X25519 recipients (lines or .pub paths)
+
+	// SourceDate, when set, makes the export reproducible: it replaces the
+	// export time and every entry timestamp.
+	SourceDate time.Time
+
+	// Since names a base archive; when set, the export is a delta holding
+	// only what was added, changed or deleted since it.
+	Since string
+
+	// PackageName and PackageVersion record a registry bundle version in the manifest.
+	PackageName    string
+	PackageVersion string
+
+	// Sections, when set, exports a bundle holding each agent and scope
+	// instead of the single From and Scope.
+	Sections []Section
+}
+
+// GitSource names a git revision holding a cas archive or an agent layout.
+type GitSource struct {
+	Repo   string // local path or URL
+	Ref    string // branch, tag or commit; empty for the default branch
+	Subdir string // path inside the repository
+	From   Agent  // agent layout to read; detected when empty
 }
 
 // ImportConfig holds the configuration for an import operation.
+// With no To or Mappings, each archive section is imported to its own agent and scope.
 type ImportConfig struct {
 	To     []Agent
 	Root   string
-	Scope  Scope
-	Input  string // input ZIP path
+	Scope  Scope      // destination scope; for bundles, selects sections of this scope
+	Input  string     // input archive path, or StdioPath
+	Git    *GitSource // read from a git revision instead of Input
 	DryRun bool
+
+	Sections []Section // import only these archive sections
+	Mappings []Mapping // route sections explicitly; overrides To and Sections
+
+	SkipInstructions bool     // import skills only
+	SkipSkills       bool     // import instructions only
+	Skills           []string // import only the skills with these names
+	ExcludeSkills    []string // skip skills whose names match these path.Match globs
+	IfAbsent         bool     // never overwrite instructions or skills that already exist
+
+	TrustedKeys   string // path to the trusted keys file
+	AllowUnsigned bool   // import unsigned or untrusted archives with a warning
+
+	Passphrase string   // passphrase for encrypted archives
+	Identities []string // X25519 identity files for encrypted archives
+
+	// Archive read limits; zero uses the archive package defaults.
+	MaxEntries   int
+	MaxEntrySize int64
+	MaxTotalSize int64
 }
@@ -0,0 +1,83 @@
+package config
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+)
+
+// File holds user configuration loaded from the cas config file.
+type File struct {
+	Agents    []AgentDefinition `json:"agents,omitempty"`
+	Fragments FragmentOptions   `json:"fragments,omitempty"`
+}
+
+// FragmentOptions controls how instructions.d fragments are composed.
+type FragmentOptions struct {
+	Headings bool `json:"headings,omitempty"` // title fragments without a heading after their file name
+	Dedup    bool `json:"dedup,omitempty"`    // drop paragraphs repeated from earlier fragments
+}
+
+// AgentDefinition declares a user-defined agent by where it keeps its config.
+// A nil scope means the agent does not support that scope.
+type AgentDefinition struct {
+	Name   string      `json:"name"`
+	Local  *AgentPaths `json:"local,omitempty"`
+	Global *AgentPaths `json:"global,omitempty"`
+}
+
+// AgentPaths lists an agent's instruction files and skill directories for one scope.
+//
+// Local paths are relative to the project root. Global paths must be absolute
+// after expansion. Both may start with ~ and reference environment variables.
+type AgentPaths struct {
+	Instructions      []string `json:"instructions,omitempty"`       // read in precedence order
+	WriteInstructions string   `json:"write_instructions,omitempty"` // defaults to the first read path
+	Skills            []string `json:"skills,omitempty"`             // read in precedence order
+	WriteSkills       string   `json:"write_skills,omitempty"`       // defaults to the first read path
+}
+
+// DefaultConfigPath returns the config file location, honoring CAS_CONFIG and XDG_CONFIG_HOME.
+func DefaultConfigPath() string {
+	if p := os.Getenv("CAS_CONFIG"); p != "" {
+		return p
+	}
+	dir := defaultConfigDir()
+	if dir == "" {
+		return ""
+	}
+	return filepath.Join(dir, "config.json")
+}
+
+// defaultConfigDir returns the cas directory under XDG_CONFIG_HOME or ~/.config.
+func defaultConfigDir() string {
+	if dir := os.Getenv("XDG_CONFIG_HOME"); dir != "" {
+		return filepath.Join(dir, "cas")
+	}
+	home, err := os.UserHomeDir()
+	if err != nil {
+		return ""
+	}
+	return filepath.Join(home, ".config", "cas")
+}
+
+// LoadFile reads a cas config file. A missing file yields an empty config
+// unless required is set.
+func LoadFile(path string, required bool) (*File, error) {
+	if path == "" {
+		return &File{}, nil
+	}
+	data, err := os.ReadFile(path)
+	if err != nil {
+		if os.IsNotExist(err) && !required {
+			return &File{}, nil
+		}
+		return nil, fmt.Errorf("reading config: %w", err)
+	}
+	var f File
+	if err := json.Unmarshal(data, &f); err != nil {
+		return nil, fmt.Errorf("parsing config %s: %w", path, err)
+	}
+	return &f, nil
+}
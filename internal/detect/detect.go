@@ -0,0 +1,205 @@
+package detect
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/LaneBirmingham/coding-agent-sync/internal/agent"
+	"github.com/LaneBirmingham/coding-agent-sync/internal/config"
+)
+
+// Instructions describes an instruction file found on disk.
+type Instructions struct {
+	Path    string    `json:"path"`
+	Size    int64     `json:"size"`
+	ModTime time.Time `json:"mod_time"`
+}
+
+// Skills describes a skill directory found on disk.
+type Skills struct {
+	Path  string `json:"path"`
+	Count int    `json:"count"`
+}
+
+// Found reports which config an agent has at one scope.
+type Found struct {
+	Agent        config.Agent  `json:"agent"`
+	Scope        config.Scope  `json:"scope"`
+	Instructions *Instructions `json:"instructions,omitempty"`
+	Skills       *Skills       `json:"skills,omitempty"`
+}
+
+// Present reports whether any config was found.
+func (f Found) Present() bool {
+	return f.Instructions != nil || f.Skills != nil
+}
+
+// Scan inspects the paths an agent reads from at loc.
+func Scan(a config.Agent, loc config.Location) (Found, error) {
+	return scan(a, loc, nil)
+}
+
+// scan inspects the paths an agent reads from at loc, passing over those
+// skip reports.
+func scan(a config.Agent, loc config.Location, skip func(path string) bool) (Found, error) {
+	impl, err := agent.Get(a)
+	if err != nil {
+		return Found{}, err
+	}
+	found := Found{Agent: a, Scope: loc.Scope}
+
+	for _, path := range impl.InstructionsSources(loc) {
+		if skip != nil && skip(path) {
+			continue
+		}
+		info, err := os.Stat(path)
+		if err != nil {
+			if os.IsNotExist(err) {
+				continue
+			}
+			return Found{}, fmt.Errorf("checking %s: %w", path, err)
+		}
+		if info.IsDir() || info.Size() == 0 {
+			continue
+		}
+		found.Instructions = &Instructions{Path: path, Size: info.Size(), ModTime: info.ModTime()}
+		break
+	}
+
+	for _, dir := range impl.SkillsDirs(loc) {
+		if skip != nil && skip(dir) {
+			continue
+		}
+		n, err := countSkills(dir)
+		if err != nil {
+			return Found{}, fmt.Errorf("checking %s: %w", dir, err)
+		}
+		if n > 0 {
+			found.Skills = &Skills{Path: dir, Count: n}
+			break
+		}
+	}
+
+	return found, nil
+}
+
+// All scans every known agent at both the project scope and the global scope,
+// returning only agents with config present.
+func All(root string) ([]Found, error) {
+	var out []Found
+	for _, loc := range []config.Location{config.Local(root), config.Global()} {
+		for _, a := range config.Agents() {
+			f, err := Scan(a, loc)
+			if err != nil {
+				return nil, err
+			}
+			if f.Present() {
+				out = append(out, f)
+			}
+		}
+	}
+	return out, nil
+}
+
+// Agents returns the agents with config of their own at scope, or at either
+// scope when scope is empty, in registry order. Paths an agent only falls
+// back to, such as OpenCode reading Claude's global CLAUDE.md, do not count.
+func Agents(root string, scope config.Scope) ([]config.Agent, error) {
+	var locs []config.Location
+	if scope == "" || scope == config.ScopeLocal {
+		locs = append(locs, config.Local(root))
+	}
+	if scope == "" || scope == config.ScopeGlobal {
+		locs = append(locs, config.Global())
+	}
+
+	var out []config.Agent
+	for _, a := range config.Agents() {
+		for _, loc := range locs {
+			f, err := scan(a, loc, fallbacks(a, loc))
+			if err != nil {
+				return nil, err
+			}
+			if f.Present() {
+				out = append(out, a)
+				break
+			}
+		}
+	}
+	return out, nil
+}
+
+// fallbacks returns a filter matching the paths a reads at loc that belong
+// to another agent: those another agent writes and a does not.
+func fallbacks(a config.Agent, loc config.Location) func(path string) bool {
+	own := make(map[string]bool)
+	others := make(map[string]bool)
+	for _, b := range config.Agents() {
+		impl, err := agent.Get(b)
+		if err != nil {
+			continue
+		}
+		paths := own
+		if b != a {
+			paths = others
+		}
+		for _, p := range []string{impl.InstructionsPath(loc), impl.SkillsPath(loc)} {
+			if p != "" {
+				paths[filepath.Clean(p)] = true
+			}
+		}
+	}
+	return func(path string) bool {
+		path = filepath.Clean(path)
+		return others[path] && !own[path]
+	}
+}
+
+// MostRecent returns the agent whose instructions at loc were modified most
+// recently. Ties go to the agent listed first in config.Agents.
+func MostRecent(loc config.Location) (config.Agent, error) {
+	var (
+		best    config.Agent
+		bestMod time.Time
+	)
+	for _, a := range config.Agents() {
+		f, err := Scan(a, loc)
+		if err != nil {
+			return "", err
+		}
+		if f.Instructions == nil {
+			continue
+		}
+		if best == "" || f.Instructions.ModTime.After(bestMod) {
+			best = a
+			bestMod = f.Instructions.ModTime
+		}
+	}
+	if best == "" {
+		return "", fmt.Errorf("no agent instructions found at %s scope", loc.Scope)
+	}
+	return best, nil
+}
+
+func countSkills(dir string) (int, error) {
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return 0, nil
+		}
+		return 0, err
+	}
+	n := 0
+	for _, e := range entries {
+		if !e.IsDir() {
+			continue
+		}
+		info, err := os.Stat(filepath.Join(dir, e.Name(), "SKILL.md"))
+		if err == nil && !info.IsDir() && info.Size() > 0 {
+			n++
+		}
+	}
+	return n, nil
+}
@@ -0,0 +1,153 @@
+package detect
+
+import (
+	"os"
+	"path/filepath"
+	"slices"
+	"testing"
+	"time"
+
+	"github.com/LaneBirmingham/coding-agent-sync/internal/config"
+)
+
+func writeFile(t *testing.T, path, content string) {
+	t.Helper()
+	if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(path, []byte(content), 0o644); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestScan_FindsInstructionsAndSkills(t *testing.T) {
+	root := t.TempDir()
+	writeFile(t, filepath.Join(root, ".claude", "CLAUDE.md"), "# Claude")
+	writeFile(t, filepath.Join(root, ".claude", "skills", "a", "SKILL.md"), "a")
+	writeFile(t, filepath.Join(root, ".claude", "skills", "b", "SKILL.md"), "b")
+	writeFile(t, filepath.Join(root, ".claude", "skills", "empty", "README.md"), "not a skill")
+
+	f, err := Scan(config.Claude, config.Local(root))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if f.Instructions == nil || f.Instructions.Path != filepath.Join(root, ".claude", "CLAUDE.md") {
+		t.Fatalf("unexpected instructions: %+v", f.Instructions)
+	}
+	if f.Instructions.Size != int64(len("# Claude")) {
+		t.Errorf("expected size %d, got %d", len("# Claude"), f.Instructions.Size)
+	}
+	if f.Skills == nil || f.Skills.Count != 2 {
+		t.Fatalf("expected 2 skills, got %+v", f.Skills)
+	}
+}
+
+func TestAll_ReportsLocalAndGlobal(t *testing.T) {
+	home := t.TempDir()
+	t.Setenv("HOME", home)
+	t.Setenv("CODEX_HOME", "")
+	root := t.TempDir()
+	writeFile(t, filepath.Join(root, "GEMINI.md"), "# Gemini")
+	writeFile(t, filepath.Join(home, ".claude", "CLAUDE.md"), "# Global")
+
+	found, err := All(root)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var sawGemini, sawClaudeGlobal bool
+	for _, f := range found {
+		if f.Agent == config.Gemini && f.Scope == config.ScopeLocal {
+			sawGemini = true
+		}
+		if f.Agent == config.Claude && f.Scope == config.ScopeGlobal {
+			sawClaudeGlobal = true
+		}
+		if f.Agent == config.Copilot {
+			t.Errorf("unexpected copilot detection: %+v", f)
+		}
+	}
+	if !sawGemini || !sawClaudeGlobal {
+		t.Fatalf("expected gemini local and claude global, got %+v", found)
+	}
+
+	// OpenCode falls back to Claude's global instructions, which does not
+	// count as OpenCode config.
+	for scope, want := range map[config.Scope][]config.Agent{
+		"":                 {config.Claude, config.Gemini},
+		config.ScopeLocal:  {config.Gemini},
+		config.ScopeGlobal: {config.Claude},
+	} {
+		agents, err := Agents(root, scope)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !slices.Equal(agents, want) {
+			t.Errorf("scope %q: expected %v, got %v", scope, want, agents)
+		}
+	}
+}
+
+func TestMostRecent(t *testing.T) {
+	root := t.TempDir()
+	writeFile(t, filepath.Join(root, "CLAUDE.md"), "# Claude")
+	writeFile(t, filepath.Join(root, "GEMINI.md"), "# Gemini")
+
+	old := time.Now().Add(-time.Hour)
+	if err := os.Chtimes(filepath.Join(root, "CLAUDE.md"), old, old); err != nil {
+		t.Fatal(err)
+	}
+
+	a, err := MostRecent(config.Local(root))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if a != config.Gemini {
+		t.Fatalf("expected gemini, got %s", a)
+	}
+}
+
+func TestMostRecent_NoneFound(t *testing.T) {
+	if _, err := MostRecent(config.Local(t.TempDir())); err == nil {
+		t.Fatal("expected error when no instructions exist")
+	}
+}
+
+func TestStatus(t *testing.T) {
+	root := t.TempDir()
+	writeFile(t, filepath.Join(root, "CLAUDE.md"), "# Shared")
+	writeFile(t, filepath.Join(root, ".claude", "skills", "a", "SKILL.md"), "a")
+	writeFile(t, filepath.Join(root, "GEMINI.md"), "# Shared")
+	writeFile(t, filepath.Join(root, "AGENTS.md"), "# Old")
+
+	rows, err := Status([]config.Location{config.Local(root)}, config.Claude)
+	if err != nil {
+		t.Fatal(err)
+	}
+	cells := make(map[config.Agent][]Cell)
+	for _, r := range rows {
+		cells[r.Agent] = r.Cells
+	}
+
+	claude := cells[config.Claude]
+	if len(claude) != 2 || claude[0].Match != MatchSource || claude[0].Size != len("# Shared") || claude[1].Count != 1 {
+		t.Fatalf("unexpected claude cells: %+v", claude)
+	}
+	if gemini := cells[config.Gemini]; gemini[0].Match != MatchInSync || gemini[1].Match != MatchDiffers || gemini[1].Present {
+		t.Fatalf("unexpected gemini cells: %+v", gemini)
+	}
+	if copilot := cells[config.Copilot]; !copilot[0].Present || copilot[0].Match != MatchDiffers {
+		t.Fatalf("unexpected copilot cells: %+v", copilot)
+	}
+
+	rows, err = Status([]config.Location{config.Local(root)}, "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, r := range rows {
+		for _, c := range r.Cells {
+			if c.Match != "" {
+				t.Fatalf("expected no comparison without a source, got %+v", c)
+			}
+		}
+	}
+}
@@ -0,0 +1,183 @@
+package detect
+
+import (
+	"fmt"
+
+	"github.com/LaneBirmingham/coding-agent-sync/internal/agent"
+	"github.com/LaneBirmingham/coding-agent-sync/internal/conditional"
+	"github.com/LaneBirmingham/coding-agent-sync/internal/config"
+	"github.com/LaneBirmingham/coding-agent-sync/internal/managed"
+)
+
+// Item kinds reported by Status.
+const (
+	KindInstructions = "instructions"
+	KindSkills       = "skills"
+)
+
+// Comparison of a cell with the same item of the source agent.
+const (
+	MatchSource  = "source"  // the cell is the source itself
+	MatchInSync  = "in sync" // same content as the source
+	MatchDiffers = "differs" // different, or missing items the source has
+)
+
+// Cell describes one item kind of one agent at one scope.
+type Cell struct {
+	Kind      string       `json:"kind"`
+	Scope     config.Scope `json:"scope"`
+	Supported bool         `json:"supported"`
+	Present   bool         `json:"present"`
+	Path      string       `json:"path,omitempty"`
+	Size      int          `json:"size,omitempty"`  // instruction bytes
+	Count     int          `json:"count,omitempty"` // number of skills
+	// Match compares the cell with the source agent; empty without a source
+	// or when the source does not have the item.
+	Match string `json:"match,omitempty"`
+}
+
+// Row holds one agent's cells, instructions then skills for each scope.
+type Row struct {
+	Agent config.Agent `json:"agent"`
+	Cells []Cell       `json:"cells"`
+}
+
+// items is the content read from one agent at one scope.
+type items struct {
+	inst   string
+	skills map[string]string
+}
+
+// Status reads every known agent at each of locs and compares it with
+// source, which may be empty to skip the comparison. Items the source does
+// not have are not counted as differences, matching what sync changes.
+func Status(locs []config.Location, source config.Agent) ([]Row, error) {
+	var rows []Row
+	src := make(map[config.Scope]items)
+	if source != "" {
+		for _, loc := range locs {
+			it, err := readItems(source, loc)
+			if err != nil {
+				return nil, err
+			}
+			src[loc.Scope] = it
+		}
+	}
+
+	for _, a := range config.Agents() {
+		impl, err := agent.Get(a)
+		if err != nil {
+			return nil, err
+		}
+		row := Row{Agent: a}
+		for _, loc := range locs {
+			found, err := Scan(a, loc)
+			if err != nil {
+				return nil, err
+			}
+			it, err := readItems(a, loc)
+			if err != nil {
+				return nil, err
+			}
+
+			inst := Cell{Kind: KindInstructions, Scope: loc.Scope, Supported: impl.InstructionsPath(loc) != ""}
+			if found.Instructions != nil {
+				inst.Present, inst.Path, inst.Size = true, found.Instructions.Path, len(it.inst)
+			}
+			skills := Cell{Kind: KindSkills, Scope: loc.Scope, Supported: impl.SkillsPath(loc) != ""}
+			if found.Skills != nil {
+				skills.Present, skills.Path, skills.Count = true, found.Skills.Path, len(it.skills)
+			}
+
+			if s, ok := src[loc.Scope]; ok {
+				if a != source {
+					if s, err = renderItems(s, conditional.Target{Agent: a, Scope: loc.Scope}); err != nil {
+						return nil, fmt.Errorf("source %s: %w", source, err)
+					}
+				}
+				if inst.Supported {
+					dst := it.inst
+					if body, ok, err := managed.Extract(dst); err == nil && ok && a != source {
+						dst, s.inst = body, managed.Body(s.inst)
+					}
+					inst.Match = matchInstructions(a == source, s.inst, dst)
+				}
+				if skills.Supported {
+					skills.Match = matchSkills(a == source, s.skills, it.skills)
+				}
+			}
+			row.Cells = append(row.Cells, inst, skills)
+		}
+		rows = append(rows, row)
+	}
+	return rows, nil
+}
+
+func readItems(a config.Agent, loc config.Location) (items, error) {
+	impl, err := agent.Get(a)
+	if err != nil {
+		return items{}, err
+	}
+	var it items
+	inst, _, err := agent.ReadComposedInstructions(impl, loc)
+	if err != nil {
+		return items{}, err
+	}
+	if inst != nil {
+		it.inst = inst.Content
+	}
+	skills, err := impl.ReadSkills(loc)
+	if err != nil {
+		return items{}, err
+	}
+	it.skills = make(map[string]string, len(skills))
+	for _, s := range skills {
+		it.skills[s.Name] = s.Content
+	}
+	return it, nil
+}
+
+// renderItems renders the cas:if sections of source items for t, which is
+// what a sync would write.
+func renderItems(src items, t conditional.Target) (items, error) {
+	inst, err := conditional.Render(src.inst, t)
+	if err != nil {
+		return items{}, fmt.Errorf("instructions: %w", err)
+	}
+	out := items{inst: inst, skills: make(map[string]string, len(src.skills))}
+	for name, content := range src.skills {
+		if out.skills[name], err = conditional.Render(content, t); err != nil {
+			return items{}, fmt.Errorf("skill %s: %w", name, err)
+		}
+	}
+	return out, nil
+}
+
+func matchInstructions(isSource bool, src, dst string) string {
+	switch {
+	case src == "":
+		// Nothing to sync; the destination's own instructions are left alone.
+		return ""
+	case isSource:
+		return MatchSource
+	case src == dst:
+		return MatchInSync
+	default:
+		return MatchDiffers
+	}
+}
+
+func matchSkills(isSource bool, src, dst map[string]string) string {
+	switch {
+	case len(src) == 0:
+		return ""
+	case isSource:
+		return MatchSource
+	}
+	for name, content := range src {
+		if got, ok := dst[name]; !ok || got != content {
+			return MatchDiffers
+		}
+	}
+	return MatchInSync
+}
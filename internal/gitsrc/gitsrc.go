@@ -0,0 +1,103 @@
+package gitsrc
+
+import (
+	"bytes"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+)
+
+// Checkout is a temporary, detached checkout of a single commit.
+type Checkout struct {
+	Dir    string // root of the working tree
+	Commit string // full SHA of the checked-out commit
+}
+
+// Fetch clones repo (a local path or any URL git understands, including
+// file://) and checks out ref, which may be a branch, tag or commit. An empty
+// ref checks out the remote HEAD. Call Close to remove the checkout.
+func Fetch(repo, ref string) (*Checkout, error) {
+	if repo == "" {
+		return nil, fmt.Errorf("git repository must not be empty")
+	}
+	if strings.HasPrefix(ref, "-") {
+		return nil, fmt.Errorf("invalid git ref %q", ref)
+	}
+	if ref == "" {
+		ref = "HEAD"
+	}
+
+	dir, err := os.MkdirTemp("", "cas-git-*")
+	if err != nil {
+		return nil, fmt.Errorf("creating checkout directory: %w", err)
+	}
+	c := &Checkout{Dir: dir}
+
+	if _, err := git("", "clone", "--quiet", "--no-checkout", "--", repo, dir); err != nil {
+		c.Close()
+		return nil, err
+	}
+
+	c.Commit, err = resolve(dir, ref)
+	if err != nil {
+		c.Close()
+		return nil, err
+	}
+
+	if _, err := git(dir, "checkout", "--quiet", "--detach", c.Commit); err != nil {
+		c.Close()
+		return nil, err
+	}
+	return c, nil
+}
+
+// Path returns the location of subdir inside the checkout. subdir must be
+// relative and stay within the working tree.
+func (c *Checkout) Path(subdir string) (string, error) {
+	clean := filepath.Clean(filepath.FromSlash(subdir))
+	if filepath.IsAbs(clean) || clean == ".." || strings.HasPrefix(clean, ".."+string(filepath.Separator)) {
+		return "", fmt.Errorf("subdirectory %q must be relative to the repository root", subdir)
+	}
+	path := filepath.Join(c.Dir, clean)
+	if _, err := os.Stat(path); err != nil {
+		return "", fmt.Errorf("%s not found at commit %s", subdir, c.Commit)
+	}
+	return path, nil
+}
+
+// Close removes the checkout.
+func (c *Checkout) Close() error {
+	return os.RemoveAll(c.Dir)
+}
+
+// resolve returns the commit SHA for ref, falling back to the remote-tracking
+// branch so branches other than the default one resolve after a clone.
+func resolve(dir, ref string) (string, error) {
+	for _, candidate := range []string{ref, "origin/" + ref} {
+		out, err := git(dir, "rev-parse", "--verify", "--quiet", "--end-of-options", candidate+"^{commit}")
+		if err == nil {
+			return out, nil
+		}
+	}
+	return "", fmt.Errorf("git ref %q not found", ref)
+}
+
+// git runs a git command in dir and returns its trimmed stdout.
+func git(dir string, args ...string) (string, error) {
+	cmd := exec.Command("git", args...)
+	cmd.Dir = dir
+	cmd.Env = append(os.Environ(), "GIT_TERMINAL_PROMPT=0")
+	var stdout, stderr bytes.Buffer
+	cmd.Stdout = &stdout
+	cmd.Stderr = &stderr
+	if err := cmd.Run(); err != nil {
+		msg := strings.TrimSpace(stderr.String())
+		if msg == "" {
+			msg = err.Error()
+		}
+		return "", fmt.Errorf("git %s: %s", args[0], msg)
+	}
+	return strings.TrimSpace(stdout.String()), nil
+}
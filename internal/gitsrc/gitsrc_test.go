@@ -0,0 +1,100 @@
+package gitsrc
+
+import (
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// initRepo creates a repository with two commits; v1 tags the first.
+func initRepo(t *testing.T) string {
+	t.Helper()
+	if _, err := exec.LookPath("git"); err != nil {
+		t.Skip("git not installed")
+	}
+	dir := t.TempDir()
+	run := func(args ...string) {
+		t.Helper()
+		cmd := exec.Command("git", append([]string{"-c", "user.name=test", "-c", "user.email=test@example.com"}, args...)...)
+		cmd.Dir = dir
+		if out, err := cmd.CombinedOutput(); err != nil {
+			t.Fatalf("git %v: %v\n%s", args, err, out)
+		}
+	}
+	write := func(name, content string) {
+		t.Helper()
+		path := filepath.Join(dir, name)
+		if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(path, []byte(content), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	run("init", "--quiet", "-b", "main")
+	write("agents/CLAUDE.md", "v1")
+	run("add", "-A")
+	run("commit", "--quiet", "-m", "one")
+	run("tag", "v1")
+	write("agents/CLAUDE.md", "v2")
+	run("commit", "--quiet", "-am", "two")
+	return dir
+}
+
+func TestFetch(t *testing.T) {
+	repo := initRepo(t)
+
+	for ref, want := range map[string]string{"": "v2", "main": "v2", "v1": "v1"} {
+		for _, url := range []string{repo, "file://" + repo} {
+			c, err := Fetch(url, ref)
+			if err != nil {
+				t.Fatalf("Fetch(%s, %q): %v", url, ref, err)
+			}
+			if len(c.Commit) != 40 {
+				t.Errorf("expected a full commit SHA, got %q", c.Commit)
+			}
+			path, err := c.Path("agents/")
+			if err != nil {
+				t.Fatal(err)
+			}
+			got, err := os.ReadFile(filepath.Join(path, "CLAUDE.md"))
+			if err != nil || string(got) != want {
+				t.Errorf("Fetch(%s, %q): expected %q, got %q (%v)", url, ref, want, got, err)
+			}
+			if err := c.Close(); err != nil {
+				t.Fatal(err)
+			}
+			if _, err := os.Stat(c.Dir); !os.IsNotExist(err) {
+				t.Errorf("expected checkout to be removed, got %v", err)
+			}
+		}
+	}
+}
+
+func TestFetchErrors(t *testing.T) {
+	repo := initRepo(t)
+
+	if _, err := Fetch(repo, "no-such-ref"); err == nil || !strings.Contains(err.Error(), "not found") {
+		t.Fatalf("expected missing ref error, got %v", err)
+	}
+	if _, err := Fetch(repo, "--upload-pack=evil"); err == nil {
+		t.Fatal("expected option-like ref to be rejected")
+	}
+	if _, err := Fetch(filepath.Join(t.TempDir(), "missing"), ""); err == nil {
+		t.Fatal("expected clone of a missing repository to fail")
+	}
+
+	c, err := Fetch(repo, "v1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer c.Close()
+	for _, sub := range []string{"../outside", "/etc", "missing"} {
+		if _, err := c.Path(sub); err == nil {
+			t.Errorf("expected error for subdir %q", sub)
+		}
+	}
+}
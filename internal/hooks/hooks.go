@@ -0,0 +1,234 @@
+package hooks
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+)
+
+const (
+	beginMarker = "# BEGIN cas"
+	endMarker   = "# END cas"
+
+	// chainedSuffix names an existing non-shell hook that the cas hook runs after itself.
+	chainedSuffix = ".cas-chained"
+
+	// frameworkMarker appears in hooks written by the pre-commit framework,
+	// which rewrites them on every install.
+	frameworkMarker = "File generated by pre-commit"
+)
+
+// ErrFramework is returned when the hook is managed by the pre-commit framework.
+var ErrFramework = errors.New("the pre-commit hook is managed by the pre-commit framework; add cas to .pre-commit-config.yaml instead")
+
+// Dir returns the hooks directory of the git repository containing dir. It
+// honors core.hooksPath, and worktrees share the main repository's hooks.
+func Dir(dir string) (string, error) {
+	top, err := git(dir, "rev-parse", "--show-toplevel")
+	if err != nil {
+		return "", err
+	}
+	hooks, err := git(top, "rev-parse", "--git-path", "hooks")
+	if err != nil {
+		return "", err
+	}
+	if !filepath.IsAbs(hooks) {
+		hooks = filepath.Join(top, hooks)
+	}
+	return filepath.Clean(hooks), nil
+}
+
+// Install adds a block running command to the named hook in hooksDir,
+// replacing any block from an earlier install. Existing shell hooks keep
+// their content and run after the block; other existing hooks are moved
+// aside and run by the new hook. It returns the hook's path.
+func Install(hooksDir, name, command string) (string, error) {
+	path := filepath.Join(hooksDir, name)
+	block := beginMarker + "\n" + command + "\n" + endMarker + "\n"
+
+	data, err := os.ReadFile(path)
+	switch {
+	case os.IsNotExist(err):
+		data = nil
+	case err != nil:
+		return "", fmt.Errorf("reading %s: %w", path, err)
+	}
+	existing := string(data)
+
+	var out string
+	switch {
+	case existing == "":
+		out = "#!/bin/sh\n" + block
+	case hasBlock(existing):
+		out = replaceBlock(existing, block)
+	case strings.Contains(existing, frameworkMarker):
+		return "", ErrFramework
+	case isShell(existing):
+		shebang, rest, _ := strings.Cut(existing, "\n")
+		out = shebang + "\n" + block + rest
+	default:
+		chained := path + chainedSuffix
+		if _, err := os.Stat(chained); err == nil {
+			return "", fmt.Errorf("%s already exists; remove it before installing", chained)
+		}
+		if err := os.Rename(path, chained); err != nil {
+			return "", fmt.Errorf("moving existing hook aside: %w", err)
+		}
+		out = "#!/bin/sh\n" + beginMarker + "\n" + command + "\n" +
+			fmt.Sprintf("exec \"$(dirname \"$0\")/%s%s\" \"$@\"\n", name, chainedSuffix) + endMarker + "\n"
+	}
+
+	if err := os.MkdirAll(hooksDir, 0o755); err != nil {
+		return "", fmt.Errorf("creating hooks directory: %w", err)
+	}
+	if err := os.WriteFile(path, []byte(out), 0o755); err != nil {
+		return "", fmt.Errorf("writing %s: %w", path, err)
+	}
+	// WriteFile keeps the mode of an existing file, which may not be executable.
+	if err := os.Chmod(path, 0o755); err != nil {
+		return "", fmt.Errorf("writing %s: %w", path, err)
+	}
+	return path, nil
+}
+
+// Uninstall removes the cas block from the named hook, deleting the hook if
+// nothing else is left and restoring a hook that Install moved aside. It
+// reports whether a block was found.
+func Uninstall(hooksDir, name string) (bool, error) {
+	path := filepath.Join(hooksDir, name)
+	data, err := os.ReadFile(path)
+	if os.IsNotExist(err) {
+		return false, nil
+	}
+	if err != nil {
+		return false, fmt.Errorf("reading %s: %w", path, err)
+	}
+	existing := string(data)
+	if !hasBlock(existing) {
+		return false, nil
+	}
+
+	chained := path + chainedSuffix
+	if _, err := os.Stat(chained); err == nil {
+		if err := os.Rename(chained, path); err != nil {
+			return false, fmt.Errorf("restoring %s: %w", path, err)
+		}
+		return true, nil
+	}
+
+	rest := replaceBlock(existing, "")
+	if shebang, body, _ := strings.Cut(rest, "\n"); strings.TrimSpace(body) == "" && (strings.TrimSpace(shebang) == "" || isShell(rest)) {
+		if err := os.Remove(path); err != nil {
+			return false, fmt.Errorf("removing %s: %w", path, err)
+		}
+		return true, nil
+	}
+	if err := os.WriteFile(path, []byte(rest), 0o755); err != nil {
+		return false, fmt.Errorf("writing %s: %w", path, err)
+	}
+	return true, nil
+}
+
+// Installed reports whether the named hook holds a cas block.
+func Installed(hooksDir, name string) bool {
+	data, err := os.ReadFile(filepath.Join(hooksDir, name))
+	return err == nil && hasBlock(string(data))
+}
+
+func hasBlock(s string) bool {
+	begin := strings.Index(s, beginMarker+"\n")
+	return begin >= 0 && strings.Contains(s[begin:], "\n"+endMarker+"\n")
+}
+
+// replaceBlock swaps the cas block in s, markers included, for block.
+func replaceBlock(s, block string) string {
+	begin := strings.Index(s, beginMarker+"\n")
+	end := begin + strings.Index(s[begin:], "\n"+endMarker+"\n") + len("\n"+endMarker+"\n")
+	return s[:begin] + block + s[end:]
+}
+
+// isShell reports whether a hook script runs under a POSIX-style shell, so
+// a block can be added to it.
+func isShell(s string) bool {
+	first, _, _ := strings.Cut(s, "\n")
+	if !strings.HasPrefix(first, "#!") {
+		return false
+	}
+	fields := strings.Fields(strings.TrimPrefix(first, "#!"))
+	if len(fields) == 0 {
+		return false
+	}
+	interp := filepath.Base(fields[0])
+	if interp == "env" && len(fields) > 1 {
+		interp = fields[1]
+	}
+	switch interp {
+	case "sh", "bash", "zsh", "dash", "ksh":
+		return true
+	}
+	return false
+}
+
+// Quote returns args as a shell command line.
+func Quote(args []string) string {
+	quoted := make([]string, len(args))
+	for i, a := range args {
+		if a != "" && strings.Trim(a, "abcdefghijklmnopqrstuvwxyzABCDEFGHIJKLMNOPQRSTUVWXYZ0123456789-_./=,:@") == "" {
+			quoted[i] = a
+			continue
+		}
+		quoted[i] = "'" + strings.ReplaceAll(a, "'", `'\''`) + "'"
+	}
+	return strings.Join(quoted, " ")
+}
+
+// Stage adds paths under the repository containing dir to the git index.
+// Paths outside the repository, missing or ignored by git are skipped.
+func Stage(dir string, paths []string) ([]string, error) {
+	top, err := git(dir, "rev-parse", "--show-toplevel")
+	if err != nil {
+		return nil, err
+	}
+	var staged []string
+	for _, p := range paths {
+		rel, err := filepath.Rel(top, p)
+		if err != nil || !filepath.IsLocal(rel) {
+			continue
+		}
+		if _, err := os.Stat(p); err != nil {
+			continue
+		}
+		if _, err := git(top, "check-ignore", "-q", "--", rel); err == nil {
+			continue
+		}
+		staged = append(staged, filepath.ToSlash(rel))
+	}
+	if len(staged) == 0 {
+		return nil, nil
+	}
+	if _, err := git(top, append([]string{"add", "--"}, staged...)...); err != nil {
+		return nil, err
+	}
+	return staged, nil
+}
+
+// git runs a git command in dir and returns its trimmed stdout.
+func git(dir string, args ...string) (string, error) {
+	cmd := exec.Command("git", args...)
+	cmd.Dir = dir
+	var stdout, stderr bytes.Buffer
+	cmd.Stdout = &stdout
+	cmd.Stderr = &stderr
+	if err := cmd.Run(); err != nil {
+		msg := strings.TrimSpace(stderr.String())
+		if msg == "" {
+			msg = err.Error()
+		}
+		return "", fmt.Errorf("git %s: %s", args[0], msg)
+	}
+	return strings.TrimSpace(stdout.String()), nil
+}
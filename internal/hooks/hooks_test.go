@@ -0,0 +1,198 @@
+package hooks
+
+import (
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func gitInit(t *testing.T) string {
+	t.Helper()
+	if _, err := exec.LookPath("git"); err != nil {
+		t.Skip("git not installed")
+	}
+	dir := t.TempDir()
+	run(t, dir, "init", "-q")
+	return dir
+}
+
+func run(t *testing.T, dir string, args ...string) {
+	t.Helper()
+	cmd := exec.Command("git", append([]string{"-c", "user.name=test", "-c", "user.email=test@example.com"}, args...)...)
+	cmd.Dir = dir
+	if out, err := cmd.CombinedOutput(); err != nil {
+		t.Fatalf("git %v: %v\n%s", args, err, out)
+	}
+}
+
+func readHook(t *testing.T, path string) string {
+	t.Helper()
+	data, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return string(data)
+}
+
+func TestDir(t *testing.T) {
+	repo := gitInit(t)
+	dir, err := Dir(repo)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := filepath.Join(repo, ".git", "hooks"); dir != want {
+		t.Fatalf("expected %s, got %s", want, dir)
+	}
+
+	run(t, repo, "config", "core.hooksPath", ".githooks")
+	if dir, err = Dir(repo); err != nil {
+		t.Fatal(err)
+	}
+	if want := filepath.Join(repo, ".githooks"); dir != want {
+		t.Fatalf("expected core.hooksPath %s, got %s", want, dir)
+	}
+}
+
+func TestDirInWorktree(t *testing.T) {
+	repo := gitInit(t)
+	run(t, repo, "commit", "-q", "--allow-empty", "-m", "init")
+	wt := filepath.Join(t.TempDir(), "wt")
+	run(t, repo, "worktree", "add", "-q", wt)
+
+	dir, err := Dir(wt)
+	if err != nil {
+		t.Fatal(err)
+	}
+	main, _ := filepath.EvalSymlinks(filepath.Join(repo, ".git", "hooks"))
+	if got, _ := filepath.EvalSymlinks(dir); got != main {
+		t.Fatalf("expected worktree to share %s, got %s", main, dir)
+	}
+}
+
+func TestInstallAndUninstallNewHook(t *testing.T) {
+	dir := t.TempDir()
+	path, err := Install(dir, "pre-commit", "cas hooks run")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := readHook(t, path); got != "#!/bin/sh\n# BEGIN cas\ncas hooks run\n# END cas\n" {
+		t.Fatalf("unexpected hook:\n%s", got)
+	}
+	if info, _ := os.Stat(path); info.Mode().Perm()&0o111 == 0 {
+		t.Fatalf("expected an executable hook, got mode %v", info.Mode())
+	}
+
+	// Installing again replaces the block rather than adding a second one.
+	if _, err := Install(dir, "pre-commit", "cas hooks run --sync"); err != nil {
+		t.Fatal(err)
+	}
+	if got := readHook(t, path); strings.Count(got, beginMarker) != 1 || !strings.Contains(got, "--sync") {
+		t.Fatalf("expected one updated block, got:\n%s", got)
+	}
+
+	removed, err := Uninstall(dir, "pre-commit")
+	if err != nil || !removed {
+		t.Fatalf("Uninstall = %t, %v", removed, err)
+	}
+	if _, err := os.Stat(path); !os.IsNotExist(err) {
+		t.Fatalf("expected the hook to be deleted, got err=%v", err)
+	}
+	if removed, _ := Uninstall(dir, "pre-commit"); removed {
+		t.Fatal("expected nothing to remove the second time")
+	}
+}
+
+func TestInstallKeepsExistingShellHook(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "pre-commit")
+	existing := "#!/usr/bin/env bash\nmake lint\nexit 0\n"
+	if err := os.WriteFile(path, []byte(existing), 0o755); err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := Install(dir, "pre-commit", "cas hooks run"); err != nil {
+		t.Fatal(err)
+	}
+	got := readHook(t, path)
+	if !strings.HasPrefix(got, "#!/usr/bin/env bash\n# BEGIN cas\n") || !strings.HasSuffix(got, "make lint\nexit 0\n") {
+		t.Fatalf("expected the block before the existing commands, got:\n%s", got)
+	}
+
+	if _, err := Uninstall(dir, "pre-commit"); err != nil {
+		t.Fatal(err)
+	}
+	if got := readHook(t, path); got != existing {
+		t.Fatalf("expected the original hook back, got:\n%s", got)
+	}
+}
+
+func TestInstallChainsOtherHooks(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "pre-commit")
+	existing := "#!/usr/bin/env python3\nprint('hi')\n"
+	if err := os.WriteFile(path, []byte(existing), 0o755); err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := Install(dir, "pre-commit", "cas hooks run"); err != nil {
+		t.Fatal(err)
+	}
+	if got := readHook(t, path); !strings.Contains(got, "pre-commit.cas-chained") {
+		t.Fatalf("expected the hook to run the original, got:\n%s", got)
+	}
+	if got := readHook(t, path+chainedSuffix); got != existing {
+		t.Fatalf("expected the original moved aside, got:\n%s", got)
+	}
+
+	if _, err := Uninstall(dir, "pre-commit"); err != nil {
+		t.Fatal(err)
+	}
+	if got := readHook(t, path); got != existing {
+		t.Fatalf("expected the original hook restored, got:\n%s", got)
+	}
+}
+
+func TestInstallRefusesFrameworkHook(t *testing.T) {
+	dir := t.TempDir()
+	hook := "#!/usr/bin/env bash\n# File generated by pre-commit: https://pre-commit.com\n"
+	if err := os.WriteFile(filepath.Join(dir, "pre-commit"), []byte(hook), 0o755); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := Install(dir, "pre-commit", "cas hooks run"); err != ErrFramework {
+		t.Fatalf("expected ErrFramework, got %v", err)
+	}
+}
+
+func TestQuote(t *testing.T) {
+	got := Quote([]string{"cas", "--to", "codex,gemini", "it's", ""})
+	if want := `cas --to codex,gemini 'it'\''s' ''`; got != want {
+		t.Fatalf("expected %s, got %s", want, got)
+	}
+}
+
+func TestStage(t *testing.T) {
+	repo := gitInit(t)
+	if err := os.WriteFile(filepath.Join(repo, ".gitignore"), []byte("ignored.md\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	for _, name := range []string{"AGENTS.md", "ignored.md"} {
+		if err := os.WriteFile(filepath.Join(repo, name), []byte("x"), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	staged, err := Stage(repo, []string{
+		filepath.Join(repo, "AGENTS.md"),
+		filepath.Join(repo, "ignored.md"),
+		filepath.Join(repo, "missing.md"),
+		filepath.Join(t.TempDir(), "outside.md"),
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(staged) != 1 || staged[0] != "AGENTS.md" {
+		t.Fatalf("expected only AGENTS.md to be staged, got %v", staged)
+	}
+}
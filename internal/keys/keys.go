@@ -0,0 +1,172 @@
+package keys
+
+import (
+	"bufio"
+	"bytes"
+	"crypto/ed25519"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/pem"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+const (
+	// SigningKeyType is the algorithm label used in public key lines.
+	SigningKeyType = "ed25519"
+
+	signingPEMType = "CAS ED25519 PRIVATE KEY"
+)
+
+// PublicKey is an ed25519 key used to verify archive signatures.
+type PublicKey struct {
+	Key     ed25519.PublicKey
+	Comment string
+}
+
+// ID returns a short fingerprint identifying the key.
+func (k PublicKey) ID() string {
+	return Fingerprint(k.Key)
+}
+
+// String formats the key as a trusted-keys line: "ed25519 <base64> [comment]".
+func (k PublicKey) String() string {
+	s := SigningKeyType + " " + base64.StdEncoding.EncodeToString(k.Key)
+	if k.Comment != "" {
+		s += " " + k.Comment
+	}
+	return s
+}
+
+// Fingerprint returns "SHA256:<base64>" of the public key bytes.
+func Fingerprint(pub []byte) string {
+	sum := sha256.Sum256(pub)
+	return "SHA256:" + base64.RawStdEncoding.EncodeToString(sum[:])
+}
+
+// GenerateSigningKey creates a new ed25519 key pair.
+func GenerateSigningKey() (ed25519.PrivateKey, error) {
+	_, priv, err := ed25519.GenerateKey(rand.Reader)
+	if err != nil {
+		return nil, fmt.Errorf("generating ed25519 key: %w", err)
+	}
+	return priv, nil
+}
+
+// MarshalSigningKey encodes a private key as PEM.
+func MarshalSigningKey(priv ed25519.PrivateKey) []byte {
+	return pem.EncodeToMemory(&pem.Block{Type: signingPEMType, Bytes: priv.Seed()})
+}
+
+// ParseSigningKey decodes a PEM private key written by MarshalSigningKey.
+func ParseSigningKey(data []byte) (ed25519.PrivateKey, error) {
+	block, _ := pem.Decode(data)
+	if block == nil || block.Type != signingPEMType {
+		return nil, fmt.Errorf("not a cas ed25519 private key")
+	}
+	if len(block.Bytes) != ed25519.SeedSize {
+		return nil, fmt.Errorf("invalid ed25519 private key length %d", len(block.Bytes))
+	}
+	return ed25519.NewKeyFromSeed(block.Bytes), nil
+}
+
+// LoadSigningKey reads a private key file.
+func LoadSigningKey(path string) (ed25519.PrivateKey, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("reading signing key: %w", err)
+	}
+	priv, err := ParseSigningKey(data)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", path, err)
+	}
+	return priv, nil
+}
+
+// ParsePublicKey parses a single "ed25519 <base64> [comment]" line.
+func ParsePublicKey(line string) (PublicKey, error) {
+	fields := strings.Fields(line)
+	if len(fields) < 2 {
+		return PublicKey{}, fmt.Errorf("expected \"%s <base64> [comment]\"", SigningKeyType)
+	}
+	if fields[0] != SigningKeyType {
+		return PublicKey{}, fmt.Errorf("unsupported key type %q", fields[0])
+	}
+	raw, err := base64.StdEncoding.DecodeString(fields[1])
+	if err != nil {
+		return PublicKey{}, fmt.Errorf("decoding key: %w", err)
+	}
+	if len(raw) != ed25519.PublicKeySize {
+		return PublicKey{}, fmt.Errorf("invalid ed25519 public key length %d", len(raw))
+	}
+	return PublicKey{Key: ed25519.PublicKey(raw), Comment: strings.Join(fields[2:], " ")}, nil
+}
+
+// LoadTrustedKeys reads a trusted keys file with one public key per line.
+// Blank lines and lines starting with # are ignored. A missing file yields no keys.
+func LoadTrustedKeys(path string) ([]PublicKey, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("reading trusted keys: %w", err)
+	}
+
+	var out []PublicKey
+	sc := bufio.NewScanner(bytes.NewReader(data))
+	lineNo := 0
+	for sc.Scan() {
+		lineNo++
+		line := strings.TrimSpace(sc.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		k, err := ParsePublicKey(line)
+		if err != nil {
+			return nil, fmt.Errorf("%s:%d: %w", path, lineNo, err)
+		}
+		out = append(out, k)
+	}
+	if err := sc.Err(); err != nil {
+		return nil, fmt.Errorf("reading trusted keys: %w", err)
+	}
+	return out, nil
+}
+
+// WriteKeyFiles writes a private key to path (mode 0600) and its public key to path.pub.
+// Existing files are not overwritten unless force is set.
+func WriteKeyFiles(path string, private, public []byte, force bool) error {
+	if !force {
+		for _, p := range []string{path, path + ".pub"} {
+			if _, err := os.Stat(p); err == nil {
+				return fmt.Errorf("%s already exists (use --force to overwrite)", p)
+			}
+		}
+	}
+	if err := os.MkdirAll(filepath.Dir(path), 0o700); err != nil {
+		return fmt.Errorf("creating key directory: %w", err)
+	}
+	if err := os.WriteFile(path, private, 0o600); err != nil {
+		return fmt.Errorf("writing private key: %w", err)
+	}
+	// WriteFile keeps the mode of an existing file, so tighten it explicitly.
+	if err := os.Chmod(path, 0o600); err != nil {
+		return fmt.Errorf("writing private key: %w", err)
+	}
+	if err := os.WriteFile(path+".pub", public, 0o644); err != nil {
+		return fmt.Errorf("writing public key: %w", err)
+	}
+	return nil
+}
+
+// DefaultTrustedKeysPath returns the trusted keys file next to the cas config file.
+func DefaultTrustedKeysPath(configPath string) string {
+	if configPath == "" {
+		return ""
+	}
+	return filepath.Join(filepath.Dir(configPath), "trusted_keys")
+}
@@ -0,0 +1,100 @@
+package keys
+
+import (
+	"crypto/ed25519"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestSigningKeyRoundTrip(t *testing.T) {
+	priv, err := GenerateSigningKey()
+	if err != nil {
+		t.Fatal(err)
+	}
+	path := filepath.Join(t.TempDir(), "key")
+	pub := PublicKey{Key: priv.Public().(ed25519.PublicKey), Comment: "alice laptop"}
+	if err := WriteKeyFiles(path, MarshalSigningKey(priv), []byte(pub.String()+"\n"), false); err != nil {
+		t.Fatal(err)
+	}
+
+	loaded, err := LoadSigningKey(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !loaded.Equal(priv) {
+		t.Fatal("loaded private key does not match")
+	}
+
+	info, err := os.Stat(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if info.Mode().Perm() != 0o600 {
+		t.Errorf("expected private key mode 0600, got %o", info.Mode().Perm())
+	}
+
+	trusted, err := LoadTrustedKeys(path + ".pub")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(trusted) != 1 || !trusted[0].Key.Equal(pub.Key) || trusted[0].Comment != "alice laptop" {
+		t.Fatalf("unexpected trusted keys: %+v", trusted)
+	}
+
+	if err := WriteKeyFiles(path, MarshalSigningKey(priv), nil, false); err == nil {
+		t.Fatal("expected error overwriting existing key without force")
+	}
+}
+
+func TestLoadTrustedKeys(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "trusted_keys")
+	if err := os.WriteFile(path, []byte("# team keys\n\nnot-a-key AAAA\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := LoadTrustedKeys(path); err == nil || !strings.Contains(err.Error(), ":3:") {
+		t.Fatalf("expected error with line number, got %v", err)
+	}
+
+	keys, err := LoadTrustedKeys(filepath.Join(t.TempDir(), "missing"))
+	if err != nil || keys != nil {
+		t.Fatalf("expected no keys for missing file, got %v, %v", keys, err)
+	}
+}
+
+func TestEncryptionKeyRoundTrip(t *testing.T) {
+	priv, err := GenerateEncryptionKey()
+	if err != nil {
+		t.Fatal(err)
+	}
+	path := filepath.Join(t.TempDir(), "identity")
+	r := Recipient{Key: priv.PublicKey(), Comment: "ci"}
+	if err := WriteKeyFiles(path, MarshalEncryptionKey(priv), []byte(r.String()+"\n"), false); err != nil {
+		t.Fatal(err)
+	}
+
+	id, err := LoadIdentity(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !id.Equal(priv) {
+		t.Fatal("loaded identity does not match")
+	}
+
+	fromFile, err := LoadRecipient(path + ".pub")
+	if err != nil {
+		t.Fatal(err)
+	}
+	fromLine, err := LoadRecipient(r.String())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !fromFile.Key.Equal(r.Key) || !fromLine.Key.Equal(r.Key) || fromFile.Comment != "ci" {
+		t.Fatalf("unexpected recipients: %v, %v", fromFile, fromLine)
+	}
+
+	if _, err := LoadIdentity(path + ".pub"); err == nil {
+		t.Fatal("expected error loading a public key as an identity")
+	}
+}
@@ -0,0 +1,105 @@
+package keys
+
+import (
+	"crypto/ecdh"
+	"crypto/rand"
+	"encoding/base64"
+	"encoding/pem"
+	"fmt"
+	"os"
+	"strings"
+)
+
+const (
+	// EncryptionKeyType is the algorithm label used in recipient lines.
+	EncryptionKeyType = "x25519"
+
+	encryptionPEMType = "CAS X25519 PRIVATE KEY"
+)
+
+// Recipient is an X25519 public key that archives can be encrypted to.
+type Recipient struct {
+	Key     *ecdh.PublicKey
+	Comment string
+}
+
+// String formats the recipient as "x25519 <base64> [comment]".
+func (r Recipient) String() string {
+	s := EncryptionKeyType + " " + base64.StdEncoding.EncodeToString(r.Key.Bytes())
+	if r.Comment != "" {
+		s += " " + r.Comment
+	}
+	return s
+}
+
+// ID returns a short fingerprint identifying the recipient.
+func (r Recipient) ID() string {
+	return Fingerprint(r.Key.Bytes())
+}
+
+// GenerateEncryptionKey creates a new X25519 identity.
+func GenerateEncryptionKey() (*ecdh.PrivateKey, error) {
+	priv, err := ecdh.X25519().GenerateKey(rand.Reader)
+	if err != nil {
+		return nil, fmt.Errorf("generating x25519 key: %w", err)
+	}
+	return priv, nil
+}
+
+// MarshalEncryptionKey encodes an X25519 identity as PEM.
+func MarshalEncryptionKey(priv *ecdh.PrivateKey) []byte {
+	return pem.EncodeToMemory(&pem.Block{Type: encryptionPEMType, Bytes: priv.Bytes()})
+}
+
+// LoadIdentity reads an X25519 identity file written by MarshalEncryptionKey.
+func LoadIdentity(path string) (*ecdh.PrivateKey, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("reading identity: %w", err)
+	}
+	block, _ := pem.Decode(data)
+	if block == nil || block.Type != encryptionPEMType {
+		return nil, fmt.Errorf("%s: not a cas x25519 private key", path)
+	}
+	priv, err := ecdh.X25519().NewPrivateKey(block.Bytes)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", path, err)
+	}
+	return priv, nil
+}
+
+// ParseRecipient parses a single "x25519 <base64> [comment]" line.
+func ParseRecipient(line string) (Recipient, error) {
+	fields := strings.Fields(line)
+	if len(fields) < 2 {
+		return Recipient{}, fmt.Errorf("expected \"%s <base64> [comment]\"", EncryptionKeyType)
+	}
+	if fields[0] != EncryptionKeyType {
+		return Recipient{}, fmt.Errorf("unsupported recipient type %q", fields[0])
+	}
+	raw, err := base64.StdEncoding.DecodeString(fields[1])
+	if err != nil {
+		return Recipient{}, fmt.Errorf("decoding recipient: %w", err)
+	}
+	pub, err := ecdh.X25519().NewPublicKey(raw)
+	if err != nil {
+		return Recipient{}, fmt.Errorf("invalid x25519 public key: %w", err)
+	}
+	return Recipient{Key: pub, Comment: strings.Join(fields[2:], " ")}, nil
+}
+
+// LoadRecipient accepts either a recipient line or the path of a file holding one.
+func LoadRecipient(s string) (Recipient, error) {
+	if strings.HasPrefix(s, EncryptionKeyType+" ") {
+		return ParseRecipient(s)
+	}
+	data, err := os.ReadFile(s)
+	if err != nil {
+		return Recipient{}, fmt.Errorf("reading recipient: %w", err)
+	}
+	r, err := ParseRecipient(strings.TrimSpace(string(data)))
+	if err != nil {
+		return Recipient{}, fmt.Errorf("%s: %w", s, err)
+	}
+	return r, nil
+}
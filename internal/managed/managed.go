@@ -0,0 +1,121 @@
+package managed
+
+import (
+	"fmt"
+	"strings"
+)
+
+// Markers delimiting the part of a destination file that sync owns.
+const (
+	Begin = "<!-- BEGIN cas:managed -->"
+	End   = "<!-- END cas:managed -->"
+)
+
+// Where a new block goes in a file that does not have one yet.
+const (
+	Top    = "top"
+	Bottom = "bottom"
+)
+
+// ParsePosition validates a block position.
+func ParsePosition(s string) (string, error) {
+	switch s := strings.ToLower(s); s {
+	case Top, Bottom:
+		return s, nil
+	}
+	return "", fmt.Errorf("unknown block position %q (valid: top, bottom)", s)
+}
+
+// Apply returns existing with the managed block's content set to content.
+// A file without a block gets one at position; text outside the markers is
+// kept byte for byte.
+func Apply(existing, content, position string) (string, error) {
+	block := Begin + "\n" + Body(content) + End + "\n"
+	b, err := locate(existing)
+	if err != nil {
+		return "", err
+	}
+	switch {
+	case b != nil:
+		return existing[:b.begin] + block + existing[b.end:], nil
+	case existing == "":
+		return block, nil
+	case position == Bottom:
+		return withNewline(existing) + "\n" + block, nil
+	default:
+		return block + "\n" + existing, nil
+	}
+}
+
+// Body returns content as Apply writes it inside the block.
+func Body(content string) string {
+	return withNewline(Strip(content))
+}
+
+// Extract returns the content of the managed block in s and whether s has one.
+func Extract(s string) (string, bool, error) {
+	b, err := locate(s)
+	if err != nil || b == nil {
+		return "", false, err
+	}
+	return s[b.innerBegin:b.innerEnd], true, nil
+}
+
+// Strip removes marker lines, so a file that holds a block can be synced
+// into another one without nesting.
+func Strip(s string) string {
+	if !strings.Contains(s, "cas:managed") {
+		return s
+	}
+	var out strings.Builder
+	for _, line := range strings.SplitAfter(s, "\n") {
+		if t := strings.TrimSpace(line); t == Begin || t == End {
+			continue
+		}
+		out.WriteString(line)
+	}
+	return out.String()
+}
+
+// block holds byte offsets of a managed block: the markers' lines span
+// begin to end and the content spans innerBegin to innerEnd.
+type block struct {
+	begin, innerBegin, innerEnd, end int
+}
+
+// locate finds the managed block in s, or nil. It rejects unpaired,
+// repeated or indented markers.
+func locate(s string) (*block, error) {
+	var b *block
+	pos := 0
+	for i, line := range strings.SplitAfter(s, "\n") {
+		trimmed := strings.TrimSpace(line)
+		if trimmed == Begin || trimmed == End {
+			if strings.TrimRight(line, "\r\n") != trimmed {
+				return nil, fmt.Errorf("line %d: %s must be on a line of its own", i+1, trimmed)
+			}
+		}
+		switch {
+		case trimmed == Begin && b != nil:
+			return nil, fmt.Errorf("line %d: more than one %s marker", i+1, Begin)
+		case trimmed == Begin:
+			b = &block{begin: pos, innerBegin: pos + len(line), end: -1}
+		case trimmed == End && (b == nil || b.end >= 0):
+			return nil, fmt.Errorf("line %d: %s without a matching %s", i+1, End, Begin)
+		case trimmed == End:
+			b.innerEnd, b.end = pos, pos+len(line)
+		}
+		pos += len(line)
+	}
+	if b != nil && b.end < 0 {
+		return nil, fmt.Errorf("%s is never closed by %s", Begin, End)
+	}
+	return b, nil
+}
+
+func withNewline(s string) string {
+	if s == "" || strings.HasSuffix(s, "\n") {
+		return s
+	}
+	return s + "\n"
+}
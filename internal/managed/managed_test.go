@@ -0,0 +1,62 @@
+package managed
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestApply(t *testing.T) {
+	block := Begin + "\nsynced\n" + End + "\n"
+	tests := []struct {
+		name, existing, position, want string
+	}{
+		{"empty file", "", Top, block},
+		{"top", "# Codex notes\n", Top, block + "\n# Codex notes\n"},
+		{"bottom", "# Codex notes", Bottom, "# Codex notes\n\n" + block},
+		{"replace", "before\n" + Begin + "\nold\n" + End + "\nafter\n", Top, "before\n" + block + "after\n"},
+		{"replace at end of file", "before\n" + Begin + "\nold\n" + End, Bottom, "before\n" + block},
+	}
+	for _, tt := range tests {
+		got, err := Apply(tt.existing, "synced", tt.position)
+		if err != nil {
+			t.Fatalf("%s: %v", tt.name, err)
+		}
+		if got != tt.want {
+			t.Errorf("%s: got %q, want %q", tt.name, got, tt.want)
+		}
+	}
+}
+
+func TestApplyStripsNestedMarkers(t *testing.T) {
+	got, err := Apply("", "a\n"+Begin+"\nb\n"+End+"\n", Top)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got != Begin+"\na\nb\n"+End+"\n" {
+		t.Errorf("expected markers from the source dropped, got %q", got)
+	}
+}
+
+func TestApplyRejectsMalformedBlocks(t *testing.T) {
+	tests := []struct{ existing, want string }{
+		{Begin + "\nx\n", "never closed"},
+		{"x\n" + End + "\n", "line 2: " + End + " without a matching"},
+		{Begin + "\n" + End + "\n" + Begin + "\n" + End + "\n", "more than one"},
+		{"  " + Begin + "\n" + End + "\n", "on a line of its own"},
+	}
+	for _, tt := range tests {
+		if _, err := Apply(tt.existing, "synced", Top); err == nil || !strings.Contains(err.Error(), tt.want) {
+			t.Errorf("Apply(%q) = %v, want %q", tt.existing, err, tt.want)
+		}
+	}
+}
+
+func TestExtract(t *testing.T) {
+	body, ok, err := Extract("notes\n" + Begin + "\nsynced\n" + End + "\n")
+	if err != nil || !ok || body != "synced\n" {
+		t.Fatalf("Extract = %q, %t, %v", body, ok, err)
+	}
+	if _, ok, _ := Extract("notes\n"); ok {
+		t.Fatal("expected no block")
+	}
+}
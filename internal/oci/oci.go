@@ -0,0 +1,507 @@
+package oci
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"net/url"
+	"os"
+	"regexp"
+	"strings"
+	"time"
+
+	"github.com/LaneBirmingham/coding-agent-sync/internal/archive"
+)
+
+// Media types of cas archives stored as OCI artifacts.
+const (
+	ArtifactType      = "application/vnd.cas.archive.v1"
+	LayerTypeZip      = "application/vnd.cas.archive.v1+zip"
+	LayerTypeTarGz    = "application/vnd.cas.archive.v1.tar+gzip"
+	ManifestMediaType = "application/vnd.oci.image.manifest.v1+json"
+
+	emptyMediaType = "application/vnd.oci.empty.v1+json"
+	emptyDigest    = "sha256:44136fa355b3678a1146ad16f7e8649e94fb4fc21fe77e8310c060f61caaff8a"
+
+	// AnnotationManifest carries the cas archive manifest as JSON, without
+	// the per-entry checksums that the archive digest already covers.
+	AnnotationManifest = "dev.cas.archive.manifest"
+	AnnotationCreated  = "org.opencontainers.image.created"
+	AnnotationTitle    = "org.opencontainers.image.title"
+
+	// maxManifestSize bounds the OCI manifest read from a registry.
+	maxManifestSize = 4 << 20
+)
+
+// ErrNotArchive is returned when a pulled artifact does not hold a cas archive.
+var ErrNotArchive = errors.New("artifact is not a cas archive")
+
+// Reference names a repository in an OCI registry and a tag or digest in it.
+type Reference struct {
+	Registry   string // host[:port]
+	Repository string
+	Tag        string
+	Digest     string // sha256:<hex>; takes precedence over Tag
+}
+
+var (
+	repositoryRE = regexp.MustCompile(`^[a-z0-9]+(?:(?:[._]|__|-+)[a-z0-9]+)*(?:/[a-z0-9]+(?:(?:[._]|__|-+)[a-z0-9]+)*)*$`)
+	tagRE        = regexp.MustCompile(`^[A-Za-z0-9_][A-Za-z0-9._-]{0,127}$`)
+	digestRE     = regexp.MustCompile(`^sha256:[a-f0-9]{64}$`)
+)
+
+// IsReference reports whether s uses the oci:// scheme.
+func IsReference(s string) bool { return strings.HasPrefix(s, "oci://") }
+
+// ParseReference parses oci://host[:port]/repository[:tag|@digest]. The tag
+// defaults to latest.
+func ParseReference(s string) (Reference, error) {
+	rest, ok := strings.CutPrefix(s, "oci://")
+	if !ok {
+		return Reference{}, fmt.Errorf("invalid OCI reference %q (expected oci://registry/repository[:tag])", s)
+	}
+	host, repo, ok := strings.Cut(rest, "/")
+	if !ok || host == "" {
+		return Reference{}, fmt.Errorf("invalid OCI reference %q (expected oci://registry/repository[:tag])", s)
+	}
+	ref := Reference{Registry: host, Tag: "latest"}
+	if name, digest, ok := strings.Cut(repo, "@"); ok {
+		repo, ref.Digest, ref.Tag = name, digest, ""
+		if !digestRE.MatchString(digest) {
+			return Reference{}, fmt.Errorf("invalid OCI reference %q: bad digest %q", s, digest)
+		}
+	} else if i := strings.LastIndex(repo, ":"); i >= 0 {
+		repo, ref.Tag = repo[:i], repo[i+1:]
+		if !tagRE.MatchString(ref.Tag) {
+			return Reference{}, fmt.Errorf("invalid OCI reference %q: bad tag %q", s, ref.Tag)
+		}
+	}
+	if !repositoryRE.MatchString(repo) {
+		return Reference{}, fmt.Errorf("invalid OCI reference %q: bad repository %q", s, repo)
+	}
+	ref.Repository = repo
+	return ref, nil
+}
+
+func (r Reference) String() string {
+	if r.Digest != "" {
+		return "oci://" + r.Registry + "/" + r.Repository + "@" + r.Digest
+	}
+	return "oci://" + r.Registry + "/" + r.Repository + ":" + r.Tag
+}
+
+// reference returns the tag or digest used in manifest URLs.
+func (r Reference) reference() string {
+	if r.Digest != "" {
+		return r.Digest
+	}
+	return r.Tag
+}
+
+// Descriptor points at a blob in a registry.
+type Descriptor struct {
+	MediaType   string            `json:"mediaType"`
+	Digest      string            `json:"digest"`
+	Size        int64             `json:"size"`
+	Data        []byte            `json:"data,omitempty"`
+	Annotations map[string]string `json:"annotations,omitempty"`
+}
+
+// Manifest is an OCI image manifest describing an artifact.
+type Manifest struct {
+	SchemaVersion int               `json:"schemaVersion"`
+	MediaType     string            `json:"mediaType"`
+	ArtifactType  string            `json:"artifactType,omitempty"`
+	Config        Descriptor        `json:"config"`
+	Layers        []Descriptor      `json:"layers"`
+	Annotations   map[string]string `json:"annotations,omitempty"`
+}
+
+// Client talks to OCI registries using the distribution API.
+type Client struct {
+	HTTP *http.Client
+	// PlainHTTP uses http instead of https. Loopback registries always use http.
+	PlainHTTP bool
+	// Username and Password authenticate to registries that ask for it.
+	Username string
+	Password string
+
+	token string // bearer token from the last challenge
+}
+
+// NewClient returns a client with credentials from $CAS_OCI_USERNAME and $CAS_OCI_PASSWORD.
+func NewClient() *Client {
+	return &Client{
+		HTTP:     &http.Client{Timeout: 5 * time.Minute},
+		Username: os.Getenv("CAS_OCI_USERNAME"),
+		Password: os.Getenv("CAS_OCI_PASSWORD"),
+	}
+}
+
+// Push uploads an archive as a single-layer artifact and tags it, returning
+// the digest of the OCI manifest.
+func (c *Client) Push(ctx context.Context, ref Reference, data []byte, layerType string, annotations map[string]string) (string, error) {
+	if ref.Digest != "" {
+		return "", fmt.Errorf("push needs a tag, not a digest: %s", ref)
+	}
+	if err := c.pushBlob(ctx, ref, []byte("{}"), emptyDigest); err != nil {
+		return "", err
+	}
+	layer := Descriptor{
+		MediaType:   layerType,
+		Digest:      digestOf(data),
+		Size:        int64(len(data)),
+		Annotations: map[string]string{AnnotationTitle: "cas-archive" + layerExt(layerType)},
+	}
+	if err := c.pushBlob(ctx, ref, data, layer.Digest); err != nil {
+		return "", err
+	}
+
+	m := Manifest{
+		SchemaVersion: 2,
+		MediaType:     ManifestMediaType,
+		ArtifactType:  ArtifactType,
+		Config:        Descriptor{MediaType: emptyMediaType, Digest: emptyDigest, Size: 2, Data: []byte("{}")},
+		Layers:        []Descriptor{layer},
+		Annotations:   annotations,
+	}
+	manifest, err := json.Marshal(m)
+	if err != nil {
+		return "", fmt.Errorf("marshaling OCI manifest: %w", err)
+	}
+	resp, err := c.do(ctx, ref, http.MethodPut, c.url(ref, "/manifests/"+ref.Tag), ManifestMediaType, manifest, nil)
+	if err != nil {
+		return "", err
+	}
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusCreated {
+		return "", registryError("pushing manifest", resp)
+	}
+	return digestOf(manifest), nil
+}
+
+// Pull fetches the archive stored at ref, returning its bytes and the OCI
+// manifest. The archive must be at most maxSize bytes.
+func (c *Client) Pull(ctx context.Context, ref Reference, maxSize int64) ([]byte, *Manifest, error) {
+	resp, err := c.do(ctx, ref, http.MethodGet, c.url(ref, "/manifests/"+ref.reference()), "", nil, http.Header{"Accept": {ManifestMediaType}})
+	if err != nil {
+		return nil, nil, err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return nil, nil, registryError("fetching manifest", resp)
+	}
+	data, err := readLimited(resp.Body, maxManifestSize)
+	if err != nil {
+		return nil, nil, fmt.Errorf("fetching manifest: %w", err)
+	}
+	if ref.Digest != "" && digestOf(data) != ref.Digest {
+		return nil, nil, fmt.Errorf("manifest for %s does not match its digest", ref)
+	}
+
+	var m Manifest
+	if err := json.Unmarshal(data, &m); err != nil {
+		return nil, nil, fmt.Errorf("parsing manifest: %w", err)
+	}
+	if m.ArtifactType != ArtifactType || len(m.Layers) != 1 {
+		return nil, nil, fmt.Errorf("%s: %w", ref, ErrNotArchive)
+	}
+	layer := m.Layers[0]
+	if layer.MediaType != LayerTypeZip && layer.MediaType != LayerTypeTarGz {
+		return nil, nil, fmt.Errorf("%s: %w (layer type %s)", ref, ErrNotArchive, layer.MediaType)
+	}
+	if layer.Size > maxSize {
+		return nil, nil, fmt.Errorf("%s: archive is %d bytes, limit %d", ref, layer.Size, maxSize)
+	}
+	// The digest names the blob to fetch, so check it before it becomes
+	// part of a URL.
+	if !digestRE.MatchString(layer.Digest) {
+		return nil, nil, fmt.Errorf("%s: manifest has a bad layer digest %q", ref, layer.Digest)
+	}
+
+	blob, err := c.do(ctx, ref, http.MethodGet, c.url(ref, "/blobs/"+layer.Digest), "", nil, nil)
+	if err != nil {
+		return nil, nil, err
+	}
+	defer blob.Body.Close()
+	if blob.StatusCode != http.StatusOK {
+		return nil, nil, registryError("fetching archive", blob)
+	}
+	content, err := readLimited(blob.Body, layer.Size)
+	if err != nil {
+		return nil, nil, fmt.Errorf("fetching archive: %w", err)
+	}
+	if int64(len(content)) != layer.Size || digestOf(content) != layer.Digest {
+		return nil, nil, fmt.Errorf("archive blob for %s does not match its digest", ref)
+	}
+	return content, &m, nil
+}
+
+// pushBlob uploads data in one request unless the registry already has it.
+func (c *Client) pushBlob(ctx context.Context, ref Reference, data []byte, digest string) error {
+	head, err := c.do(ctx, ref, http.MethodHead, c.url(ref, "/blobs/"+digest), "", nil, nil)
+	if err != nil {
+		return err
+	}
+	head.Body.Close()
+	if head.StatusCode == http.StatusOK {
+		return nil
+	}
+
+	start, err := c.do(ctx, ref, http.MethodPost, c.url(ref, "/blobs/uploads/"), "", nil, nil)
+	if err != nil {
+		return err
+	}
+	start.Body.Close()
+	if start.StatusCode != http.StatusAccepted {
+		return registryError("starting blob upload", start)
+	}
+	loc, err := start.Request.URL.Parse(start.Header.Get("Location"))
+	if err != nil || start.Header.Get("Location") == "" {
+		return fmt.Errorf("starting blob upload: registry returned no upload location")
+	}
+	q := loc.Query()
+	q.Set("digest", digest)
+	loc.RawQuery = q.Encode()
+
+	resp, err := c.do(ctx, ref, http.MethodPut, loc.String(), "application/octet-stream", data, nil)
+	if err != nil {
+		return err
+	}
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusCreated {
+		return registryError("uploading blob", resp)
+	}
+	return nil
+}
+
+func (c *Client) url(ref Reference, path string) string {
+	scheme := "https"
+	if c.PlainHTTP || isLoopback(ref.Registry) {
+		scheme = "http"
+	}
+	return scheme + "://" + ref.Registry + "/v2/" + ref.Repository + path
+}
+
+// do sends a request, answering one authentication challenge if the registry
+// responds with 401.
+func (c *Client) do(ctx context.Context, ref Reference, method, u, contentType string, body []byte, header http.Header) (*http.Response, error) {
+	send := func() (*http.Response, error) {
+		req, err := http.NewRequestWithContext(ctx, method, u, bytes.NewReader(body))
+		if err != nil {
+			return nil, err
+		}
+		for k, v := range header {
+			req.Header[k] = v
+		}
+		if contentType != "" {
+			req.Header.Set("Content-Type", contentType)
+		}
+		if c.token != "" {
+			req.Header.Set("Authorization", "Bearer "+c.token)
+		} else if c.Username != "" {
+			req.SetBasicAuth(c.Username, c.Password)
+		}
+		resp, err := c.HTTP.Do(req)
+		if err != nil {
+			return nil, fmt.Errorf("contacting %s: %w", ref.Registry, err)
+		}
+		return resp, nil
+	}
+
+	resp, err := send()
+	if err != nil || resp.StatusCode != http.StatusUnauthorized {
+		return resp, err
+	}
+	challenge := resp.Header.Get("WWW-Authenticate")
+	resp.Body.Close()
+	if err := c.authenticate(ctx, ref, challenge); err != nil {
+		return nil, fmt.Errorf("authenticating to %s: %w", ref.Registry, err)
+	}
+	return send()
+}
+
+// authenticate answers a Bearer challenge by fetching a token, or a Basic
+// challenge by checking that credentials are configured.
+func (c *Client) authenticate(ctx context.Context, ref Reference, challenge string) error {
+	scheme, params := parseChallenge(challenge)
+	switch scheme {
+	case "basic":
+		if c.Username == "" {
+			return fmt.Errorf("registry requires credentials (set CAS_OCI_USERNAME and CAS_OCI_PASSWORD)")
+		}
+		return fmt.Errorf("registry rejected the credentials")
+	case "bearer":
+	default:
+		return fmt.Errorf("unsupported challenge %q", challenge)
+	}
+
+	realm, err := url.Parse(params["realm"])
+	if err != nil || params["realm"] == "" {
+		return fmt.Errorf("bearer challenge has no realm")
+	}
+	q := realm.Query()
+	for _, k := range []string{"service", "scope"} {
+		if params[k] != "" {
+			q.Set(k, params[k])
+		}
+	}
+	realm.RawQuery = q.Encode()
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, realm.String(), nil)
+	if err != nil {
+		return err
+	}
+	withheld := c.Username != "" && !c.trustsRealm(ref, realm)
+	if c.Username != "" && !withheld {
+		req.SetBasicAuth(c.Username, c.Password)
+	}
+	resp, err := c.HTTP.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		err := registryError("fetching token", resp)
+		if withheld {
+			err = fmt.Errorf("%w (credentials are only sent to a realm on %s over https)", err, ref.Registry)
+		}
+		return err
+	}
+	var tok struct {
+		Token       string `json:"token"`
+		AccessToken string `json:"access_token"`
+	}
+	data, err := readLimited(resp.Body, 1<<20)
+	if err != nil {
+		return err
+	}
+	if err := json.Unmarshal(data, &tok); err != nil {
+		return fmt.Errorf("parsing token: %w", err)
+	}
+	c.token = tok.Token
+	if c.token == "" {
+		c.token = tok.AccessToken
+	}
+	if c.token == "" {
+		return fmt.Errorf("token response has no token")
+	}
+	return nil
+}
+
+// parseChallenge splits a WWW-Authenticate header into its lowercased scheme
+// and its parameters.
+func parseChallenge(h string) (string, map[string]string) {
+	scheme, rest, _ := strings.Cut(strings.TrimSpace(h), " ")
+	params := map[string]string{}
+	for rest != "" {
+		var key, val string
+		key, rest, _ = strings.Cut(strings.TrimLeft(rest, " ,"), "=")
+		if strings.HasPrefix(rest, `"`) {
+			val, rest, _ = strings.Cut(rest[1:], `"`)
+		} else {
+			val, rest, _ = strings.Cut(rest, ",")
+		}
+		params[strings.ToLower(strings.TrimSpace(key))] = val
+	}
+	return strings.ToLower(scheme), params
+}
+
+// registryError describes a failed registry response, including the
+// distribution API error messages when present.
+func registryError(action string, resp *http.Response) error {
+	var body struct {
+		Errors []struct {
+			Code    string `json:"code"`
+			Message string `json:"message"`
+		} `json:"errors"`
+	}
+	if data, err := readLimited(resp.Body, 64<<10); err == nil {
+		_ = json.Unmarshal(data, &body)
+	}
+	var msgs []string
+	for _, e := range body.Errors {
+		msgs = append(msgs, strings.TrimSpace(e.Code+" "+e.Message))
+	}
+	if len(msgs) > 0 {
+		return fmt.Errorf("%s: %s: %s", action, resp.Status, strings.Join(msgs, "; "))
+	}
+	return fmt.Errorf("%s: %s", action, resp.Status)
+}
+
+func readLimited(r io.Reader, max int64) ([]byte, error) {
+	data, err := io.ReadAll(io.LimitReader(r, max+1))
+	if err != nil {
+		return nil, err
+	}
+	if int64(len(data)) > max {
+		return nil, fmt.Errorf("response larger than %d bytes", max)
+	}
+	return data, nil
+}
+
+func digestOf(data []byte) string {
+	sum := sha256.Sum256(data)
+	return "sha256:" + hex.EncodeToString(sum[:])
+}
+
+// LayerType returns the layer media type for an archive container format.
+func LayerType(format archive.Format) string {
+	if format == archive.FormatTarGz {
+		return LayerTypeTarGz
+	}
+	return LayerTypeZip
+}
+
+func layerExt(layerType string) string {
+	if layerType == LayerTypeTarGz {
+		return ".tar.gz"
+	}
+	return ".zip"
+}
+
+// trustsRealm reports whether credentials for ref's registry may be sent to
+// a token realm: it must be on the registry's host and use https, or the
+// scheme the registry itself is reached with.
+func (c *Client) trustsRealm(ref Reference, realm *url.URL) bool {
+	host := ref.Registry
+	if h, _, err := net.SplitHostPort(host); err == nil {
+		host = h
+	}
+	if !strings.EqualFold(realm.Hostname(), strings.Trim(host, "[]")) {
+		return false
+	}
+	return realm.Scheme == "https" || (realm.Scheme == "http" && (c.PlainHTTP || isLoopback(ref.Registry)))
+}
+
+func isLoopback(host string) bool {
+	if h, _, err := net.SplitHostPort(host); err == nil {
+		host = h
+	}
+	if host == "localhost" {
+		return true
+	}
+	ip := net.ParseIP(strings.Trim(host, "[]"))
+	return ip != nil && ip.IsLoopback()
+}
+
+// Annotations describes a cas archive manifest as OCI manifest annotations.
+func Annotations(m *archive.Manifest) (map[string]string, error) {
+	summary := *m
+	summary.Files = nil
+	data, err := json.Marshal(summary)
+	if err != nil {
+		return nil, fmt.Errorf("marshaling archive manifest: %w", err)
+	}
+	return map[string]string{
+		AnnotationManifest: string(data),
+		AnnotationCreated:  m.ExportedAt.UTC().Format(time.RFC3339),
+	}, nil
+}
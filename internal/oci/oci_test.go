@@ -0,0 +1,188 @@
+package oci
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/LaneBirmingham/coding-agent-sync/internal/archive"
+	"github.com/LaneBirmingham/coding-agent-sync/internal/oci/ocitest"
+)
+
+func TestParseReference(t *testing.T) {
+	tests := []struct {
+		in   string
+		want Reference
+	}{
+		{"oci://ghcr.io/team/agents:1.2", Reference{Registry: "ghcr.io", Repository: "team/agents", Tag: "1.2"}},
+		{"oci://localhost:5000/agents", Reference{Registry: "localhost:5000", Repository: "agents", Tag: "latest"}},
+		{"oci://r.example/a/b@sha256:" + strings.Repeat("a", 64), Reference{Registry: "r.example", Repository: "a/b", Digest: "sha256:" + strings.Repeat("a", 64)}},
+	}
+	for _, tt := range tests {
+		got, err := ParseReference(tt.in)
+		if err != nil {
+			t.Fatalf("ParseReference(%q): %v", tt.in, err)
+		}
+		if got != tt.want {
+			t.Errorf("ParseReference(%q) = %+v, want %+v", tt.in, got, tt.want)
+		}
+		if got.String() != tt.in && tt.want.Tag != "latest" {
+			t.Errorf("String() = %q, want %q", got.String(), tt.in)
+		}
+	}
+	for _, bad := range []string{"ghcr.io/team", "oci://ghcr.io", "oci://ghcr.io/Team:1", "oci://ghcr.io/team:bad tag", "oci://ghcr.io/team@md5:1"} {
+		if _, err := ParseReference(bad); err == nil {
+			t.Errorf("expected ParseReference(%q) to fail", bad)
+		}
+	}
+}
+
+func TestPushPull(t *testing.T) {
+	reg := ocitest.NewRegistry()
+	defer reg.Close()
+	reg.RequireAuth("ci", "secret")
+
+	ref, err := ParseReference("oci://" + reg.Host() + "/team/agents:1.2")
+	if err != nil {
+		t.Fatal(err)
+	}
+	m := &archive.Manifest{Version: archive.FormatVersion, Agent: "claude", Scope: "local", ExportedAt: time.Date(2024, 1, 2, 3, 4, 5, 0, time.UTC), Digest: "sha256:abc", Files: map[string]string{"instructions.md": "x"}}
+	annotations, err := Annotations(m)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	anonymous := &Client{HTTP: reg.Client()}
+	if _, err := anonymous.Push(context.Background(), ref, []byte("PK archive"), LayerTypeZip, annotations); err == nil {
+		t.Fatal("expected push without credentials to fail")
+	}
+
+	c := &Client{HTTP: reg.Client(), Username: "ci", Password: "secret"}
+	digest, err := c.Push(context.Background(), ref, []byte("PK archive"), LayerTypeZip, annotations)
+	if err != nil {
+		t.Fatalf("push: %v", err)
+	}
+
+	var stored Manifest
+	if err := json.Unmarshal(reg.Manifest("team/agents", "1.2"), &stored); err != nil {
+		t.Fatal(err)
+	}
+	if stored.ArtifactType != ArtifactType || stored.Layers[0].MediaType != LayerTypeZip {
+		t.Fatalf("unexpected stored manifest %+v", stored)
+	}
+	var carried archive.Manifest
+	if err := json.Unmarshal([]byte(stored.Annotations[AnnotationManifest]), &carried); err != nil {
+		t.Fatal(err)
+	}
+	if carried.Agent != "claude" || carried.Digest != "sha256:abc" || carried.Files != nil {
+		t.Fatalf("unexpected manifest annotation %+v", carried)
+	}
+	if stored.Annotations[AnnotationCreated] != "2024-01-02T03:04:05Z" {
+		t.Fatalf("unexpected created annotation %q", stored.Annotations[AnnotationCreated])
+	}
+
+	data, _, err := c.Pull(context.Background(), ref, 1<<20)
+	if err != nil {
+		t.Fatalf("pull: %v", err)
+	}
+	if !bytes.Equal(data, []byte("PK archive")) {
+		t.Fatalf("unexpected pulled data %q", data)
+	}
+
+	byDigest := ref
+	byDigest.Tag, byDigest.Digest = "", digest
+	if _, _, err := c.Pull(context.Background(), byDigest, 1<<20); err != nil {
+		t.Fatalf("pull by digest: %v", err)
+	}
+	if _, _, err := c.Pull(context.Background(), ref, 4); err == nil {
+		t.Fatal("expected pull over the size limit to fail")
+	}
+
+	reg.SetBlob(stored.Layers[0].Digest, []byte("PK tampered"))
+	if _, _, err := c.Pull(context.Background(), ref, 1<<20); err == nil {
+		t.Fatal("expected a tampered blob to fail")
+	}
+}
+
+func TestPullRejectsOtherArtifacts(t *testing.T) {
+	reg := ocitest.NewRegistry()
+	defer reg.Close()
+	ref, _ := ParseReference("oci://" + reg.Host() + "/images/app:1")
+
+	c := &Client{HTTP: reg.Client()}
+	if _, err := c.Push(context.Background(), ref, []byte("layer"), "application/vnd.oci.image.layer.v1.tar", nil); err != nil {
+		t.Fatal(err)
+	}
+	if _, _, err := c.Pull(context.Background(), ref, 1<<20); !errors.Is(err, ErrNotArchive) {
+		t.Fatalf("expected ErrNotArchive, got %v", err)
+	}
+}
+
+func TestPullRejectsBadLayerDigest(t *testing.T) {
+	reg := ocitest.NewRegistry()
+	defer reg.Close()
+	ref, _ := ParseReference("oci://" + reg.Host() + "/team/agents:1")
+
+	m := Manifest{ArtifactType: ArtifactType, Layers: []Descriptor{{MediaType: LayerTypeZip, Digest: "../../../admin?x=", Size: 4}}}
+	data, err := json.Marshal(m)
+	if err != nil {
+		t.Fatal(err)
+	}
+	reg.SetManifest("team/agents", "1", data)
+
+	c := &Client{HTTP: reg.Client()}
+	if _, _, err := c.Pull(context.Background(), ref, 1<<20); err == nil || !strings.Contains(err.Error(), "bad layer digest") {
+		t.Fatalf("expected a bad layer digest to be rejected, got %v", err)
+	}
+}
+
+func TestCredentialsWithheldFromOtherRealms(t *testing.T) {
+	var sent bool
+	foreign := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		_, _, sent = req.BasicAuth()
+		w.WriteHeader(http.StatusUnauthorized)
+	}))
+	defer foreign.Close()
+
+	reg := ocitest.NewRegistry()
+	defer reg.Close()
+	reg.RequireAuth("ci", "secret")
+	reg.SetRealm(strings.Replace(foreign.URL, "127.0.0.1", "localhost", 1) + "/token")
+
+	ref, _ := ParseReference("oci://" + reg.Host() + "/team/agents:1")
+	c := &Client{HTTP: reg.Client(), Username: "ci", Password: "secret"}
+	if _, err := c.Push(context.Background(), ref, []byte("PK archive"), LayerTypeZip, nil); err == nil || !strings.Contains(err.Error(), "only sent to a realm") {
+		t.Fatalf("expected the token request to fail without credentials, got %v", err)
+	}
+	if sent {
+		t.Fatal("expected credentials to be withheld from a realm on another host")
+	}
+
+	tests := []struct {
+		registry, realm string
+		plain           bool
+		want            bool
+	}{
+		{"registry.example.com", "https://registry.example.com/token", false, true},
+		{"registry.example.com", "https://REGISTRY.example.com:8443/token", false, true},
+		{"registry.example.com", "http://registry.example.com/token", false, false},
+		{"registry.example.com", "http://registry.example.com/token", true, true},
+		{"registry.example.com", "https://auth.example.com/token", false, false},
+		{"registry.example.com", "https://auth.example.com/token", true, false},
+		{"127.0.0.1:5000", "http://127.0.0.1:5001/token", false, true},
+	}
+	for _, tt := range tests {
+		realm, _ := url.Parse(tt.realm)
+		c := &Client{PlainHTTP: tt.plain}
+		if got := c.trustsRealm(Reference{Registry: tt.registry}, realm); got != tt.want {
+			t.Errorf("trustsRealm(%s, %s, plain=%t) = %t, want %t", tt.registry, tt.realm, tt.plain, got, tt.want)
+		}
+	}
+}
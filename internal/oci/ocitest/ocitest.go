@@ -0,0 +1,172 @@
+package ocitest
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+)
+
+// Registry is an in-memory stand-in for an OCI distribution registry,
+// supporting monolithic blob uploads and manifests by tag or digest.
+type Registry struct {
+	*httptest.Server
+
+	mu        sync.Mutex
+	blobs     map[string][]byte
+	manifests map[string][]byte // "<repo>:<tag or digest>"
+	uploads   int
+
+	username, password string // credentials required by RequireAuth
+	realm              string // token endpoint named in challenges; defaults to /token
+}
+
+// testToken is the bearer token the registry issues after RequireAuth.
+const testToken = "ocitest-token"
+
+// RequireAuth makes the registry answer unauthenticated requests with a
+// Bearer challenge whose token endpoint accepts these credentials.
+func (r *Registry) RequireAuth(username, password string) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.username, r.password = username, password
+}
+
+// SetRealm makes challenges name realm as the token endpoint instead of
+// the registry's own /token.
+func (r *Registry) SetRealm(realm string) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.realm = realm
+}
+
+// NewRegistry starts a registry on a loopback address. Close it when done.
+func NewRegistry() *Registry {
+	r := &Registry{blobs: map[string][]byte{}, manifests: map[string][]byte{}}
+	r.Server = httptest.NewServer(http.HandlerFunc(r.serve))
+	return r
+}
+
+// Host returns the host:port to use in oci:// references.
+func (r *Registry) Host() string {
+	return strings.TrimPrefix(r.URL, "http://")
+}
+
+// Manifest returns the stored manifest for repo and tag, or nil.
+func (r *Registry) Manifest(repo, tag string) []byte {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return r.manifests[repo+":"+tag]
+}
+
+// SetManifest stores a manifest for repo and tag as given, for simulating
+// a hostile registry.
+func (r *Registry) SetManifest(repo, tag string, data []byte) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.manifests[repo+":"+tag] = data
+}
+
+// SetBlob replaces a stored blob, for simulating corruption.
+func (r *Registry) SetBlob(digest string, data []byte) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.blobs[digest] = data
+}
+
+func (r *Registry) serve(w http.ResponseWriter, req *http.Request) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if r.username != "" {
+		if req.URL.Path == "/token" {
+			if user, pass, ok := req.BasicAuth(); !ok || user != r.username || pass != r.password {
+				writeError(w, http.StatusUnauthorized, "UNAUTHORIZED")
+				return
+			}
+			fmt.Fprintf(w, `{"token":%q}`, testToken)
+			return
+		}
+		if req.Header.Get("Authorization") != "Bearer "+testToken {
+			realm := r.realm
+			if realm == "" {
+				realm = r.URL + "/token"
+			}
+			w.Header().Set("WWW-Authenticate", fmt.Sprintf(`Bearer realm="%s",service="ocitest",scope="repository:any:pull,push"`, realm))
+			writeError(w, http.StatusUnauthorized, "UNAUTHORIZED")
+			return
+		}
+	}
+
+	path, ok := strings.CutPrefix(req.URL.Path, "/v2/")
+	if !ok {
+		http.NotFound(w, req)
+		return
+	}
+
+	switch {
+	case strings.HasSuffix(path, "/blobs/uploads/") && req.Method == http.MethodPost:
+		r.uploads++
+		w.Header().Set("Location", fmt.Sprintf("/v2/%supload-%d", path, r.uploads))
+		w.WriteHeader(http.StatusAccepted)
+
+	case strings.Contains(path, "/blobs/uploads/") && req.Method == http.MethodPut:
+		data, _ := io.ReadAll(req.Body)
+		digest := req.URL.Query().Get("digest")
+		if digestOf(data) != digest {
+			writeError(w, http.StatusBadRequest, "DIGEST_INVALID")
+			return
+		}
+		r.blobs[digest] = data
+		w.WriteHeader(http.StatusCreated)
+
+	case strings.Contains(path, "/blobs/"):
+		digest := path[strings.LastIndex(path, "/")+1:]
+		data, ok := r.blobs[digest]
+		if !ok {
+			writeError(w, http.StatusNotFound, "BLOB_UNKNOWN")
+			return
+		}
+		if req.Method == http.MethodGet {
+			w.Write(data)
+		}
+
+	case strings.Contains(path, "/manifests/"):
+		i := strings.LastIndex(path, "/manifests/")
+		repo, ref := path[:i], path[i+len("/manifests/"):]
+		switch req.Method {
+		case http.MethodPut:
+			data, _ := io.ReadAll(req.Body)
+			r.manifests[repo+":"+ref] = data
+			r.manifests[repo+":"+digestOf(data)] = data
+			w.Header().Set("Docker-Content-Digest", digestOf(data))
+			w.WriteHeader(http.StatusCreated)
+		default:
+			data, ok := r.manifests[repo+":"+ref]
+			if !ok {
+				writeError(w, http.StatusNotFound, "MANIFEST_UNKNOWN")
+				return
+			}
+			w.Header().Set("Content-Type", "application/vnd.oci.image.manifest.v1+json")
+			w.Write(data)
+		}
+
+	default:
+		http.NotFound(w, req)
+	}
+}
+
+func writeError(w http.ResponseWriter, status int, code string) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	fmt.Fprintf(w, `{"errors":[{"code":%q,"message":"%s"}]}`, code, strings.ToLower(strings.ReplaceAll(code, "_", " ")))
+}
+
+func digestOf(data []byte) string {
+	sum := sha256.Sum256(data)
+	return "sha256:" + hex.EncodeToString(sum[:])
+}
@@ -0,0 +1,298 @@
+package registry
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"time"
+)
+
+const (
+	indexFile = "index.json"
+	lockFile  = "index.lock"
+
+	lockWait  = 10 * time.Second
+	lockRetry = 100 * time.Millisecond
+)
+
+// ErrNotFound is returned when no published version matches a request.
+var ErrNotFound = errors.New("no matching bundle in registry")
+
+// Registry is a directory of published bundles, such as a shared or NFS
+// mount. Each bundle version is stored as <name>/<version>.zip and listed in
+// index.json.
+type Registry struct {
+	Dir string
+}
+
+// Index lists the published versions of each bundle.
+type Index struct {
+	Bundles map[string][]Entry `json:"bundles"`
+}
+
+// Entry records one published bundle version.
+type Entry struct {
+	Version     string    `json:"version"`
+	Path        string    `json:"path"` // relative to the registry directory
+	SHA256      string    `json:"sha256"`
+	PublishedAt time.Time `json:"published_at"`
+}
+
+// ValidateName checks that a bundle name is safe to use as a directory name.
+func ValidateName(name string) error {
+	if name == "" {
+		return fmt.Errorf("bundle name must not be empty")
+	}
+	if name[0] == '.' || name[0] == '-' {
+		return fmt.Errorf("bundle name %q must start with a letter or digit", name)
+	}
+	for _, r := range name {
+		if (r < 'a' || r > 'z') && (r < '0' || r > '9') && r != '-' && r != '_' && r != '.' {
+			return fmt.Errorf("bundle name %q must contain only lowercase letters, digits, '-', '_' or '.'", name)
+		}
+	}
+	return nil
+}
+
+// ParseRef splits "name@constraint" into its parts. The constraint is
+// empty when omitted, which selects the latest release.
+func ParseRef(ref string) (string, Constraint, error) {
+	name, constraint, _ := strings.Cut(ref, "@")
+	if err := ValidateName(name); err != nil {
+		return "", Constraint{}, err
+	}
+	c, err := ParseConstraint(constraint)
+	if err != nil {
+		return "", Constraint{}, err
+	}
+	return name, c, nil
+}
+
+// ArchivePath returns where a bundle version's archive is stored.
+func (r *Registry) ArchivePath(name string, v Version) string {
+	return filepath.Join(r.Dir, name, v.String()+".zip")
+}
+
+// Index reads the registry index. A registry without an index is empty.
+func (r *Registry) Index() (*Index, error) {
+	data, err := os.ReadFile(filepath.Join(r.Dir, indexFile))
+	if os.IsNotExist(err) {
+		if _, statErr := os.Stat(r.Dir); statErr != nil {
+			return nil, fmt.Errorf("opening registry: %w", statErr)
+		}
+		return &Index{Bundles: map[string][]Entry{}}, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("reading registry index: %w", err)
+	}
+	var idx Index
+	if err := json.Unmarshal(data, &idx); err != nil {
+		return nil, fmt.Errorf("parsing registry index: %w", err)
+	}
+	if idx.Bundles == nil {
+		idx.Bundles = map[string][]Entry{}
+	}
+	return &idx, nil
+}
+
+// Names returns the bundle names in the index, sorted.
+func (idx *Index) Names() []string {
+	names := make([]string, 0, len(idx.Bundles))
+	for name := range idx.Bundles {
+		names = append(names, name)
+	}
+	slices.Sort(names)
+	return names
+}
+
+// CheckUnpublished returns an error if the version is already published, so
+// a publish can fail early. Add checks again under the lock.
+func (r *Registry) CheckUnpublished(name string, v Version) error {
+	idx, err := r.Index()
+	if err != nil && !errors.Is(err, os.ErrNotExist) {
+		return err
+	}
+	if idx != nil && slices.ContainsFunc(idx.Bundles[name], func(e Entry) bool { return e.Version == v.String() }) {
+		return fmt.Errorf("%s@%s is already published; versions are immutable", name, v)
+	}
+	if _, err := os.Stat(r.ArchivePath(name, v)); err == nil {
+		return fmt.Errorf("%s already exists; versions are immutable", r.ArchivePath(name, v))
+	}
+	return nil
+}
+
+// CreateTemp creates an empty file in the registry directory for a publish
+// to write its archive to before Add moves it into place.
+func (r *Registry) CreateTemp() (string, error) {
+	if err := os.MkdirAll(r.Dir, 0o755); err != nil {
+		return "", fmt.Errorf("creating registry: %w", err)
+	}
+	f, err := os.CreateTemp(r.Dir, ".publish-*.zip")
+	if err != nil {
+		return "", fmt.Errorf("creating archive in registry: %w", err)
+	}
+	if err := f.Close(); err != nil {
+		os.Remove(f.Name())
+		return "", fmt.Errorf("creating archive in registry: %w", err)
+	}
+	return f.Name(), nil
+}
+
+// Add publishes the archive written to tmp as ArchivePath(name, v) and
+// records it in the index. The archive is hard-linked into place and the
+// index replaced atomically under a lock file, so concurrent publishers on a
+// shared directory neither lose entries nor replace each other's archives.
+// The caller still removes tmp.
+func (r *Registry) Add(name string, v Version, tmp string) (Entry, error) {
+	path := r.ArchivePath(name, v)
+	sum, err := fileSHA256(tmp)
+	if err != nil {
+		return Entry{}, err
+	}
+	rel, err := filepath.Rel(r.Dir, path)
+	if err != nil {
+		return Entry{}, err
+	}
+	e := Entry{Version: v.String(), Path: filepath.ToSlash(rel), SHA256: sum, PublishedAt: time.Now().UTC()}
+
+	unlock, err := r.lock()
+	if err != nil {
+		return Entry{}, err
+	}
+	defer unlock()
+
+	idx, err := r.Index()
+	if err != nil {
+		return Entry{}, err
+	}
+	if slices.ContainsFunc(idx.Bundles[name], func(x Entry) bool { return x.Version == e.Version }) {
+		return Entry{}, fmt.Errorf("%s@%s is already published; versions are immutable", name, v)
+	}
+	if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+		return Entry{}, fmt.Errorf("publishing %s@%s: %w", name, v, err)
+	}
+	if err := os.Link(tmp, path); err != nil {
+		if os.IsExist(err) {
+			return Entry{}, fmt.Errorf("%s already exists; versions are immutable", path)
+		}
+		return Entry{}, fmt.Errorf("publishing %s@%s: %w", name, v, err)
+	}
+	entries := append(idx.Bundles[name], e)
+	slices.SortStableFunc(entries, func(a, b Entry) int {
+		return mustVersion(a.Version).Compare(mustVersion(b.Version))
+	})
+	idx.Bundles[name] = entries
+
+	if err := r.writeIndex(idx); err != nil {
+		os.Remove(path)
+		return Entry{}, err
+	}
+	return e, nil
+}
+
+// Resolve returns the highest published version of name matching c and the
+// path of its archive, after checking the archive against the index checksum.
+func (r *Registry) Resolve(name string, c Constraint) (Entry, string, error) {
+	idx, err := r.Index()
+	if err != nil {
+		return Entry{}, "", err
+	}
+
+	var best *Entry
+	var bestVersion Version
+	for i, e := range idx.Bundles[name] {
+		v, err := ParseVersion(e.Version)
+		if err != nil || !c.Match(v) {
+			continue
+		}
+		if best == nil || v.Compare(bestVersion) > 0 {
+			best, bestVersion = &idx.Bundles[name][i], v
+		}
+	}
+	if best == nil {
+		return Entry{}, "", fmt.Errorf("%w: %s@%s", ErrNotFound, name, c)
+	}
+
+	path := filepath.Join(r.Dir, filepath.FromSlash(best.Path))
+	if !filepath.IsLocal(filepath.FromSlash(best.Path)) {
+		return Entry{}, "", fmt.Errorf("registry entry %s@%s has unsafe path %q", name, best.Version, best.Path)
+	}
+	sum, err := fileSHA256(path)
+	if err != nil {
+		return Entry{}, "", err
+	}
+	if sum != best.SHA256 {
+		return Entry{}, "", fmt.Errorf("%s does not match the checksum recorded in the registry index", path)
+	}
+	return *best, path, nil
+}
+
+// lock takes the index lock file, waiting for other publishers to finish.
+func (r *Registry) lock() (func(), error) {
+	path := filepath.Join(r.Dir, lockFile)
+	deadline := time.Now().Add(lockWait)
+	for {
+		f, err := os.OpenFile(path, os.O_CREATE|os.O_EXCL|os.O_WRONLY, 0o644)
+		if err == nil {
+			f.Close()
+			return func() { os.Remove(path) }, nil
+		}
+		if !os.IsExist(err) {
+			return nil, fmt.Errorf("locking registry: %w", err)
+		}
+		if time.Now().After(deadline) {
+			return nil, fmt.Errorf("locking registry: %s is held by another publish (remove it if stale)", path)
+		}
+		time.Sleep(lockRetry)
+	}
+}
+
+func (r *Registry) writeIndex(idx *Index) error {
+	data, err := json.MarshalIndent(idx, "", "  ")
+	if err != nil {
+		return fmt.Errorf("marshaling registry index: %w", err)
+	}
+	tmp, err := os.CreateTemp(r.Dir, ".index-*.json")
+	if err != nil {
+		return fmt.Errorf("writing registry index: %w", err)
+	}
+	defer os.Remove(tmp.Name())
+	if _, err := tmp.Write(append(data, '\n')); err != nil {
+		tmp.Close()
+		return fmt.Errorf("writing registry index: %w", err)
+	}
+	if err := tmp.Close(); err != nil {
+		return fmt.Errorf("writing registry index: %w", err)
+	}
+	if err := os.Chmod(tmp.Name(), 0o644); err != nil {
+		return fmt.Errorf("writing registry index: %w", err)
+	}
+	if err := os.Rename(tmp.Name(), filepath.Join(r.Dir, indexFile)); err != nil {
+		return fmt.Errorf("writing registry index: %w", err)
+	}
+	return nil
+}
+
+func fileSHA256(path string) (string, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return "", fmt.Errorf("reading %s: %w", path, err)
+	}
+	sum := sha256.Sum256(data)
+	return hex.EncodeToString(sum[:]), nil
+}
+
+// mustVersion parses a version for sorting; unparseable versions sort first.
+func mustVersion(s string) Version {
+	v, err := ParseVersion(s)
+	if err != nil {
+		return Version{Major: -1}
+	}
+	return v
+}
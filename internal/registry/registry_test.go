@@ -0,0 +1,168 @@
+package registry
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestConstraintMatch(t *testing.T) {
+	tests := []struct {
+		constraint string
+		version    string
+		want       bool
+	}{
+		{"^1.0", "1.9.3", true},
+		{"^1.0", "2.0.0", false},
+		{"^1.2", "1.1.9", false},
+		{"^0.2", "0.2.5", true},
+		{"^0.2", "0.3.0", false},
+		{"~1.2", "1.2.9", true},
+		{"~1.2", "1.3.0", false},
+		{"1.2", "1.2.7", true},
+		{"1.2", "1.3.0", false},
+		{"1.3.0", "1.3.0", true},
+		{"=1.3.0", "1.3.1", false},
+		{">=1.0 <2.0", "1.5.0", true},
+		{">=1.0 <2.0", "2.0.0", false},
+		{"latest", "9.9.9", true},
+		{"", "0.0.1", true},
+		{"^1.0", "1.5.0-rc.1", false},
+		{"1.5.0-rc.1", "1.5.0-rc.1", true},
+	}
+	for _, tt := range tests {
+		c, err := ParseConstraint(tt.constraint)
+		if err != nil {
+			t.Fatalf("ParseConstraint(%q): %v", tt.constraint, err)
+		}
+		v, err := ParseVersion(tt.version)
+		if err != nil {
+			t.Fatalf("ParseVersion(%q): %v", tt.version, err)
+		}
+		if got := c.Match(v); got != tt.want {
+			t.Errorf("%q matching %s = %t, want %t", tt.constraint, tt.version, got, tt.want)
+		}
+	}
+}
+
+func TestParseErrors(t *testing.T) {
+	for _, s := range []string{"1.2", "01.2.3", "1.2.3.4", "1.2.3-", "x"} {
+		if _, err := ParseVersion(s); err == nil {
+			t.Errorf("expected ParseVersion(%q) to fail", s)
+		}
+	}
+	for _, s := range []string{"!1.0", "^x", ">=1.0 <"} {
+		if _, err := ParseConstraint(s); err == nil {
+			t.Errorf("expected ParseConstraint(%q) to fail", s)
+		}
+	}
+	for _, s := range []string{"Team", "../x", "", ".hidden"} {
+		if _, _, err := ParseRef(s + "@1.0"); err == nil {
+			t.Errorf("expected ParseRef(%q) to fail", s)
+		}
+	}
+}
+
+func TestVersionOrder(t *testing.T) {
+	order := []string{"1.0.0-alpha", "1.0.0-alpha.1", "1.0.0-alpha.beta", "1.0.0-beta.2", "1.0.0-beta.11", "1.0.0-rc.1", "1.0.0", "1.0.1", "1.10.0"}
+	for i := 1; i < len(order); i++ {
+		a, _ := ParseVersion(order[i-1])
+		b, _ := ParseVersion(order[i])
+		if a.Compare(b) >= 0 {
+			t.Errorf("expected %s < %s", a, b)
+		}
+	}
+}
+
+func publish(t *testing.T, reg *Registry, name, version string) {
+	t.Helper()
+	v, err := ParseVersion(version)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := reg.CheckUnpublished(name, v); err != nil {
+		t.Fatal(err)
+	}
+	tmp, err := reg.CreateTemp()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.Remove(tmp)
+	if err := os.WriteFile(tmp, []byte(name+" "+version), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := reg.Add(name, v, tmp); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestPublishAndResolve(t *testing.T) {
+	reg := &Registry{Dir: t.TempDir()}
+	for _, v := range []string{"1.0.0", "1.3.0", "1.10.0", "2.0.0", "2.1.0-rc.1"} {
+		publish(t, reg, "team-python", v)
+	}
+
+	idx, err := reg.Index()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := idx.Bundles["team-python"]; len(got) != 5 || got[2].Version != "1.10.0" {
+		t.Fatalf("expected versions sorted by precedence, got %+v", got)
+	}
+
+	for constraint, want := range map[string]string{"^1.0": "1.10.0", "": "2.0.0", "~1.3": "1.3.0", "2.1.0-rc.1": "2.1.0-rc.1"} {
+		c, err := ParseConstraint(constraint)
+		if err != nil {
+			t.Fatal(err)
+		}
+		e, path, err := reg.Resolve("team-python", c)
+		if err != nil {
+			t.Fatalf("resolve %q: %v", constraint, err)
+		}
+		if e.Version != want || filepath.Base(path) != want+".zip" {
+			t.Errorf("resolve %q: got %s at %s, want %s", constraint, e.Version, path, want)
+		}
+	}
+
+	c, _ := ParseConstraint("^3")
+	if _, _, err := reg.Resolve("team-python", c); !errors.Is(err, ErrNotFound) {
+		t.Fatalf("expected ErrNotFound, got %v", err)
+	}
+
+	v, _ := ParseVersion("1.3.0")
+	if err := reg.CheckUnpublished("team-python", v); err == nil {
+		t.Fatal("expected republishing a version to fail")
+	}
+	tmp, err := reg.CreateTemp()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.Remove(tmp)
+	if _, err := reg.Add("team-python", v, tmp); err == nil {
+		t.Fatal("expected adding a published version to fail")
+	}
+
+	// A stray archive, such as one a concurrent publisher just linked, is
+	// never replaced.
+	v4, _ := ParseVersion("4.0.0")
+	stray := reg.ArchivePath("team-python", v4)
+	if err := os.WriteFile(stray, []byte("theirs"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := reg.Add("team-python", v4, tmp); err == nil || !strings.Contains(err.Error(), "immutable") {
+		t.Fatalf("expected an existing archive to be kept, got %v", err)
+	}
+	if got, _ := os.ReadFile(stray); string(got) != "theirs" {
+		t.Fatalf("expected the existing archive to be untouched, got %q", got)
+	}
+
+	if err := os.WriteFile(reg.ArchivePath("team-python", v), []byte("tampered"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	c, _ = ParseConstraint("~1.3")
+	if _, _, err := reg.Resolve("team-python", c); err == nil {
+		t.Fatal("expected a tampered archive to fail the checksum")
+	}
+}
@@ -0,0 +1,200 @@
+package registry
+
+import (
+	"cmp"
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// Version is a semantic version (https://semver.org). Build metadata is
+// accepted and ignored.
+type Version struct {
+	Major, Minor, Patch int
+	Pre                 string // pre-release, such as "rc.1"
+}
+
+// ParseVersion parses MAJOR.MINOR.PATCH, allowing a leading "v", a
+// pre-release after "-" and build metadata after "+".
+func ParseVersion(s string) (Version, error) {
+	v, n, err := parsePartial(s)
+	if err != nil {
+		return Version{}, err
+	}
+	if n != 3 {
+		return Version{}, fmt.Errorf("invalid version %q (expected MAJOR.MINOR.PATCH)", s)
+	}
+	return v, nil
+}
+
+// parsePartial parses a version that may omit the minor and patch numbers,
+// returning how many numbers were present.
+func parsePartial(s string) (Version, int, error) {
+	rest := strings.TrimPrefix(strings.TrimSpace(s), "v")
+	rest, _, _ = strings.Cut(rest, "+")
+	core, pre, hasPre := strings.Cut(rest, "-")
+	if hasPre && pre == "" {
+		return Version{}, 0, fmt.Errorf("invalid version %q (empty pre-release)", s)
+	}
+
+	parts := strings.Split(core, ".")
+	if len(parts) > 3 {
+		return Version{}, 0, fmt.Errorf("invalid version %q", s)
+	}
+	var nums [3]int
+	for i, p := range parts {
+		n, err := strconv.Atoi(p)
+		if err != nil || n < 0 || (len(p) > 1 && p[0] == '0') {
+			return Version{}, 0, fmt.Errorf("invalid version %q", s)
+		}
+		nums[i] = n
+	}
+	if hasPre && len(parts) != 3 {
+		return Version{}, 0, fmt.Errorf("invalid version %q (pre-release needs MAJOR.MINOR.PATCH)", s)
+	}
+	return Version{Major: nums[0], Minor: nums[1], Patch: nums[2], Pre: pre}, len(parts), nil
+}
+
+func (v Version) String() string {
+	s := fmt.Sprintf("%d.%d.%d", v.Major, v.Minor, v.Patch)
+	if v.Pre != "" {
+		s += "-" + v.Pre
+	}
+	return s
+}
+
+// Compare orders versions by semver precedence.
+func (v Version) Compare(o Version) int {
+	if c := cmp.Compare(v.Major, o.Major); c != 0 {
+		return c
+	}
+	if c := cmp.Compare(v.Minor, o.Minor); c != 0 {
+		return c
+	}
+	if c := cmp.Compare(v.Patch, o.Patch); c != 0 {
+		return c
+	}
+	return comparePre(v.Pre, o.Pre)
+}
+
+// comparePre orders pre-releases: a release sorts after its pre-releases, and
+// dot-separated identifiers compare numerically when both are numbers.
+func comparePre(a, b string) int {
+	switch {
+	case a == b:
+		return 0
+	case a == "":
+		return 1
+	case b == "":
+		return -1
+	}
+	as, bs := strings.Split(a, "."), strings.Split(b, ".")
+	for i := 0; i < len(as) && i < len(bs); i++ {
+		an, aErr := strconv.Atoi(as[i])
+		bn, bErr := strconv.Atoi(bs[i])
+		var c int
+		switch {
+		case aErr == nil && bErr == nil:
+			c = cmp.Compare(an, bn)
+		case aErr == nil:
+			c = -1
+		case bErr == nil:
+			c = 1
+		default:
+			c = strings.Compare(as[i], bs[i])
+		}
+		if c != 0 {
+			return c
+		}
+	}
+	return cmp.Compare(len(as), len(bs))
+}
+
+// Constraint selects versions, such as "^1.2", "~1.2.3", ">=1.0 <2.0",
+// "1.3.0" or "latest".
+type Constraint struct {
+	text  string
+	exact bool
+	check []func(Version) bool
+}
+
+// ParseConstraint parses space-separated comparisons, all of which must
+// hold. An empty constraint, "*" or "latest" matches any release.
+func ParseConstraint(s string) (Constraint, error) {
+	c := Constraint{text: strings.TrimSpace(s)}
+	if c.text == "" || c.text == "*" || c.text == "latest" {
+		return c, nil
+	}
+
+	for _, term := range strings.Fields(c.text) {
+		op := term[:len(term)-len(strings.TrimLeft(term, "^~<>="))]
+		v, n, err := parsePartial(term[len(op):])
+		if err != nil {
+			return Constraint{}, fmt.Errorf("invalid version constraint %q: %w", s, err)
+		}
+		switch op {
+		case "^":
+			upper := Version{Major: v.Major + 1}
+			if v.Major == 0 && n > 1 {
+				upper = Version{Minor: v.Minor + 1}
+			}
+			c.check = append(c.check, between(v, upper))
+		case "~":
+			upper := Version{Major: v.Major, Minor: v.Minor + 1}
+			if n == 1 {
+				upper = Version{Major: v.Major + 1}
+			}
+			c.check = append(c.check, between(v, upper))
+		case ">=":
+			c.check = append(c.check, func(x Version) bool { return x.Compare(v) >= 0 })
+		case ">":
+			c.check = append(c.check, func(x Version) bool { return x.Compare(v) > 0 })
+		case "<=":
+			c.check = append(c.check, func(x Version) bool { return x.Compare(v) <= 0 })
+		case "<":
+			c.check = append(c.check, func(x Version) bool { return x.Compare(v) < 0 })
+		case "", "=":
+			if n == 3 {
+				c.exact = true
+				c.check = append(c.check, func(x Version) bool { return x.Compare(v) == 0 })
+				break
+			}
+			// A partial version such as "1.2" matches any 1.2.x.
+			upper := Version{Major: v.Major + 1}
+			if n == 2 {
+				upper = Version{Major: v.Major, Minor: v.Minor + 1}
+			}
+			c.check = append(c.check, between(v, upper))
+		default:
+			return Constraint{}, fmt.Errorf("invalid version constraint %q: unknown operator %q", s, op)
+		}
+	}
+	return c, nil
+}
+
+// between matches lo <= x < hi, where hi excludes its own pre-releases.
+func between(lo, hi Version) func(Version) bool {
+	hi.Pre = "0"
+	return func(x Version) bool { return x.Compare(lo) >= 0 && x.Compare(hi) < 0 }
+}
+
+// Match reports whether v satisfies the constraint. Pre-releases only match
+// an exact version.
+func (c Constraint) Match(v Version) bool {
+	if v.Pre != "" && !c.exact {
+		return false
+	}
+	for _, check := range c.check {
+		if !check(v) {
+			return false
+		}
+	}
+	return true
+}
+
+func (c Constraint) String() string {
+	if c.text == "" {
+		return "latest"
+	}
+	return c.text
+}
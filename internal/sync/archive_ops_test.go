@@ -1,6 +1,8 @@
 package sync
 
 import (
+	"crypto/ed25519"
+	"errors"
 	"os"
 	"path/filepath"
 	"strings"
@@ -10,6 +12,7 @@ import (
 	"github.com/LaneBirmingham/coding-agent-sync/internal/agent"
 	"github.com/LaneBirmingham/coding-agent-sync/internal/archive"
 	"github.com/LaneBirmingham/coding-agent-sync/internal/config"
+	"github.com/LaneBirmingham/coding-agent-sync/internal/keys"
 )
 
 func TestExportDryRun(t *testing.T) {
@@ -71,11 +74,12 @@ func TestExportAndImportRoundTrip(t *testing.T) {
 	}
 
 	result, err := Import(&config.ImportConfig{
-		To:     []config.Agent{config.Copilot},
-		Root:   root,
-		Scope:  config.ScopeLocal,
-		Input:  archivePath,
-		DryRun: false,
+		To:            []config.Agent{config.Copilot},
+		Root:          root,
+		Scope:         config.ScopeLocal,
+		Input:         archivePath,
+		DryRun:        false,
+		AllowUnsigned: true,
 	})
 	if err != nil {
 		t.Fatal(err)
@@ -122,11 +126,12 @@ func TestImportSkipsUnsupportedInstructions(t *testing.T) {
 	}
 
 	result, err := Import(&config.ImportConfig{
-		To:     []config.Agent{config.Copilot},
-		Root:   t.TempDir(),
-		Scope:  config.ScopeGlobal,
-		Input:  archivePath,
-		DryRun: false,
+		To:            []config.Agent{config.Copilot},
+		Root:          t.TempDir(),
+		Scope:         config.ScopeGlobal,
+		Input:         archivePath,
+		DryRun:        false,
+		AllowUnsigned: true,
 	})
 	if err != nil {
 		t.Fatal(err)
@@ -153,3 +158,326 @@ func TestImportSkipsUnsupportedInstructions(t *testing.T) {
 		t.Fatalf("expected imported skill content, got %q", string(data))
 	}
 }
+
+func writeSignedArchive(t *testing.T, path string, trusted bool) (keysPath string) {
+	t.Helper()
+	priv, err := keys.GenerateSigningKey()
+	if err != nil {
+		t.Fatal(err)
+	}
+	keyPath := filepath.Join(t.TempDir(), "signing_key")
+	pub := keys.PublicKey{Key: priv.Public().(ed25519.PublicKey), Comment: "team"}
+	if err := keys.WriteKeyFiles(keyPath, keys.MarshalSigningKey(priv), []byte(pub.String()+"\n"), false); err != nil {
+		t.Fatal(err)
+	}
+
+	root := t.TempDir()
+	writeFile(t, filepath.Join(root, "CLAUDE.md"), "# Signed")
+	if _, err := Export(&config.ExportConfig{
+		From:       config.Claude,
+		Root:       root,
+		Scope:      config.ScopeLocal,
+		Output:     path,
+		CASVersion: "test",
+		SigningKey: keyPath,
+	}); err != nil {
+		t.Fatal(err)
+	}
+
+	trustedPath := filepath.Join(t.TempDir(), "trusted_keys")
+	if trusted {
+		if err := os.Rename(keyPath+".pub", trustedPath); err != nil {
+			t.Fatal(err)
+		}
+	}
+	return trustedPath
+}
+
+func TestImportRequiresTrustedSignature(t *testing.T) {
+	archivePath := filepath.Join(t.TempDir(), "signed.zip")
+	trustedPath := writeSignedArchive(t, archivePath, true)
+
+	root := t.TempDir()
+	result, err := Import(&config.ImportConfig{
+		To:          []config.Agent{config.Claude},
+		Root:        root,
+		Scope:       config.ScopeLocal,
+		Input:       archivePath,
+		TrustedKeys: trustedPath,
+	})
+	if err != nil {
+		t.Fatalf("expected trusted signed archive to import, got %v", err)
+	}
+	if !strings.Contains(result.Signer, "team") {
+		t.Fatalf("expected signer to be reported, got %q", result.Signer)
+	}
+	if got := readFile(t, filepath.Join(root, "CLAUDE.md")); got != "# Signed" {
+		t.Fatalf("unexpected imported content %q", got)
+	}
+}
+
+func TestImportRejectsUntrustedAndUnsigned(t *testing.T) {
+	signed := filepath.Join(t.TempDir(), "signed.zip")
+	trustedPath := writeSignedArchive(t, signed, false)
+
+	cfg := &config.ImportConfig{
+		To:          []config.Agent{config.Claude},
+		Root:        t.TempDir(),
+		Scope:       config.ScopeLocal,
+		Input:       signed,
+		DryRun:      true,
+		TrustedKeys: trustedPath,
+	}
+	if _, err := Import(cfg); !errors.Is(err, archive.ErrUntrustedKey) {
+		t.Fatalf("expected untrusted key error, got %v", err)
+	}
+
+	unsigned := filepath.Join(t.TempDir(), "unsigned.zip")
+	if err := archive.Write(unsigned, &archive.Archive{
+		Manifest:     &archive.Manifest{Version: archive.FormatVersion, Agent: "claude", Scope: "local", ExportedAt: time.Now().UTC()},
+		Instructions: &agent.Instruction{Content: "# Unsigned"},
+	}); err != nil {
+		t.Fatal(err)
+	}
+	cfg.Input = unsigned
+	if _, err := Import(cfg); !errors.Is(err, archive.ErrUnsigned) {
+		t.Fatalf("expected unsigned error, got %v", err)
+	}
+
+	cfg.AllowUnsigned = true
+	result, err := Import(cfg)
+	if err != nil {
+		t.Fatalf("expected --allow-unsigned import to succeed, got %v", err)
+	}
+	if len(result.Warnings) == 0 || !strings.Contains(result.Warnings[0], "not signed") {
+		t.Fatalf("expected unsigned warning, got %v", result.Warnings)
+	}
+}
+
+func TestBundleExportAndImport(t *testing.T) {
+	root := t.TempDir()
+	home := t.TempDir()
+	t.Setenv("HOME", home)
+	archivePath := filepath.Join(t.TempDir(), "bundle.zip")
+
+	if err := os.WriteFile(filepath.Join(root, "CLAUDE.md"), []byte("# Claude local"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.MkdirAll(filepath.Join(home, ".gemini"), 0o755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(home, ".gemini", "GEMINI.md"), []byte("# Gemini global"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	result, err := Export(&config.ExportConfig{
+		Root:   root,
+		Output: archivePath,
+		Sections: []config.Section{
+			{Agent: config.Claude, Scope: config.ScopeLocal},
+			{Agent: config.Gemini, Scope: config.ScopeLocal},
+			{Agent: config.Claude, Scope: config.ScopeGlobal},
+			{Agent: config.Gemini, Scope: config.ScopeGlobal},
+		},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(result.Actions) != 8 {
+		t.Fatalf("expected 8 actions, got %d", len(result.Actions))
+	}
+
+	a, err := archive.Read(archivePath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var names []string
+	for _, s := range a.Contents() {
+		names = append(names, s.String())
+	}
+	if strings.Join(names, ",") != "claude/local,gemini/global" {
+		t.Fatalf("expected only non-empty sections, got %v", names)
+	}
+
+	// Default mapping: each section returns to its own agent and scope.
+	if err := os.Remove(filepath.Join(root, "CLAUDE.md")); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Remove(filepath.Join(home, ".gemini", "GEMINI.md")); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := Import(&config.ImportConfig{Root: root, Input: archivePath, AllowUnsigned: true}); err != nil {
+		t.Fatal(err)
+	}
+	for path, want := range map[string]string{
+		filepath.Join(root, "CLAUDE.md"):            "# Claude local",
+		filepath.Join(home, ".gemini", "GEMINI.md"): "# Gemini global",
+	} {
+		got, err := os.ReadFile(path)
+		if err != nil || string(got) != want {
+			t.Fatalf("expected %q at %s, got %q (%v)", want, path, got, err)
+		}
+	}
+
+	// --scope selects sections; --to retargets them.
+	result, err = Import(&config.ImportConfig{
+		To:            []config.Agent{config.Codex},
+		Root:          root,
+		Scope:         config.ScopeLocal,
+		Input:         archivePath,
+		AllowUnsigned: true,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(result.Actions) != 2 || result.Actions[0].Agent != config.Codex || result.Actions[0].Scope != config.ScopeLocal {
+		t.Fatalf("unexpected actions: %v", result.Actions)
+	}
+
+	// --map routes a section to another agent and scope.
+	if _, err := Import(&config.ImportConfig{
+		Root:          root,
+		Input:         archivePath,
+		AllowUnsigned: true,
+		Mappings: []config.Mapping{{
+			From: config.Section{Agent: config.Gemini, Scope: config.ScopeGlobal},
+			To:   config.Section{Agent: config.OpenCode, Scope: config.ScopeLocal},
+		}},
+	}); err != nil {
+		t.Fatal(err)
+	}
+	got, err := os.ReadFile(filepath.Join(root, "AGENTS.md"))
+	if err != nil || string(got) != "# Gemini global" {
+		t.Fatalf("expected mapped instructions in AGENTS.md, got %q (%v)", got, err)
+	}
+
+	_, err = Import(&config.ImportConfig{
+		Root:          root,
+		Input:         archivePath,
+		AllowUnsigned: true,
+		Sections:      []config.Section{{Agent: config.Codex, Scope: config.ScopeGlobal}},
+	})
+	if err == nil || !strings.Contains(err.Error(), "no section codex/global") {
+		t.Fatalf("expected missing section error, got %v", err)
+	}
+}
+
+func TestImportFromStdin(t *testing.T) {
+	root := t.TempDir()
+	archivePath := filepath.Join(t.TempDir(), "stream.tar.gz")
+	if err := archive.Write(archivePath, &archive.Archive{
+		Manifest: &archive.Manifest{
+			Version:    archive.FormatVersion,
+			Agent:      "claude",
+			Scope:      "local",
+			ExportedAt: time.Now().UTC(),
+		},
+		Instructions: &agent.Instruction{Content: "# Streamed"},
+	}); err != nil {
+		t.Fatal(err)
+	}
+
+	f, err := os.Open(archivePath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+	prevStdin := os.Stdin
+	os.Stdin = f
+	defer func() { os.Stdin = prevStdin }()
+
+	if _, err := Import(&config.ImportConfig{
+		To:            []config.Agent{config.Gemini},
+		Root:          root,
+		Input:         config.StdioPath,
+		AllowUnsigned: true,
+	}); err != nil {
+		t.Fatal(err)
+	}
+	got, err := os.ReadFile(filepath.Join(root, "GEMINI.md"))
+	if err != nil || string(got) != "# Streamed" {
+		t.Fatalf("expected streamed instructions, got %q (%v)", got, err)
+	}
+}
+
+func TestImportSelectsSkills(t *testing.T) {
+	root := t.TempDir()
+	archivePath := filepath.Join(t.TempDir(), "team.zip")
+	if err := archive.Write(archivePath, &archive.Archive{
+		Manifest: &archive.Manifest{
+			Version:    archive.FormatVersion,
+			Agent:      "claude",
+			Scope:      "local",
+			ExportedAt: time.Now().UTC(),
+		},
+		Instructions: &agent.Instruction{Content: "# Team"},
+		Skills: []agent.Skill{
+			{Name: "review", Content: "team review"},
+			{Name: "deploy", Content: "team deploy"},
+			{Name: "draft-notes", Content: "draft"},
+		},
+	}); err != nil {
+		t.Fatal(err)
+	}
+
+	skillsDir := filepath.Join(root, ".claude", "skills")
+	if err := os.MkdirAll(filepath.Join(skillsDir, "review"), 0o755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(skillsDir, "review", "SKILL.md"), []byte("mine"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	result, err := Import(&config.ImportConfig{
+		To:               []config.Agent{config.Claude},
+		Root:             root,
+		Input:            archivePath,
+		AllowUnsigned:    true,
+		SkipInstructions: true,
+		ExcludeSkills:    []string{"draft-*"},
+		IfAbsent:         true,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(result.Actions) != 1 || result.Actions[0].Kind != Skills {
+		t.Fatalf("expected a single skills action, got %v", result.Actions)
+	}
+	if !strings.Contains(result.Actions[0].Detail, "imported 1 skill(s): deploy (kept existing: review)") {
+		t.Fatalf("unexpected detail %q", result.Actions[0].Detail)
+	}
+	if got, _ := os.ReadFile(filepath.Join(skillsDir, "review", "SKILL.md")); string(got) != "mine" {
+		t.Fatalf("expected existing skill to be kept, got %q", got)
+	}
+	if _, err := os.Stat(filepath.Join(skillsDir, "draft-notes")); !os.IsNotExist(err) {
+		t.Fatalf("expected excluded skill to be skipped, got %v", err)
+	}
+	if _, err := os.Stat(filepath.Join(root, "CLAUDE.md")); !os.IsNotExist(err) {
+		t.Fatalf("expected instructions to be skipped, got %v", err)
+	}
+
+	result, err = Import(&config.ImportConfig{
+		To:            []config.Agent{config.Claude},
+		Root:          root,
+		Input:         archivePath,
+		AllowUnsigned: true,
+		SkipSkills:    true,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(result.Actions) != 1 || result.Actions[0].Kind != Instructions {
+		t.Fatalf("expected a single instructions action, got %v", result.Actions)
+	}
+
+	_, err = Import(&config.ImportConfig{
+		To:            []config.Agent{config.Claude},
+		Root:          root,
+		Input:         archivePath,
+		AllowUnsigned: true,
+		Skills:        []string{"deploy", "missing"},
+	})
+	if err == nil || !strings.Contains(err.Error(), "no skill named missing") {
+		t.Fatalf("expected unknown skill error, got %v", err)
+	}
+}
@@ -0,0 +1,274 @@
+package sync
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+
+	"github.com/LaneBirmingham/coding-agent-sync/internal/agent"
+	"github.com/LaneBirmingham/coding-agent-sync/internal/archive"
+	"github.com/LaneBirmingham/coding-agent-sync/internal/config"
+)
+
+// exportDelta reduces a to the changes since the base archive cfg.Since and
+// rewrites the export actions to describe the delta.
+func exportDelta(cfg *config.ExportConfig, a *archive.Archive, actions []ArchiveAction) error {
+	base, err := archive.Read(cfg.Since)
+	if err != nil {
+		return fmt.Errorf("reading delta base: %w", err)
+	}
+	if err := a.DeltaFrom(base); err != nil {
+		return err
+	}
+
+	verb := "exported"
+	if cfg.DryRun {
+		verb = "would export"
+	}
+	for i := range actions {
+		act := &actions[i]
+		info := archive.SectionInfo{Agent: string(act.Agent), Scope: string(act.Scope)}
+		ds := a.Manifest.Delta.Section(info)
+		if ds == nil {
+			continue
+		}
+		sec := contentOf(a, info)
+		switch act.Kind {
+		case Instructions:
+			switch {
+			case sec.Instructions != nil && ds.BaseInstructions == "":
+				act.Detail = fmt.Sprintf("%s (%d bytes, added since base)", verb, len(sec.Instructions.Content))
+			case sec.Instructions != nil:
+				act.Detail = fmt.Sprintf("%s (%d bytes, changed since base)", verb, len(sec.Instructions.Content))
+			case ds.DeleteInstructions:
+				act.Detail = "deleted since base"
+			case ds.BaseInstructions != "":
+				act.Status = "skipped"
+				act.Detail = "skipped (unchanged since base)"
+			}
+		case Skills:
+			var parts []string
+			if len(sec.Skills) > 0 {
+				parts = append(parts, fmt.Sprintf("%s %d skill(s): %s", verb, len(sec.Skills), skillNames(sec.Skills)))
+			}
+			if len(ds.DeletedSkills) > 0 {
+				parts = append(parts, "deleted: "+strings.Join(ds.DeletedSkills, ", "))
+			}
+			if len(parts) == 0 && len(ds.BaseSkills) > 0 {
+				act.Status = "skipped"
+				parts = append(parts, "skipped (unchanged since base)")
+			}
+			if len(parts) > 0 {
+				act.Detail = strings.Join(parts, "; ")
+			}
+		}
+	}
+	return nil
+}
+
+// contentOf returns the content of one section of a.
+func contentOf(a *archive.Archive, info archive.SectionInfo) archive.Section {
+	for _, s := range a.Contents() {
+		if s.SectionInfo == info {
+			return s
+		}
+	}
+	return archive.Section{SectionInfo: info}
+}
+
+// checkDeltaImport rejects import options that would apply only part of a
+// delta, which would leave the destination matching neither the base nor
+// the delta's target state.
+func checkDeltaImport(cfg *config.ImportConfig) error {
+	switch {
+	case cfg.SkipInstructions || cfg.SkipSkills:
+		return fmt.Errorf("delta archives must be imported whole (drop --only)")
+	case len(cfg.Skills) > 0 || len(cfg.ExcludeSkills) > 0:
+		return fmt.Errorf("delta archives must be imported whole (drop --skill and --exclude-skill)")
+	case cfg.IfAbsent:
+		return fmt.Errorf("delta archives cannot be imported with --if-absent")
+	}
+	return nil
+}
+
+// verifyDeltaBase checks that a destination still holds the base state a
+// delta section was made against: the same instructions, the same base
+// skills, and no conflicting copy of a skill the delta adds. It reads the
+// files the import writes and deletes, not the paths an agent falls back to,
+// and finally compares the destination against the delta's base digest.
+func verifyDeltaBase(root string, delta *archive.Delta, bundle bool, sec archive.Section, dest config.Section) error {
+	ds := delta.Section(sec.SectionInfo)
+	if ds == nil {
+		return fmt.Errorf("delta archive has no base state for section %s", sec.SectionInfo)
+	}
+	loc := config.Location{Root: root, Scope: dest.Scope}
+	dst, err := agent.GetAt(dest.Agent, loc)
+	if err != nil {
+		return err
+	}
+	mismatch := func(format string, args ...any) error {
+		return fmt.Errorf("%s does not match the delta base: %s", dest, fmt.Sprintf(format, args...))
+	}
+
+	// The destination holds the base as import rendered it for dest, so
+	// base content with cas:if sections is rendered before comparing. A
+	// match records the base's own checksum for the digest check below.
+	rendered := func(sum, content string) (string, error) {
+		if content == "" {
+			return sum, nil
+		}
+		out, err := renderFor(content, dest.Agent, dest.Scope)
+		if err != nil {
+			return "", fmt.Errorf("delta base of section %s: %w", sec.SectionInfo, err)
+		}
+		return archive.Hash(out), nil
+	}
+
+	current := archive.DeltaSection{SectionInfo: sec.SectionInfo}
+	content, err := readTarget(dst.InstructionsPath(loc))
+	if err != nil {
+		return fmt.Errorf("reading instructions from %s: %w", dest.Agent, err)
+	}
+	want, err := rendered(ds.BaseInstructions, ds.BaseInstructionsContent)
+	if err != nil {
+		return err
+	}
+	switch {
+	case content == "" && ds.BaseInstructions != "":
+		return mismatch("instructions differ from the base")
+	case content != "" && ds.BaseInstructions == "":
+		return mismatch("instructions exist but the base had none")
+	case content != "" && archive.Hash(content) != want:
+		return mismatch("instructions differ from the base")
+	}
+	current.BaseInstructions = ds.BaseInstructions
+
+	dir := dst.SkillsPath(loc)
+	skill := func(name string) (string, error) {
+		if dir == "" {
+			return "", nil
+		}
+		content, err := readTarget(filepath.Join(dir, name, "SKILL.md"))
+		if err != nil {
+			return "", fmt.Errorf("reading skill %s from %s: %w", name, dest.Agent, err)
+		}
+		if content == "" {
+			return "", nil
+		}
+		return archive.Hash(content), nil
+	}
+
+	names := make([]string, 0, len(ds.BaseSkills))
+	for name := range ds.BaseSkills {
+		names = append(names, name)
+	}
+	slices.Sort(names)
+	for _, name := range names {
+		want, err := rendered(ds.BaseSkills[name], ds.BaseSkillsContent[name])
+		if err != nil {
+			return err
+		}
+		got, err := skill(name)
+		switch {
+		case err != nil:
+			return err
+		case got == "":
+			return mismatch("skill %s is missing", name)
+		case got != want:
+			return mismatch("skill %s differs from the base", name)
+		}
+		if current.BaseSkills == nil {
+			current.BaseSkills = make(map[string]string, len(names))
+		}
+		current.BaseSkills[name] = ds.BaseSkills[name]
+	}
+	for _, s := range sec.Skills {
+		if _, inBase := ds.BaseSkills[s.Name]; inBase {
+			continue
+		}
+		got, err := skill(s.Name)
+		if err != nil {
+			return err
+		}
+		if got != "" && got != archive.Hash(s.Content) {
+			return mismatch("skill %s already exists with different content", s.Name)
+		}
+	}
+
+	if delta.DigestWith(bundle, &current) != delta.BaseDigest {
+		return mismatch("content does not match the base digest %s", delta.BaseDigest)
+	}
+	return nil
+}
+
+// readTarget reads a file an import writes, returning "" when it does not exist.
+func readTarget(path string) (string, error) {
+	if path == "" {
+		return "", nil
+	}
+	data, err := os.ReadFile(path)
+	if os.IsNotExist(err) {
+		return "", nil
+	}
+	return string(data), err
+}
+
+// applyDeletions removes the instructions and skills a delta section deletes.
+func applyDeletions(cfg *config.ImportConfig, ds *archive.DeltaSection, dest config.Section) ([]ArchiveAction, error) {
+	loc := config.Location{Root: cfg.Root, Scope: dest.Scope}
+	dst, err := agent.GetAt(dest.Agent, loc)
+	if err != nil {
+		return nil, err
+	}
+	var actions []ArchiveAction
+
+	if ds.DeleteInstructions {
+		act := ArchiveAction{Kind: Instructions, Agent: dest.Agent, Scope: dest.Scope, Status: "deleted", Detail: "deleted (removed since base)"}
+		if cfg.DryRun {
+			act.Status, act.Detail = "dry-run", "would delete (removed since base)"
+		} else if path := dst.InstructionsPath(loc); path != "" {
+			if err := os.Remove(path); err != nil && !os.IsNotExist(err) {
+				return nil, fmt.Errorf("deleting instructions from %s: %w", dest.Agent, err)
+			}
+		}
+		actions = append(actions, act)
+	}
+
+	if len(ds.DeletedSkills) > 0 {
+		names := strings.Join(ds.DeletedSkills, ", ")
+		act := ArchiveAction{Kind: Skills, Agent: dest.Agent, Scope: dest.Scope, Status: "deleted",
+			Detail: fmt.Sprintf("deleted %d skill(s): %s", len(ds.DeletedSkills), names)}
+		if cfg.DryRun {
+			act.Status = "dry-run"
+			act.Detail = fmt.Sprintf("would delete %d skill(s): %s", len(ds.DeletedSkills), names)
+		} else if dir := dst.SkillsPath(loc); dir != "" {
+			for _, name := range ds.DeletedSkills {
+				if err := removeSkill(filepath.Join(dir, name)); err != nil {
+					return nil, fmt.Errorf("deleting skill %s from %s: %w", name, dest.Agent, err)
+				}
+			}
+		}
+		actions = append(actions, act)
+	}
+	return actions, nil
+}
+
+// removeSkill deletes a skill's SKILL.md, the only file a delta tracks, and
+// then its directory if nothing else is left in it.
+func removeSkill(dir string) error {
+	if err := os.Remove(filepath.Join(dir, "SKILL.md")); err != nil && !os.IsNotExist(err) {
+		return err
+	}
+	entries, err := os.ReadDir(dir)
+	switch {
+	case os.IsNotExist(err):
+		return nil
+	case err != nil:
+		return err
+	case len(entries) > 0:
+		return nil
+	}
+	return os.Remove(dir)
+}
@@ -0,0 +1,235 @@
+package sync
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/LaneBirmingham/coding-agent-sync/internal/archive"
+	"github.com/LaneBirmingham/coding-agent-sync/internal/config"
+)
+
+func writeClaudeSkill(t *testing.T, root, name, content string) {
+	t.Helper()
+	dir := filepath.Join(root, ".claude", "skills", name)
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(dir, "SKILL.md"), []byte(content), 0o644); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func exportClaude(t *testing.T, root, output, since string) {
+	t.Helper()
+	if _, err := Export(&config.ExportConfig{
+		From:       config.Claude,
+		Root:       root,
+		Scope:      config.ScopeLocal,
+		Output:     output,
+		CASVersion: "test",
+		Since:      since,
+	}); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func importClaude(root, input string) (*ArchiveResult, error) {
+	return Import(&config.ImportConfig{
+		Root:          root,
+		Scope:         config.ScopeLocal,
+		Input:         input,
+		AllowUnsigned: true,
+	})
+}
+
+func TestDeltaExportAndImport(t *testing.T) {
+	src, dst := t.TempDir(), t.TempDir()
+	dir := t.TempDir()
+	basePath, deltaPath := filepath.Join(dir, "base.zip"), filepath.Join(dir, "delta.zip")
+
+	if err := os.WriteFile(filepath.Join(src, "CLAUDE.md"), []byte("# Base"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	writeClaudeSkill(t, src, "keep", "same")
+	writeClaudeSkill(t, src, "edit", "old")
+	writeClaudeSkill(t, src, "gone", "bye")
+	exportClaude(t, src, basePath, "")
+	if _, err := importClaude(dst, basePath); err != nil {
+		t.Fatal(err)
+	}
+
+	writeClaudeSkill(t, src, "edit", "new")
+	writeClaudeSkill(t, src, "added", "hi")
+	if err := os.RemoveAll(filepath.Join(src, ".claude", "skills", "gone")); err != nil {
+		t.Fatal(err)
+	}
+	exportClaude(t, src, deltaPath, basePath)
+
+	a, err := archive.Read(deltaPath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if a.Manifest.Delta == nil || a.Instructions != nil || len(a.Skills) != 2 {
+		t.Fatalf("expected a delta with only the two changed skills, got %+v", a)
+	}
+
+	if _, err := importClaude(dst, deltaPath); err != nil {
+		t.Fatal(err)
+	}
+	for name, want := range map[string]string{"keep": "same", "edit": "new", "added": "hi"} {
+		got, err := os.ReadFile(filepath.Join(dst, ".claude", "skills", name, "SKILL.md"))
+		if err != nil || string(got) != want {
+			t.Fatalf("skill %s: expected %q, got %q (err=%v)", name, want, got, err)
+		}
+	}
+	if _, err := os.Stat(filepath.Join(dst, ".claude", "skills", "gone")); !os.IsNotExist(err) {
+		t.Fatalf("expected deleted skill to be removed, got err=%v", err)
+	}
+
+	// The destination no longer holds the base, so applying again is refused.
+	if _, err := importClaude(dst, deltaPath); err == nil || !strings.Contains(err.Error(), "does not match the delta base") {
+		t.Fatalf("expected a base mismatch error, got %v", err)
+	}
+}
+
+func TestDeltaImportOverConditionalBase(t *testing.T) {
+	src, dst := t.TempDir(), t.TempDir()
+	dir := t.TempDir()
+	basePath, deltaPath := filepath.Join(dir, "base.zip"), filepath.Join(dir, "delta.zip")
+
+	cond := "# Base\n<!-- cas:if agent=codex -->\nCodex only.\n<!-- cas:endif -->\n"
+	if err := os.WriteFile(filepath.Join(src, "CLAUDE.md"), []byte(cond), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	writeClaudeSkill(t, src, "s1", cond)
+	writeClaudeSkill(t, src, "s2", "one")
+	exportClaude(t, src, basePath, "")
+	if _, err := importClaude(dst, basePath); err != nil {
+		t.Fatal(err)
+	}
+	if got, _ := os.ReadFile(filepath.Join(dst, "CLAUDE.md")); string(got) != "# Base\n" {
+		t.Fatalf("expected the base rendered for claude, got %q", got)
+	}
+
+	writeClaudeSkill(t, src, "s2", "two")
+	exportClaude(t, src, deltaPath, basePath)
+	if _, err := importClaude(dst, deltaPath); err != nil {
+		t.Fatalf("expected the delta to apply over the rendered base, got %v", err)
+	}
+	if got, _ := os.ReadFile(filepath.Join(dst, ".claude", "skills", "s2", "SKILL.md")); string(got) != "two" {
+		t.Fatalf("expected s2 updated, got %q", got)
+	}
+
+	if err := os.WriteFile(filepath.Join(dst, "CLAUDE.md"), []byte(cond), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	writeClaudeSkill(t, dst, "s2", "one")
+	if _, err := importClaude(dst, deltaPath); err == nil || !strings.Contains(err.Error(), "instructions differ") {
+		t.Fatalf("expected unrendered instructions to differ from the base, got %v", err)
+	}
+}
+
+func TestDeltaImportRefusesDriftedDestination(t *testing.T) {
+	src, dst := t.TempDir(), t.TempDir()
+	dir := t.TempDir()
+	basePath, deltaPath := filepath.Join(dir, "base.zip"), filepath.Join(dir, "delta.zip")
+
+	if err := os.WriteFile(filepath.Join(src, "CLAUDE.md"), []byte("# Base"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	writeClaudeSkill(t, src, "s1", "one")
+	exportClaude(t, src, basePath, "")
+	if _, err := importClaude(dst, basePath); err != nil {
+		t.Fatal(err)
+	}
+	writeClaudeSkill(t, src, "s1", "two")
+	exportClaude(t, src, deltaPath, basePath)
+
+	if err := os.WriteFile(filepath.Join(dst, "CLAUDE.md"), []byte("# Local edit"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := importClaude(dst, deltaPath); err == nil || !strings.Contains(err.Error(), "instructions differ") {
+		t.Fatalf("expected an instructions mismatch, got %v", err)
+	}
+	got, err := os.ReadFile(filepath.Join(dst, ".claude", "skills", "s1", "SKILL.md"))
+	if err != nil || string(got) != "one" {
+		t.Fatalf("expected refused delta to leave skills untouched, got %q (err=%v)", got, err)
+	}
+
+	_, err = Import(&config.ImportConfig{
+		Root:             dst,
+		Scope:            config.ScopeLocal,
+		Input:            deltaPath,
+		AllowUnsigned:    true,
+		SkipInstructions: true,
+	})
+	if err == nil || !strings.Contains(err.Error(), "imported whole") {
+		t.Fatalf("expected partial delta import to be refused, got %v", err)
+	}
+}
+
+func TestDeltaImportKeepsUntrackedSkillFiles(t *testing.T) {
+	src, dst := t.TempDir(), t.TempDir()
+	dir := t.TempDir()
+	basePath, deltaPath := filepath.Join(dir, "base.zip"), filepath.Join(dir, "delta.zip")
+
+	writeClaudeSkill(t, src, "gone", "bye")
+	writeClaudeSkill(t, src, "plain", "bye too")
+	exportClaude(t, src, basePath, "")
+	if _, err := importClaude(dst, basePath); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.RemoveAll(filepath.Join(src, ".claude", "skills")); err != nil {
+		t.Fatal(err)
+	}
+	exportClaude(t, src, deltaPath, basePath)
+
+	script := filepath.Join(dst, ".claude", "skills", "gone", "run.sh")
+	if err := os.WriteFile(script, []byte("echo hi"), 0o755); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := importClaude(dst, deltaPath); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := os.Stat(filepath.Join(dst, ".claude", "skills", "gone", "SKILL.md")); !os.IsNotExist(err) {
+		t.Fatalf("expected SKILL.md to be deleted, got err=%v", err)
+	}
+	if _, err := os.Stat(script); err != nil {
+		t.Fatalf("expected files the delta does not track to survive, got %v", err)
+	}
+	if _, err := os.Stat(filepath.Join(dst, ".claude", "skills", "plain")); !os.IsNotExist(err) {
+		t.Fatalf("expected the emptied skill directory to be removed, got err=%v", err)
+	}
+}
+
+func TestDeltaImportIgnoresFallbackInstructions(t *testing.T) {
+	src, dst := t.TempDir(), t.TempDir()
+	dir := t.TempDir()
+	basePath, deltaPath := filepath.Join(dir, "base.zip"), filepath.Join(dir, "delta.zip")
+
+	if err := os.WriteFile(filepath.Join(src, "CLAUDE.md"), []byte("# Base"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	exportClaude(t, src, basePath, "")
+	if err := os.Remove(filepath.Join(src, "CLAUDE.md")); err != nil {
+		t.Fatal(err)
+	}
+	exportClaude(t, src, deltaPath, basePath)
+
+	// Claude reads .claude/CLAUDE.md too, but the delta would delete CLAUDE.md,
+	// so only that file counts as the base.
+	if err := os.MkdirAll(filepath.Join(dst, ".claude"), 0o755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(dst, ".claude", "CLAUDE.md"), []byte("# Base"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := importClaude(dst, deltaPath); err == nil || !strings.Contains(err.Error(), "does not match the delta base") {
+		t.Fatalf("expected a base mismatch error, got %v", err)
+	}
+	if _, err := os.Stat(filepath.Join(dst, ".claude", "CLAUDE.md")); err != nil {
+		t.Fatalf("expected the fallback file to be left alone, got %v", err)
+	}
+}
@@ -0,0 +1,194 @@
+package sync
+
+import (
+	"fmt"
+	"path/filepath"
+	"slices"
+	"strings"
+
+	"github.com/LaneBirmingham/coding-agent-sync/internal/agent"
+	"github.com/LaneBirmingham/coding-agent-sync/internal/archive"
+	"github.com/LaneBirmingham/coding-agent-sync/internal/config"
+	"github.com/LaneBirmingham/coding-agent-sync/internal/textdiff"
+)
+
+// SkillChange describes how one skill differs between the two sides of a diff.
+type SkillChange struct {
+	Name   string
+	Status string // "added", "removed", "changed", or "kept" for destination-only skills an import leaves alone
+	Diff   string // unified diff of SKILL.md for changed skills
+}
+
+// SectionDiff holds the differences between two versions of one section.
+type SectionDiff struct {
+	Old, New     string // labels of the compared sides
+	Instructions string // unified diff of the instructions, empty when equal
+	Skills       []SkillChange
+}
+
+// Empty reports whether the two sides are identical. Kept skills are not
+// differences, since an import leaves them as they are.
+func (d SectionDiff) Empty() bool {
+	if d.Instructions != "" {
+		return false
+	}
+	for _, s := range d.Skills {
+		if s.Status != "kept" {
+			return false
+		}
+	}
+	return true
+}
+
+// side is one version of a section's content, with labels for its files.
+type side struct {
+	label        string
+	instructions string
+	instPath     string
+	skills       []agent.Skill
+	skillPath    func(name string) string
+}
+
+// archiveSide returns one section of an archive labelled with its entry names.
+func archiveSide(label string, a *archive.Archive, sec archive.Section) side {
+	prefix := label + ":" + a.EntryPrefix(sec.SectionInfo)
+	s := side{
+		label:     fmt.Sprintf("%s [%s]", label, sec.SectionInfo),
+		instPath:  prefix + "instructions.md",
+		skills:    sec.Skills,
+		skillPath: func(name string) string { return prefix + "skills/" + name + "/SKILL.md" },
+	}
+	if sec.Instructions != nil {
+		s.instructions = sec.Instructions.Content
+	}
+	return s
+}
+
+// DiffArchives compares two archives. Two single-section archives are compared
+// with each other whatever agent they came from; otherwise sections are paired
+// by agent and scope, and a section on one side only shows as all added or removed.
+func DiffArchives(oldLabel string, old *archive.Archive, newLabel string, new *archive.Archive) []SectionDiff {
+	oldSecs, newSecs := old.Contents(), new.Contents()
+	if !old.IsBundle() && !new.IsBundle() {
+		return []SectionDiff{diffSides(archiveSide(oldLabel, old, oldSecs[0]), archiveSide(newLabel, new, newSecs[0]), false)}
+	}
+
+	var diffs []SectionDiff
+	for _, o := range oldSecs {
+		newSide := side{label: fmt.Sprintf("%s [%s]", newLabel, o.SectionInfo), instPath: "/dev/null", skillPath: func(string) string { return "/dev/null" }}
+		if i := slices.IndexFunc(newSecs, func(n archive.Section) bool { return n.SectionInfo == o.SectionInfo }); i >= 0 {
+			newSide = archiveSide(newLabel, new, newSecs[i])
+		}
+		diffs = append(diffs, diffSides(archiveSide(oldLabel, old, o), newSide, false))
+	}
+	for _, n := range newSecs {
+		if slices.ContainsFunc(oldSecs, func(o archive.Section) bool { return o.SectionInfo == n.SectionInfo }) {
+			continue
+		}
+		oldSide := side{label: fmt.Sprintf("%s [%s]", oldLabel, n.SectionInfo), instPath: "/dev/null", skillPath: func(string) string { return "/dev/null" }}
+		diffs = append(diffs, diffSides(oldSide, archiveSide(newLabel, new, n), false))
+	}
+	return diffs
+}
+
+// DiffAgent compares an archive section with the live config it would be
+// imported over, so the diff reads as the changes an import would make. from
+// selects a bundle section; when zero, the section matching dest or the
+// archive's only section is used.
+func DiffAgent(label string, a *archive.Archive, from config.Section, dest config.Section, root string) (SectionDiff, error) {
+	sec, err := pickSection(a, from, dest)
+	if err != nil {
+		return SectionDiff{}, err
+	}
+	// Import renders cas:if sections for the destination, so compare that.
+	if sec, err = renderSection(sec, dest); err != nil {
+		return SectionDiff{}, err
+	}
+
+	loc := config.Location{Root: root, Scope: dest.Scope}
+	dst, err := agent.GetAt(dest.Agent, loc)
+	if err != nil {
+		return SectionDiff{}, err
+	}
+	inst, err := dst.ReadInstructions(loc)
+	if err != nil {
+		return SectionDiff{}, fmt.Errorf("reading instructions from %s: %w", dest.Agent, err)
+	}
+	skills, err := dst.ReadSkills(loc)
+	if err != nil {
+		return SectionDiff{}, fmt.Errorf("reading skills from %s: %w", dest.Agent, err)
+	}
+
+	skillsDir := dst.SkillsPath(loc)
+	live := side{
+		label:     dest.String(),
+		instPath:  dst.InstructionsPath(loc),
+		skills:    skills,
+		skillPath: func(name string) string { return filepath.Join(skillsDir, name, "SKILL.md") },
+	}
+	if inst != nil {
+		live.instructions = inst.Content
+	}
+	return diffSides(live, archiveSide(label, a, sec), true), nil
+}
+
+// pickSection chooses the archive section DiffAgent compares.
+func pickSection(a *archive.Archive, from, dest config.Section) (archive.Section, error) {
+	secs := a.Contents()
+	want := archive.SectionInfo{Agent: string(from.Agent), Scope: string(from.Scope)}
+	if from == (config.Section{}) {
+		if len(secs) == 1 {
+			return secs[0], nil
+		}
+		want = archive.SectionInfo{Agent: string(dest.Agent), Scope: string(dest.Scope)}
+	}
+	for _, s := range secs {
+		if s.SectionInfo == want {
+			return s, nil
+		}
+	}
+	names := make([]string, 0, len(secs))
+	for _, s := range secs {
+		names = append(names, s.String())
+	}
+	return archive.Section{}, fmt.Errorf("archive has no section %s (sections: %s)", want, strings.Join(names, ", "))
+}
+
+// diffSides compares instructions and skills. With keepOld, the old side is
+// an import destination: skills only there are reported as kept rather than
+// removed, and missing new instructions leave the old ones alone, since import
+// never deletes either.
+func diffSides(old, new side, keepOld bool) SectionDiff {
+	d := SectionDiff{Old: old.label, New: new.label}
+	if !keepOld || new.instructions != "" {
+		d.Instructions = textdiff.Unified(old.instPath, new.instPath, old.instructions, new.instructions)
+	}
+
+	oldSkills := make(map[string]string, len(old.skills))
+	for _, s := range old.skills {
+		oldSkills[s.Name] = s.Content
+	}
+	newSkills := make(map[string]string, len(new.skills))
+	for _, s := range new.skills {
+		newSkills[s.Name] = s.Content
+		prev, ok := oldSkills[s.Name]
+		switch {
+		case !ok:
+			d.Skills = append(d.Skills, SkillChange{Name: s.Name, Status: "added", Diff: textdiff.Unified("/dev/null", new.skillPath(s.Name), "", s.Content)})
+		case prev != s.Content:
+			d.Skills = append(d.Skills, SkillChange{Name: s.Name, Status: "changed", Diff: textdiff.Unified(old.skillPath(s.Name), new.skillPath(s.Name), prev, s.Content)})
+		}
+	}
+	for _, s := range old.skills {
+		if _, ok := newSkills[s.Name]; ok {
+			continue
+		}
+		if keepOld {
+			d.Skills = append(d.Skills, SkillChange{Name: s.Name, Status: "kept"})
+		} else {
+			d.Skills = append(d.Skills, SkillChange{Name: s.Name, Status: "removed", Diff: textdiff.Unified(old.skillPath(s.Name), "/dev/null", s.Content, "")})
+		}
+	}
+	slices.SortFunc(d.Skills, func(a, b SkillChange) int { return strings.Compare(a.Name, b.Name) })
+	return d
+}
@@ -0,0 +1,122 @@
+package sync
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/LaneBirmingham/coding-agent-sync/internal/agent"
+	"github.com/LaneBirmingham/coding-agent-sync/internal/archive"
+	"github.com/LaneBirmingham/coding-agent-sync/internal/config"
+)
+
+func diffArchive(agentName, inst string, skills ...agent.Skill) *archive.Archive {
+	return &archive.Archive{
+		Manifest:     &archive.Manifest{Version: archive.FormatVersion, Agent: agentName, Scope: "local", ExportedAt: time.Now()},
+		Instructions: &agent.Instruction{Content: inst},
+		Skills:       skills,
+	}
+}
+
+func TestDiffArchives(t *testing.T) {
+	old := diffArchive("claude", "# Team\nold rule\n",
+		agent.Skill{Name: "lint", Content: "lint\n"},
+		agent.Skill{Name: "review", Content: "v1\n"})
+	new := diffArchive("codex", "# Team\nnew rule\n",
+		agent.Skill{Name: "review", Content: "v2\n"},
+		agent.Skill{Name: "test", Content: "test\n"})
+
+	diffs := DiffArchives("old.zip", old, "new.zip", new)
+	if len(diffs) != 1 {
+		t.Fatalf("expected single-section archives to pair, got %d diffs", len(diffs))
+	}
+	d := diffs[0]
+	if !strings.Contains(d.Instructions, "-old rule\n+new rule\n") {
+		t.Fatalf("unexpected instructions diff:\n%s", d.Instructions)
+	}
+	var got []string
+	for _, s := range d.Skills {
+		got = append(got, s.Name+":"+s.Status)
+	}
+	if strings.Join(got, ",") != "lint:removed,review:changed,test:added" {
+		t.Fatalf("unexpected skill changes %v", got)
+	}
+	if !strings.Contains(d.Skills[1].Diff, "+++ new.zip:skills/review/SKILL.md") {
+		t.Fatalf("unexpected skill diff:\n%s", d.Skills[1].Diff)
+	}
+
+	if d := DiffArchives("a", old, "b", old)[0]; !d.Empty() {
+		t.Fatalf("expected identical archives to have no differences, got %+v", d)
+	}
+}
+
+func TestDiffAgent(t *testing.T) {
+	root := t.TempDir()
+	if err := os.WriteFile(filepath.Join(root, "AGENTS.md"), []byte("# Mine\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.MkdirAll(filepath.Join(root, ".agents", "skills", "personal"), 0o755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(root, ".agents", "skills", "personal", "SKILL.md"), []byte("mine\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	a := diffArchive("claude", "# Team\n", agent.Skill{Name: "review", Content: "v1\n"})
+	d, err := DiffAgent("team.zip", a, config.Section{}, config.Section{Agent: config.Codex, Scope: config.ScopeLocal}, root)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(d.Instructions, "-# Mine\n+# Team\n") {
+		t.Fatalf("unexpected instructions diff:\n%s", d.Instructions)
+	}
+	if len(d.Skills) != 2 || d.Skills[0].Status != "kept" || d.Skills[1].Status != "added" {
+		t.Fatalf("unexpected skill changes %+v", d.Skills)
+	}
+
+	same := diffArchive("claude", "# Mine\n")
+	if d, err := DiffAgent("same.zip", same, config.Section{}, config.Section{Agent: config.Codex, Scope: config.ScopeLocal}, root); err != nil || !d.Empty() {
+		t.Fatalf("expected only a kept skill to be no difference, got %+v (%v)", d, err)
+	}
+
+	bundle := &archive.Archive{
+		Manifest: &archive.Manifest{Version: archive.FormatVersion, ExportedAt: time.Now(),
+			Sections: []archive.SectionInfo{{Agent: "claude", Scope: "local"}, {Agent: "gemini", Scope: "local"}}},
+		Sections: []archive.Section{
+			{SectionInfo: archive.SectionInfo{Agent: "claude", Scope: "local"}},
+			{SectionInfo: archive.SectionInfo{Agent: "gemini", Scope: "local"}},
+		},
+	}
+	if _, err := DiffAgent("bundle.zip", bundle, config.Section{}, config.Section{Agent: config.Codex, Scope: config.ScopeLocal}, root); err == nil {
+		t.Fatal("expected an error when no bundle section matches the agent")
+	}
+	if _, err := DiffAgent("bundle.zip", bundle, config.Section{Agent: "gemini", Scope: config.ScopeLocal}, config.Section{Agent: config.Codex, Scope: config.ScopeLocal}, root); err != nil {
+		t.Fatalf("expected an explicit section to be used, got %v", err)
+	}
+}
+
+func TestDiffAgentRendersConditionals(t *testing.T) {
+	root := t.TempDir()
+	inst := "# Team\n<!-- cas:if agent=claude -->\nClaude only.\n<!-- cas:endif -->\n"
+	a := diffArchive("claude", inst, agent.Skill{Name: "review", Content: inst})
+	dest := config.Section{Agent: config.Codex, Scope: config.ScopeLocal}
+	if err := os.WriteFile(filepath.Join(root, "AGENTS.md"), []byte("# Team\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.MkdirAll(filepath.Join(root, ".agents", "skills", "review"), 0o755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(root, ".agents", "skills", "review", "SKILL.md"), []byte("# Team\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	d, err := DiffAgent("team.zip", a, config.Section{}, dest, root)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !d.Empty() {
+		t.Fatalf("expected the rendered archive to match codex's files, got %+v", d)
+	}
+}
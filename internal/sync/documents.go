@@ -0,0 +1,110 @@
+package sync
+
+import (
+	"fmt"
+	"path/filepath"
+	"strings"
+
+	"github.com/LaneBirmingham/coding-agent-sync/internal/agent"
+	"github.com/LaneBirmingham/coding-agent-sync/internal/config"
+)
+
+// SyncCommands syncs slash commands from source to destination.
+func SyncCommands(cfg *config.SyncConfig, to config.Agent) (SyncAction, error) {
+	return syncDocuments(cfg, to, Commands)
+}
+
+// SyncRules syncs rules from source to destination.
+func SyncRules(cfg *config.SyncConfig, to config.Agent) (SyncAction, error) {
+	return syncDocuments(cfg, to, Rules)
+}
+
+// syncDocuments copies the commands or rules of kind from the source's
+// directory to the destination's, renaming files to the destination's
+// suffix. Documents are always copied, even with --link or --reference.
+func syncDocuments(cfg *config.SyncConfig, to config.Agent, kind ItemKind) (SyncAction, error) {
+	srcLoc := config.Location{Root: cfg.Root, Scope: cfg.FromScope}
+	dstLoc := config.Location{Root: cfg.Root, Scope: cfg.ToScope}
+
+	src, err := agent.Get(cfg.From)
+	if err != nil {
+		return SyncAction{}, err
+	}
+	dst, err := agent.GetAt(to, dstLoc)
+	if err != nil {
+		return SyncAction{}, err
+	}
+
+	action := SyncAction{
+		Kind:      kind,
+		From:      cfg.From,
+		To:        to,
+		FromScope: cfg.FromScope,
+		ToScope:   cfg.ToScope,
+	}
+
+	dirOf := agent.CommandsDir
+	if kind == Rules {
+		dirOf = agent.RulesDir
+	}
+	srcDir, srcSuffix := dirOf(src, srcLoc)
+	dstDir, dstSuffix := dirOf(dst, dstLoc)
+	switch {
+	case srcDir == "":
+		action.Status = "skipped"
+		action.Detail = fmt.Sprintf("skipped (%s does not support %s %s)", cfg.From, srcLoc.Scope, kind)
+		return action, nil
+	case dstDir == "":
+		action.Status = "skipped"
+		action.Detail = fmt.Sprintf("skipped (%s does not support %s %s)", to, dstLoc.Scope, kind)
+		return action, nil
+	case srcDir == dstDir && srcSuffix == dstSuffix:
+		action.Status = "noop"
+		action.Detail = fmt.Sprintf("already in sync (both use %s)", srcDir)
+		return action, nil
+	}
+
+	docs, err := agent.ReadDocuments(srcDir, srcSuffix)
+	if err != nil {
+		return SyncAction{}, fmt.Errorf("reading %s from %s: %w", kind, cfg.From, err)
+	}
+	if len(docs) == 0 {
+		action.Status = "skipped"
+		action.Detail = fmt.Sprintf("skipped (no %s found)", kind)
+		return action, nil
+	}
+
+	noun := strings.TrimSuffix(kind.String(), "s")
+	for i, d := range docs {
+		content, err := renderFor(d.Content, to, dstLoc.Scope)
+		if err != nil {
+			return SyncAction{}, fmt.Errorf("%s %s from %s: %w", noun, d.Name, cfg.From, err)
+		}
+		docs[i].Content = content
+	}
+
+	if cfg.DryRun {
+		action.Status = "dry-run"
+		action.Detail = fmt.Sprintf("would write %d %s(s): %s", len(docs), noun, documentNames(docs))
+		return action, nil
+	}
+
+	if err := agent.WriteDocuments(dstDir, dstSuffix, docs); err != nil {
+		return SyncAction{}, fmt.Errorf("writing %s to %s: %w", kind, to, err)
+	}
+
+	action.Status = "synced"
+	action.Detail = fmt.Sprintf("synced %d %s(s): %s", len(docs), noun, documentNames(docs))
+	for _, d := range docs {
+		action.Paths = append(action.Paths, filepath.Join(dstDir, d.Name+dstSuffix))
+	}
+	return action, nil
+}
+
+func documentNames(docs []agent.Document) string {
+	names := make([]string, len(docs))
+	for i, d := range docs {
+		names[i] = d.Name
+	}
+	return strings.Join(names, ", ")
+}
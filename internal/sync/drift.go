@@ -0,0 +1,181 @@
+package sync
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/LaneBirmingham/coding-agent-sync/internal/agent"
+	"github.com/LaneBirmingham/coding-agent-sync/internal/config"
+	"github.com/LaneBirmingham/coding-agent-sync/internal/managed"
+)
+
+// CheckDrift compares each destination with the source without writing,
+// reporting "drifted" actions for items a sync would change and "in sync"
+// or "skipped" for the rest. Destination-only skills are not drift, since
+// sync never deletes them. Several sources are compared as their merge.
+func CheckDrift(cfg *config.SyncConfig, kind ItemKind) (*Result, error) {
+	srcLoc := config.Location{Root: cfg.Root, Scope: cfg.FromScope}
+	dstLoc := config.Location{Root: cfg.Root, Scope: cfg.ToScope}
+	src, err := agent.Get(cfg.From)
+	if err != nil {
+		return nil, err
+	}
+
+	var inst *agent.Instruction
+	var skills []agent.Skill
+	merging := len(cfg.Sources) > 1
+	if merging {
+		m, err := mergeSources(cfg, kind)
+		if err != nil {
+			return nil, err
+		}
+		if m.inst != "" {
+			inst = &agent.Instruction{Content: m.inst}
+		}
+		skills = m.skills
+	}
+	if kind.has(Instructions) && !merging {
+		if inst, _, err = agent.ReadComposedInstructions(src, srcLoc); err != nil {
+			return nil, fmt.Errorf("reading instructions from %s: %w", cfg.From, err)
+		}
+	}
+	if kind.has(Skills) && !merging {
+		if skills, err = src.ReadSkills(srcLoc); err != nil {
+			return nil, fmt.Errorf("reading skills from %s: %w", cfg.From, err)
+		}
+	}
+
+	var result Result
+	for _, to := range cfg.To {
+		dst, err := agent.GetAt(to, dstLoc)
+		if err != nil {
+			return nil, err
+		}
+		base := SyncAction{From: cfg.From, To: to, FromScope: cfg.FromScope, ToScope: cfg.ToScope}
+
+		if kind.has(Instructions) {
+			action := base
+			action.Kind = Instructions
+			path := dst.InstructionsPath(dstLoc)
+			switch {
+			case path == "":
+				action.Status, action.Detail = "skipped", fmt.Sprintf("skipped (%s does not support %s instructions)", to, dstLoc.Scope)
+			case inst == nil:
+				action.Status, action.Detail = "skipped", "skipped (no source file found)"
+			case !merging && path == src.InstructionsPath(srcLoc):
+				action.Status, action.Detail = "in sync", fmt.Sprintf("in sync (both use %s)", path)
+			default:
+				want, err := renderFor(inst.Content, to, dstLoc.Scope)
+				if err != nil {
+					return nil, fmt.Errorf("instructions from %s: %w", cfg.From, err)
+				}
+				state, err := compareInstructions(path, want)
+				if err != nil {
+					return nil, err
+				}
+				action.Status, action.Detail = "drifted", fmt.Sprintf("drifted (%s %s)", path, state)
+				if state == "" {
+					action.Status, action.Detail = "in sync", fmt.Sprintf("in sync (%s)", path)
+				}
+			}
+			result.Actions = append(result.Actions, action)
+		}
+
+		if kind.has(Skills) {
+			action := base
+			action.Kind = Skills
+			dir := dst.SkillsPath(dstLoc)
+			switch {
+			case len(skills) == 0:
+				action.Status, action.Detail = "skipped", "skipped (no skills found)"
+			case dir == "":
+				action.Status, action.Detail = "skipped", fmt.Sprintf("skipped (%s does not support %s skills)", to, dstLoc.Scope)
+			default:
+				var missing, changed []string
+				for _, s := range skills {
+					want, err := renderFor(s.Content, to, dstLoc.Scope)
+					if err != nil {
+						return nil, fmt.Errorf("skill %s from %s: %w", s.Name, cfg.From, err)
+					}
+					state, err := compareFile(filepath.Join(dir, s.Name, "SKILL.md"), want)
+					if err != nil {
+						return nil, err
+					}
+					switch state {
+					case stateMissing:
+						missing = append(missing, s.Name)
+					case stateDiffers:
+						changed = append(changed, s.Name)
+					}
+				}
+				action.Status, action.Detail = "in sync", fmt.Sprintf("in sync (%d skill(s))", len(skills))
+				if len(missing) > 0 || len(changed) > 0 {
+					var parts []string
+					if len(missing) > 0 {
+						parts = append(parts, "missing: "+strings.Join(missing, ", "))
+					}
+					if len(changed) > 0 {
+						parts = append(parts, "differ: "+strings.Join(changed, ", "))
+					}
+					action.Status, action.Detail = "drifted", "drifted ("+strings.Join(parts, "; ")+")"
+				}
+			}
+			result.Actions = append(result.Actions, action)
+		}
+	}
+	return &result, nil
+}
+
+// Drifted returns the actions whose destination differs from the source.
+func (r *Result) Drifted() []SyncAction {
+	var out []SyncAction
+	for _, a := range r.Actions {
+		if a.Status == "drifted" {
+			out = append(out, a)
+		}
+	}
+	return out
+}
+
+const (
+	stateMissing = "is missing"
+	stateDiffers = "differs from the source"
+)
+
+// compareInstructions is compareFile for an instructions file, comparing
+// only the managed block when the file has one.
+func compareInstructions(path, want string) (string, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return compareFile(path, want)
+	}
+	body, ok, err := managed.Extract(string(data))
+	if err != nil {
+		return "", fmt.Errorf("%s: %w", path, err)
+	}
+	if !ok {
+		return compareFile(path, want)
+	}
+	if body != managed.Body(want) {
+		return stateDiffers, nil
+	}
+	return "", nil
+}
+
+// compareFile reports how the file at path differs from want: stateMissing,
+// stateDiffers, or "" when it matches.
+func compareFile(path, want string) (string, error) {
+	data, err := os.ReadFile(path)
+	if os.IsNotExist(err) {
+		return stateMissing, nil
+	}
+	if err != nil {
+		return "", err
+	}
+	if string(data) != want {
+		return stateDiffers, nil
+	}
+	return "", nil
+}
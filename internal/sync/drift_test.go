@@ -0,0 +1,62 @@
+package sync
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/LaneBirmingham/coding-agent-sync/internal/config"
+)
+
+func TestCheckDrift(t *testing.T) {
+	root := t.TempDir()
+	if err := os.WriteFile(filepath.Join(root, "CLAUDE.md"), []byte("# A"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	writeClaudeSkill(t, root, "s1", "one")
+	writeClaudeSkill(t, root, "s2", "two")
+	cfg := &config.SyncConfig{
+		From:      config.Claude,
+		To:        []config.Agent{config.Copilot},
+		Root:      root,
+		FromScope: config.ScopeLocal,
+		ToScope:   config.ScopeLocal,
+	}
+
+	result, err := CheckDrift(cfg, All)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := len(result.Drifted()); got != 2 {
+		t.Fatalf("expected instructions and skills to drift, got %v", result.Actions)
+	}
+
+	if _, err := SyncAll(cfg, All); err != nil {
+		t.Fatal(err)
+	}
+	if result, err = CheckDrift(cfg, All); err != nil {
+		t.Fatal(err)
+	}
+	if drifted := result.Drifted(); len(drifted) != 0 {
+		t.Fatalf("expected no drift after sync, got %v", drifted)
+	}
+
+	// A destination-only skill is not drift; an edited one is.
+	if err := os.MkdirAll(filepath.Join(root, ".github", "skills", "extra"), 0o755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(root, ".github", "skills", "extra", "SKILL.md"), []byte("x"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(root, ".github", "skills", "s2", "SKILL.md"), []byte("edited"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	if result, err = CheckDrift(cfg, All); err != nil {
+		t.Fatal(err)
+	}
+	drifted := result.Drifted()
+	if len(drifted) != 1 || drifted[0].Kind != Skills || !strings.Contains(drifted[0].Detail, "differ: s2") {
+		t.Fatalf("expected only s2 to drift, got %v", drifted)
+	}
+}
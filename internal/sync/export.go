@@ -2,35 +2,127 @@ package sync
 
 import (
 	"fmt"
+	"os"
 	"time"
 
 	"github.com/LaneBirmingham/coding-agent-sync/internal/agent"
 	"github.com/LaneBirmingham/coding-agent-sync/internal/archive"
 	"github.com/LaneBirmingham/coding-agent-sync/internal/config"
+	"github.com/LaneBirmingham/coding-agent-sync/internal/keys"
 )
 
-// Export reads config from an agent and writes it to a ZIP archive.
+// Export reads config from an agent and writes it to an archive. When
+// cfg.Sections is set, it writes a bundle with one section per agent and scope.
 func Export(cfg *config.ExportConfig) (*ArchiveResult, error) {
-	src, err := agent.Get(cfg.From)
+	if len(cfg.Sections) > 0 {
+		return exportBundle(cfg)
+	}
+
+	result := &ArchiveResult{}
+	inst, skills, actions, err := exportSection(cfg, config.Section{Agent: cfg.From, Scope: cfg.Scope})
 	if err != nil {
 		return nil, err
 	}
+	result.Actions = append(result.Actions, actions...)
+
+	// Build and write archive
+	a := &archive.Archive{
+		Manifest: &archive.Manifest{
+			Version:    archive.FormatVersion,
+			Agent:      string(cfg.From),
+			Scope:      string(cfg.Scope),
+			ExportedAt: exportTime(cfg),
+			CASVersion: cfg.CASVersion,
+			Package:    exportPackage(cfg),
+		},
+		Instructions: inst,
+		Skills:       skills,
+	}
+
+	if cfg.Since != "" {
+		if err := exportDelta(cfg, a, result.Actions); err != nil {
+			return nil, err
+		}
+	}
+	if cfg.DryRun {
+		return result, nil
+	}
+
+	if err := writeArchive(cfg, a); err != nil {
+		return nil, err
+	}
+	return result, nil
+}
 
-	loc := config.Location{Root: cfg.Root, Scope: cfg.Scope}
+// exportBundle writes every non-empty section in cfg.Sections to one archive.
+func exportBundle(cfg *config.ExportConfig) (*ArchiveResult, error) {
 	result := &ArchiveResult{}
+	a := &archive.Archive{
+		Manifest: &archive.Manifest{
+			Version:    archive.FormatVersion,
+			ExportedAt: exportTime(cfg),
+			CASVersion: cfg.CASVersion,
+			Package:    exportPackage(cfg),
+		},
+	}
+
+	for _, sec := range cfg.Sections {
+		inst, skills, actions, err := exportSection(cfg, sec)
+		if err != nil {
+			return nil, err
+		}
+		result.Actions = append(result.Actions, actions...)
+		// A delta keeps empty sections so their old content can be deleted.
+		if inst == nil && len(skills) == 0 && cfg.Since == "" {
+			continue
+		}
+		info := archive.SectionInfo{Agent: string(sec.Agent), Scope: string(sec.Scope)}
+		a.Manifest.Sections = append(a.Manifest.Sections, info)
+		a.Sections = append(a.Sections, archive.Section{SectionInfo: info, Instructions: inst, Skills: skills})
+	}
+
+	if cfg.Since != "" {
+		if err := exportDelta(cfg, a, result.Actions); err != nil {
+			return nil, err
+		}
+	}
+	if cfg.DryRun {
+		return result, nil
+	}
+	if len(a.Sections) == 0 {
+		return nil, fmt.Errorf("nothing to export: no instructions or skills found in any section")
+	}
+
+	if err := writeArchive(cfg, a); err != nil {
+		return nil, err
+	}
+	return result, nil
+}
+
+// exportSection reads the instructions and skills for one agent and scope,
+// returning nil instructions when there are none.
+func exportSection(cfg *config.ExportConfig, sec config.Section) (*agent.Instruction, []agent.Skill, []ArchiveAction, error) {
+	loc := config.Location{Root: cfg.Root, Scope: sec.Scope}
+	src, err := agent.Get(sec.Agent)
+	if err != nil {
+		return nil, nil, nil, err
+	}
+
+	var actions []ArchiveAction
 
 	// Read instructions
-	inst, err := src.ReadInstructions(loc)
+	inst, _, err := agent.ReadComposedInstructions(src, loc)
 	if err != nil {
-		return nil, fmt.Errorf("reading instructions from %s: %w", cfg.From, err)
+		return nil, nil, nil, fmt.Errorf("reading instructions from %s: %w", sec.Agent, err)
 	}
 
 	instAction := ArchiveAction{
 		Kind:  Instructions,
-		Agent: cfg.From,
-		Scope: cfg.Scope,
+		Agent: sec.Agent,
+		Scope: sec.Scope,
 	}
 	if inst == nil || inst.Content == "" {
+		inst = nil
 		instAction.Status = "skipped"
 		instAction.Detail = "skipped (no instructions found)"
 	} else if cfg.DryRun {
@@ -40,18 +132,18 @@ func Export(cfg *config.ExportConfig) (*ArchiveResult, error) {
 		instAction.Status = "exported"
 		instAction.Detail = fmt.Sprintf("exported (%d bytes)", len(inst.Content))
 	}
-	result.Actions = append(result.Actions, instAction)
+	actions = append(actions, instAction)
 
 	// Read skills
 	skills, err := src.ReadSkills(loc)
 	if err != nil {
-		return nil, fmt.Errorf("reading skills from %s: %w", cfg.From, err)
+		return nil, nil, nil, fmt.Errorf("reading skills from %s: %w", sec.Agent, err)
 	}
 
 	skillAction := ArchiveAction{
 		Kind:  Skills,
-		Agent: cfg.From,
-		Scope: cfg.Scope,
+		Agent: sec.Agent,
+		Scope: sec.Scope,
 	}
 	if len(skills) == 0 {
 		skillAction.Status = "skipped"
@@ -63,30 +155,61 @@ func Export(cfg *config.ExportConfig) (*ArchiveResult, error) {
 		skillAction.Status = "exported"
 		skillAction.Detail = fmt.Sprintf("exported %d skill(s): %s", len(skills), skillNames(skills))
 	}
-	result.Actions = append(result.Actions, skillAction)
+	actions = append(actions, skillAction)
 
-	if cfg.DryRun {
-		return result, nil
+	return inst, skills, actions, nil
+}
+
+// exportPackage returns the registry bundle version named in cfg, if any.
+func exportPackage(cfg *config.ExportConfig) *archive.Package {
+	if cfg.PackageName == "" {
+		return nil
 	}
+	return &archive.Package{Name: cfg.PackageName, Version: cfg.PackageVersion}
+}
 
-	// Build and write archive
-	a := &archive.Archive{
-		Manifest: &archive.Manifest{
-			Version:    archive.FormatVersion,
-			Agent:      string(cfg.From),
-			Scope:      string(cfg.Scope),
-			ExportedAt: time.Now().UTC(),
-			CASVersion: cfg.CASVersion,
-		},
+// exportTime is the time recorded in the manifest: the fixed source date of a
+// reproducible export, or now.
+func exportTime(cfg *config.ExportConfig) time.Time {
+	if !cfg.SourceDate.IsZero() {
+		return cfg.SourceDate.UTC()
 	}
-	if inst != nil && inst.Content != "" {
-		a.Instructions = inst
+	return time.Now().UTC()
+}
+
+// writeArchive loads the signing and encryption keys named in cfg and writes a.
+func writeArchive(cfg *config.ExportConfig, a *archive.Archive) error {
+	opts := archive.WriteOptions{ModTime: cfg.SourceDate.UTC()}
+	if cfg.SigningKey != "" {
+		var err error
+		if opts.SigningKey, err = keys.LoadSigningKey(cfg.SigningKey); err != nil {
+			return err
+		}
+	}
+	opts.Passphrase = []byte(cfg.Passphrase)
+	for _, r := range cfg.Recipients {
+		recipient, err := keys.LoadRecipient(r)
+		if err != nil {
+			return err
+		}
+		opts.Recipients = append(opts.Recipients, recipient.Key)
 	}
-	a.Skills = skills
 
-	if err := archive.Write(cfg.Output, a); err != nil {
-		return nil, fmt.Errorf("writing archive: %w", err)
+	if cfg.Format != "" {
+		var err error
+		if opts.Format, err = archive.ParseFormat(cfg.Format); err != nil {
+			return err
+		}
 	}
 
-	return result, nil
+	var err error
+	if cfg.Output == config.StdioPath {
+		err = archive.WriteTo(os.Stdout, a, opts)
+	} else {
+		err = archive.WriteWith(cfg.Output, a, opts)
+	}
+	if err != nil {
+		return fmt.Errorf("writing archive: %w", err)
+	}
+	return nil
 }
@@ -0,0 +1,180 @@
+package sync
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/LaneBirmingham/coding-agent-sync/internal/agent"
+	"github.com/LaneBirmingham/coding-agent-sync/internal/archive"
+	"github.com/LaneBirmingham/coding-agent-sync/internal/config"
+	"github.com/LaneBirmingham/coding-agent-sync/internal/detect"
+	"github.com/LaneBirmingham/coding-agent-sync/internal/gitsrc"
+)
+
+// importGit imports from a git revision. The path at cfg.Git.Subdir may be a
+// cas archive (a file, or a directory with manifest.json) or an agent layout
+// such as a directory holding CLAUDE.md and .claude/skills.
+func importGit(cfg *config.ImportConfig) (*ArchiveResult, error) {
+	co, err := gitsrc.Fetch(cfg.Git.Repo, cfg.Git.Ref)
+	if err != nil {
+		return nil, err
+	}
+	defer co.Close()
+
+	path, err := co.Path(cfg.Git.Subdir)
+	if err != nil {
+		return nil, err
+	}
+
+	result := &ArchiveResult{Commit: co.Commit}
+
+	if isArchive(path) {
+		opts, err := readOptions(cfg)
+		if err != nil {
+			return nil, err
+		}
+		a, err := archive.ReadWith(path, opts)
+		if err != nil {
+			return nil, fmt.Errorf("reading archive: %w", err)
+		}
+		if err := checkTrust(a, cfg, result); err != nil {
+			return nil, err
+		}
+		return importArchive(a, cfg, result)
+	}
+
+	// A plain agent layout carries no signature, so it is only imported with
+	// --allow-unsigned; the pinned commit identifies what was read.
+	a, err := layoutArchive(co.Dir, path, cfg.Git.From, result)
+	if err != nil {
+		return nil, err
+	}
+	if err := checkTrust(a, cfg, result); err != nil {
+		return nil, err
+	}
+	return importArchive(a, cfg, result)
+}
+
+// isArchive reports whether path is an archive file or an archive directory.
+func isArchive(path string) bool {
+	info, err := os.Stat(path)
+	if err != nil {
+		return false
+	}
+	if !info.IsDir() {
+		return true
+	}
+	return exists(filepath.Join(path, "manifest.json"))
+}
+
+// layoutArchive reads an agent layout rooted at dir, inside the checkout
+// top, into an in-memory archive. Without from, it picks the agent with the
+// most config present, preferring agents listed first.
+func layoutArchive(top, dir string, from config.Agent, result *ArchiveResult) (*archive.Archive, error) {
+	loc := config.Local(dir)
+	if from == "" {
+		best, bestScore := config.Agent(""), 0
+		for _, a := range config.Agents() {
+			f, err := detect.Scan(a, loc)
+			if err != nil {
+				return nil, err
+			}
+			score := 0
+			if f.Instructions != nil {
+				score++
+			}
+			if f.Skills != nil {
+				score += f.Skills.Count
+			}
+			if score > bestScore {
+				best, bestScore = a, score
+			}
+		}
+		if best == "" {
+			return nil, fmt.Errorf("no cas archive or agent instructions or skills found at the requested path")
+		}
+		from = best
+		result.Warnings = append(result.Warnings, fmt.Sprintf("reading %s layout from the repository (use --from to choose another agent)", from))
+	}
+
+	src, err := agent.Get(from)
+	if err != nil {
+		return nil, err
+	}
+	if err := checkLayoutPaths(top, src, loc); err != nil {
+		return nil, err
+	}
+	inst, err := src.ReadInstructions(loc)
+	if err != nil {
+		return nil, fmt.Errorf("reading instructions from %s: %w", from, err)
+	}
+	if inst != nil && inst.Content == "" {
+		inst = nil
+	}
+	skills, err := src.ReadSkills(loc)
+	if err != nil {
+		return nil, fmt.Errorf("reading skills from %s: %w", from, err)
+	}
+
+	return &archive.Archive{
+		Manifest: &archive.Manifest{
+			Version:    archive.FormatVersion,
+			Agent:      string(from),
+			Scope:      string(config.ScopeLocal),
+			ExportedAt: time.Now().UTC(),
+		},
+		Instructions: inst,
+		Skills:       skills,
+	}, nil
+}
+
+// checkLayoutPaths rejects symlinks anywhere on the paths an agent reads
+// from a checkout, so a repository cannot make the import read files outside
+// it, such as a CLAUDE.md linking to a secret in the home directory.
+func checkLayoutPaths(top string, src agent.Agent, loc config.Location) error {
+	paths := append([]string{}, src.InstructionsSources(loc)...)
+	for _, dir := range src.SkillsDirs(loc) {
+		paths = append(paths, dir)
+		entries, err := os.ReadDir(dir)
+		if err != nil {
+			continue
+		}
+		for _, e := range entries {
+			paths = append(paths, filepath.Join(dir, e.Name()), filepath.Join(dir, e.Name(), "SKILL.md"))
+		}
+	}
+	for _, p := range paths {
+		if err := checkNoSymlink(top, p); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// checkNoSymlink fails when path is outside top or any existing component
+// of it below top is a symlink.
+func checkNoSymlink(top, path string) error {
+	rel, err := filepath.Rel(top, path)
+	if err != nil || !filepath.IsLocal(rel) {
+		return fmt.Errorf("%s is outside the repository", path)
+	}
+	cur := top
+	for _, part := range strings.Split(rel, string(filepath.Separator)) {
+		cur = filepath.Join(cur, part)
+		info, err := os.Lstat(cur)
+		if os.IsNotExist(err) {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+		if info.Mode()&os.ModeSymlink != 0 {
+			rel, _ := filepath.Rel(top, cur)
+			return fmt.Errorf("refusing to import %s from the repository: it is a symlink", filepath.ToSlash(rel))
+		}
+	}
+	return nil
+}
@@ -0,0 +1,168 @@
+package sync
+
+import (
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/LaneBirmingham/coding-agent-sync/internal/agent"
+	"github.com/LaneBirmingham/coding-agent-sync/internal/archive"
+	"github.com/LaneBirmingham/coding-agent-sync/internal/config"
+)
+
+// commitRepo creates a git repository from files and returns its path.
+func commitRepo(t *testing.T, files map[string]string, setup func(dir string)) string {
+	t.Helper()
+	if _, err := exec.LookPath("git"); err != nil {
+		t.Skip("git not installed")
+	}
+	dir := t.TempDir()
+	for name, content := range files {
+		path := filepath.Join(dir, name)
+		if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(path, []byte(content), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if setup != nil {
+		setup(dir)
+	}
+	for _, args := range [][]string{
+		{"init", "--quiet", "-b", "main"},
+		{"add", "-A"},
+		{"-c", "user.name=test", "-c", "user.email=test@example.com", "commit", "--quiet", "-m", "init"},
+		{"tag", "v1.2"},
+	} {
+		cmd := exec.Command("git", args...)
+		cmd.Dir = dir
+		if out, err := cmd.CombinedOutput(); err != nil {
+			t.Fatalf("git %v: %v\n%s", args, err, out)
+		}
+	}
+	return dir
+}
+
+func TestImportGitLayout(t *testing.T) {
+	repo := commitRepo(t, map[string]string{
+		"agents/CLAUDE.md":                      "# Team",
+		"agents/.claude/skills/review/SKILL.md": "review",
+	}, nil)
+	root := t.TempDir()
+
+	cfg := &config.ImportConfig{
+		To:   []config.Agent{config.Codex},
+		Root: root,
+		Git:  &config.GitSource{Repo: "file://" + repo, Ref: "v1.2", Subdir: "agents/"},
+	}
+	if _, err := Import(cfg); err == nil || !strings.Contains(err.Error(), "--allow-unsigned") {
+		t.Fatalf("expected an unsigned layout to require --allow-unsigned, got %v", err)
+	}
+	cfg.AllowUnsigned = true
+	result, err := Import(cfg)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(result.Commit) != 40 {
+		t.Fatalf("expected pinned commit SHA, got %q", result.Commit)
+	}
+	if !strings.Contains(strings.Join(result.Warnings, "\n"), "reading claude layout") {
+		t.Fatalf("expected detected layout warning, got %v", result.Warnings)
+	}
+	if got, err := os.ReadFile(filepath.Join(root, "AGENTS.md")); err != nil || string(got) != "# Team" {
+		t.Fatalf("expected imported instructions, got %q (%v)", got, err)
+	}
+	if got, err := os.ReadFile(filepath.Join(root, ".agents", "skills", "review", "SKILL.md")); err != nil || string(got) != "review" {
+		t.Fatalf("expected imported skill, got %q (%v)", got, err)
+	}
+}
+
+func TestImportGitArchive(t *testing.T) {
+	repo := commitRepo(t, nil, func(dir string) {
+		if err := archive.WriteWith(filepath.Join(dir, "team"), &archive.Archive{
+			Manifest: &archive.Manifest{
+				Version:    archive.FormatVersion,
+				Agent:      "claude",
+				Scope:      "local",
+				ExportedAt: time.Now().UTC(),
+			},
+			Instructions: &agent.Instruction{Content: "# Archived"},
+		}, archive.WriteOptions{Format: archive.FormatDir}); err != nil {
+			t.Fatal(err)
+		}
+	})
+	root := t.TempDir()
+
+	cfg := &config.ImportConfig{
+		To:   []config.Agent{config.Gemini},
+		Root: root,
+		Git:  &config.GitSource{Repo: repo, Subdir: "team"},
+	}
+	if _, err := Import(cfg); err == nil {
+		t.Fatal("expected unsigned archive from git to require --allow-unsigned")
+	}
+	cfg.AllowUnsigned = true
+	if _, err := Import(cfg); err != nil {
+		t.Fatal(err)
+	}
+	if got, err := os.ReadFile(filepath.Join(root, "GEMINI.md")); err != nil || string(got) != "# Archived" {
+		t.Fatalf("expected imported instructions, got %q (%v)", got, err)
+	}
+}
+
+func TestImportGitLayoutRejectsSymlinks(t *testing.T) {
+	secret := filepath.Join(t.TempDir(), "secret")
+	if err := os.WriteFile(secret, []byte("token"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	repo := commitRepo(t, nil, func(dir string) {
+		if err := os.Symlink(secret, filepath.Join(dir, "CLAUDE.md")); err != nil {
+			t.Fatal(err)
+		}
+	})
+	root := t.TempDir()
+
+	_, err := Import(&config.ImportConfig{
+		To:            []config.Agent{config.Codex},
+		Root:          root,
+		AllowUnsigned: true,
+		Git:           &config.GitSource{Repo: repo, From: config.Claude},
+	})
+	if err == nil || !strings.Contains(err.Error(), "symlink") {
+		t.Fatalf("expected a symlink error, got %v", err)
+	}
+	if _, err := os.Stat(filepath.Join(root, "AGENTS.md")); !os.IsNotExist(err) {
+		t.Fatalf("expected nothing written, got %v", err)
+	}
+}
+
+func TestImportGitLayoutRendersConditionals(t *testing.T) {
+	repo := commitRepo(t, map[string]string{
+		"CLAUDE.md":                      "# Team\n<!-- cas:if agent=codex -->\nCodex only.\n<!-- cas:endif -->\n<!-- cas:if agent=gemini -->\nGemini only.\n<!-- cas:endif -->\n",
+		".claude/skills/review/SKILL.md": "review\n<!-- cas:if agent=gemini -->\nGemini only.\n<!-- cas:endif -->\n",
+	}, nil)
+	root := t.TempDir()
+
+	if _, err := Import(&config.ImportConfig{
+		To:            []config.Agent{config.Codex, config.Gemini},
+		Root:          root,
+		AllowUnsigned: true,
+		Git:           &config.GitSource{Repo: repo, From: config.Claude},
+	}); err != nil {
+		t.Fatal(err)
+	}
+	for path, want := range map[string]string{
+		"AGENTS.md":                      "# Team\nCodex only.\n",
+		"GEMINI.md":                      "# Team\nGemini only.\n",
+		".agents/skills/review/SKILL.md": "review\n",
+		".gemini/skills/review/SKILL.md": "review\nGemini only.\n",
+	} {
+		if got, err := os.ReadFile(filepath.Join(root, path)); err != nil || string(got) != want {
+			t.Errorf("%s: expected %q, got %q (%v)", path, want, got, err)
+		}
+	}
+}
@@ -1,87 +1,400 @@
 package sync
 
 import (
+	"errors"
 	"fmt"
+	"os"
+	"path"
+	"path/filepath"
+	"slices"
+	"strings"
 
 	"github.com/LaneBirmingham/coding-agent-sync/internal/agent"
 	"github.com/LaneBirmingham/coding-agent-sync/internal/archive"
 	"github.com/LaneBirmingham/coding-agent-sync/internal/config"
+	"github.com/LaneBirmingham/coding-agent-sync/internal/keys"
 )
 
-// Import reads a ZIP archive and writes its contents to one or more agents.
+// Import reads an archive and writes its contents to one or more agents.
 func Import(cfg *config.ImportConfig) (*ArchiveResult, error) {
-	a, err := archive.Read(cfg.Input)
+	if cfg.Git != nil {
+		return importGit(cfg)
+	}
+
+	opts, err := readOptions(cfg)
+	if err != nil {
+		return nil, err
+	}
+
+	var a *archive.Archive
+	if cfg.Input == config.StdioPath {
+		a, err = archive.ReadFrom(os.Stdin, opts)
+	} else {
+		a, err = archive.ReadWith(cfg.Input, opts)
+	}
 	if err != nil {
 		return nil, fmt.Errorf("reading archive: %w", err)
 	}
 
 	result := &ArchiveResult{}
 
-	for _, to := range cfg.To {
+	if err := checkTrust(a, cfg, result); err != nil {
+		return nil, err
+	}
+
+	return importArchive(a, cfg, result)
+}
+
+// readOptions loads the decryption keys and read limits named in cfg.
+func readOptions(cfg *config.ImportConfig) (archive.ReadOptions, error) {
+	opts := archive.ReadOptions{
+		Passphrase: []byte(cfg.Passphrase),
+		Limits: archive.Limits{
+			MaxEntries:   cfg.MaxEntries,
+			MaxEntrySize: cfg.MaxEntrySize,
+			MaxTotalSize: cfg.MaxTotalSize,
+		},
+	}
+	for _, path := range cfg.Identities {
+		id, err := keys.LoadIdentity(path)
+		if err != nil {
+			return opts, err
+		}
+		opts.Identities = append(opts.Identities, id)
+	}
+	return opts, nil
+}
+
+// importArchive writes the sections of an archive that has passed the trust check.
+func importArchive(a *archive.Archive, cfg *config.ImportConfig, result *ArchiveResult) (*ArchiveResult, error) {
+	targets, err := planImport(a, cfg)
+	if err != nil {
+		return nil, err
+	}
+	if err := checkSkillNames(targets, cfg.Skills); err != nil {
+		return nil, err
+	}
+	for i := range targets {
+		if targets[i].section, err = renderSection(targets[i].section, targets[i].to); err != nil {
+			return nil, err
+		}
+	}
+
+	// A delta is applied only where the destination still holds its base, so
+	// every target is checked before anything is written.
+	delta := a.Manifest.Delta
+	if delta != nil {
+		if err := checkDeltaImport(cfg); err != nil {
+			return nil, err
+		}
+		for _, t := range targets {
+			if err := verifyDeltaBase(cfg.Root, delta, a.IsBundle(), t.section, t.to); err != nil {
+				return nil, err
+			}
+		}
+	}
+
+	for _, t := range targets {
 		// Warn on agent mismatch
-		if config.Agent(a.Manifest.Agent) != to {
+		if config.Agent(t.section.Agent) != t.to.Agent {
 			result.Warnings = append(result.Warnings,
-				fmt.Sprintf("archive was exported from %s, importing to %s", a.Manifest.Agent, to))
+				fmt.Sprintf("archive was exported from %s, importing to %s", t.section.Agent, t.to.Agent))
 		}
 		// Warn on scope mismatch
-		if config.Scope(a.Manifest.Scope) != cfg.Scope {
+		if config.Scope(t.section.Scope) != t.to.Scope {
 			result.Warnings = append(result.Warnings,
-				fmt.Sprintf("archive scope is %s, importing to %s scope", a.Manifest.Scope, cfg.Scope))
+				fmt.Sprintf("archive scope is %s, importing to %s scope", t.section.Scope, t.to.Scope))
 		}
 
-		dst, err := agent.Get(to)
+		actions, err := importSection(cfg, t.section, t.to)
 		if err != nil {
 			return nil, err
 		}
+		result.Actions = append(result.Actions, actions...)
+
+		if delta != nil {
+			actions, err := applyDeletions(cfg, delta.Section(t.section.SectionInfo), t.to)
+			if err != nil {
+				return nil, err
+			}
+			result.Actions = append(result.Actions, actions...)
+		}
+	}
+
+	return result, nil
+}
 
-		loc := config.Location{Root: cfg.Root, Scope: cfg.Scope}
+// importTarget routes one archive section to a destination agent and scope.
+type importTarget struct {
+	section archive.Section
+	to      config.Section
+}
 
-		// Import instructions
+// planImport decides where each archive section goes. Explicit mappings win;
+// otherwise the selected sections go to cfg.To, or to their own agent when
+// cfg.To is empty. A single-agent archive goes to cfg.Scope (default local),
+// while bundle sections keep their scope and cfg.Scope selects among them.
+func planImport(a *archive.Archive, cfg *config.ImportConfig) ([]importTarget, error) {
+	sections := a.Contents()
+	find := func(want config.Section) (archive.Section, error) {
+		for _, s := range sections {
+			if config.Agent(s.Agent) == want.Agent && config.Scope(s.Scope) == want.Scope {
+				return s, nil
+			}
+		}
+		names := make([]string, 0, len(sections))
+		for _, s := range sections {
+			names = append(names, s.String())
+		}
+		return archive.Section{}, fmt.Errorf("archive has no section %s (available: %s)", want, strings.Join(names, ", "))
+	}
+
+	var targets []importTarget
+	if len(cfg.Mappings) > 0 {
+		for _, m := range cfg.Mappings {
+			s, err := find(m.From)
+			if err != nil {
+				return nil, err
+			}
+			targets = append(targets, importTarget{section: s, to: m.To})
+		}
+		return targets, nil
+	}
+
+	selected := sections
+	if len(cfg.Sections) > 0 {
+		selected = nil
+		for _, want := range cfg.Sections {
+			s, err := find(want)
+			if err != nil {
+				return nil, err
+			}
+			selected = append(selected, s)
+		}
+	} else if a.IsBundle() && cfg.Scope != "" {
+		selected = slices.DeleteFunc(slices.Clone(sections), func(s archive.Section) bool {
+			return config.Scope(s.Scope) != cfg.Scope
+		})
+	}
+
+	for _, s := range selected {
+		scope := config.Scope(s.Scope)
+		if !a.IsBundle() {
+			scope = cfg.Scope
+			if scope == "" {
+				scope = config.ScopeLocal
+			}
+		}
+		agents := cfg.To
+		if len(agents) == 0 {
+			own, err := config.ParseAgent(s.Agent)
+			if err != nil {
+				return nil, fmt.Errorf("section %s: %w (pass --to or --map)", s, err)
+			}
+			agents = []config.Agent{own}
+		}
+		for _, to := range agents {
+			targets = append(targets, importTarget{section: s, to: config.Section{Agent: to, Scope: scope}})
+		}
+	}
+	if len(targets) == 0 {
+		return nil, fmt.Errorf("no archive sections match the requested scope %s", cfg.Scope)
+	}
+	return targets, nil
+}
+
+// renderSection renders the cas:if sections of an archive section's
+// instructions and skills for the destination it is imported to.
+func renderSection(sec archive.Section, dest config.Section) (archive.Section, error) {
+	if sec.Instructions != nil {
+		content, err := renderFor(sec.Instructions.Content, dest.Agent, dest.Scope)
+		if err != nil {
+			return archive.Section{}, fmt.Errorf("instructions in section %s: %w", sec.SectionInfo, err)
+		}
+		sec.Instructions = &agent.Instruction{Content: content}
+	}
+	skills := make([]agent.Skill, len(sec.Skills))
+	for i, s := range sec.Skills {
+		content, err := renderFor(s.Content, dest.Agent, dest.Scope)
+		if err != nil {
+			return archive.Section{}, fmt.Errorf("skill %s in section %s: %w", s.Name, sec.SectionInfo, err)
+		}
+		skills[i] = agent.Skill{Name: s.Name, Content: content}
+	}
+	sec.Skills = skills
+	return sec, nil
+}
+
+// importSection writes one archive section's instructions and skills to an agent.
+func importSection(cfg *config.ImportConfig, sec archive.Section, dest config.Section) ([]ArchiveAction, error) {
+	to := dest.Agent
+	loc := config.Location{Root: cfg.Root, Scope: dest.Scope}
+	dst, err := agent.GetAt(to, loc)
+	if err != nil {
+		return nil, err
+	}
+
+	var actions []ArchiveAction
+
+	// Import instructions
+	if !cfg.SkipInstructions {
 		instAction := ArchiveAction{
 			Kind:  Instructions,
 			Agent: to,
-			Scope: cfg.Scope,
+			Scope: dest.Scope,
 		}
 		dstPath := dst.InstructionsPath(loc)
 		if dstPath == "" {
 			instAction.Status = "skipped"
-			instAction.Detail = fmt.Sprintf("skipped (%s does not support %s instructions)", to, cfg.Scope)
-		} else if a.Instructions == nil || a.Instructions.Content == "" {
+			instAction.Detail = fmt.Sprintf("skipped (%s does not support %s instructions)", to, dest.Scope)
+		} else if sec.Instructions == nil || sec.Instructions.Content == "" {
 			instAction.Status = "skipped"
 			instAction.Detail = "skipped (no instructions in archive)"
+		} else if cfg.IfAbsent && exists(dstPath) {
+			instAction.Status = "skipped"
+			instAction.Detail = fmt.Sprintf("skipped (%s already exists)", dstPath)
 		} else if cfg.DryRun {
 			instAction.Status = "dry-run"
-			instAction.Detail = fmt.Sprintf("would import (%d bytes)", len(a.Instructions.Content))
+			instAction.Detail = fmt.Sprintf("would import (%d bytes)", len(sec.Instructions.Content))
 		} else {
-			if err := dst.WriteInstructions(loc, a.Instructions); err != nil {
+			if err := dst.WriteInstructions(loc, sec.Instructions); err != nil {
 				return nil, fmt.Errorf("writing instructions to %s: %w", to, err)
 			}
 			instAction.Status = "imported"
-			instAction.Detail = fmt.Sprintf("imported (%d bytes)", len(a.Instructions.Content))
+			instAction.Detail = fmt.Sprintf("imported (%d bytes)", len(sec.Instructions.Content))
 		}
-		result.Actions = append(result.Actions, instAction)
+		actions = append(actions, instAction)
+	}
 
-		// Import skills
+	// Import skills
+	if !cfg.SkipSkills {
 		skillAction := ArchiveAction{
 			Kind:  Skills,
 			Agent: to,
-			Scope: cfg.Scope,
+			Scope: dest.Scope,
+		}
+		skills := selectSkills(sec.Skills, cfg)
+		var existing []agent.Skill
+		if cfg.IfAbsent {
+			skills, existing = splitExisting(skills, dst.SkillsPath(loc))
+		}
+		kept := ""
+		if len(existing) > 0 {
+			kept = fmt.Sprintf(" (kept existing: %s)", skillNames(existing))
 		}
-		if len(a.Skills) == 0 {
+
+		if len(sec.Skills) == 0 {
 			skillAction.Status = "skipped"
 			skillAction.Detail = "skipped (no skills in archive)"
+		} else if len(skills) == 0 && len(existing) > 0 {
+			skillAction.Status = "skipped"
+			skillAction.Detail = fmt.Sprintf("skipped (all selected skills already exist: %s)", skillNames(existing))
+		} else if len(skills) == 0 {
+			skillAction.Status = "skipped"
+			skillAction.Detail = "skipped (no skills match the filters)"
 		} else if cfg.DryRun {
 			skillAction.Status = "dry-run"
-			skillAction.Detail = fmt.Sprintf("would import %d skill(s): %s", len(a.Skills), skillNames(a.Skills))
+			skillAction.Detail = fmt.Sprintf("would import %d skill(s): %s%s", len(skills), skillNames(skills), kept)
 		} else {
-			if err := dst.WriteSkills(loc, a.Skills); err != nil {
+			if err := dst.WriteSkills(loc, skills); err != nil {
 				return nil, fmt.Errorf("writing skills to %s: %w", to, err)
 			}
 			skillAction.Status = "imported"
-			skillAction.Detail = fmt.Sprintf("imported %d skill(s): %s", len(a.Skills), skillNames(a.Skills))
+			skillAction.Detail = fmt.Sprintf("imported %d skill(s): %s%s", len(skills), skillNames(skills), kept)
 		}
-		result.Actions = append(result.Actions, skillAction)
+		actions = append(actions, skillAction)
 	}
 
-	return result, nil
+	return actions, nil
+}
+
+// selectSkills applies the --skill and --exclude-skill filters in cfg.
+// Patterns are validated by the caller, so match errors are ignored.
+func selectSkills(skills []agent.Skill, cfg *config.ImportConfig) []agent.Skill {
+	var out []agent.Skill
+	for _, s := range skills {
+		if len(cfg.Skills) > 0 && !slices.Contains(cfg.Skills, s.Name) {
+			continue
+		}
+		if slices.ContainsFunc(cfg.ExcludeSkills, func(pattern string) bool {
+			ok, _ := path.Match(pattern, s.Name)
+			return ok
+		}) {
+			continue
+		}
+		out = append(out, s)
+	}
+	return out
+}
+
+// splitExisting separates skills that already have a SKILL.md under dir.
+func splitExisting(skills []agent.Skill, dir string) (absent, existing []agent.Skill) {
+	for _, s := range skills {
+		if dir != "" && exists(filepath.Join(dir, s.Name, "SKILL.md")) {
+			existing = append(existing, s)
+		} else {
+			absent = append(absent, s)
+		}
+	}
+	return absent, existing
+}
+
+// checkSkillNames reports --skill names that appear in none of the imported sections.
+func checkSkillNames(targets []importTarget, names []string) error {
+	var missing []string
+	for _, name := range names {
+		found := slices.ContainsFunc(targets, func(t importTarget) bool {
+			return slices.ContainsFunc(t.section.Skills, func(s agent.Skill) bool { return s.Name == name })
+		})
+		if !found {
+			missing = append(missing, name)
+		}
+	}
+	if len(missing) > 0 {
+		return fmt.Errorf("archive has no skill named %s", strings.Join(missing, ", "))
+	}
+	return nil
+}
+
+func exists(name string) bool {
+	_, err := os.Stat(name)
+	return err == nil
+}
+
+// checkTrust requires a valid signature from a trusted key. Unsigned or
+// untrusted archives are allowed with a warning only when cfg.AllowUnsigned is
+// set; a signature that does not match the manifest is always an error.
+func checkTrust(a *archive.Archive, cfg *config.ImportConfig, result *ArchiveResult) error {
+	var trusted []keys.PublicKey
+	if cfg.TrustedKeys != "" {
+		var err error
+		trusted, err = keys.LoadTrustedKeys(cfg.TrustedKeys)
+		if err != nil {
+			return err
+		}
+	}
+
+	k, err := a.VerifySignature(trusted)
+	switch {
+	case err == nil:
+		result.Signer = k.ID()
+		if k.Comment != "" {
+			result.Signer += " (" + k.Comment + ")"
+		}
+		return nil
+	case errors.Is(err, archive.ErrBadSignature):
+		return err
+	case cfg.AllowUnsigned:
+		result.Warnings = append(result.Warnings, err.Error())
+		return nil
+	default:
+		return fmt.Errorf("%w (add the signer to %s or pass --allow-unsigned)", err, trustedKeysLabel(cfg.TrustedKeys))
+	}
+}
+
+func trustedKeysLabel(path string) string {
+	if path == "" {
+		return "the trusted keys file"
+	}
+	return path
 }
@@ -2,25 +2,29 @@ package sync
 
 import (
 	"fmt"
+	"os"
+	"strings"
 
 	"github.com/LaneBirmingham/coding-agent-sync/internal/agent"
+	"github.com/LaneBirmingham/coding-agent-sync/internal/conditional"
 	"github.com/LaneBirmingham/coding-agent-sync/internal/config"
+	"github.com/LaneBirmingham/coding-agent-sync/internal/managed"
 )
 
 // SyncInstructions syncs instructions from source to destination.
 func SyncInstructions(cfg *config.SyncConfig, to config.Agent) (SyncAction, error) {
+	srcLoc := config.Location{Root: cfg.Root, Scope: cfg.FromScope}
+	dstLoc := config.Location{Root: cfg.Root, Scope: cfg.ToScope}
+
 	src, err := agent.Get(cfg.From)
 	if err != nil {
 		return SyncAction{}, err
 	}
-	dst, err := agent.Get(to)
+	dst, err := agent.GetAt(to, dstLoc)
 	if err != nil {
 		return SyncAction{}, err
 	}
 
-	srcLoc := config.Location{Root: cfg.Root, Scope: cfg.FromScope}
-	dstLoc := config.Location{Root: cfg.Root, Scope: cfg.ToScope}
-
 	action := SyncAction{
 		Kind:      Instructions,
 		From:      cfg.From,
@@ -39,7 +43,7 @@ func SyncInstructions(cfg *config.SyncConfig, to config.Agent) (SyncAction, erro
 
 	// Check if source supports instructions at this scope
 	srcPath := src.InstructionsPath(srcLoc)
-	if srcPath == "" {
+	if len(src.InstructionsSources(srcLoc)) == 0 {
 		action.Status = "skipped"
 		action.Detail = fmt.Sprintf("skipped (%s does not support %s instructions)", cfg.From, srcLoc.Scope)
 		return action, nil
@@ -52,7 +56,7 @@ func SyncInstructions(cfg *config.SyncConfig, to config.Agent) (SyncAction, erro
 		return action, nil
 	}
 
-	inst, err := src.ReadInstructions(srcLoc)
+	inst, frags, err := agent.ReadComposedInstructions(src, srcLoc)
 	if err != nil {
 		return SyncAction{}, fmt.Errorf("reading instructions from %s: %w", cfg.From, err)
 	}
@@ -61,18 +65,72 @@ func SyncInstructions(cfg *config.SyncConfig, to config.Agent) (SyncAction, erro
 		action.Detail = "skipped (no source file found)"
 		return action, nil
 	}
+	if (cfg.Link || cfg.Reference) && len(frags) > 0 {
+		return SyncAction{}, fmt.Errorf("instructions from %s are composed from %s, which --link and --reference cannot do", cfg.From, agent.FragmentsDirName)
+	}
+
+	if (cfg.Link || cfg.Reference) && conditional.Has(inst.Content) {
+		return SyncAction{}, fmt.Errorf("instructions from %s have cas:if sections, which --link and --reference cannot render", cfg.From)
+	}
+	content, err := renderFor(inst.Content, to, dstLoc.Scope)
+	if err != nil {
+		return SyncAction{}, fmt.Errorf("instructions from %s: %w", cfg.From, err)
+	}
+
+	switch {
+	case cfg.Link:
+		return linkInstructions(cfg, action, src, srcLoc, dstPath)
+	case cfg.Reference:
+		return referenceInstructions(cfg, action, src, dst, srcLoc, dstLoc)
+	}
+
+	return writeInstructions(cfg, action, dst, dstLoc, dstPath, content, fragmentList(frags))
+}
+
+// writeInstructions writes rendered content to the destination, inside its
+// managed block when cfg asks for one. note is appended to the detail.
+func writeInstructions(cfg *config.SyncConfig, action SyncAction, dst agent.Agent, dstLoc config.Location, dstPath, content, note string) (SyncAction, error) {
+	size := len(content)
+	if cfg.ManagedBlock != "" {
+		existing, err := os.ReadFile(dstPath)
+		if err != nil && !os.IsNotExist(err) {
+			return SyncAction{}, fmt.Errorf("reading %s: %w", dstPath, err)
+		}
+		if content, err = managed.Apply(string(existing), content, cfg.ManagedBlock); err != nil {
+			return SyncAction{}, fmt.Errorf("%s: %w", dstPath, err)
+		}
+		note = " in managed block" + note
+	}
 
 	if cfg.DryRun {
 		action.Status = "dry-run"
-		action.Detail = fmt.Sprintf("would write (%d bytes)", len(inst.Content))
+		action.Detail = fmt.Sprintf("would write (%d bytes%s)", size, note)
 		return action, nil
 	}
 
-	if err := dst.WriteInstructions(dstLoc, inst); err != nil {
-		return SyncAction{}, fmt.Errorf("writing instructions to %s: %w", to, err)
+	if err := dst.WriteInstructions(dstLoc, &agent.Instruction{Content: content}); err != nil {
+		return SyncAction{}, fmt.Errorf("writing instructions to %s: %w", action.To, err)
 	}
 
 	action.Status = "synced"
-	action.Detail = fmt.Sprintf("synced (%d bytes)", len(inst.Content))
+	action.Detail = fmt.Sprintf("synced (%d bytes%s)", size, note)
+	action.Paths = []string{dstPath}
 	return action, nil
 }
+
+// fragmentList describes the fragments instructions were composed from.
+func fragmentList(frags []string) string {
+	if len(frags) == 0 {
+		return ""
+	}
+	return " from " + strings.Join(frags, ", ")
+}
+
+// renderFor renders the cas:if sections of content for a destination. The
+// canonical layout is a source, so it keeps them as written.
+func renderFor(content string, to config.Agent, scope config.Scope) (string, error) {
+	if to == config.Canonical {
+		return content, conditional.Validate(content)
+	}
+	return conditional.Render(content, conditional.Target{Agent: to, Scope: scope})
+}
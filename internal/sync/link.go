@@ -0,0 +1,174 @@
+package sync
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+
+	"github.com/LaneBirmingham/coding-agent-sync/internal/agent"
+	"github.com/LaneBirmingham/coding-agent-sync/internal/config"
+)
+
+// linkInstructions replaces the destination instruction file with a relative
+// symlink to the source file.
+func linkInstructions(cfg *config.SyncConfig, action SyncAction, src agent.Agent, srcLoc config.Location, dstPath string) (SyncAction, error) {
+	target := sourceFile(src.InstructionsSources(srcLoc))
+	if target == "" {
+		action.Status, action.Detail = "skipped", "skipped (no source file found)"
+		return action, nil
+	}
+	return link(cfg, action, target, dstPath)
+}
+
+// referenceInstructions makes the destination load the source instruction
+// file through the agent's own import mechanism.
+func referenceInstructions(cfg *config.SyncConfig, action SyncAction, src, dst agent.Agent, srcLoc, dstLoc config.Location) (SyncAction, error) {
+	ref, ok := dst.(agent.Referencer)
+	if !ok {
+		action.Status, action.Detail = "skipped", fmt.Sprintf("skipped (%s cannot reference other files; use --link)", action.To)
+		return action, nil
+	}
+	target := sourceFile(src.InstructionsSources(srcLoc))
+	if target == "" {
+		action.Status, action.Detail = "skipped", "skipped (no source file found)"
+		return action, nil
+	}
+	if dstPath := dst.InstructionsPath(dstLoc); samePath(target, dstPath) {
+		action.Status, action.Detail = "skipped", fmt.Sprintf("skipped (the source is %s itself)", dstPath)
+		return action, nil
+	}
+	if cfg.DryRun {
+		action.Status, action.Detail = "dry-run", fmt.Sprintf("would reference %s", target)
+		return action, nil
+	}
+	path, err := ref.WriteReference(dstLoc, target)
+	if err != nil {
+		return SyncAction{}, fmt.Errorf("writing reference for %s: %w", action.To, err)
+	}
+	action.Status, action.Detail = "referenced", fmt.Sprintf("referenced (%s loads %s)", path, target)
+	action.Paths = []string{path}
+	return action, nil
+}
+
+// linkSkills replaces each destination skill directory with a relative
+// symlink to the source skill's directory.
+func linkSkills(cfg *config.SyncConfig, action SyncAction, src agent.Agent, srcLoc config.Location, dstDir string, skills []agent.Skill) (SyncAction, error) {
+	var linked []agent.Skill
+	for _, s := range skills {
+		target := sourceFile(skillFiles(src.SkillsDirs(srcLoc), s.Name))
+		if target == "" {
+			continue
+		}
+		target = filepath.Dir(target)
+		path := filepath.Join(dstDir, s.Name)
+		if path == target {
+			continue
+		}
+		res, err := link(cfg, action, target, path)
+		if err != nil {
+			return SyncAction{}, err
+		}
+		if res.Status == "linked" || res.Status == "dry-run" {
+			linked = append(linked, s)
+			action.Paths = append(action.Paths, res.Paths...)
+		}
+	}
+	switch {
+	case len(linked) == 0:
+		action.Status, action.Detail = "noop", "already in sync (skills are shared)"
+	case cfg.DryRun:
+		action.Status, action.Detail = "dry-run", fmt.Sprintf("would link %d skill(s): %s", len(linked), skillNames(linked))
+	default:
+		action.Status, action.Detail = "linked", fmt.Sprintf("linked %d skill(s): %s", len(linked), skillNames(linked))
+	}
+	return action, nil
+}
+
+// link points path at target with a relative symlink, replacing a file, an
+// earlier symlink or a skill directory holding only SKILL.md.
+func link(cfg *config.SyncConfig, action SyncAction, target, path string) (SyncAction, error) {
+	rel, err := filepath.Rel(filepath.Dir(path), target)
+	if err != nil {
+		return SyncAction{}, fmt.Errorf("linking %s: %w", path, err)
+	}
+	if cur, err := os.Readlink(path); err == nil && cur == rel {
+		action.Status, action.Detail = "noop", fmt.Sprintf("already linked (%s → %s)", path, rel)
+		return action, nil
+	}
+	// The source may fall back to the destination's own file; replacing it
+	// would delete the only copy.
+	if samePath(target, path) {
+		action.Status, action.Detail = "skipped", fmt.Sprintf("skipped (the source is %s itself)", path)
+		return action, nil
+	}
+	if cfg.DryRun {
+		action.Status, action.Detail = "dry-run", fmt.Sprintf("would link %s → %s", path, rel)
+		return action, nil
+	}
+
+	info, err := os.Lstat(path)
+	switch {
+	case os.IsNotExist(err):
+	case err != nil:
+		return SyncAction{}, err
+	case info.IsDir():
+		entries, err := os.ReadDir(path)
+		if err != nil {
+			return SyncAction{}, err
+		}
+		if len(entries) > 1 || (len(entries) == 1 && entries[0].Name() != "SKILL.md") {
+			return SyncAction{}, fmt.Errorf("%s holds files other than SKILL.md; remove it before linking", path)
+		}
+		if err := os.RemoveAll(path); err != nil {
+			return SyncAction{}, err
+		}
+	default:
+		if err := os.Remove(path); err != nil {
+			return SyncAction{}, err
+		}
+	}
+
+	if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+		return SyncAction{}, err
+	}
+	if err := os.Symlink(rel, path); err != nil {
+		return SyncAction{}, fmt.Errorf("linking %s: %w", path, err)
+	}
+	action.Status, action.Detail = "linked", fmt.Sprintf("linked (%s → %s)", path, rel)
+	action.Paths = []string{path}
+	return action, nil
+}
+
+// samePath reports whether a and b name the same file once symlinks are
+// resolved.
+func samePath(a, b string) bool {
+	if filepath.Clean(a) == filepath.Clean(b) {
+		return true
+	}
+	ra, err := filepath.EvalSymlinks(a)
+	if err != nil {
+		return false
+	}
+	rb, err := filepath.EvalSymlinks(b)
+	return err == nil && ra == rb
+}
+
+// sourceFile returns the first of paths that is a non-empty file, matching
+// the precedence agents read in.
+func sourceFile(paths []string) string {
+	for _, p := range paths {
+		if info, err := os.Stat(p); err == nil && !info.IsDir() && info.Size() > 0 {
+			return p
+		}
+	}
+	return ""
+}
+
+// skillFiles returns the SKILL.md path of a skill in each of dirs.
+func skillFiles(dirs []string, name string) []string {
+	paths := make([]string, len(dirs))
+	for i, d := range dirs {
+		paths[i] = filepath.Join(d, name, "SKILL.md")
+	}
+	return paths
+}
@@ -0,0 +1,127 @@
+package sync
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/LaneBirmingham/coding-agent-sync/internal/config"
+)
+
+func TestSyncAll_Link(t *testing.T) {
+	root := t.TempDir()
+	writeFile(t, filepath.Join(root, "CLAUDE.md"), "# Shared")
+	writeFile(t, filepath.Join(root, ".claude", "skills", "review", "SKILL.md"), "review")
+	// A previous copy is replaced by the link.
+	writeFile(t, filepath.Join(root, ".gemini", "skills", "review", "SKILL.md"), "old")
+
+	cfg := localCfg(root, config.Claude, []config.Agent{config.Gemini}, false)
+	cfg.Link = true
+	result, err := SyncAll(cfg, All)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, a := range result.Actions {
+		if a.Status != "linked" {
+			t.Fatalf("expected linked, got %s", a)
+		}
+	}
+
+	if got, err := os.Readlink(filepath.Join(root, "GEMINI.md")); err != nil || got != "CLAUDE.md" {
+		t.Fatalf("expected GEMINI.md → CLAUDE.md, got %q (%v)", got, err)
+	}
+	skill := filepath.Join(root, ".gemini", "skills", "review")
+	if got, err := os.Readlink(skill); err != nil || got != filepath.Join("..", "..", ".claude", "skills", "review") {
+		t.Fatalf("expected a relative skill link, got %q (%v)", got, err)
+	}
+	if got := readFile(t, filepath.Join(skill, "SKILL.md")); got != "review" {
+		t.Fatalf("expected the linked skill, got %q", got)
+	}
+
+	result, err = SyncAll(cfg, Instructions)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if result.Actions[0].Status != "noop" {
+		t.Fatalf("expected an existing link to be a noop, got %s", result.Actions[0])
+	}
+}
+
+func TestSyncSkills_LinkRefusesExtraFiles(t *testing.T) {
+	root := t.TempDir()
+	writeFile(t, filepath.Join(root, ".claude", "skills", "review", "SKILL.md"), "review")
+	writeFile(t, filepath.Join(root, ".gemini", "skills", "review", "script.sh"), "echo")
+
+	cfg := localCfg(root, config.Claude, nil, false)
+	cfg.Link = true
+	if _, err := SyncSkills(cfg, config.Gemini); err == nil || !strings.Contains(err.Error(), "other than SKILL.md") {
+		t.Fatalf("expected an error for a skill directory with extra files, got %v", err)
+	}
+}
+
+func TestSyncInstructions_Reference(t *testing.T) {
+	root := t.TempDir()
+	writeFile(t, filepath.Join(root, "AGENTS.md"), "# Shared")
+	writeFile(t, filepath.Join(root, ".gemini", "settings.json"), `{"theme": "dark"}`)
+
+	cfg := localCfg(root, config.Codex, nil, false)
+	cfg.Reference = true
+
+	action, err := SyncInstructions(cfg, config.Claude)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if action.Status != "referenced" {
+		t.Fatalf("expected referenced, got %s", action)
+	}
+	if got := readFile(t, filepath.Join(root, "CLAUDE.md")); got != "@AGENTS.md\n" {
+		t.Fatalf("expected an import stub, got %q", got)
+	}
+
+	if _, err := SyncInstructions(cfg, config.Gemini); err != nil {
+		t.Fatal(err)
+	}
+	got := readFile(t, filepath.Join(root, ".gemini", "settings.json"))
+	if !strings.Contains(got, `"fileName": "AGENTS.md"`) || !strings.Contains(got, `"theme": "dark"`) {
+		t.Fatalf("expected context.fileName set and other settings kept, got:\n%s", got)
+	}
+
+	cfg = localCfg(root, config.Claude, nil, false)
+	cfg.Reference = true
+	writeFile(t, filepath.Join(root, "CLAUDE.md"), "# Claude")
+	action, err = SyncInstructions(cfg, config.OpenCode)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if action.Status != "skipped" || !strings.Contains(action.Detail, "--link") {
+		t.Fatalf("expected agents without imports to be skipped, got %s", action)
+	}
+}
+
+func TestSyncInstructions_LinkAndReferenceSkipOwnFallback(t *testing.T) {
+	home := t.TempDir()
+	t.Setenv("HOME", home)
+	path := filepath.Join(home, ".claude", "CLAUDE.md")
+	writeFile(t, path, "# Mine")
+
+	// OpenCode falls back to ~/.claude/CLAUDE.md globally, which is the
+	// destination itself.
+	for _, mode := range []string{"link", "reference"} {
+		cfg := &config.SyncConfig{From: config.OpenCode, Root: t.TempDir(), FromScope: config.ScopeGlobal, ToScope: config.ScopeGlobal}
+		cfg.Link, cfg.Reference = mode == "link", mode == "reference"
+		action, err := SyncInstructions(cfg, config.Claude)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if action.Status != "skipped" {
+			t.Errorf("%s: expected skipped, got %s", mode, action)
+		}
+		if info, err := os.Lstat(path); err != nil || info.Mode()&os.ModeSymlink != 0 {
+			t.Fatalf("%s: expected the file kept, got %v %v", mode, info, err)
+		}
+		if got := readFile(t, path); got != "# Mine" {
+			t.Fatalf("%s: expected the content kept, got %q", mode, got)
+		}
+	}
+}
@@ -0,0 +1,273 @@
+package sync
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/LaneBirmingham/coding-agent-sync/internal/agent"
+	"github.com/LaneBirmingham/coding-agent-sync/internal/config"
+)
+
+// merged is the content read from several source agents and combined.
+type merged struct {
+	inst      string         // combined instructions, "" when no source has any
+	instFrom  []config.Agent // sources that contributed instructions
+	skills    []agent.Skill
+	skillNote string // how skill collisions were resolved, for action details
+}
+
+// syncMerged syncs the combined content of cfg.Sources to each target. The
+// sources are read once up front, so targets that are also sources receive
+// the merge of the original files.
+func syncMerged(cfg *config.SyncConfig, kind ItemKind) (*Result, error) {
+	if cfg.Link || cfg.Reference {
+		return nil, fmt.Errorf("--link and --reference need a single source agent")
+	}
+	if kind == Commands || kind == Rules {
+		return nil, fmt.Errorf("%s need a single source agent", kind)
+	}
+	m, err := mergeSources(cfg, kind)
+	if err != nil {
+		return nil, err
+	}
+	dstLoc := config.Location{Root: cfg.Root, Scope: cfg.ToScope}
+
+	var result Result
+	for _, to := range cfg.To {
+		dst, err := agent.GetAt(to, dstLoc)
+		if err != nil {
+			return nil, err
+		}
+		base := SyncAction{From: cfg.From, To: to, FromScope: cfg.FromScope, ToScope: cfg.ToScope}
+
+		if kind.has(Instructions) {
+			action, err := syncMergedInstructions(cfg, base, dst, dstLoc, m)
+			if err != nil {
+				return nil, err
+			}
+			result.Actions = append(result.Actions, action)
+		}
+		if kind.has(Skills) {
+			action, err := syncMergedSkills(cfg, base, dst, dstLoc, m)
+			if err != nil {
+				return nil, err
+			}
+			result.Actions = append(result.Actions, action)
+		}
+	}
+	return &result, nil
+}
+
+func syncMergedInstructions(cfg *config.SyncConfig, action SyncAction, dst agent.Agent, dstLoc config.Location, m *merged) (SyncAction, error) {
+	action.Kind = Instructions
+	dstPath := dst.InstructionsPath(dstLoc)
+	switch {
+	case dstPath == "":
+		action.Status = "skipped"
+		action.Detail = fmt.Sprintf("skipped (%s does not support %s instructions)", action.To, dstLoc.Scope)
+		return action, nil
+	case m.inst == "":
+		action.Status = "skipped"
+		action.Detail = "skipped (no source file found)"
+		return action, nil
+	}
+	content, err := renderFor(m.inst, action.To, dstLoc.Scope)
+	if err != nil {
+		return SyncAction{}, fmt.Errorf("merged instructions: %w", err)
+	}
+	return writeInstructions(cfg, action, dst, dstLoc, dstPath, content, " merged from "+agentList(m.instFrom))
+}
+
+func syncMergedSkills(cfg *config.SyncConfig, action SyncAction, dst agent.Agent, dstLoc config.Location, m *merged) (SyncAction, error) {
+	action.Kind = Skills
+	if len(m.skills) == 0 {
+		action.Status = "skipped"
+		action.Detail = "skipped (no skills found)"
+		return action, nil
+	}
+	skills := make([]agent.Skill, len(m.skills))
+	for i, s := range m.skills {
+		content, err := renderFor(s.Content, action.To, dstLoc.Scope)
+		if err != nil {
+			return SyncAction{}, fmt.Errorf("skill %s: %w", s.Name, err)
+		}
+		skills[i] = agent.Skill{Name: s.Name, Content: content}
+	}
+	return writeSkills(cfg, action, dst, dstLoc, skills, m.skillNote)
+}
+
+// mergeSources reads and combines the instructions and skills of cfg.Sources.
+func mergeSources(cfg *config.SyncConfig, kind ItemKind) (*merged, error) {
+	loc := config.Location{Root: cfg.Root, Scope: cfg.FromScope}
+	var (
+		m       merged
+		pieces  []source
+		owner   = make(map[string]config.Agent) // skill name → source it came from
+		index   = make(map[string]int)          // skill name → position in m.skills
+		resolve []string
+	)
+	for _, a := range cfg.Sources {
+		impl, err := agent.Get(a)
+		if err != nil {
+			return nil, err
+		}
+		if kind.has(Instructions) {
+			inst, _, err := agent.ReadComposedInstructions(impl, loc)
+			if err != nil {
+				return nil, fmt.Errorf("reading instructions from %s: %w", a, err)
+			}
+			if inst != nil {
+				pieces = append(pieces, source{agent: a, content: inst.Content})
+			}
+		}
+		if !kind.has(Skills) {
+			continue
+		}
+		skills, err := impl.ReadSkills(loc)
+		if err != nil {
+			return nil, fmt.Errorf("reading skills from %s: %w", a, err)
+		}
+		for _, s := range skills {
+			i, seen := index[s.Name]
+			switch {
+			case !seen:
+				index[s.Name], owner[s.Name] = len(m.skills), a
+				m.skills = append(m.skills, s)
+			case m.skills[i].Content == s.Content:
+			case cfg.OnCollide == config.ConflictError:
+				return nil, fmt.Errorf("skill %s differs between %s and %s (use --skill-conflict priority or rename)", s.Name, owner[s.Name], a)
+			case cfg.OnCollide == config.ConflictRename:
+				renamed := s.Name + "-" + string(a)
+				if _, taken := index[renamed]; taken {
+					return nil, fmt.Errorf("skill %s from %s cannot be renamed to %s, which already exists", s.Name, a, renamed)
+				}
+				index[renamed], owner[renamed] = len(m.skills), a
+				m.skills = append(m.skills, agent.Skill{Name: renamed, Content: s.Content})
+				resolve = append(resolve, fmt.Sprintf("%s's %s as %s", a, s.Name, renamed))
+			default:
+				resolve = append(resolve, fmt.Sprintf("%s's %s over %s's", owner[s.Name], s.Name, a))
+			}
+		}
+	}
+
+	pieces = distinct(pieces)
+	for _, p := range pieces {
+		m.instFrom = append(m.instFrom, p.agent)
+	}
+	m.inst = mergeInstructions(pieces, cfg.Merge)
+	if len(resolve) > 0 {
+		verb := "kept "
+		if cfg.OnCollide == config.ConflictRename {
+			verb = "renamed "
+		}
+		m.skillNote = " (" + verb + strings.Join(resolve, ", ") + ")"
+	}
+	return &m, nil
+}
+
+// source is one agent's instructions in a merge.
+type source struct {
+	agent   config.Agent
+	content string
+}
+
+// distinct drops sources whose instructions repeat an earlier source's, so
+// merging files that were already consolidated changes nothing.
+func distinct(pieces []source) []source {
+	var out []source
+	for _, p := range pieces {
+		dup := false
+		for _, q := range out {
+			if strings.TrimSpace(q.content) == strings.TrimSpace(p.content) {
+				dup = true
+				break
+			}
+		}
+		if !dup {
+			out = append(out, p)
+		}
+	}
+	return out
+}
+
+// mergeInstructions combines distinct source instructions, highest priority
+// first. A single source is returned as written.
+func mergeInstructions(pieces []source, strategy config.MergeStrategy) string {
+	switch {
+	case len(pieces) == 0:
+		return ""
+	case len(pieces) == 1 || strategy == config.MergePriority:
+		return pieces[0].content
+	}
+
+	var parts []string
+	if strategy == config.MergeDedup {
+		seen := make(map[string]bool)
+		for _, p := range pieces {
+			for _, sec := range sections(p.content) {
+				if key := sectionKey(sec); !seen[key] {
+					seen[key] = true
+					parts = append(parts, sec)
+				}
+			}
+		}
+	} else {
+		for _, p := range pieces {
+			parts = append(parts, "## "+string(p.agent)+"\n\n"+strings.TrimSpace(p.content))
+		}
+	}
+	return strings.Join(parts, "\n\n") + "\n"
+}
+
+// sections splits markdown at its headings, ignoring fenced code blocks.
+// Text before the first heading is a section of its own.
+func sections(content string) []string {
+	var (
+		out   []string
+		cur   []string
+		fence bool
+	)
+	flush := func() {
+		if sec := strings.TrimSpace(strings.Join(cur, "\n")); sec != "" {
+			out = append(out, sec)
+		}
+		cur = nil
+	}
+	for _, line := range strings.Split(content, "\n") {
+		trimmed := strings.TrimSpace(line)
+		if strings.HasPrefix(trimmed, "```") || strings.HasPrefix(trimmed, "~~~") {
+			fence = !fence
+		}
+		if !fence && isHeading(line) {
+			flush()
+		}
+		cur = append(cur, line)
+	}
+	flush()
+	return out
+}
+
+// sectionKey identifies a section by its heading, or by its whole text when
+// it has none.
+func sectionKey(sec string) string {
+	first, _, _ := strings.Cut(sec, "\n")
+	if !isHeading(first) {
+		return sec
+	}
+	level := len(first) - len(strings.TrimLeft(first, "#"))
+	return strings.Repeat("#", level) + " " + strings.ToLower(strings.Join(strings.Fields(first[level:]), " "))
+}
+
+func isHeading(line string) bool {
+	rest := strings.TrimLeft(line, "#")
+	level := len(line) - len(rest)
+	return level >= 1 && level <= 6 && (rest == "" || rest[0] == ' ' || rest[0] == '\t')
+}
+
+func agentList(agents []config.Agent) string {
+	names := make([]string, len(agents))
+	for i, a := range agents {
+		names[i] = string(a)
+	}
+	return strings.Join(names, ", ")
+}
@@ -0,0 +1,117 @@
+package sync
+
+import (
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/LaneBirmingham/coding-agent-sync/internal/config"
+)
+
+func mergeCfg(root string, merge config.MergeStrategy) *config.SyncConfig {
+	cfg := localCfg(root, config.Claude, []config.Agent{config.Claude, config.Codex, config.Gemini}, false)
+	cfg.Sources = []config.Agent{config.Claude, config.Codex, config.Gemini}
+	cfg.Merge = merge
+	return cfg
+}
+
+func TestSyncAll_MergeStrategies(t *testing.T) {
+	claude := "# Project\n\nClaude intro.\n\n## Testing\n\nRun go test.\n"
+	codex := "# Project\n\nCodex intro.\n\n## Testing\n\nRun make test.\n\n## Style\n\nUse gofmt.\n"
+	tests := []struct {
+		merge config.MergeStrategy
+		want  string
+	}{
+		{config.MergeConcat, "## claude\n\n" + strings.TrimSpace(claude) + "\n\n## codex\n\n" + strings.TrimSpace(codex) + "\n"},
+		{config.MergeDedup, "# Project\n\nClaude intro.\n\n## Testing\n\nRun go test.\n\n## Style\n\nUse gofmt.\n"},
+		{config.MergePriority, claude},
+	}
+	for _, tt := range tests {
+		root := t.TempDir()
+		writeFile(t, filepath.Join(root, "CLAUDE.md"), claude)
+		writeFile(t, filepath.Join(root, "AGENTS.md"), codex)
+
+		result, err := SyncAll(mergeCfg(root, tt.merge), Instructions)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if d := result.Actions[0].Detail; !strings.Contains(d, "merged from claude, codex") {
+			t.Errorf("%s: expected the sources in the detail, got %q", tt.merge, d)
+		}
+		// Every target, sources included, gets the merge of the original files.
+		for _, name := range []string{"CLAUDE.md", "AGENTS.md", "GEMINI.md"} {
+			if got := readFile(t, filepath.Join(root, name)); got != tt.want {
+				t.Errorf("%s: %s = %q, want %q", tt.merge, name, got, tt.want)
+			}
+		}
+
+		// Once consolidated, merging again changes nothing.
+		if _, err := SyncAll(mergeCfg(root, tt.merge), Instructions); err != nil {
+			t.Fatal(err)
+		}
+		if got := readFile(t, filepath.Join(root, "CLAUDE.md")); got != tt.want {
+			t.Errorf("%s: expected a second merge to be stable, got %q", tt.merge, got)
+		}
+	}
+}
+
+func TestSyncAll_MergeSkillConflicts(t *testing.T) {
+	setup := func() string {
+		root := t.TempDir()
+		writeFile(t, filepath.Join(root, ".claude", "skills", "review", "SKILL.md"), "claude review")
+		writeFile(t, filepath.Join(root, ".agents", "skills", "review", "SKILL.md"), "codex review")
+		writeFile(t, filepath.Join(root, ".agents", "skills", "deploy", "SKILL.md"), "deploy")
+		return root
+	}
+
+	root := setup()
+	cfg := mergeCfg(root, config.MergeConcat)
+	cfg.To = []config.Agent{config.OpenCode}
+	result, err := SyncAll(cfg, Skills)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if d := result.Actions[0].Detail; !strings.Contains(d, "kept claude's review over codex's") {
+		t.Errorf("unexpected detail: %q", d)
+	}
+	if got := readFile(t, filepath.Join(root, ".opencode", "skills", "review", "SKILL.md")); got != "claude review" {
+		t.Errorf("expected the first source to win, got %q", got)
+	}
+	if got := readFile(t, filepath.Join(root, ".opencode", "skills", "deploy", "SKILL.md")); got != "deploy" {
+		t.Errorf("expected skills to be unioned, got %q", got)
+	}
+
+	root = setup()
+	cfg = mergeCfg(root, config.MergeConcat)
+	cfg.To, cfg.OnCollide = []config.Agent{config.OpenCode}, config.ConflictRename
+	if _, err := SyncAll(cfg, Skills); err != nil {
+		t.Fatal(err)
+	}
+	if got := readFile(t, filepath.Join(root, ".opencode", "skills", "review-codex", "SKILL.md")); got != "codex review" {
+		t.Errorf("expected the later skill renamed, got %q", got)
+	}
+
+	cfg.OnCollide = config.ConflictError
+	if _, err := SyncAll(cfg, Skills); err == nil || !strings.Contains(err.Error(), "differs between claude and codex") {
+		t.Errorf("expected a conflict error, got %v", err)
+	}
+}
+
+func TestCheckDrift_Merged(t *testing.T) {
+	root := t.TempDir()
+	writeFile(t, filepath.Join(root, "CLAUDE.md"), "# A\n")
+	writeFile(t, filepath.Join(root, "AGENTS.md"), "# B\n")
+	cfg := mergeCfg(root, config.MergeDedup)
+	cfg.To = []config.Agent{config.Gemini}
+
+	if _, err := SyncAll(cfg, Instructions); err != nil {
+		t.Fatal(err)
+	}
+	drift, err := CheckDrift(cfg, Instructions)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if d := drift.Drifted(); len(d) != 0 {
+		t.Errorf("expected the merged output to be in sync, got %v", d)
+	}
+}
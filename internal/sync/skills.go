@@ -2,26 +2,28 @@ package sync
 
 import (
 	"fmt"
+	"path/filepath"
 	"strings"
 
 	"github.com/LaneBirmingham/coding-agent-sync/internal/agent"
+	"github.com/LaneBirmingham/coding-agent-sync/internal/conditional"
 	"github.com/LaneBirmingham/coding-agent-sync/internal/config"
 )
 
 // SyncSkills syncs skills from source to destination.
 func SyncSkills(cfg *config.SyncConfig, to config.Agent) (SyncAction, error) {
+	srcLoc := config.Location{Root: cfg.Root, Scope: cfg.FromScope}
+	dstLoc := config.Location{Root: cfg.Root, Scope: cfg.ToScope}
+
 	src, err := agent.Get(cfg.From)
 	if err != nil {
 		return SyncAction{}, err
 	}
-	dst, err := agent.Get(to)
+	dst, err := agent.GetAt(to, dstLoc)
 	if err != nil {
 		return SyncAction{}, err
 	}
 
-	srcLoc := config.Location{Root: cfg.Root, Scope: cfg.FromScope}
-	dstLoc := config.Location{Root: cfg.Root, Scope: cfg.ToScope}
-
 	action := SyncAction{
 		Kind:      Skills,
 		From:      cfg.From,
@@ -40,18 +42,51 @@ func SyncSkills(cfg *config.SyncConfig, to config.Agent) (SyncAction, error) {
 		return action, nil
 	}
 
+	rendered := make([]agent.Skill, len(skills))
+	for i, sk := range skills {
+		if cfg.Link && conditional.Has(sk.Content) {
+			return SyncAction{}, fmt.Errorf("skill %s from %s has cas:if sections, which --link cannot render", sk.Name, cfg.From)
+		}
+		content, err := renderFor(sk.Content, to, dstLoc.Scope)
+		if err != nil {
+			return SyncAction{}, fmt.Errorf("skill %s from %s: %w", sk.Name, cfg.From, err)
+		}
+		rendered[i] = agent.Skill{Name: sk.Name, Content: content}
+	}
+	skills = rendered
+
+	if cfg.Link {
+		dir := dst.SkillsPath(dstLoc)
+		if dir == "" {
+			action.Status = "skipped"
+			action.Detail = fmt.Sprintf("skipped (%s does not support %s skills)", to, dstLoc.Scope)
+			return action, nil
+		}
+		return linkSkills(cfg, action, src, srcLoc, dir, skills)
+	}
+
+	return writeSkills(cfg, action, dst, dstLoc, skills, "")
+}
+
+// writeSkills writes rendered skills to the destination. note is appended
+// to the detail.
+func writeSkills(cfg *config.SyncConfig, action SyncAction, dst agent.Agent, dstLoc config.Location, skills []agent.Skill, note string) (SyncAction, error) {
 	if cfg.DryRun {
 		action.Status = "dry-run"
-		action.Detail = fmt.Sprintf("would write %d skill(s): %s", len(skills), skillNames(skills))
+		action.Detail = fmt.Sprintf("would write %d skill(s): %s%s", len(skills), skillNames(skills), note)
 		return action, nil
 	}
 
 	if err := dst.WriteSkills(dstLoc, skills); err != nil {
-		return SyncAction{}, fmt.Errorf("writing skills to %s: %w", to, err)
+		return SyncAction{}, fmt.Errorf("writing skills to %s: %w", action.To, err)
 	}
 
 	action.Status = "synced"
-	action.Detail = fmt.Sprintf("synced %d skill(s): %s", len(skills), skillNames(skills))
+	action.Detail = fmt.Sprintf("synced %d skill(s): %s%s", len(skills), skillNames(skills), note)
+	dir := dst.SkillsPath(dstLoc)
+	for _, s := range skills {
+		action.Paths = append(action.Paths, filepath.Join(dir, s.Name, "SKILL.md"))
+	}
 	return action, nil
 }
 
@@ -13,8 +13,30 @@ const (
 	All          ItemKind = iota
 	Instructions
 	Skills
+	Commands
+	Rules
 )
 
+// has reports whether k covers item.
+func (k ItemKind) has(item ItemKind) bool {
+	return k == All || k == item
+}
+
+func (k ItemKind) String() string {
+	switch k {
+	case Instructions:
+		return "instructions"
+	case Skills:
+		return "skills"
+	case Commands:
+		return "commands"
+	case Rules:
+		return "rules"
+	default:
+		return "all"
+	}
+}
+
 // SyncAction represents the outcome of a single sync operation.
 type SyncAction struct {
 	Kind      ItemKind
@@ -22,15 +44,13 @@ type SyncAction struct {
 	To        config.Agent
 	FromScope config.Scope
 	ToScope   config.Scope
-	Status    string // "synced", "skipped", "dry-run", "noop"
+	Status    string // "synced", "linked", "referenced", "skipped", "dry-run", "noop"
 	Detail    string
+	Paths     []string // files the action wrote or linked
 }
 
 func (a SyncAction) String() string {
-	label := "instructions"
-	if a.Kind == Skills {
-		label = "skills"
-	}
+	label := a.Kind.String()
 	scope := ""
 	if a.FromScope == config.ScopeGlobal || a.ToScope == config.ScopeGlobal {
 		scope = fmt.Sprintf(" [%s→%s]", a.FromScope, a.ToScope)
@@ -53,10 +73,7 @@ type ArchiveAction struct {
 }
 
 func (a ArchiveAction) String() string {
-	label := "instructions"
-	if a.Kind == Skills {
-		label = "skills"
-	}
+	label := a.Kind.String()
 	scope := ""
 	if a.Scope == config.ScopeGlobal {
 		scope = fmt.Sprintf(" [%s]", a.Scope)
@@ -68,14 +85,20 @@ func (a ArchiveAction) String() string {
 type ArchiveResult struct {
 	Actions  []ArchiveAction
 	Warnings []string
+	Signer   string // description of the trusted key that signed an imported archive
+	Commit   string // git commit an import was read from
 }
 
-// SyncAll runs the sync operation for each target agent.
+// SyncAll runs the sync operation for each target agent, merging the
+// sources first when cfg has several.
 func SyncAll(cfg *config.SyncConfig, kind ItemKind) (*Result, error) {
+	if len(cfg.Sources) > 1 {
+		return syncMerged(cfg, kind)
+	}
 	var result Result
 
 	for _, to := range cfg.To {
-		if kind == All || kind == Instructions {
+		if kind.has(Instructions) {
 			action, err := SyncInstructions(cfg, to)
 			if err != nil {
 				return nil, err
@@ -83,13 +106,28 @@ func SyncAll(cfg *config.SyncConfig, kind ItemKind) (*Result, error) {
 			result.Actions = append(result.Actions, action)
 		}
 
-		if kind == All || kind == Skills {
+		if kind.has(Skills) {
 			action, err := SyncSkills(cfg, to)
 			if err != nil {
 				return nil, err
 			}
 			result.Actions = append(result.Actions, action)
 		}
+
+		// Commands and rules belong to the canonical layout; a sync between
+		// two agents leaves them alone unless asked for by name.
+		if kind != All || cfg.From == config.Canonical || to == config.Canonical {
+			for _, k := range []ItemKind{Commands, Rules} {
+				if !kind.has(k) {
+					continue
+				}
+				action, err := syncDocuments(cfg, to, k)
+				if err != nil {
+					return nil, err
+				}
+				result.Actions = append(result.Actions, action)
+			}
+		}
 	}
 
 	return &result, nil
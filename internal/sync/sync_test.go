@@ -7,6 +7,7 @@ import (
 	"testing"
 
 	"github.com/LaneBirmingham/coding-agent-sync/internal/config"
+	"github.com/LaneBirmingham/coding-agent-sync/internal/managed"
 )
 
 func writeFile(t *testing.T, path, content string) {
@@ -207,6 +208,32 @@ func TestSyncAll_InstructionsOnly(t *testing.T) {
 
 // --- Global sync tests ---
 
+func TestSyncAll_CommandsOnlyWhenAsked(t *testing.T) {
+	root := t.TempDir()
+	writeFile(t, filepath.Join(root, "CLAUDE.md"), "# Instructions")
+	writeFile(t, filepath.Join(root, ".claude", "commands", "deploy.md"), "Deploy")
+
+	cfg := localCfg(root, config.Claude, []config.Agent{config.OpenCode}, false)
+	if _, err := SyncAll(cfg, All); err != nil {
+		t.Fatal(err)
+	}
+	cmd := filepath.Join(root, ".opencode", "command", "deploy.md")
+	if _, err := os.Stat(cmd); !os.IsNotExist(err) {
+		t.Fatalf("expected commands left alone between agents, got %v", err)
+	}
+
+	result, err := SyncAll(cfg, Commands)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(result.Actions) != 1 || result.Actions[0].String() != "commands: claude → opencode: synced 1 command(s): deploy" {
+		t.Fatalf("unexpected actions: %v", result.Actions)
+	}
+	if got := readFile(t, cmd); got != "Deploy" {
+		t.Errorf("expected the command copied, got %q", got)
+	}
+}
+
 func TestSyncInstructions_Global_ClaudeToOpenCode(t *testing.T) {
 	home := t.TempDir()
 	t.Setenv("HOME", home)
@@ -437,3 +464,90 @@ func TestSyncAction_String_HidesScopeForLocal(t *testing.T) {
 		t.Errorf("should not show scope for local→local, got %q", got)
 	}
 }
+
+func TestSyncAll_RendersConditionals(t *testing.T) {
+	root := t.TempDir()
+	content := "# A\n<!-- cas:if agent=claude -->\nClaude only.\n<!-- cas:endif -->\n"
+	writeFile(t, filepath.Join(root, ".cas", "instructions.md"), content)
+	writeFile(t, filepath.Join(root, ".cas", "skills", "review", "SKILL.md"), content)
+
+	cfg := localCfg(root, config.Canonical, []config.Agent{config.Claude, config.Codex}, false)
+	if _, err := SyncAll(cfg, All); err != nil {
+		t.Fatal(err)
+	}
+	if got := readFile(t, filepath.Join(root, "CLAUDE.md")); got != "# A\nClaude only.\n" {
+		t.Errorf("unexpected claude instructions: %q", got)
+	}
+	if got := readFile(t, filepath.Join(root, "AGENTS.md")); got != "# A\n" {
+		t.Errorf("unexpected codex instructions: %q", got)
+	}
+	if got := readFile(t, filepath.Join(root, ".agents", "skills", "review", "SKILL.md")); got != "# A\n" {
+		t.Errorf("unexpected codex skill: %q", got)
+	}
+
+	drift, err := CheckDrift(cfg, All)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if d := drift.Drifted(); len(d) != 0 {
+		t.Errorf("expected rendered output to count as in sync, got %v", d)
+	}
+
+	writeFile(t, filepath.Join(root, ".cas", "instructions.md"), "<!-- cas:if agent=claude -->\n")
+	if _, err := SyncAll(cfg, Instructions); err == nil || !strings.Contains(err.Error(), "never closed") {
+		t.Errorf("expected a validation error, got %v", err)
+	}
+}
+
+func TestSyncInstructions_Fragments(t *testing.T) {
+	root := t.TempDir()
+	writeFile(t, filepath.Join(root, ".cas", "instructions.d", "10-intro.md"), "# Intro\n")
+	writeFile(t, filepath.Join(root, ".cas", "instructions.d", "20-rules.md"), "Be brief.\n")
+
+	cfg := localCfg(root, config.Canonical, nil, false)
+	action, err := SyncInstructions(cfg, config.Gemini)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.HasSuffix(action.Detail, "from 10-intro.md, 20-rules.md)") {
+		t.Errorf("expected the fragments in the detail, got %q", action.Detail)
+	}
+	if got := readFile(t, filepath.Join(root, "GEMINI.md")); got != "# Intro\n\nBe brief.\n" {
+		t.Errorf("unexpected composed instructions: %q", got)
+	}
+
+	cfg.Link = true
+	if _, err := SyncInstructions(cfg, config.Gemini); err == nil {
+		t.Error("expected --link to refuse composed instructions")
+	}
+}
+
+func TestSyncInstructions_ManagedBlock(t *testing.T) {
+	root := t.TempDir()
+	writeFile(t, filepath.Join(root, "CLAUDE.md"), "# Shared\n")
+	writeFile(t, filepath.Join(root, "AGENTS.md"), "# Codex notes\n")
+
+	cfg := localCfg(root, config.Claude, []config.Agent{config.Codex}, false)
+	cfg.ManagedBlock = managed.Bottom
+	for range 2 {
+		action, err := SyncInstructions(cfg, config.Codex)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !strings.Contains(action.Detail, "managed block") {
+			t.Errorf("expected the managed block in the detail, got %q", action.Detail)
+		}
+	}
+	want := "# Codex notes\n\n" + managed.Begin + "\n# Shared\n" + managed.End + "\n"
+	if got := readFile(t, filepath.Join(root, "AGENTS.md")); got != want {
+		t.Errorf("expected %q, got %q", want, got)
+	}
+
+	drift, err := CheckDrift(cfg, Instructions)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if d := drift.Drifted(); len(d) != 0 {
+		t.Errorf("expected the managed block to count as in sync, got %v", d)
+	}
+}
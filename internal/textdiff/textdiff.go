@@ -0,0 +1,201 @@
+package textdiff
+
+import (
+	"fmt"
+	"slices"
+	"strings"
+)
+
+// context is the number of unchanged lines shown around each change.
+const context = 3
+
+type opKind int
+
+const (
+	opEqual opKind = iota
+	opDelete
+	opInsert
+)
+
+type op struct {
+	kind opKind
+	line string
+}
+
+// Unified returns a unified diff of a and b with the given file labels, or ""
+// when they are equal.
+func Unified(oldLabel, newLabel, a, b string) string {
+	if a == b {
+		return ""
+	}
+	ops := diffLines(splitLines(a), splitLines(b))
+
+	var out strings.Builder
+	fmt.Fprintf(&out, "--- %s\n+++ %s\n", oldLabel, newLabel)
+	for _, h := range hunks(ops) {
+		writeHunk(&out, ops, h)
+	}
+	return out.String()
+}
+
+// splitLines splits s into lines, marking a missing final newline the way diff does.
+func splitLines(s string) []string {
+	if s == "" {
+		return nil
+	}
+	lines := strings.SplitAfter(s, "\n")
+	if lines[len(lines)-1] == "" {
+		lines = lines[:len(lines)-1]
+	} else {
+		lines[len(lines)-1] += "\n\\ No newline at end of file\n"
+	}
+	return lines
+}
+
+// diffLines computes a shortest edit script with Myers' algorithm.
+func diffLines(a, b []string) []op {
+	n, m := len(a), len(b)
+	limit := n + m
+	offset := limit + 1
+	v := make([]int, 2*limit+3)
+	// trace[d] holds v[-d-1..d+1] as it stood before step d.
+	var trace [][]int
+
+	for d := 0; d <= limit; d++ {
+		trace = append(trace, slices.Clone(v[offset-d-1:offset+d+2]))
+		for k := -d; k <= d; k += 2 {
+			var x int
+			if k == -d || (k != d && v[offset+k-1] < v[offset+k+1]) {
+				x = v[offset+k+1]
+			} else {
+				x = v[offset+k-1] + 1
+			}
+			y := x - k
+			for x < n && y < m && a[x] == b[y] {
+				x++
+				y++
+			}
+			v[offset+k] = x
+			if x >= n && y >= m {
+				return backtrack(a, b, trace)
+			}
+		}
+	}
+	return nil
+}
+
+// backtrack walks the saved V arrays from the end to recover the edit script.
+func backtrack(a, b []string, trace [][]int) []op {
+	x, y := len(a), len(b)
+	var ops []op
+	for d := len(trace) - 1; d >= 0; d-- {
+		v := trace[d]
+		at := func(k int) int { return v[k+d+1] }
+		k := x - y
+		var prevK int
+		if k == -d || (k != d && at(k-1) < at(k+1)) {
+			prevK = k + 1
+		} else {
+			prevK = k - 1
+		}
+		prevX := at(prevK)
+		prevY := prevX - prevK
+		for x > prevX && y > prevY {
+			x--
+			y--
+			ops = append(ops, op{opEqual, a[x]})
+		}
+		if d > 0 {
+			if x == prevX {
+				y--
+				ops = append(ops, op{opInsert, b[y]})
+			} else {
+				x--
+				ops = append(ops, op{opDelete, a[x]})
+			}
+		}
+	}
+	for i, j := 0, len(ops)-1; i < j; i, j = i+1, j-1 {
+		ops[i], ops[j] = ops[j], ops[i]
+	}
+	return ops
+}
+
+// hunk is a range of ops, with the line numbers where it starts in each file.
+type hunk struct {
+	start, end int
+	oldStart   int
+	newStart   int
+	oldN, newN int
+}
+
+// hunks groups changes that are within 2*context lines of each other.
+func hunks(ops []op) []hunk {
+	var out []hunk
+	oldLine, newLine := 0, 0
+	lineAt := make([][2]int, len(ops))
+	for i, o := range ops {
+		lineAt[i] = [2]int{oldLine, newLine}
+		if o.kind != opInsert {
+			oldLine++
+		}
+		if o.kind != opDelete {
+			newLine++
+		}
+	}
+
+	for i := 0; i < len(ops); {
+		if ops[i].kind == opEqual {
+			i++
+			continue
+		}
+		start := max(i-context, 0)
+		end := i
+		for j := i; j < len(ops); j++ {
+			if ops[j].kind != opEqual {
+				end = j + 1
+			} else if j-end >= 2*context {
+				break
+			}
+		}
+		end = min(end+context, len(ops))
+
+		h := hunk{start: start, end: end, oldStart: lineAt[start][0], newStart: lineAt[start][1]}
+		for _, o := range ops[start:end] {
+			if o.kind != opInsert {
+				h.oldN++
+			}
+			if o.kind != opDelete {
+				h.newN++
+			}
+		}
+		out = append(out, h)
+		i = end
+	}
+	return out
+}
+
+func writeHunk(out *strings.Builder, ops []op, h hunk) {
+	fmt.Fprintf(out, "@@ -%s +%s @@\n", hunkRange(h.oldStart, h.oldN), hunkRange(h.newStart, h.newN))
+	for _, o := range ops[h.start:h.end] {
+		switch o.kind {
+		case opEqual:
+			out.WriteString(" " + o.line)
+		case opDelete:
+			out.WriteString("-" + o.line)
+		case opInsert:
+			out.WriteString("+" + o.line)
+		}
+	}
+}
+
+// hunkRange formats a hunk's start line and length, with 1-based line numbers.
+func hunkRange(start, n int) string {
+	if n == 0 {
+		return fmt.Sprintf("%d,0", start)
+	}
+	if n == 1 {
+		return fmt.Sprintf("%d", start+1)
+	}
+	return fmt.Sprintf("%d,%d", start+1, n)
+}
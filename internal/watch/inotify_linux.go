@@ -0,0 +1,104 @@
+package watch
+
+import (
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"syscall"
+)
+
+const inotifyMask = syscall.IN_CREATE | syscall.IN_DELETE | syscall.IN_MODIFY | syscall.IN_CLOSE_WRITE |
+	syscall.IN_MOVED_FROM | syscall.IN_MOVED_TO | syscall.IN_DELETE_SELF | syscall.IN_MOVE_SELF
+
+// inotify watches the parent directory of every path, so files replaced by
+// an editor's rename-on-save are still seen, and every directory under the
+// paths that are directories.
+type inotify struct {
+	fd    int
+	file  *os.File
+	paths []string
+	ch    chan struct{}
+	errs  chan error
+}
+
+func newInotify(paths []string) (*inotify, error) {
+	fd, err := syscall.InotifyInit1(syscall.IN_CLOEXEC | syscall.IN_NONBLOCK)
+	if err != nil {
+		return nil, fmt.Errorf("starting inotify: %w", err)
+	}
+	n := &inotify{
+		fd:    fd,
+		file:  os.NewFile(uintptr(fd), "inotify"),
+		paths: paths,
+		ch:    make(chan struct{}, 1),
+		errs:  make(chan error, 1),
+	}
+	if err := n.rescan(); err != nil {
+		n.file.Close()
+		return nil, err
+	}
+	go n.read()
+	return n, nil
+}
+
+// read turns inotify events into change signals. The events themselves are
+// not decoded: the watcher confirms changes by checksum.
+func (n *inotify) read() {
+	buf := make([]byte, 64*1024)
+	for {
+		if _, err := n.file.Read(buf); err != nil {
+			if !errors.Is(err, os.ErrClosed) {
+				n.errs <- fmt.Errorf("reading inotify events: %w", err)
+			}
+			return
+		}
+		signal(n.ch)
+	}
+}
+
+func (n *inotify) rescan() error {
+	for _, path := range n.paths {
+		if err := n.add(existingAncestor(filepath.Dir(path))); err != nil {
+			return err
+		}
+		_ = filepath.WalkDir(path, func(p string, d fs.DirEntry, err error) error {
+			if err == nil && d.IsDir() {
+				// A directory can vanish mid-walk; the next rescan catches up.
+				_ = n.add(p)
+			}
+			return nil
+		})
+	}
+	return nil
+}
+
+// add watches dir. Adding a directory that is already watched is a no-op.
+func (n *inotify) add(dir string) error {
+	if _, err := syscall.InotifyAddWatch(n.fd, dir, inotifyMask); err != nil {
+		if errors.Is(err, syscall.ENOENT) {
+			return nil
+		}
+		return fmt.Errorf("watching %s: %w", dir, err)
+	}
+	return nil
+}
+
+func (n *inotify) events() <-chan struct{} { return n.ch }
+func (n *inotify) errors() <-chan error    { return n.errs }
+func (n *inotify) close() error            { return n.file.Close() }
+
+// existingAncestor returns dir or its nearest ancestor that exists.
+func existingAncestor(dir string) string {
+	for {
+		if _, err := os.Stat(dir); err == nil {
+			return dir
+		}
+		parent := filepath.Dir(dir)
+		if parent == dir {
+			return dir
+		}
+		dir = parent
+	}
+}
@@ -0,0 +1,10 @@
+//go:build !linux
+
+package watch
+
+import "errors"
+
+// newInotify is only available on Linux; other platforms poll.
+func newInotify(paths []string) (notifier, error) {
+	return nil, errors.New("inotify is not available on this platform")
+}
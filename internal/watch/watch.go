@@ -0,0 +1,160 @@
+package watch
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"io/fs"
+	"maps"
+	"os"
+	"path/filepath"
+	"time"
+)
+
+const (
+	DefaultDebounce = 300 * time.Millisecond
+	DefaultInterval = time.Second
+)
+
+// Options controls how a Watcher detects changes.
+type Options struct {
+	Debounce time.Duration // quiet period before reporting a change; DefaultDebounce when zero
+	Interval time.Duration // polling interval; DefaultInterval when zero
+	Poll     bool          // poll even where inotify is available
+}
+
+// Watcher reports changes to a set of files and directory trees. Changes are
+// confirmed by comparing content checksums, so events that leave the content
+// as it was (an editor touching a file, a sync rewriting identical content)
+// are not reported.
+type Watcher struct {
+	paths    []string
+	debounce time.Duration
+	n        notifier
+	backend  string
+	last     map[string]string // content when last reported
+}
+
+// notifier signals that something under the watched paths may have changed.
+type notifier interface {
+	events() <-chan struct{}
+	errors() <-chan error
+	// rescan updates the watch list after files or directories appear or vanish.
+	rescan() error
+	close() error
+}
+
+// New watches paths, which may be files or directories and need not exist
+// yet. It uses inotify where available and otherwise polls.
+func New(paths []string, opts Options) (*Watcher, error) {
+	if opts.Debounce <= 0 {
+		opts.Debounce = DefaultDebounce
+	}
+	if opts.Interval <= 0 {
+		opts.Interval = DefaultInterval
+	}
+	w := &Watcher{paths: paths, debounce: opts.Debounce, last: fingerprint(paths)}
+
+	if !opts.Poll {
+		n, err := newInotify(paths)
+		if err == nil {
+			w.n, w.backend = n, "inotify"
+			return w, nil
+		}
+	}
+	w.n, w.backend = newPoller(paths, opts.Interval), "polling"
+	return w, nil
+}
+
+// Backend names the change detection in use: "inotify" or "polling".
+func (w *Watcher) Backend() string { return w.backend }
+
+// Run calls onChange each time the watched content changes and then stays
+// unchanged for the debounce period, starting from the content when New was
+// called. It returns nil when ctx is done.
+func (w *Watcher) Run(ctx context.Context, onChange func()) error {
+	defer w.n.close()
+
+	var settle <-chan time.Time
+	for {
+		select {
+		case <-ctx.Done():
+			return nil
+		case err := <-w.n.errors():
+			return err
+		case <-w.n.events():
+			settle = time.After(w.debounce)
+		case <-settle:
+			settle = nil
+			if err := w.n.rescan(); err != nil {
+				return err
+			}
+			if now := fingerprint(w.paths); !maps.Equal(now, w.last) {
+				onChange()
+				// Take the state after onChange so its own writes are not reported.
+				w.last = fingerprint(w.paths)
+			}
+		}
+	}
+}
+
+// fingerprint maps every regular file under paths to a checksum of its content.
+func fingerprint(paths []string) map[string]string {
+	sums := make(map[string]string)
+	for _, root := range paths {
+		_ = filepath.WalkDir(root, func(path string, d fs.DirEntry, err error) error {
+			if err != nil || !d.Type().IsRegular() {
+				return nil
+			}
+			data, err := os.ReadFile(path)
+			if err != nil {
+				return nil
+			}
+			sum := sha256.Sum256(data)
+			sums[path] = hex.EncodeToString(sum[:])
+			return nil
+		})
+	}
+	return sums
+}
+
+// poller compares fingerprints on a timer.
+type poller struct {
+	ch   chan struct{}
+	errs chan error
+	stop chan struct{}
+}
+
+func newPoller(paths []string, interval time.Duration) *poller {
+	p := &poller{ch: make(chan struct{}, 1), errs: make(chan error), stop: make(chan struct{})}
+	last := fingerprint(paths)
+	go func() {
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-p.stop:
+				return
+			case <-ticker.C:
+				if now := fingerprint(paths); !maps.Equal(now, last) {
+					last = now
+					signal(p.ch)
+				}
+			}
+		}
+	}()
+	return p
+}
+
+func (p *poller) events() <-chan struct{} { return p.ch }
+func (p *poller) errors() <-chan error    { return p.errs }
+func (p *poller) rescan() error           { return nil }
+func (p *poller) close() error            { close(p.stop); return nil }
+
+// signal sends on ch without blocking; a pending signal already covers the change.
+func signal(ch chan struct{}) {
+	select {
+	case ch <- struct{}{}:
+	default:
+	}
+}
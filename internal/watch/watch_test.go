@@ -0,0 +1,129 @@
+package watch
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"runtime"
+	"testing"
+	"time"
+)
+
+// runWatcher starts w and returns a channel that receives once per reported change.
+func runWatcher(t *testing.T, w *Watcher) <-chan struct{} {
+	t.Helper()
+	ctx, cancel := context.WithCancel(context.Background())
+	changes := make(chan struct{}, 10)
+	done := make(chan error)
+	go func() { done <- w.Run(ctx, func() { changes <- struct{}{} }) }()
+	t.Cleanup(func() {
+		cancel()
+		if err := <-done; err != nil {
+			t.Errorf("Run: %v", err)
+		}
+	})
+	return changes
+}
+
+func expectChange(t *testing.T, changes <-chan struct{}, what string) {
+	t.Helper()
+	select {
+	case <-changes:
+	case <-time.After(5 * time.Second):
+		t.Fatalf("no change reported after %s", what)
+	}
+}
+
+func expectQuiet(t *testing.T, changes <-chan struct{}, what string) {
+	t.Helper()
+	select {
+	case <-changes:
+		t.Fatalf("unexpected change reported after %s", what)
+	case <-time.After(300 * time.Millisecond):
+	}
+}
+
+func write(t *testing.T, path, content string) {
+	t.Helper()
+	if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(path, []byte(content), 0o644); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func testWatch(t *testing.T, opts Options) {
+	root := t.TempDir()
+	inst := filepath.Join(root, "CLAUDE.md")
+	skills := filepath.Join(root, ".claude", "skills")
+	write(t, inst, "# One")
+
+	opts.Debounce, opts.Interval = 20*time.Millisecond, 20*time.Millisecond
+	w, err := New([]string{inst, skills}, opts)
+	if err != nil {
+		t.Fatal(err)
+	}
+	changes := runWatcher(t, w)
+
+	write(t, inst, "# Two")
+	expectChange(t, changes, "editing the instructions")
+
+	// Rewriting identical content is not a change.
+	write(t, inst, "# Two")
+	expectQuiet(t, changes, "rewriting identical content")
+
+	// Editors often save by writing a temporary file and renaming it into place.
+	tmp := filepath.Join(root, ".CLAUDE.md.swp")
+	write(t, tmp, "# Three")
+	if err := os.Rename(tmp, inst); err != nil {
+		t.Fatal(err)
+	}
+	expectChange(t, changes, "a rename-on-save")
+
+	// The skills directory did not exist when the watch started.
+	write(t, filepath.Join(skills, "review", "SKILL.md"), "review")
+	expectChange(t, changes, "adding a skill")
+
+	write(t, filepath.Join(skills, "review", "SKILL.md"), "review v2")
+	expectChange(t, changes, "editing a skill")
+
+	if err := os.RemoveAll(filepath.Join(skills, "review")); err != nil {
+		t.Fatal(err)
+	}
+	expectChange(t, changes, "removing a skill")
+
+	write(t, filepath.Join(root, "unrelated.txt"), "x")
+	expectQuiet(t, changes, "writing an unwatched file")
+}
+
+func TestWatchInotify(t *testing.T) {
+	if runtime.GOOS != "linux" {
+		t.Skip("inotify is Linux-only")
+	}
+	w, err := New(nil, Options{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if w.Backend() != "inotify" {
+		t.Skipf("inotify unavailable, using %s", w.Backend())
+	}
+	w.n.close()
+	testWatch(t, Options{})
+}
+
+func TestWatchPolling(t *testing.T) {
+	testWatch(t, Options{Poll: true})
+}
+
+func TestRunStopsOnCancel(t *testing.T) {
+	w, err := New([]string{t.TempDir()}, Options{Poll: true})
+	if err != nil {
+		t.Fatal(err)
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	if err := w.Run(ctx, func() { t.Error("unexpected change") }); err != nil {
+		t.Fatal(err)
+	}
+}